@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/testutils/pasemu"
+)
+
+type parameters struct {
+	addr          string        // address to listen on
+	certFile      string        // certificate file
+	keyFile       string        // private key file
+	certOut       string        // file to write generated certificate to
+	user          string        // user that can get tokens
+	password      string        // password of user
+	token         string        // static bearer token
+	pageSize      int           // default page size
+	tokenLifetime time.Duration // lifetime of issued tokens
+	log           bool          // whether to log requests
+}
+
+var errUsage error = errors.New("Usage error")
+
+// getParameters gets parsed command line parameters
+func getParameters() (*parameters, error) {
+
+	opt := &parameters{}
+	flag.StringVar(&opt.addr, "addr", "127.0.0.1:8443", "address to listen on.  Use it as the tenant URL, e.g., -server for secretcli")
+	flag.StringVar(&opt.certFile, "cert", "", "TLS certificate file.  A self-signed certificate is generated if it is not specified")
+	flag.StringVar(&opt.keyFile, "key", "", "TLS private key file.  Required if -cert is specified")
+	flag.StringVar(&opt.certOut, "certout", "", "file to write the generated self-signed certificate to, in PEM format.\n"+
+		"Clients can trust it by setting the environment variable SSL_CERT_FILE to this file")
+	flag.StringVar(&opt.user, "user", "", "user name (or client ID) that can get access tokens from /oauth2/token")
+	flag.StringVar(&opt.password, "password", "", "password (or client secret) of -user")
+	flag.StringVar(&opt.token, "token", "", "bearer token that is always accepted")
+	flag.IntVar(&opt.pageSize, "pagesize", 0, "number of secrets in each page of a secret list when limit is not specified.  Default is 10")
+	flag.DurationVar(&opt.tokenLifetime, "tokenlifetime", pasemu.DefaultTokenLifetime, "lifetime of access tokens")
+	flag.BoolVar(&opt.log, "log", false, "log requests")
+	flag.Parse()
+
+	if (opt.certFile == "") != (opt.keyFile == "") {
+		fmt.Println("-cert and -key must be specified together")
+		return nil, errUsage
+	}
+	if opt.certFile != "" && opt.certOut != "" {
+		fmt.Println("-certout cannot be used with -cert")
+		return nil, errUsage
+	}
+	if opt.user == "" && opt.token == "" {
+		fmt.Println("must specify -user and -password, or -token")
+		return nil, errUsage
+	}
+	if opt.user != "" && opt.password == "" {
+		fmt.Println("must specify -password for -user")
+		return nil, errUsage
+	}
+	return opt, nil
+}
+
+// getCertificate loads the certificate specified in the parameters, or generates a self-signed certificate
+func getCertificate(params *parameters) (tls.Certificate, error) {
+	if params.certFile != "" {
+		return tls.LoadX509KeyPair(params.certFile, params.keyFile)
+	}
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(params.addr); err == nil && host != "" {
+		hosts = append(hosts, host)
+	}
+	cert, certPEM, err := pasemu.NewCertificate(hosts...)
+	if err != nil {
+		return cert, err
+	}
+	if params.certOut != "" {
+		if err = ioutil.WriteFile(params.certOut, certPEM, 0644); err != nil {
+			return cert, err
+		}
+	}
+	return cert, nil
+}
+
+// logRequests logs each request handled by 'handler'
+func logRequests(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s\n", r.Method, r.URL.RequestURI())
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func main() {
+	params, err := getParameters()
+	if err != nil {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	emulator := pasemu.NewServer()
+	emulator.PageSize = params.pageSize
+	emulator.TokenLifetime = params.tokenLifetime
+	if params.user != "" {
+		emulator.AddUser(params.user, params.password)
+	}
+	if params.token != "" {
+		emulator.AddToken(params.token)
+	}
+
+	cert, err := getCertificate(params)
+	if err != nil {
+		fmt.Printf("Cannot get TLS certificate: %v\n", err)
+		os.Exit(1)
+	}
+
+	var handler http.Handler = emulator
+	if params.log {
+		handler = logRequests(handler)
+	}
+	server := &http.Server{
+		Addr:      params.addr,
+		Handler:   handler,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}
+
+	// shut down the server on interrupt
+	done := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+		close(done)
+	}()
+
+	log.Printf("PAS emulator listening on https://%s\n", params.addr)
+	err = server.ListenAndServeTLS("", "")
+	if err != http.ErrServerClosed {
+		fmt.Printf("Server error: %v\n", err)
+		os.Exit(1)
+	}
+	<-done
+}
@@ -8,10 +8,12 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/centrify/cloud-golang-sdk/oauth"
 	"github.com/centrify/platform-go-sdk/dmc"
 	"github.com/centrify/platform-go-sdk/secret"
+	"github.com/centrify/platform-go-sdk/vault"
 	"golang.org/x/term"
 )
 
@@ -24,6 +26,14 @@ type actions struct {
 	getMetaData  bool
 	list         bool
 	modify       bool
+	exportTree   bool
+	importTree   bool
+	move         bool
+	rename       bool
+	copyTree     bool
+	plan         bool
+	apply        bool
+	watch        bool
 }
 
 type operation int
@@ -36,6 +46,14 @@ const (
 	getMetaData
 	list
 	modify
+	exportTree
+	importTree
+	move
+	rename
+	copyTree
+	plan
+	apply
+	watch
 )
 
 // Parameters defines the configuration parameters
@@ -44,7 +62,7 @@ type Parameters struct {
 	ConfigFile string
 	// URL where the secret is stored
 	ServerPath string `json:"server"`
-	// server type: pas, tss or dsv.  Only pas is supported in current version
+	// server type: pas, hcvault, tss or dsv
 	ServerType string `json:"servertype"`
 	// whether to use DMC or not
 	UseDMC bool `json:"useDMC"`
@@ -62,9 +80,13 @@ type Parameters struct {
 	Username string `json:"user"`
 	// password
 	Password string `json:"password"`
+	// HashiCorp Vault token
+	Token string `json:"token"`
 
 	// Path to secret object/folder
 	SecretPath string `json:"name"`
+	// whether SecretPath is the ID of secret object/folder
+	ByID bool `json:"id"`
 	// optional description of secret
 	Description string `json:"description"`
 
@@ -76,14 +98,34 @@ type Parameters struct {
 	JSONDataFile string `json:"jsonfile"`
 	// Keyvalue secret value stored in a JSON string
 	JSONString string `json:"jsonstring"`
+	// whether to list the contents of subfolders, or to delete a folder with its contents
+	Recursive bool `json:"recursive"`
+	// whether to only list the secrets/folders that would be deleted or copied
+	DryRun bool `json:"dryrun"`
+	// file to export secrets to, or import secrets from, or manifest file, or binary value of secret
+	File string `json:"file"`
+	// format of export file: json or yaml
+	Format string `json:"format"`
+	// optional passphrase to encrypt the export file, or to decrypt the import file
+	Passphrase string `json:"passphrase"`
+	// what to do when an imported secret already exists: skip, overwrite or fail
+	Conflict string `json:"conflict"`
+	// new path of secret/folder to move or copy to, or its new name
+	Destination string `json:"to"`
+	// whether to delete the secrets/folders that are not in the manifest
+	Prune bool `json:"prune"`
+	// interval between polls with -watch, e.g., 30s
+	Interval string `json:"interval"`
 
 	// These parameters are derived from other parameters and not specified in the
 	// command line or in the configuration file.
 	// type of secret operation
 	Operation operation
-	// Type of secret.  Must be one of "text" or "keyvalue"
+	// Type of secret.  Must be one of "text", "keyvalue" or "file"
 	SecretType string
 	KVSecret   map[string]string // content of keyvalue pair secret
+	// interval between polls with -watch.  0 means the default interval
+	WatchInterval time.Duration
 
 	// These parameters are related to HTTP operations
 	// whether to enable debug messages or not
@@ -109,7 +151,21 @@ Either JsonFile or JsonString must be specified when creating/modifying a keyval
 const usageHeaders = `Specify extra HTTP headers as a comma-separated list.  Each header is specified as <name>:<value>.
 Comma (,) and colon (:) are not allowed as part of the header name or value. 
 Example: "X-TZOFF:480, X-Special:Marker`
-const usageServerType = "Server type: pas for Centrify PAS"
+const usageServerType = `Server type: pas for Centrify PAS, hcvault for HashiCorp Vault, dsv for DevOps Secrets Vault,
+tss for Secret Server`
+const usageToken = `HashiCorp Vault token, DSV or Secret Server access token.  The environment variable VAULT_TOKEN,
+DSV_TOKEN or TSS_TOKEN is used if it is not specified.  Not used for HashiCorp Vault when -useDMC is specified`
+const usagePassphrase = `passphrase to encrypt the export file with -export, or to decrypt the file with -import.
+The export file is not encrypted if it is not specified`
+const usageConflict = `what to do with -import or -copy when a secret already exists: skip (default), overwrite or fail`
+const usageRecursive = `list contents of subfolders as well with -list, or delete a folder including its contents with -delete`
+const usageDestination = `new path of secret object/folder with -move or -copy, or its new name with -rename`
+const usageFile = `file to export secrets to, or import secrets from, or manifest file.  Required for -export, -import,
+-plan and -apply.  With -create or -modify, the file that contains the binary value of the secret.  With -get, the file
+to save a binary value to`
+const usageID = `treat -name as the ID of the secret object/folder with -get, -getmetadata, -modify or -delete,
+e.g., an ID shown by -list`
+const usageDryRun = `only list the secrets and folders that -delete -recursive would delete, or that -copy would change`
 
 // loadConfigFromFile loads the configuration parameters from a json file
 func loadConfigFromFile(path string, result *Parameters) error {
@@ -142,13 +198,24 @@ func getConfiguration() (*Parameters, error) {
 	flag.StringVar(&cliOpt.AppID, "appid", "", "application ID")
 	flag.StringVar(&cliOpt.Username, "user", "", "username")
 	flag.StringVar(&cliOpt.Password, "password", "", "password")
+	flag.StringVar(&cliOpt.Token, "token", "", usageToken)
 	flag.StringVar(&cliOpt.ClientID, "clientid", "", "clientID")
 	flag.StringVar(&cliOpt.ClientSecret, "clientsecret", "", "client Secret")
 	flag.StringVar(&cliOpt.SecretPath, "name", "", "Path of secret")
+	flag.BoolVar(&cliOpt.ByID, "id", false, usageID)
 	flag.StringVar(&cliOpt.Description, "description", "", "optional description of secret")
 	flag.StringVar(&cliOpt.TextValue, "text", "", usageText)
 	flag.StringVar(&cliOpt.JSONDataFile, "jsonfile", "", usageJSONFile)
 	flag.StringVar(&cliOpt.JSONString, "jsonstring", "", usageJSONString)
+	flag.BoolVar(&cliOpt.Recursive, "recursive", false, usageRecursive)
+	flag.BoolVar(&cliOpt.DryRun, "dryrun", false, usageDryRun)
+	flag.StringVar(&cliOpt.File, "file", "", usageFile)
+	flag.StringVar(&cliOpt.Format, "format", "", "format of export file: json (default) or yaml")
+	flag.StringVar(&cliOpt.Passphrase, "passphrase", "", usagePassphrase)
+	flag.StringVar(&cliOpt.Conflict, "conflict", "", usageConflict)
+	flag.StringVar(&cliOpt.Destination, "to", "", usageDestination)
+	flag.BoolVar(&cliOpt.Prune, "prune", false, "delete secrets and folders that are not in the manifest with -plan or -apply")
+	flag.StringVar(&cliOpt.Interval, "interval", "", "interval between polls with -watch, e.g., 30s (default 1m)")
 	flag.BoolVar(&cliOpt.Debug, "debug", false, "Enable debug messages")
 	flag.StringVar(&cliOpt.UserAgent, "useragent", "", "specify a different user agent in HTTP header")
 	flag.StringVar(&cliOpt.ExtraHeaders, "headers", "", usageHeaders)
@@ -164,6 +231,14 @@ func getConfiguration() (*Parameters, error) {
 	flag.BoolVar(&action.getMetaData, "getmetadata", false, "get secret metadata")
 	flag.BoolVar(&action.list, "list", false, "list folder contents")
 	flag.BoolVar(&action.modify, "modify", false, "modify secret")
+	flag.BoolVar(&action.exportTree, "export", false, "export secrets in folder to a file")
+	flag.BoolVar(&action.importTree, "import", false, "import secrets from a file to folder")
+	flag.BoolVar(&action.move, "move", false, "move secret object/folder to the path specified in -to")
+	flag.BoolVar(&action.rename, "rename", false, "rename secret object/folder to the name specified in -to")
+	flag.BoolVar(&action.copyTree, "copy", false, "copy secret object/folder, including its contents, to the path specified in -to")
+	flag.BoolVar(&action.plan, "plan", false, "list the changes that make folder match the manifest specified in -file")
+	flag.BoolVar(&action.apply, "apply", false, "change folder to match the manifest specified in -file")
+	flag.BoolVar(&action.watch, "watch", false, "report changes of secret object, or of secrets in folder, until interrupted")
 
 	flag.Parse()
 
@@ -201,6 +276,15 @@ func getConfiguration() (*Parameters, error) {
 	return options, nil
 }
 
+// getHCVaultToken returns the HashiCorp Vault token for the user
+func getHCVaultToken(cfg *Parameters) (string, error) {
+	if cfg.UseDMC {
+		// get vault token for the machine account
+		return vault.GetHashiVaultToken(cfg.Scope, cfg.ServerPath)
+	}
+	return cfg.Token, nil
+}
+
 // getAccessToken returns the Oauth access token for the user
 func getAccessToken(cfg *Parameters) (string, error) {
 	if cfg.UseDMC {
@@ -259,9 +343,15 @@ func mergeParameters(cliOpt *Parameters, cfgOpt *Parameters) {
 	if cliOpt.Password != "" {
 		cfgOpt.Password = cliOpt.Password
 	}
+	if cliOpt.Token != "" {
+		cfgOpt.Token = cliOpt.Token
+	}
 	if cliOpt.SecretPath != "" {
 		cfgOpt.SecretPath = cliOpt.SecretPath
 	}
+	if cliOpt.ByID {
+		cfgOpt.ByID = true
+	}
 	if cliOpt.Description != "" {
 		cfgOpt.Description = cliOpt.Description
 	}
@@ -274,6 +364,33 @@ func mergeParameters(cliOpt *Parameters, cfgOpt *Parameters) {
 	if cliOpt.JSONString != "" {
 		cfgOpt.JSONString = cliOpt.JSONString
 	}
+	if cliOpt.Recursive {
+		cfgOpt.Recursive = true
+	}
+	if cliOpt.DryRun {
+		cfgOpt.DryRun = true
+	}
+	if cliOpt.File != "" {
+		cfgOpt.File = cliOpt.File
+	}
+	if cliOpt.Format != "" {
+		cfgOpt.Format = cliOpt.Format
+	}
+	if cliOpt.Passphrase != "" {
+		cfgOpt.Passphrase = cliOpt.Passphrase
+	}
+	if cliOpt.Conflict != "" {
+		cfgOpt.Conflict = cliOpt.Conflict
+	}
+	if cliOpt.Destination != "" {
+		cfgOpt.Destination = cliOpt.Destination
+	}
+	if cliOpt.Prune {
+		cfgOpt.Prune = true
+	}
+	if cliOpt.Interval != "" {
+		cfgOpt.Interval = cliOpt.Interval
+	}
 	if cliOpt.UserAgent != "" {
 		cfgOpt.UserAgent = cliOpt.UserAgent
 	}
@@ -325,13 +442,45 @@ func checkOperationSelection(options *Parameters, selAction *actions) bool {
 		selOperation = modify
 		optCount++
 	}
+	if selAction.exportTree {
+		selOperation = exportTree
+		optCount++
+	}
+	if selAction.importTree {
+		selOperation = importTree
+		optCount++
+	}
+	if selAction.move {
+		selOperation = move
+		optCount++
+	}
+	if selAction.rename {
+		selOperation = rename
+		optCount++
+	}
+	if selAction.copyTree {
+		selOperation = copyTree
+		optCount++
+	}
+	if selAction.plan {
+		selOperation = plan
+		optCount++
+	}
+	if selAction.apply {
+		selOperation = apply
+		optCount++
+	}
+	if selAction.watch {
+		selOperation = watch
+		optCount++
+	}
 
 	if optCount > 1 {
-		fmt.Println("Can only specify one of -create, -createFolder, -delete, -get, -getMetaData, -list or -modify")
+		fmt.Println("Can only specify one of -create, -createFolder, -delete, -get, -getMetaData, -list, -modify, -export, -import, -move, -rename, -copy, -plan, -apply or -watch")
 		return false
 	}
 	if optCount == 0 {
-		fmt.Println("Must specify one of -create, -createFolder, -delete, -get, -getMetaData, -list or -modify")
+		fmt.Println("Must specify one of -create, -createFolder, -delete, -get, -getMetaData, -list, -modify, -export, -import, -move, -rename, -copy, -plan, -apply or -watch")
 		return false
 	}
 	options.Operation = selOperation
@@ -368,15 +517,54 @@ func checkPasCred(options *Parameters) bool {
 	return true
 }
 
+// check credential requirement for HashiCorp Vault
+func checkHCVaultCred(options *Parameters) bool {
+	if options.UseDMC {
+		if options.Scope == "" {
+			fmt.Println("Must specify scope using -scope")
+			return false
+		}
+		return true
+	}
+	if options.Token == "" {
+		options.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if options.Token == "" {
+		fmt.Println("must specify Vault token using -token or VAULT_TOKEN")
+		return false
+	}
+	return true
+}
+
+// check credential requirement for DevOps Secrets Vault and Secret Server, where the access token
+// is specified in -token or the environment variable 'envName'
+func checkTokenCred(options *Parameters, envName string) bool {
+	if options.Token == "" {
+		options.Token = os.Getenv(envName)
+	}
+	if options.Token == "" {
+		fmt.Printf("must specify access token using -token or %s\n", envName)
+		return false
+	}
+	return true
+}
+
 // checkCredSpecified checks if all information required to authenticate the user is specified
 func checkCredSpecified(options *Parameters) bool {
 	if options.ServerType == secret.ServerPAS {
 		return checkPasCred(options)
 	}
+	if options.ServerType == secret.ServerHCVault {
+		return checkHCVaultCred(options)
+	}
+	if options.ServerType == secret.ServerDSV {
+		return checkTokenCred(options, "DSV_TOKEN")
+	}
+	if options.ServerType == secret.ServerTSS {
+		return checkTokenCred(options, "TSS_TOKEN")
+	}
 
 	// Note:  checkRequiredParameters already check whether server type is correct
-	// Need to add additional credential check functions for server types such as DSV/TSS when support
-	// is added
 	fmt.Printf("%s is not supported\n", options.ServerType)
 	return true
 }
@@ -397,21 +585,60 @@ func checkRequiredParameters(options *Parameters) bool {
 		return false
 	}
 	options.ServerType = strings.TrimSpace(strings.ToLower(options.ServerType))
-	if options.ServerType != secret.ServerPAS {
-		fmt.Println("must specify \"pas\" as servertype")
+	if options.ServerType != secret.ServerPAS && options.ServerType != secret.ServerHCVault &&
+		options.ServerType != secret.ServerDSV && options.ServerType != secret.ServerTSS {
+		fmt.Println("must specify \"pas\", \"hcvault\", \"dsv\" or \"tss\" as servertype")
 	}
 	return true
 }
 
 func checkOptionalParameters(options *Parameters) bool {
 
+	if options.ByID && options.Operation != get && options.Operation != getMetaData &&
+		options.Operation != modify && options.Operation != delete {
+		fmt.Println("-id can only be specified with -get, -getmetadata, -modify or -delete")
+		return false
+	}
+
+	if options.Operation == exportTree || options.Operation == importTree {
+		return checkTransferParameters(options)
+	}
+
+	if options.Operation == plan || options.Operation == apply {
+		if options.File == "" {
+			fmt.Println("must specify the manifest file using -file")
+			return false
+		}
+		return true
+	}
+
+	if options.Operation == watch {
+		if options.Interval != "" {
+			interval, err := time.ParseDuration(options.Interval)
+			if err != nil || interval <= 0 {
+				fmt.Printf("[%s] is not a valid interval for -interval\n", options.Interval)
+				return false
+			}
+			options.WatchInterval = interval
+		}
+		return true
+	}
+
+	if options.Operation == move || options.Operation == rename || options.Operation == copyTree {
+		if options.Destination == "" {
+			fmt.Println("must specify the new path or name using -to")
+			return false
+		}
+		return checkConflictPolicy(options)
+	}
+
 	if options.Operation != create && options.Operation != modify {
 		// no need to check additional parameters
 		return true
 	}
 
 	// for create and modify:
-	// 1. Only one and only one of TextValue, JSONDataFile or JSONString must be specified
+	// 1. Only one and only one of TextValue, JSONDataFile, JSONString or File must be specified
 
 	var nSources int
 	var secretType string
@@ -430,8 +657,12 @@ func checkOptionalParameters(options *Parameters) bool {
 		nSources++
 		secretType = secret.SecretTypeKV
 	}
+	if options.File != "" {
+		nSources++
+		secretType = secret.SecretTypeFile
+	}
 	if nSources != 1 {
-		fmt.Printf("Must specify one and only one of -text, -jsonfile or -jsonstring")
+		fmt.Printf("Must specify one and only one of -text, -jsonfile, -jsonstring or -file")
 		return false
 	}
 	options.SecretType = secretType
@@ -460,6 +691,32 @@ func checkOptionalParameters(options *Parameters) bool {
 	return true
 }
 
+// checkTransferParameters checks the parameters for export and import
+func checkTransferParameters(options *Parameters) bool {
+	if options.File == "" {
+		fmt.Println("must specify the file to export to or import from using -file")
+		return false
+	}
+	switch options.Format {
+	case "", secret.ExportFormatJSON, secret.ExportFormatYAML:
+	default:
+		fmt.Printf("Format must be %s or %s\n", secret.ExportFormatJSON, secret.ExportFormatYAML)
+		return false
+	}
+	return checkConflictPolicy(options)
+}
+
+// checkConflictPolicy checks the conflict policy specified in -conflict
+func checkConflictPolicy(options *Parameters) bool {
+	switch secret.ConflictPolicy(options.Conflict) {
+	case "", secret.ConflictSkip, secret.ConflictOverwrite, secret.ConflictFail:
+	default:
+		fmt.Printf("Conflict policy must be %s, %s or %s\n", secret.ConflictSkip, secret.ConflictOverwrite, secret.ConflictFail)
+		return false
+	}
+	return true
+}
+
 // parseExtraHeaders parses the user specified comma separated list into a string map
 func parseExtraHeaders(options *Parameters) bool {
 	if options.ExtraHeaders == "" {
@@ -490,8 +747,16 @@ func (op operation) String() string {
 		"getmetadata",
 		"list",
 		"modify",
-	}
-	if op >= create && op <= modify {
+		"export",
+		"import",
+		"move",
+		"rename",
+		"copy",
+		"plan",
+		"apply",
+		"watch",
+	}
+	if op >= create && op <= watch {
 		return names[op]
 	}
 	return "unknown"
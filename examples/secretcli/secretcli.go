@@ -1,13 +1,18 @@
 // secretcli is a sample program that demonstrates how to use the secret package
-// to manage secrets stored in Centrify PAS, Thycotic Secret Server and Thycotic Devops Secret Vault.
+// to manage secrets stored in Centrify PAS, HashiCorp Vault, Thycotic Secret Server and Thycotic Devops Secret Vault.
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
 
 	"golang.org/x/sys/unix"
 
@@ -18,11 +23,13 @@ import (
 // There are the exit status code and the corresponding errors:
 // EPERM (1): ErrSecretTypeNotSupported, ErrCannotModifySecretType, ErrCannotModifySecretFolder
 // ENOENT (2):	ErrFolderNotFound, ErrSecretNotFound
-// EACCES (13):	ErrNoCreatePermission, ErrNoDeletePermission, ErrNoModifyPermission, ErrNoGetMetaDataPermission, ErrNoRetrievePermission
+// EIO (5): ErrImportIncomplete, ErrMoveIncomplete, ErrDeleteIncomplete, ErrCopyIncomplete
+// EACCES (13):	ErrNoCreatePermission, ErrNoDeletePermission, ErrNoModifyPermission, ErrNoGetMetaDataPermission, ErrNoRetrievePermission, ErrBadPassphrase, ErrPassphraseRequired
 // EEXIST (17): ErrExists, ErrDeletedSecretExists
 // ENOTDIR (20): ErrNotSecretFolder
 // EISDIR (21): ErrNotSecretObject
-// EINVAL (22): ErrBadPathName, ErrBadServerType
+// EINVAL (22): ErrBadPathName, ErrBadServerType, ErrInvalidListOption, ErrInvalidExportDocument, ErrInvalidExportOption, ErrInvalidManifest
+// EFBIG (27): ErrSecretTooLarge
 // ENOSYS (38): ErrNotImplementedYet
 // ENOTEMPTY (39): ErrFolderNotEmpty
 // EPROTO(72): ErrUnexpectedResponse
@@ -37,7 +44,8 @@ func convertErrToExitStatus(err error) int {
 		return int(unix.ENOENT)
 	} else if errors.Is(err, secret.ErrNoCreatePermission) || errors.Is(err, secret.ErrNoDeletePermission) ||
 		errors.Is(err, secret.ErrNoModifyPermission) || errors.Is(err, secret.ErrNoRetrievePermission) ||
-		errors.Is(err, secret.ErrNoGetMetaDataPermission) {
+		errors.Is(err, secret.ErrNoGetMetaDataPermission) || errors.Is(err, secret.ErrBadPassphrase) ||
+		errors.Is(err, secret.ErrPassphraseRequired) {
 		return int(unix.EACCES)
 	} else if errors.Is(err, secret.ErrExists) || errors.Is(err, secret.ErrDeletedSecretExists) {
 		return int(unix.EEXIST)
@@ -45,14 +53,21 @@ func convertErrToExitStatus(err error) int {
 		return int(unix.ENOTDIR)
 	} else if errors.Is(err, secret.ErrNotSecretObject) {
 		return int(unix.EISDIR)
-	} else if errors.Is(err, secret.ErrBadPathName) || errors.Is(err, secret.ErrBadServerType) {
+	} else if errors.Is(err, secret.ErrBadPathName) || errors.Is(err, secret.ErrBadServerType) ||
+		errors.Is(err, secret.ErrInvalidListOption) || errors.Is(err, secret.ErrInvalidExportDocument) ||
+		errors.Is(err, secret.ErrInvalidExportOption) || errors.Is(err, secret.ErrInvalidManifest) {
 		return int(unix.EINVAL)
+	} else if errors.Is(err, secret.ErrSecretTooLarge) {
+		return int(unix.EFBIG)
 	} else if errors.Is(err, secret.ErrNotImplementedYet) {
 		return int(unix.ENOSYS)
 	} else if errors.Is(err, secret.ErrFolderNotEmpty) {
 		return int(unix.ENOTEMPTY)
 	} else if errors.Is(err, secret.ErrUnexpectedResponse) {
 		return int(unix.EPROTO)
+	} else if errors.Is(err, secret.ErrImportIncomplete) || errors.Is(err, secret.ErrMoveIncomplete) ||
+		errors.Is(err, secret.ErrDeleteIncomplete) || errors.Is(err, secret.ErrCopyIncomplete) {
+		return int(unix.EIO)
 	}
 	return -2 // unknown error
 }
@@ -80,9 +95,18 @@ func main() {
 			os.Exit(-3)
 		}
 
+	} else if params.ServerType == secret.ServerHCVault {
+		accessToken, err = getHCVaultToken(params)
+		if err != nil {
+			fmt.Printf("Error in getting Vault token: %v\n", err)
+			os.Exit(-3)
+		}
+	} else if params.ServerType == secret.ServerDSV || params.ServerType == secret.ServerTSS {
+		accessToken = params.Token
 	}
 	// create a client handle to access secrets backend
-	cl, err = secret.NewSecretClient(params.ServerPath, params.ServerType, accessToken, clientFactory)
+	cl, err = secret.NewSecretClient(params.ServerPath, params.ServerType, accessToken, clientFactory,
+		secret.WithBinaryValues())
 	if err != nil {
 		fmt.Printf("Error in setting up client: %v\n", err)
 		os.Exit(-3)
@@ -101,6 +125,12 @@ func main() {
 		cl.AddDefaultHeaders(params.ExtraHeadersMap)
 	}
 
+	if params.ByID {
+		if err = resolveID(cl, params); err != nil {
+			os.Exit(convertErrToExitStatus(err))
+		}
+	}
+
 	switch params.Operation {
 	case create:
 		err = doCreate(cl, params)
@@ -122,19 +152,62 @@ func main() {
 
 	case modify:
 		err = doModify(cl, params)
+
+	case exportTree:
+		err = doExport(cl, params)
+
+	case importTree:
+		err = doImport(cl, params)
+
+	case move:
+		err = doMove(cl, params)
+
+	case rename:
+		err = doRename(cl, params)
+
+	case copyTree:
+		err = doCopy(cl, params)
+
+	case plan:
+		_, err = doPlan(cl, params)
+
+	case apply:
+		err = doApply(cl, params)
+
+	case watch:
+		err = doWatch(cl, params)
 	}
 	os.Exit(convertErrToExitStatus(err))
 }
 
+// resolveID replaces the ID in params.SecretPath with the current path of the secret object/folder
+func resolveID(cl secret.Secret, params *Parameters) error {
+	fmt.Printf("Getting path of ID [%s]\n", params.SecretPath)
+	path, err := secret.ResolveRef(cl, secret.ByID(params.SecretPath))
+	if err != nil {
+		fmt.Printf("Error in getting path of ID: %v\n", err)
+		return err
+	}
+	params.SecretPath = path
+	return nil
+}
+
 func doCreate(cl secret.Secret, params *Parameters) error {
 	fmt.Printf("Creating secret of type %s in path [%s]\n", params.SecretType, params.SecretPath)
 	var success bool
 	var id string
 	var r *http.Response
 	var err error
-	if params.SecretType == secret.SecretTypeKV {
+	switch params.SecretType {
+	case secret.SecretTypeKV:
 		success, id, r, err = cl.Create(params.SecretPath, params.Description, params.KVSecret)
-	} else {
+	case secret.SecretTypeFile:
+		var f *os.File
+		if f, err = os.Open(params.File); err == nil {
+			success, id, r, err = cl.Create(params.SecretPath, params.Description, f)
+			f.Close()
+		}
+	default:
 		success, id, r, err = cl.Create(params.SecretPath, params.Description, params.TextValue)
 	}
 	if success {
@@ -174,6 +247,9 @@ func doCreateFolder(cl secret.Secret, params *Parameters) error {
 	return err
 }
 func doDelete(cl secret.Secret, params *Parameters) error {
+	if params.Recursive {
+		return doDeleteTree(cl, params)
+	}
 
 	fmt.Printf("Deleting secret in path [%s]\n", params.SecretPath)
 	r, reqError := cl.Delete(params.SecretPath)
@@ -190,6 +266,33 @@ func doDelete(cl secret.Secret, params *Parameters) error {
 
 	return reqError
 }
+
+func doDeleteTree(cl secret.Secret, params *Parameters) error {
+	if params.DryRun {
+		fmt.Printf("Listing secrets to delete in path [%s] and its subfolders\n", params.SecretPath)
+	} else {
+		fmt.Printf("Deleting secrets in path [%s] and its subfolders\n", params.SecretPath)
+	}
+	results, err := secret.DeleteTree(cl, params.SecretPath, &secret.DeleteTreeOptions{DryRun: params.DryRun})
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("%s\tType: %s\tPath: %s\tError: %v\n", result.Action, result.Type, result.Path, result.Err)
+		} else {
+			fmt.Printf("%s\tType: %s\tPath: %s\n", result.Action, result.Type, result.Path)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error in deleting secrets: %v\n", err)
+		return err
+	}
+	if params.DryRun {
+		fmt.Printf("Number of items to delete: %d\n", len(results))
+	} else {
+		fmt.Printf("Number of items deleted: %d\n", len(results))
+	}
+	return nil
+}
+
 func doGet(cl secret.Secret, params *Parameters) error {
 	fmt.Printf("Getting secret from path [%s]\n", params.SecretPath)
 	value, r, err := cl.Get(params.SecretPath)
@@ -204,6 +307,18 @@ func doGet(cl secret.Secret, params *Parameters) error {
 				fmt.Printf("Key: %s\tValue:%v\n", k, v)
 			}
 			return nil
+		case []byte:
+			data := value.([]byte)
+			if params.File == "" {
+				fmt.Printf("Secret is binary data of %d bytes.  Use -file to save it\n", len(data))
+				return nil
+			}
+			if err = ioutil.WriteFile(params.File, data, 0600); err != nil {
+				fmt.Printf("Error in writing file %s: %v\n", params.File, err)
+				return err
+			}
+			fmt.Printf("Secret is binary data of %d bytes.  Saved to file %s\n", len(data), params.File)
+			return nil
 		default:
 			fmt.Printf("Unknown secret type: %T\n", value)
 			return secret.ErrUnexpectedResponse
@@ -222,12 +337,30 @@ func doGetMetaData(cl secret.Secret, params *Parameters) error {
 		fmt.Printf("Metadata returned:\nType: %s\n", value.Type)
 		fmt.Printf("ID: %s\n", value.ID)
 		fmt.Printf("CRN: %s\n", value.CRN)
+		fmt.Printf("Description: %s\n", value.Description)
 		fmt.Printf("Created: %v\n", value.WhenCreated)
+		if value.CreatedBy != "" {
+			fmt.Printf("Created by: %s\n", value.CreatedBy)
+		}
 		if value.WhenModified.IsZero() {
 			fmt.Println("Not modified")
 		} else {
 			fmt.Printf("Last modified time: %v\n", value.WhenModified)
 		}
+		if value.ModifiedBy != "" {
+			fmt.Printf("Last modified by: %s\n", value.ModifiedBy)
+		}
+		if value.Version != "" {
+			fmt.Printf("Version: %s\n", value.Version)
+		}
+		keys := make([]string, 0, len(value.Attributes))
+		for k := range value.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("%s: %s\n", k, value.Attributes[k])
+		}
 		return nil
 	}
 	fmt.Printf("Error in getting secret metadata: %v\n", err)
@@ -239,6 +372,9 @@ func doGetMetaData(cl secret.Secret, params *Parameters) error {
 }
 
 func doList(cl secret.Secret, params *Parameters) error {
+	if params.Recursive {
+		return doListRecursive(cl, params)
+	}
 	fmt.Printf("Listing contents of [%s]\n", params.SecretPath)
 	items, r, err := cl.List(params.SecretPath)
 	if err != nil {
@@ -254,15 +390,46 @@ func doList(cl secret.Secret, params *Parameters) error {
 	}
 	return nil
 }
+
+func doListRecursive(cl secret.Secret, params *Parameters) error {
+	fmt.Printf("Listing contents of [%s] and its subfolders\n", params.SecretPath)
+	count := 0
+	err := secret.Walk(cl, params.SecretPath, func(path string, info *secret.MetaData, err error) error {
+		if err != nil {
+			fmt.Printf("Error in listing [%s]: %v\n", path, err)
+			return err
+		}
+		if path == params.SecretPath {
+			// do not show the folder being listed
+			return nil
+		}
+		count++
+		fmt.Printf("ID: %s\tType: %s\tPath: %s\n", info.ID, info.Type, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Number of items in tree: %d\n", count)
+	return nil
+}
+
 func doModify(cl secret.Secret, params *Parameters) error {
 	fmt.Printf("Modifying secret of type %s in path [%s]\n", params.SecretType, params.SecretPath)
 	var success bool
 	var id string
 	var r *http.Response
 	var err error
-	if params.SecretType == secret.SecretTypeKV {
+	switch params.SecretType {
+	case secret.SecretTypeKV:
 		success, id, r, err = cl.Modify(params.SecretPath, params.Description, params.KVSecret)
-	} else {
+	case secret.SecretTypeFile:
+		var f *os.File
+		if f, err = os.Open(params.File); err == nil {
+			success, id, r, err = cl.Modify(params.SecretPath, params.Description, f)
+			f.Close()
+		}
+	default:
 		success, id, r, err = cl.Modify(params.SecretPath, params.Description, params.TextValue)
 	}
 	if success {
@@ -277,3 +444,190 @@ func doModify(cl secret.Secret, params *Parameters) error {
 	}
 	return err
 }
+
+func doExport(cl secret.Secret, params *Parameters) error {
+	fmt.Printf("Exporting contents of [%s] to file %s\n", params.SecretPath, params.File)
+	f, err := os.OpenFile(params.File, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		fmt.Printf("Error in creating file: %v\n", err)
+		return err
+	}
+	opts := &secret.ExportOptions{
+		Format:     params.Format,
+		Passphrase: params.Passphrase,
+	}
+	err = secret.Export(cl, params.SecretPath, f, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Printf("Error in exporting secrets: %v\n", err)
+		return err
+	}
+	fmt.Println("Secrets exported.")
+	return nil
+}
+
+func doImport(cl secret.Secret, params *Parameters) error {
+	fmt.Printf("Importing secrets from file %s to [%s]\n", params.File, params.SecretPath)
+	f, err := os.Open(params.File)
+	if err != nil {
+		fmt.Printf("Error in opening file: %v\n", err)
+		return err
+	}
+	defer f.Close()
+
+	opts := &secret.ImportOptions{
+		Passphrase: params.Passphrase,
+		OnConflict: secret.ConflictPolicy(params.Conflict),
+	}
+	results, err := secret.Import(cl, params.SecretPath, f, opts)
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("%s\tType: %s\tPath: %s\tError: %v\n", result.Action, result.Type, result.Path, result.Err)
+		} else {
+			fmt.Printf("%s\tType: %s\tPath: %s\n", result.Action, result.Type, result.Path)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error in importing secrets: %v\n", err)
+		return err
+	}
+	fmt.Printf("Number of items imported: %d\n", len(results))
+	return nil
+}
+
+func doMove(cl secret.Secret, params *Parameters) error {
+	fmt.Printf("Moving [%s] to [%s]\n", params.SecretPath, params.Destination)
+	id, r, err := cl.Move(params.SecretPath, params.Destination)
+	return reportMove(id, r, err)
+}
+
+func doRename(cl secret.Secret, params *Parameters) error {
+	fmt.Printf("Renaming [%s] to [%s]\n", params.SecretPath, params.Destination)
+	id, r, err := cl.Rename(params.SecretPath, params.Destination)
+	return reportMove(id, r, err)
+}
+
+func doCopy(cl secret.Secret, params *Parameters) error {
+	if params.DryRun {
+		fmt.Printf("Comparing [%s] with [%s]\n", params.SecretPath, params.Destination)
+	} else {
+		fmt.Printf("Copying [%s] to [%s]\n", params.SecretPath, params.Destination)
+	}
+	opts := &secret.CopyOptions{
+		OnConflict: secret.ConflictPolicy(params.Conflict),
+		Preview:    params.DryRun,
+	}
+	results, err := secret.Copy(cl, params.SecretPath, cl, params.Destination, opts)
+	for _, result := range results {
+		fmt.Printf("%s\tType: %s\tPath: %s", result.Action, result.Type, result.DstPath)
+		if len(result.Diff) > 0 {
+			fmt.Printf("\tDifferences: %s", strings.Join(result.Diff, ", "))
+		}
+		if result.Err != nil {
+			fmt.Printf("\tError: %v", result.Err)
+		}
+		fmt.Println()
+	}
+	if err != nil {
+		fmt.Printf("Error in copying secrets: %v\n", err)
+		return err
+	}
+	if params.DryRun {
+		fmt.Printf("Number of items compared: %d\n", len(results))
+	} else {
+		fmt.Printf("Number of items copied: %d\n", len(results))
+	}
+	return nil
+}
+
+func doPlan(cl secret.Secret, params *Parameters) (*secret.Plan, error) {
+	fmt.Printf("Comparing [%s] with manifest %s\n", params.SecretPath, params.File)
+	m, err := secret.ReadManifestFile(params.File)
+	if err != nil {
+		fmt.Printf("Error in reading manifest: %v\n", err)
+		return nil, err
+	}
+	plan, err := secret.PlanManifest(cl, params.SecretPath, m, &secret.PlanOptions{Prune: params.Prune})
+	if err != nil {
+		fmt.Printf("Error in comparing secrets: %v\n", err)
+		return nil, err
+	}
+	for _, change := range plan.Changes {
+		fmt.Printf("%s\tType: %s\tPath: %s", change.Action, change.Type, change.Path)
+		if len(change.Diff) > 0 {
+			fmt.Printf("\tDifferences: %s", strings.Join(change.Diff, ", "))
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Number of changes: %d\n", len(plan.Changes))
+	return plan, nil
+}
+
+func doApply(cl secret.Secret, params *Parameters) error {
+	plan, err := doPlan(cl, params)
+	if err != nil {
+		return err
+	}
+	n, err := secret.ApplyPlan(cl, plan)
+	if err != nil {
+		fmt.Printf("Error in applying changes: %v\n", err)
+		fmt.Printf("Number of changes applied: %d\n", n)
+		return err
+	}
+	fmt.Printf("Number of changes applied: %d\n", n)
+	return nil
+}
+
+// doWatch prints the changes of the secret or folder until the program is interrupted.  Secret
+// values are not printed.
+func doWatch(cl secret.Secret, params *Parameters) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, unix.SIGTERM)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	fmt.Printf("Watching [%s] for changes.  Press Ctrl-C to stop\n", params.SecretPath)
+	for event := range secret.Watch(ctx, cl, params.SecretPath, params.WatchInterval) {
+		if event.Action == secret.WatchFailed {
+			fmt.Printf("%s\tError: %v\n", event.Action, event.Err)
+			continue
+		}
+		fmt.Printf("%s\tPath: %s", event.Action, event.Path)
+		if event.Info != nil {
+			fmt.Printf("\tType: %s", event.Info.Type)
+			if !event.Info.WhenModified.IsZero() {
+				fmt.Printf("\tLast modified time: %v", event.Info.WhenModified)
+			}
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// reportMove prints the result of a move or rename operation
+func reportMove(id string, r *http.Response, err error) error {
+	if err == nil {
+		fmt.Printf("Secret moved. ID: %s\n", id)
+		return nil
+	}
+	fmt.Printf("Error in moving secret: %v\n", err)
+	var moveErr *secret.MoveError
+	if errors.As(err, &moveErr) {
+		for _, path := range moveErr.Created {
+			fmt.Printf("Copy is not removed: %s\n", path)
+		}
+		for _, path := range moveErr.NotDeleted {
+			fmt.Printf("Source is not deleted: %s\n", path)
+		}
+	}
+	if r != nil {
+		fmt.Printf("HTTP response: %v\n", *r)
+	}
+	return err
+}
@@ -6,7 +6,9 @@ require (
 	github.com/centrify/cloud-golang-sdk v0.0.0-20210529091956-21a9177656f3
 	github.com/mitchellh/go-ps v1.0.0
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
 	golang.org/x/sys v0.0.0-20210324051608-47abb6519492
 	golang.org/x/term v0.0.0-20210503060354-a79de5458b56
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
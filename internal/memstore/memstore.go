@@ -0,0 +1,397 @@
+// Package memstore implements an in-memory secret store that follows the semantics of the
+// secrets API in Centrify PAS:
+//
+//	- Secrets and folders are addressed by paths separated by "/".  Leading and trailing "/" are ignored.
+//	- Parent folders are created when a secret or folder is created in a folder that does not exist.
+//	- A path can only be used by one secret or folder.
+//	- Only empty folders can be deleted.
+//	- The type of a secret cannot be changed, and folders cannot be modified.
+//	- Each object has a unique ID and CRN, and its creation and modification time.
+//
+// It is used by the in-memory secret backend and the PAS secrets API emulator.
+package memstore
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// types of objects
+const (
+	TypeFolder   = "folder"
+	TypeText     = "text"
+	TypeKeyValue = "keyvalue"
+)
+
+// Errors returned by Store
+var (
+	ErrBadPath        = errors.New("Invalid path name")
+	ErrBadQuery       = errors.New("Invalid search, filter or ordering options")
+	ErrExists         = errors.New("Object already exists")
+	ErrFolderNotEmpty = errors.New("Folder is not empty")
+	ErrIsFolder       = errors.New("Object is a folder")
+	ErrNotFolder      = errors.New("Object is not a folder")
+	ErrNotFound       = errors.New("Object not found")
+	ErrTypeChanged    = errors.New("Cannot change type of object")
+)
+
+// Object is a secret or folder in the store
+type Object struct {
+	ID          string            // unique ID
+	CRN         string            // unique name that can be used in URL path
+	Path        string            // full path, without leading and trailing "/"
+	Name        string            // last segment of path
+	Type        string            // TypeFolder, TypeText or TypeKeyValue
+	Description string            // description of object
+	Text        string            // value of text secret
+	KeyValue    map[string]string // value of keyvalue secret
+	Attributes  map[string]string // custom metadata of object
+	Created     time.Time         // creation time
+	Modified    time.Time         // last modification time
+}
+
+// Query specifies the secrets returned by Search
+type Query struct {
+	Search  string   // text that the path of secrets contains, ignoring case
+	Filter  string   // filter expression, e.g., "type eq 'text' and name co 'db'"
+	OrderBy []string // properties to sort by, e.g., "name desc"
+}
+
+// Store is an in-memory secret store.  It is safe for concurrent use.
+type Store struct {
+	// Now returns the current time, which is used as the creation and modification time of objects.
+	// It can be replaced to get predictable times.
+	Now func() time.Time
+
+	mu      sync.RWMutex       // protects objects
+	objects map[string]*Object // objects indexed by path
+	byID    map[string]*Object // objects indexed by ID
+}
+
+// New returns an empty store
+func New() *Store {
+	return &Store{
+		Now:     func() time.Time { return time.Now().UTC() },
+		objects: make(map[string]*Object),
+		byID:    make(map[string]*Object),
+	}
+}
+
+// CleanPath removes leading and trailing "/" in 'path', and checks that each segment in the path
+// is a valid name.  A name cannot be blank, or contain "<", ">" or "&#".
+func CleanPath(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", ErrBadPath
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if strings.TrimSpace(segment) == "" || strings.ContainsAny(segment, "<>") || strings.Contains(segment, "&#") {
+			return "", fmt.Errorf("Invalid name [%s]: %w", segment, ErrBadPath)
+		}
+	}
+	return path, nil
+}
+
+// Create creates the secret or folder 'obj' in 'path'.  The type, description and value are taken
+// from 'obj'.  Missing parent folders are created.  It returns a copy of the object created.
+func (s *Store) Create(path string, obj *Object) (*Object, error) {
+	path, err := CleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	switch obj.Type {
+	case TypeFolder, TypeText, TypeKeyValue:
+	default:
+		return nil, fmt.Errorf("Unknown type [%s]", obj.Type)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[path]; ok {
+		return nil, ErrExists
+	}
+
+	// check parent folders before creating any of them
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		if parent, ok := s.objects[strings.Join(segments[:i], "/")]; ok && parent.Type != TypeFolder {
+			return nil, fmt.Errorf("Parent [%s] is not a folder: %w", parent.Path, ErrNotFolder)
+		}
+	}
+	for i := 1; i < len(segments); i++ {
+		parentPath := strings.Join(segments[:i], "/")
+		if _, ok := s.objects[parentPath]; !ok {
+			s.add(parentPath, &Object{Type: TypeFolder})
+		}
+	}
+	return s.add(path, obj).clone(), nil
+}
+
+// Get returns a copy of the object in 'path'
+func (s *Store) Get(path string) (*Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[strings.Trim(path, "/")]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return obj.clone(), nil
+}
+
+// GetByID returns a copy of the object with ID 'id'
+func (s *Store) GetByID(id string) (*Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.byID[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return obj.clone(), nil
+}
+
+// List returns copies of the objects in the folder 'path', sorted by name.  The top level folder is
+// specified by an empty path or "/".
+func (s *Store) List(path string) ([]*Object, error) {
+	path = strings.Trim(path, "/")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if path != "" {
+		folder, ok := s.objects[path]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		if folder.Type != TypeFolder {
+			return nil, ErrNotFolder
+		}
+	}
+	children := s.children(path)
+	result := make([]*Object, len(children))
+	for i, obj := range children {
+		result[i] = obj.clone()
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Modify replaces the value of the secret in 'path' with the value in 'update'.  The description is
+// replaced if the description in 'update' is not empty.  It returns a copy of the modified object.
+func (s *Store) Modify(path string, update *Object) (*Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[strings.Trim(path, "/")]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if obj.Type == TypeFolder {
+		return nil, ErrIsFolder
+	}
+	if update.Type != obj.Type {
+		return nil, ErrTypeChanged
+	}
+	obj.Text = update.Text
+	obj.KeyValue = copyMap(update.KeyValue)
+	if update.Description != "" {
+		obj.Description = update.Description
+	}
+	obj.Modified = s.Now()
+	return obj.clone(), nil
+}
+
+// ModifyMetaData modifies the description and attributes of the secret or folder in 'path'.  If
+// 'description' is not empty, it replaces the current description.  Each key in 'attributes' is set
+// to its value, or removed if the value is empty.  It returns a copy of the modified object.
+func (s *Store) ModifyMetaData(path string, description string, attributes map[string]string) (*Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[strings.Trim(path, "/")]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if description != "" {
+		obj.Description = description
+	}
+	for k, v := range attributes {
+		if v == "" {
+			delete(obj.Attributes, k)
+			continue
+		}
+		if obj.Attributes == nil {
+			obj.Attributes = make(map[string]string)
+		}
+		obj.Attributes[k] = v
+	}
+	if len(obj.Attributes) == 0 {
+		obj.Attributes = nil
+	}
+	obj.Modified = s.Now()
+	return obj.clone(), nil
+}
+
+// Move moves the secret or folder in 'path', including the contents of a folder, to 'newPath'.
+// The objects keep their IDs and CRNs.  Missing parent folders of 'newPath' are created.  It returns
+// a copy of the moved object.
+func (s *Store) Move(path string, newPath string) (*Object, error) {
+	path = strings.Trim(path, "/")
+	newPath, err := CleanPath(newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[path]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if newPath == path {
+		return obj.clone(), nil
+	}
+	if strings.HasPrefix(newPath, path+"/") {
+		return nil, fmt.Errorf("Cannot move [%s] into itself: %w", path, ErrBadPath)
+	}
+	if _, ok := s.objects[newPath]; ok {
+		return nil, ErrExists
+	}
+	segments := strings.Split(newPath, "/")
+	for i := 1; i < len(segments); i++ {
+		if parent, ok := s.objects[strings.Join(segments[:i], "/")]; ok && parent.Type != TypeFolder {
+			return nil, fmt.Errorf("Parent [%s] is not a folder: %w", parent.Path, ErrNotFolder)
+		}
+	}
+	for i := 1; i < len(segments); i++ {
+		parentPath := strings.Join(segments[:i], "/")
+		if _, ok := s.objects[parentPath]; !ok {
+			s.add(parentPath, &Object{Type: TypeFolder})
+		}
+	}
+
+	// move the object and its contents
+	prefix := path + "/"
+	for p, o := range s.objects {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(s.objects, p)
+			o.Path = newPath + p[len(path):]
+			o.Name = o.Path[strings.LastIndex(o.Path, "/")+1:]
+			s.objects[o.Path] = o
+		}
+	}
+	obj.Modified = s.Now()
+	return obj.clone(), nil
+}
+
+// Delete deletes the secret or empty folder in 'path'
+func (s *Store) Delete(path string) error {
+	path = strings.Trim(path, "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[path]
+	if !ok {
+		return ErrNotFound
+	}
+	if obj.Type == TypeFolder && len(s.children(path)) > 0 {
+		return ErrFolderNotEmpty
+	}
+	delete(s.objects, path)
+	delete(s.byID, obj.ID)
+	return nil
+}
+
+// Clear deletes all objects in the store
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = make(map[string]*Object)
+	s.byID = make(map[string]*Object)
+}
+
+// Search returns copies of the secrets that match 'query'.  Folders are not returned.
+// The secrets are sorted by path unless the query specifies the order.
+func (s *Store) Search(query *Query) ([]*Object, error) {
+	match, err := parseFilter(query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	less, err := parseOrderBy(query.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	search := strings.ToLower(query.Search)
+
+	s.mu.RLock()
+	var result []*Object
+	for _, obj := range s.objects {
+		if obj.Type != TypeFolder && strings.Contains(strings.ToLower(obj.Path), search) && match(obj) {
+			result = append(result, obj.clone())
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(result, func(i, j int) bool { return less(result[i], result[j]) })
+	return result, nil
+}
+
+// add adds the object 'obj' in 'path' with a new ID and CRN.  The caller must hold the write lock.
+func (s *Store) add(path string, obj *Object) *Object {
+	now := s.Now()
+	obj = obj.clone()
+	obj.ID = newID()
+	obj.CRN = "crn:secret:" + obj.ID
+	obj.Path = path
+	obj.Name = path[strings.LastIndex(path, "/")+1:]
+	obj.Created = now
+	obj.Modified = now
+	s.objects[path] = obj
+	s.byID[obj.ID] = obj
+	return obj
+}
+
+// children returns the objects in the folder 'path'.  The caller must hold the lock.
+func (s *Store) children(path string) []*Object {
+	prefix := ""
+	if path != "" {
+		prefix = path + "/"
+	}
+	var result []*Object
+	for p, obj := range s.objects {
+		if strings.HasPrefix(p, prefix) && !strings.Contains(p[len(prefix):], "/") {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// clone returns a copy of the object
+func (o *Object) clone() *Object {
+	c := *o
+	c.KeyValue = copyMap(o.KeyValue)
+	c.Attributes = copyMap(o.Attributes)
+	return &c
+}
+
+// copyMap returns a copy of 'm'
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// newID returns a random ID in the format of an UUID
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// fall back to time based ID, which is still unique in a store
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
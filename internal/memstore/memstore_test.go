@@ -0,0 +1,247 @@
+package memstore
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// memstore test DOES NOT need to parse command line argument
+// do this here to avoid errors if -config is passed in
+// Anyway we need to declare them here so that go test will not complain
+var (
+	configPtr      = flag.String("config", "", "configuration file")
+	configString   = flag.String("config-string", "", "configuration string")
+	VaultRootToken = flag.String("vault-root-token", "root", "Vault root token")
+)
+
+type MemStoreTestSuite struct {
+	suite.Suite
+	store *Store
+	clock time.Time
+}
+
+func TestMemStoreTestSuite(t *testing.T) {
+	suite.Run(t, &MemStoreTestSuite{})
+}
+
+func (s *MemStoreTestSuite) SetupTest() {
+	s.store = New()
+	s.clock = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	s.store.Now = func() time.Time {
+		s.clock = s.clock.Add(time.Second)
+		return s.clock
+	}
+}
+
+func (s *MemStoreTestSuite) TestCleanPath() {
+	testCases := []struct {
+		path  string
+		clean string
+		valid bool
+	}{
+		{"/a/b/", "a/b", true},
+		{"---", "---", true},
+		{"a&b", "a&b", true},
+		{"", "", false},
+		{"/", "", false},
+		{"&#", "", false},
+		{"abc<def", "", false},
+		{"def>abc", "", false},
+		{"   ", "", false},
+		{"  /empty_parent", "", false},
+		{"a//b", "", false},
+	}
+	for _, tc := range testCases {
+		clean, err := CleanPath(tc.path)
+		if tc.valid {
+			s.Assert().NoError(err, "Path [%s] should be valid", tc.path)
+			s.Assert().Equal(tc.clean, clean)
+		} else {
+			s.Assert().ErrorIs(err, ErrBadPath, "Path [%s] should be invalid", tc.path)
+		}
+	}
+}
+
+func (s *MemStoreTestSuite) TestCreate() {
+	obj, err := s.store.Create("/a/b/secret", &Object{Type: TypeText, Text: "value", Description: "desc"})
+	s.Require().NoError(err)
+	s.Assert().Equal("a/b/secret", obj.Path)
+	s.Assert().Equal("secret", obj.Name)
+	s.Assert().NotEmpty(obj.ID)
+	s.Assert().Equal("crn:secret:"+obj.ID, obj.CRN)
+	s.Assert().Equal(obj.Created, obj.Modified)
+
+	parent, err := s.store.Get("a/b")
+	s.Require().NoError(err, "Parent folder should be created")
+	s.Assert().Equal(TypeFolder, parent.Type)
+	s.Assert().True(parent.Created.Before(obj.Created), "Parent should be created first")
+
+	_, err = s.store.Create("a/b/secret", &Object{Type: TypeFolder})
+	s.Assert().ErrorIs(err, ErrExists)
+	_, err = s.store.Create("a/b/secret/child", &Object{Type: TypeText})
+	s.Assert().ErrorIs(err, ErrNotFolder)
+	_, err = s.store.Get("a/b/secret/child")
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	byID, err := s.store.GetByID(obj.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(obj, byID)
+}
+
+func (s *MemStoreTestSuite) TestCopies() {
+	kv := map[string]string{"k": "v"}
+	_, err := s.store.Create("bag", &Object{Type: TypeKeyValue, KeyValue: kv})
+	s.Require().NoError(err)
+	kv["k"] = "changed"
+
+	obj, _ := s.store.Get("bag")
+	s.Assert().Equal("v", obj.KeyValue["k"], "Store should keep its own copy")
+	obj.KeyValue["k"] = "changed"
+	obj, _ = s.store.Get("bag")
+	s.Assert().Equal("v", obj.KeyValue["k"], "Returned object should be a copy")
+}
+
+func (s *MemStoreTestSuite) TestModifyAndDelete() {
+	created, _ := s.store.Create("f/text", &Object{Type: TypeText, Text: "old", Description: "desc"})
+
+	obj, err := s.store.Modify("f/text", &Object{Type: TypeText, Text: "new"})
+	s.Require().NoError(err)
+	s.Assert().Equal("new", obj.Text)
+	s.Assert().Equal("desc", obj.Description, "Empty description should keep current description")
+	s.Assert().Equal(created.Created, obj.Created)
+	s.Assert().True(obj.Modified.After(created.Modified))
+
+	_, err = s.store.Modify("f/text", &Object{Type: TypeKeyValue})
+	s.Assert().ErrorIs(err, ErrTypeChanged)
+	_, err = s.store.Modify("f", &Object{Type: TypeText})
+	s.Assert().ErrorIs(err, ErrIsFolder)
+	_, err = s.store.Modify("missing", &Object{Type: TypeText})
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	s.Assert().ErrorIs(s.store.Delete("f"), ErrFolderNotEmpty)
+	s.Assert().NoError(s.store.Delete("f/text"))
+	s.Assert().NoError(s.store.Delete("f"))
+	s.Assert().ErrorIs(s.store.Delete("f"), ErrNotFound)
+	_, err = s.store.GetByID(created.ID)
+	s.Assert().ErrorIs(err, ErrNotFound)
+}
+
+func (s *MemStoreTestSuite) TestModifyMetaData() {
+	created, _ := s.store.Create("f/text", &Object{Type: TypeText, Text: "value", Description: "desc", Attributes: map[string]string{"owner": "ops"}})
+
+	obj, err := s.store.ModifyMetaData("f/text", "", map[string]string{"env": "prod"})
+	s.Require().NoError(err)
+	s.Assert().Equal("desc", obj.Description, "Empty description should keep current description")
+	s.Assert().Equal(map[string]string{"owner": "ops", "env": "prod"}, obj.Attributes)
+	s.Assert().Equal("value", obj.Text)
+	s.Assert().True(obj.Modified.After(created.Modified))
+
+	obj, err = s.store.ModifyMetaData("f/text", "new", map[string]string{"owner": "", "env": ""})
+	s.Require().NoError(err)
+	s.Assert().Equal("new", obj.Description)
+	s.Assert().Nil(obj.Attributes, "Attributes with empty values should be removed")
+
+	_, err = s.store.ModifyMetaData("f", "folder", nil)
+	s.Assert().NoError(err, "Should modify metadata of folder")
+	_, err = s.store.ModifyMetaData("missing", "", nil)
+	s.Assert().ErrorIs(err, ErrNotFound)
+}
+
+func (s *MemStoreTestSuite) TestMove() {
+	folder, _ := s.store.Create("f", &Object{Type: TypeFolder})
+	secret, _ := s.store.Create("f/sub/text", &Object{Type: TypeText, Text: "value"})
+	s.store.Create("other", &Object{Type: TypeText})
+
+	moved, err := s.store.Move("f", "g/h")
+	s.Require().NoError(err)
+	s.Assert().Equal(folder.ID, moved.ID, "Moved object should keep its ID")
+	s.Assert().Equal("g/h", moved.Path)
+	s.Assert().Equal("h", moved.Name)
+	obj, err := s.store.GetByID(secret.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal("g/h/sub/text", obj.Path, "Contents should be moved with folder")
+	s.Assert().Equal("value", obj.Text)
+	_, err = s.store.Get("f/sub")
+	s.Assert().ErrorIs(err, ErrNotFound)
+	obj, err = s.store.Get("g")
+	s.Require().NoError(err, "Parent folder should be created")
+	s.Assert().Equal(TypeFolder, obj.Type)
+
+	_, err = s.store.Move("g/h", "other")
+	s.Assert().ErrorIs(err, ErrExists)
+	_, err = s.store.Move("g", "g/h/x")
+	s.Assert().ErrorIs(err, ErrBadPath)
+	_, err = s.store.Move("g", "other/x")
+	s.Assert().ErrorIs(err, ErrNotFolder)
+	_, err = s.store.Move("missing", "x")
+	s.Assert().ErrorIs(err, ErrNotFound)
+}
+
+func (s *MemStoreTestSuite) TestList() {
+	s.store.Create("top", &Object{Type: TypeText})
+	s.store.Create("b/secret", &Object{Type: TypeText})
+	s.store.Create("a", &Object{Type: TypeFolder})
+
+	objects, err := s.store.List("/")
+	s.Require().NoError(err)
+	s.Require().Len(objects, 3)
+	s.Assert().Equal([]string{"a", "b", "top"}, []string{objects[0].Name, objects[1].Name, objects[2].Name})
+
+	objects, err = s.store.List("a")
+	s.Require().NoError(err)
+	s.Assert().Empty(objects)
+
+	_, err = s.store.List("top")
+	s.Assert().ErrorIs(err, ErrNotFolder)
+	_, err = s.store.List("missing")
+	s.Assert().ErrorIs(err, ErrNotFound)
+}
+
+func (s *MemStoreTestSuite) TestSearch() {
+	db, _ := s.store.Create("app/db", &Object{Type: TypeText, Description: "database"})
+	s.store.Create("app/api", &Object{Type: TypeKeyValue})
+	s.store.Create("web/it's", &Object{Type: TypeText})
+
+	testCases := []struct {
+		query Query
+		paths []string
+	}{
+		{Query{}, []string{"app/api", "app/db", "web/it's"}},
+		{Query{Search: "APP"}, []string{"app/api", "app/db"}},
+		{Query{Filter: "type eq 'text'"}, []string{"app/db", "web/it's"}},
+		{Query{Filter: "type eq 'text' and name sw 'app'"}, []string{"app/db"}},
+		{Query{Filter: "description co 'DATA'"}, []string{"app/db"}},
+		{Query{Filter: "name ew 'it''s'"}, []string{"web/it's"}},
+		{Query{Filter: "type ne 'text'"}, []string{"app/api"}},
+		{Query{Filter: "id eq '" + db.ID + "'"}, []string{"app/db"}},
+		{Query{OrderBy: []string{"name desc"}}, []string{"web/it's", "app/db", "app/api"}},
+		{Query{OrderBy: []string{"type desc", "name"}}, []string{"app/db", "web/it's", "app/api"}},
+		{Query{OrderBy: []string{"created desc"}}, []string{"web/it's", "app/api", "app/db"}},
+	}
+	for _, tc := range testCases {
+		objects, err := s.store.Search(&tc.query)
+		s.Require().NoError(err, "Query %v", tc.query)
+		var paths []string
+		for _, obj := range objects {
+			paths = append(paths, obj.Path)
+		}
+		s.Assert().Equal(tc.paths, paths, "Query %v", tc.query)
+	}
+
+	badQueries := []Query{
+		{Filter: "size eq '1'"},
+		{Filter: "type is 'text'"},
+		{Filter: "type eq text"},
+		{Filter: "type eq 'text"},
+		{Filter: "type eq 'text' or name eq 'a'"},
+		{OrderBy: []string{"size"}},
+		{OrderBy: []string{"name up"}},
+	}
+	for _, query := range badQueries {
+		_, err := s.store.Search(&query)
+		s.Assert().ErrorIs(err, ErrBadQuery, "Query %v", query)
+	}
+}
@@ -0,0 +1,190 @@
+package memstore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// condition is a condition in a filter expression
+type condition struct {
+	field string // property of object
+	op    string // comparison operator
+	value string // value to compare
+}
+
+// parseFilter parses a filter expression and returns a function that checks whether an object
+// matches the filter.  The expression is a list of conditions joined by "and".  Each condition
+// has the form <property> <operator> '<value>', where property is name, type, description or id, and
+// operator is one of eq (equal), ne (not equal), co (contains), sw (starts with) and ew (ends with).
+// A quote in the value is written as two quotes.  Comparison ignores case.  The name of a secret
+// is its path.
+func parseFilter(filter string) (func(obj *Object) bool, error) {
+	var conditions []condition
+	rest := strings.TrimSpace(filter)
+	for rest != "" {
+		if len(conditions) > 0 {
+			var word string
+			word, rest = nextWord(rest)
+			if !strings.EqualFold(word, "and") {
+				return nil, fmt.Errorf("Expect \"and\" but got [%s]: %w", word, ErrBadQuery)
+			}
+		}
+		var cond condition
+		cond.field, rest = nextWord(rest)
+		cond.op, rest = nextWord(rest)
+		cond.field = strings.ToLower(cond.field)
+		cond.op = strings.ToLower(cond.op)
+		switch cond.field {
+		case "name", "type", "description", "id":
+		default:
+			return nil, fmt.Errorf("Cannot filter by [%s]: %w", cond.field, ErrBadQuery)
+		}
+		switch cond.op {
+		case "eq", "ne", "co", "sw", "ew":
+		default:
+			return nil, fmt.Errorf("Unknown operator [%s]: %w", cond.op, ErrBadQuery)
+		}
+		var err error
+		cond.value, rest, err = quotedValue(rest)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+
+	return func(obj *Object) bool {
+		for _, cond := range conditions {
+			if !cond.match(obj) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// match checks whether the object matches the condition
+func (c *condition) match(obj *Object) bool {
+	var property string
+	switch c.field {
+	case "name":
+		property = obj.Path
+	case "type":
+		property = obj.Type
+	case "description":
+		property = obj.Description
+	case "id":
+		property = obj.ID
+	}
+	property = strings.ToLower(property)
+	value := strings.ToLower(c.value)
+	switch c.op {
+	case "eq":
+		return property == value
+	case "ne":
+		return property != value
+	case "co":
+		return strings.Contains(property, value)
+	case "sw":
+		return strings.HasPrefix(property, value)
+	case "ew":
+		return strings.HasSuffix(property, value)
+	}
+	return false
+}
+
+// parseOrderBy parses the ordering options and returns a function that compares two objects.
+// Each option has the form <property> [asc|desc], where property is name, type, created or modified.
+// Objects are ordered by path if there is no option.
+func parseOrderBy(orderBy []string) (func(a, b *Object) bool, error) {
+	type ordering struct {
+		field      string
+		descending bool
+	}
+	var orderings []ordering
+	for _, option := range orderBy {
+		words := strings.Fields(strings.ToLower(option))
+		if len(words) == 0 || len(words) > 2 {
+			return nil, fmt.Errorf("Invalid ordering [%s]: %w", option, ErrBadQuery)
+		}
+		o := ordering{field: words[0]}
+		switch o.field {
+		case "name", "type", "created", "modified":
+		default:
+			return nil, fmt.Errorf("Cannot order by [%s]: %w", o.field, ErrBadQuery)
+		}
+		if len(words) == 2 {
+			switch words[1] {
+			case "asc":
+			case "desc":
+				o.descending = true
+			default:
+				return nil, fmt.Errorf("Invalid ordering [%s]: %w", option, ErrBadQuery)
+			}
+		}
+		orderings = append(orderings, o)
+	}
+	orderings = append(orderings, ordering{field: "name"})
+
+	return func(a, b *Object) bool {
+		for _, o := range orderings {
+			cmp := 0
+			switch o.field {
+			case "name":
+				cmp = strings.Compare(a.Path, b.Path)
+			case "type":
+				cmp = strings.Compare(a.Type, b.Type)
+			case "created":
+				cmp = compareTime(a.Created.UnixNano(), b.Created.UnixNano())
+			case "modified":
+				cmp = compareTime(a.Modified.UnixNano(), b.Modified.UnixNano())
+			}
+			if cmp != 0 {
+				return (cmp < 0) != o.descending
+			}
+		}
+		return false
+	}, nil
+}
+
+// compareTime compares two times in nanoseconds
+func compareTime(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// nextWord returns the next word in 's' and the rest of 's'
+func nextWord(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i:])
+	}
+	return s, ""
+}
+
+// quotedValue returns the quoted value at the start of 's' and the rest of 's'
+func quotedValue(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "'") {
+		return "", "", fmt.Errorf("Value must be quoted: %w", ErrBadQuery)
+	}
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '\'' {
+			value.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\'' {
+			// escaped quote
+			value.WriteByte('\'')
+			i++
+			continue
+		}
+		return value.String(), strings.TrimSpace(s[i+1:]), nil
+	}
+	return "", "", fmt.Errorf("Missing closing quote: %w", ErrBadQuery)
+}
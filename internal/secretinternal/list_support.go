@@ -0,0 +1,103 @@
+package secretinternal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrPageURLNotOnServer is returned by SecretsListPage when the paging URL is on another server than
+// the one in the configuration, so that the access token is not sent to it.
+var ErrPageURLNotOnServer = errors.New("Paging URL is not on the configured server")
+
+// SecretsListQuery retrieves the first page of a secret list with the parameters in 'query', e.g.,
+// limit, orderBy, search and filter.  Unlike SecretsList, it sends each parameter as it is, so
+// that an orderBy value such as "name desc,created" is not split at its spaces by
+// parameterToString.
+func (c *APIClient) SecretsListQuery(ctx context.Context, query url.Values) (SecretList, *http.Response, error) {
+	return c.SecretsListPage(ctx, "secrets?"+query.Encode())
+}
+
+// SecretsListPage retrieves a page of a secret list.  'pageURL' is the next_url or previous_url
+// returned in a previous SecretList.  It can be an absolute URL, or a URL relative to the
+// server URL in the configuration.
+func (c *APIClient) SecretsListPage(ctx context.Context, pageURL string) (SecretList, *http.Response, error) {
+	var result SecretList
+
+	fullURL, err := c.resolvePageURL(ctx, pageURL)
+	if err != nil {
+		return result, nil, err
+	}
+
+	headers := map[string]string{
+		"Accept": "application/json",
+	}
+	req, err := c.prepareRequest(ctx, fullURL, http.MethodGet, nil, headers, url.Values{}, url.Values{}, "", "", nil)
+	if err != nil {
+		return result, nil, err
+	}
+
+	resp, err := c.callAPI(req)
+	if err != nil || resp == nil {
+		return result, resp, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	if err != nil {
+		return result, resp, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return result, resp, GenericOpenAPIError{
+			body:  body,
+			error: resp.Status,
+		}
+	}
+
+	err = c.decode(&result, body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return result, resp, GenericOpenAPIError{
+			body:  body,
+			error: err.Error(),
+		}
+	}
+	return result, resp, nil
+}
+
+// resolvePageURL converts a paging URL into an absolute URL.
+// An absolute URL must have the same scheme and host as the server URL, or ErrPageURLNotOnServer is
+// returned.  A relative URL that already contains the base path of the server (e.g.,
+// /api/v1.0/secrets?...) is resolved against the server host.  Any other relative URL is appended
+// to the server URL.
+func (c *APIClient) resolvePageURL(ctx context.Context, pageURL string) (string, error) {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	base, err := c.cfg.ServerURLWithContext(ctx, "SecretsApiService.SecretsList")
+	if err != nil {
+		return "", err
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	if page.IsAbs() || page.Host != "" {
+		if !strings.EqualFold(page.Scheme, baseURL.Scheme) || !strings.EqualFold(page.Host, baseURL.Host) {
+			return "", fmt.Errorf("[%s]: %w", pageURL, ErrPageURLNotOnServer)
+		}
+		return baseURL.ResolveReference(page).String(), nil
+	}
+	if strings.HasPrefix(page.Path, baseURL.Path+"/") {
+		return baseURL.ResolveReference(page).String(), nil
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(pageURL, "/"), nil
+}
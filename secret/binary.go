@@ -0,0 +1,91 @@
+package secret
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// DefaultMaxBinarySize is the default maximum size in bytes of a binary secret value.  See
+// WithMaxBinarySize.
+const DefaultMaxBinarySize = 64 * 1024
+
+// binaryPrefix marks a text secret that stores a binary value.  The value is saved as a data URL
+// with the base64 encoded bytes after the prefix.
+const binaryPrefix = "data:application/octet-stream;base64,"
+
+// WithMaxBinarySize specifies the maximum size in bytes of a []byte or io.Reader value that can be
+// saved by Create and Modify.  A larger value is rejected with ErrSecretTooLarge without sending any
+// request.  If 'size' is 0 or less, DefaultMaxBinarySize is used.
+//
+// A binary value is saved as base64 text, which is about a third larger than the value.  The limit
+// should not exceed the maximum size of a text secret in the secret store.
+func WithMaxBinarySize(size int) ClientOption {
+	return func(o *clientOptions) {
+		o.maxBinarySize = size
+	}
+}
+
+// WithBinaryValues makes Get return the value of a secret saved from a []byte or io.Reader value
+// as []byte.  Without this option, Get returns such a value as the text it is saved as, so that a
+// text secret that happens to begin with the same data URL prefix, e.g., one saved by another
+// application, is still returned as string.
+func WithBinaryValues() ClientOption {
+	return func(o *clientOptions) {
+		o.binaryValues = true
+	}
+}
+
+// binaryCodec is implemented by the secret clients that save binary values
+type binaryCodec interface {
+	setBinaryOptions(maxSize int, decode bool)
+}
+
+// encodeValue returns the value to save for 'value' in Create and Modify.  A []byte value, or the
+// content read from an io.Reader, of at most 'limit' bytes is returned as the text of a binary
+// secret.  Other values are returned as they are.
+func encodeValue(value interface{}, limit int) (interface{}, error) {
+	if limit <= 0 {
+		limit = DefaultMaxBinarySize
+	}
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case io.Reader:
+		// read one more byte to find out whether the content exceeds the limit
+		var err error
+		data, err = ioutil.ReadAll(io.LimitReader(v, int64(limit)+1))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read secret value: %w", err)
+		}
+	default:
+		return value, nil
+	}
+	if len(data) > limit {
+		return nil, fmt.Errorf("binary value is larger than %d bytes: %w", limit, ErrSecretTooLarge)
+	}
+	return binaryText(data), nil
+}
+
+// binaryText returns the text that a binary value is saved as
+func binaryText(data []byte) string {
+	return binaryPrefix + base64.StdEncoding.EncodeToString(data)
+}
+
+// decodeValue returns the value of a secret returned by Get.  If 'decode' is set, the text of a
+// binary secret is returned as []byte.  Other values are returned as they are.
+func decodeValue(value interface{}, decode bool) interface{} {
+	text, ok := value.(string)
+	if !decode || !ok || !strings.HasPrefix(text, binaryPrefix) {
+		return value
+	}
+	data, err := base64.StdEncoding.DecodeString(text[len(binaryPrefix):])
+	if err != nil {
+		// not saved by encodeValue
+		return value
+	}
+	return data
+}
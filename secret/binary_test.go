@@ -0,0 +1,134 @@
+package secret
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretBinaryTestSuite struct {
+	testutils.CfyTestSuite
+	handle Secret // interface to secret API
+}
+
+func TestSecretBinaryTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretBinaryTestSuite))
+}
+
+func (s *SecretBinaryTestSuite) SetupTest() {
+	var err error
+	s.handle, err = NewSecretClient("", ServerMemory, "", nil, WithMaxBinarySize(16), WithBinaryValues())
+	s.Require().NoError(err)
+}
+
+func (s *SecretBinaryTestSuite) TestCreateAndGet() {
+	data := []byte{0, 1, 2, 0xff, 0xfe}
+	_, _, _, err := s.handle.Create("tls/key", "key", data)
+	s.Require().NoError(err)
+	value, _, err := s.handle.Get("tls/key")
+	s.Require().NoError(err)
+	s.Assert().Equal(data, value)
+	metadata, _, err := s.handle.GetMetaData("tls/key")
+	s.Require().NoError(err)
+	s.Assert().Equal(SecretTypeText, strings.ToLower(metadata.Type), "Binary value should be saved in text secret")
+
+	_, _, _, err = s.handle.Modify("tls/key", "", bytes.NewReader([]byte("new key")))
+	s.Require().NoError(err)
+	value, _, _ = s.handle.Get("tls/key")
+	s.Assert().Equal([]byte("new key"), value, "Should save content of io.Reader")
+
+	_, _, _, err = s.handle.Create("tls/empty", "", []byte{})
+	s.Require().NoError(err)
+	value, _, _ = s.handle.Get("tls/empty")
+	s.Assert().Equal([]byte{}, value)
+
+	s.handle.Create("tls/text", "", binaryPrefix+"not base64")
+	value, _, _ = s.handle.Get("tls/text")
+	s.Assert().Equal(binaryPrefix+"not base64", value, "Text that is not a binary value should be returned as string")
+}
+
+func (s *SecretBinaryTestSuite) TestTextWithoutBinaryValues() {
+	cl, err := NewSecretClient("", ServerMemory, "", nil)
+	s.Require().NoError(err)
+	text := binaryPrefix + "YWJj"
+	_, _, _, err = cl.Create("logo", "", text)
+	s.Require().NoError(err)
+	value, _, err := cl.Get("logo")
+	s.Require().NoError(err)
+	s.Assert().Equal(text, value, "Existing data URL should be returned as string without WithBinaryValues")
+
+	_, _, _, err = cl.Create("tls/key", "", []byte("abc"))
+	s.Require().NoError(err)
+	value, _, _ = cl.Get("tls/key")
+	s.Assert().Equal(text, value, "Binary value should be returned as text without WithBinaryValues")
+}
+
+func (s *SecretBinaryTestSuite) TestSizeLimit() {
+	_, _, _, err := s.handle.Create("big", "", make([]byte, 17))
+	s.Assert().ErrorIs(err, ErrSecretTooLarge)
+	_, _, _, err = s.handle.Create("big", "", strings.NewReader(strings.Repeat("x", 17)))
+	s.Assert().ErrorIs(err, ErrSecretTooLarge)
+	_, _, err = s.handle.GetMetaData("big")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Secret should not be created")
+
+	_, _, _, err = s.handle.Create("big", "", strings.NewReader(strings.Repeat("x", 16)))
+	s.Require().NoError(err)
+	_, _, _, err = s.handle.Modify("big", "", make([]byte, 17))
+	s.Assert().ErrorIs(err, ErrSecretTooLarge)
+
+	cl, _ := NewSecretClient("", ServerMemory, "", nil)
+	_, _, _, err = cl.Create("big", "", make([]byte, DefaultMaxBinarySize+1))
+	s.Assert().ErrorIs(err, ErrSecretTooLarge)
+	_, _, _, err = cl.Create("big", "", make([]byte, DefaultMaxBinarySize))
+	s.Assert().NoError(err)
+}
+
+func (s *SecretBinaryTestSuite) TestCache() {
+	s.handle.Create("bin", "", []byte("value"))
+	handle := NewCachedSecretClient(s.handle, &CacheOptions{TTL: time.Minute})
+
+	value, _, err := handle.Get("bin")
+	s.Require().NoError(err)
+	value.([]byte)[0] = 'V'
+	value, _, _ = handle.Get("bin")
+	s.Assert().Equal([]byte("value"), value, "Cached value should not be changed by caller")
+
+	stored := handle.entries[cacheKey{op: cacheOpGet, path: "bin"}].Value.(*cacheEntry).value.(cachedBinary)
+	handle.Invalidate("bin")
+	s.Assert().Equal(cachedBinary{0, 0, 0, 0, 0}, stored, "Invalidated value should be zeroed")
+}
+
+func (s *SecretBinaryTestSuite) TestExportImportAndCopy() {
+	data := []byte{0, 1, 2, 3}
+	s.handle.Create("app/bin", "binary", data)
+
+	var buf bytes.Buffer
+	s.Require().NoError(Export(s.handle, "app", &buf, nil))
+	doc, err := ReadExport(bytes.NewReader(buf.Bytes()), "")
+	s.Require().NoError(err)
+	s.Require().Len(doc.Items, 1)
+	s.Assert().Equal(ExportItem{Path: "bin", Type: SecretTypeFile, Description: "binary", Data: "AAECAw=="}, doc.Items[0])
+
+	other, _ := NewSecretClient("", ServerMemory, "", nil, WithBinaryValues())
+	results, err := Import(other, "imported", &buf, nil)
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Assert().Equal(SecretTypeFile, results[0].Type)
+	value, _, _ := other.Get("imported/bin")
+	s.Assert().Equal(data, value)
+
+	_, err = ReadExport(strings.NewReader(`{"version": 1, "items": [{"path": "bin", "type": "file", "data": "!"}]}`), "")
+	s.Assert().ErrorIs(err, ErrInvalidExportDocument)
+
+	other.Modify("imported/bin", "binary", []byte{0})
+	copied, err := Copy(s.handle, "app", other, "imported", &CopyOptions{OnConflict: ConflictOverwrite})
+	s.Require().NoError(err)
+	s.Require().Len(copied, 2)
+	s.Assert().Equal(CopyResult{SrcPath: "app/bin", DstPath: "imported/bin", Type: SecretTypeFile, Action: CopyOverwritten, Diff: []string{"value"}}, copied[1])
+	value, _, _ = other.Get("imported/bin")
+	s.Assert().Equal(data, value)
+}
@@ -0,0 +1,572 @@
+package secret
+
+import (
+	"container/list"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheTTL is the time-to-live of cached results when CacheOptions.TTL is not specified.
+	DefaultCacheTTL = time.Minute
+
+	// DefaultCacheSize is the maximum number of cached results when CacheOptions.MaxEntries is not specified.
+	DefaultCacheSize = 1000
+)
+
+// CacheOptions specifies the options for NewCachedSecretClient.
+type CacheOptions struct {
+	// TTL is how long a cached result is returned without sending any request to the secret store.
+	// 0 means DefaultCacheTTL.
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of results cached.  When it is exceeded, the least recently
+	// used result is evicted.  0 means DefaultCacheSize.
+	MaxEntries int
+
+	// Revalidate specifies whether a secret value is revalidated when its TTL expires.  If it is set,
+	// Get retrieves the metadata of the secret, and returns the cached value for another TTL if the
+	// modification time of the secret is unchanged.  This requires an additional GetMetaData request
+	// when a secret value is not cached, and is only useful when the secret store reports the
+	// modification time of secrets.
+	Revalidate bool
+}
+
+// CachedSecretClient implements the Secret interface by caching the results of Get, GetMetaData
+// and List of another Secret.  All other methods are passed to the underlying Secret.
+//
+// Create, CreateFolder, Delete, Modify and ModifyMetaData invalidate the cached results of the
+// path and the listings of its parent folders.  Changes made through other clients are only seen
+// after the TTL of a cached result expires.  Results are cached by the path used, so a secret
+// accessed by different paths, e.g., by its ID, is cached separately.  Errors are not cached, and
+// neither are results that are invalidated while they are being retrieved.
+//
+// Cached secret values are kept in byte slices that are zeroed when they are evicted, invalidated
+// or replaced.  The values returned to the caller are copies, and are not zeroed.
+type CachedSecretClient struct {
+	Secret // underlying secret client
+
+	ttl        time.Duration    // time-to-live of cached results
+	maxEntries int              // maximum number of cached results
+	revalidate bool             // whether to revalidate expired secret values
+	now        func() time.Time // returns the current time
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element // cached results
+	lru     *list.List                 // cached results, most recently used first
+	pending map[cacheKey]*cacheFetches // results being retrieved from the secret store
+}
+
+// cacheOp is the operation whose result is cached
+type cacheOp int
+
+const (
+	cacheOpGet cacheOp = iota
+	cacheOpGetMetaData
+	cacheOpList
+)
+
+var cacheOps = []cacheOp{cacheOpGet, cacheOpGetMetaData, cacheOpList}
+
+// cacheKey identifies a cached result
+type cacheKey struct {
+	op   cacheOp
+	path string
+}
+
+// cacheEntry is a cached result
+type cacheEntry struct {
+	key          cacheKey
+	value        interface{}    // []byte, map[string][]byte, *MetaData or []Item
+	response     *http.Response // response when the result is retrieved, without body
+	whenModified time.Time      // modification time of the secret when the value is retrieved
+	expires      time.Time      // when the result needs to be retrieved again
+}
+
+// cacheFetches counts the requests in flight for a result, and the invalidations of the result
+// while they are in flight
+type cacheFetches struct {
+	requests      int
+	invalidations int
+}
+
+// cacheFetch is a request in flight for the result of 'key'.  The result is only cached if it is
+// not invalidated after the request is sent.
+type cacheFetch struct {
+	key           cacheKey
+	fetches       *cacheFetches
+	invalidations int // invalidations of the result when the request is sent
+}
+
+// NewCachedSecretClient returns a client that caches the results of 'cl' as specified in 'opts'.
+// 'opts' can be nil, which uses the default TTL and size.
+func NewCachedSecretClient(cl Secret, opts *CacheOptions) *CachedSecretClient {
+	if opts == nil {
+		opts = &CacheOptions{}
+	}
+	c := &CachedSecretClient{
+		Secret:     cl,
+		ttl:        opts.TTL,
+		maxEntries: opts.MaxEntries,
+		revalidate: opts.Revalidate,
+		now:        time.Now,
+		entries:    make(map[cacheKey]*list.Element),
+		lru:        list.New(),
+		pending:    make(map[cacheKey]*cacheFetches),
+	}
+	if c.ttl <= 0 {
+		c.ttl = DefaultCacheTTL
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = DefaultCacheSize
+	}
+	return c
+}
+
+// Get returns the secret content, from the cache if it has not expired.
+// The response returned for a cached value is a copy of the response when the value is
+// retrieved, with an empty body.
+func (c *CachedSecretClient) Get(path string) (interface{}, *http.Response, error) {
+	return c.GetContext(context.Background(), path)
+}
+
+// GetContext is the same as Get, but uses 'ctx' for the requests.
+func (c *CachedSecretClient) GetContext(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	key := cacheKey{op: cacheOpGet, path: cachePath(path)}
+	value, resp, whenModified, fresh, ok := c.lookup(key)
+	if ok && fresh {
+		return value, resp, nil
+	}
+
+	fetch := c.startFetch(key)
+	defer c.endFetch(fetch)
+	var currentModified time.Time
+	if c.revalidate {
+		metaFetch := c.startFetch(cacheKey{op: cacheOpGetMetaData, path: key.path})
+		metadata, r, err := c.Secret.GetMetaDataContext(ctx, path)
+		if err == nil {
+			c.add(metaFetch, metadata, r, time.Time{})
+		}
+		c.endFetch(metaFetch)
+		if err == nil {
+			currentModified = metadata.WhenModified
+			if ok && !currentModified.IsZero() && currentModified.Equal(whenModified) {
+				if value, resp, ok = c.renew(key, whenModified); ok {
+					return value, resp, nil
+				}
+			}
+		} else if ctx.Err() != nil {
+			return nil, r, err
+		}
+	}
+
+	value, resp, err := c.Secret.GetContext(ctx, path)
+	if err != nil {
+		c.remove(key)
+		return value, resp, err
+	}
+	c.add(fetch, value, resp, currentModified)
+	return value, resp, nil
+}
+
+// GetMetaData returns the metadata of a secret, from the cache if it has not expired.
+func (c *CachedSecretClient) GetMetaData(path string) (*MetaData, *http.Response, error) {
+	return c.GetMetaDataContext(context.Background(), path)
+}
+
+// GetMetaDataContext is the same as GetMetaData, but uses 'ctx' for the request.
+func (c *CachedSecretClient) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	key := cacheKey{op: cacheOpGetMetaData, path: cachePath(path)}
+	if value, resp, _, fresh, ok := c.lookup(key); ok && fresh {
+		return value.(*MetaData), resp, nil
+	}
+	fetch := c.startFetch(key)
+	defer c.endFetch(fetch)
+	metadata, resp, err := c.Secret.GetMetaDataContext(ctx, path)
+	if err != nil {
+		c.remove(key)
+		return metadata, resp, err
+	}
+	c.add(fetch, metadata, resp, time.Time{})
+	return metadata, resp, nil
+}
+
+// List lists all secrets in a folder, from the cache if it has not expired.
+func (c *CachedSecretClient) List(path string) ([]Item, *http.Response, error) {
+	return c.ListContext(context.Background(), path)
+}
+
+// ListContext is the same as List, but uses 'ctx' for the requests.
+func (c *CachedSecretClient) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	key := cacheKey{op: cacheOpList, path: cachePath(path)}
+	if value, resp, _, fresh, ok := c.lookup(key); ok && fresh {
+		return value.([]Item), resp, nil
+	}
+	fetch := c.startFetch(key)
+	defer c.endFetch(fetch)
+	items, resp, err := c.Secret.ListContext(ctx, path)
+	if err != nil {
+		c.remove(key)
+		return items, resp, err
+	}
+	c.add(fetch, items, resp, time.Time{})
+	return items, resp, nil
+}
+
+// Create creates a secret in 'path', and invalidates the cached results of 'path' and
+// the listings of its parent folders.
+func (c *CachedSecretClient) Create(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.CreateContext(context.Background(), path, description, value)
+}
+
+// CreateContext is the same as Create, but uses 'ctx' for the request.
+func (c *CachedSecretClient) CreateContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	defer c.invalidateTree(path)
+	return c.Secret.CreateContext(ctx, path, description, value)
+}
+
+// CreateFolder creates a secret folder in 'path', and invalidates the cached results of 'path'
+// and the listings of its parent folders.
+func (c *CachedSecretClient) CreateFolder(path string, description string) (bool, string, *http.Response, error) {
+	return c.CreateFolderContext(context.Background(), path, description)
+}
+
+// CreateFolderContext is the same as CreateFolder, but uses 'ctx' for the request.
+func (c *CachedSecretClient) CreateFolderContext(ctx context.Context, path string, description string) (bool, string, *http.Response, error) {
+	defer c.invalidateTree(path)
+	return c.Secret.CreateFolderContext(ctx, path, description)
+}
+
+// Delete deletes the folder/secret specified in 'path', and invalidates the cached results of
+// 'path' and the listings of its parent folders.
+func (c *CachedSecretClient) Delete(path string) (*http.Response, error) {
+	return c.DeleteContext(context.Background(), path)
+}
+
+// DeleteContext is the same as Delete, but uses 'ctx' for the request.
+func (c *CachedSecretClient) DeleteContext(ctx context.Context, path string) (*http.Response, error) {
+	defer c.invalidateTree(path)
+	return c.Secret.DeleteContext(ctx, path)
+}
+
+// Modify modifies a secret in 'path', and invalidates the cached results of 'path'.
+func (c *CachedSecretClient) Modify(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.ModifyContext(context.Background(), path, description, value)
+}
+
+// ModifyContext is the same as Modify, but uses 'ctx' for the request.
+func (c *CachedSecretClient) ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	defer c.Invalidate(path)
+	return c.Secret.ModifyContext(ctx, path, description, value)
+}
+
+// ModifyMetaData modifies the description and attributes of the secret or folder in 'path', and
+// invalidates the cached results of 'path'.
+func (c *CachedSecretClient) ModifyMetaData(path string, description string, attributes map[string]string) (*http.Response, error) {
+	return c.ModifyMetaDataContext(context.Background(), path, description, attributes)
+}
+
+// ModifyMetaDataContext is the same as ModifyMetaData, but uses 'ctx' for the requests.
+func (c *CachedSecretClient) ModifyMetaDataContext(ctx context.Context, path string, description string, attributes map[string]string) (*http.Response, error) {
+	defer c.Invalidate(path)
+	return c.Secret.ModifyMetaDataContext(ctx, path, description, attributes)
+}
+
+// Move moves the secret or folder in 'srcPath' to 'dstPath', and invalidates the cached results of
+// both paths, the contents of a folder, and the listings of their parent folders.
+func (c *CachedSecretClient) Move(srcPath string, dstPath string) (string, *http.Response, error) {
+	return c.MoveContext(context.Background(), srcPath, dstPath)
+}
+
+// MoveContext is the same as Move, but uses 'ctx' for the requests.
+func (c *CachedSecretClient) MoveContext(ctx context.Context, srcPath string, dstPath string) (string, *http.Response, error) {
+	defer c.invalidateContents(dstPath)
+	defer c.invalidateContents(srcPath)
+	return c.Secret.MoveContext(ctx, srcPath, dstPath)
+}
+
+// Rename renames the secret or folder in 'path' to 'newName', and invalidates the cached results
+// in the same way as Move.
+func (c *CachedSecretClient) Rename(path string, newName string) (string, *http.Response, error) {
+	return c.RenameContext(context.Background(), path, newName)
+}
+
+// RenameContext is the same as Rename, but uses 'ctx' for the requests.
+func (c *CachedSecretClient) RenameContext(ctx context.Context, path string, newName string) (string, *http.Response, error) {
+	newPath, err := renamePath(path, newName)
+	if err != nil {
+		return "", nil, err
+	}
+	return c.MoveContext(ctx, path, newPath)
+}
+
+// Invalidate removes the cached results of 'path', so that they are retrieved from the secret
+// store when they are requested again.
+func (c *CachedSecretClient) Invalidate(path string) {
+	path = cachePath(path)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, op := range cacheOps {
+		c.invalidateLocked(cacheKey{op: op, path: path})
+	}
+}
+
+// Purge removes all cached results.
+func (c *CachedSecretClient) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.lru.Len() > 0 {
+		c.removeLocked(c.lru.Front().Value.(*cacheEntry).key)
+	}
+	for _, fetches := range c.pending {
+		fetches.invalidations++
+	}
+}
+
+// Len returns the number of cached results.
+func (c *CachedSecretClient) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// invalidateTree removes the cached results of 'path' and the listings of its parent folders
+func (c *CachedSecretClient) invalidateTree(path string) {
+	path = cachePath(path)
+	c.Invalidate(path)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path != "" {
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			path = path[:i]
+		} else {
+			path = ""
+		}
+		c.invalidateLocked(cacheKey{op: cacheOpList, path: path})
+	}
+}
+
+// invalidateContents removes the cached results of 'path', the objects in it if it is a folder,
+// and the listings of its parent folders
+func (c *CachedSecretClient) invalidateContents(path string) {
+	c.invalidateTree(path)
+	prefix := cachePath(path) + "/"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key.path, prefix) {
+			c.removeLocked(key)
+		}
+	}
+	for key, fetches := range c.pending {
+		if strings.HasPrefix(key.path, prefix) {
+			fetches.invalidations++
+		}
+	}
+}
+
+// lookup returns a copy of the cached result of 'key', the modification time of the secret when
+// the result is retrieved, and whether the result has not expired.  'ok' is false if there is
+// no cached result.
+func (c *CachedSecretClient) lookup(key cacheKey) (value interface{}, resp *http.Response, whenModified time.Time, fresh bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, nil, time.Time{}, false, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	c.lru.MoveToFront(elem)
+	return cachedValue(entry.value), cachedResponse(entry.response), entry.whenModified, c.now().Before(entry.expires), true
+}
+
+// renew extends the TTL of the cached result of 'key' and returns a copy of it, if the secret
+// is not modified since the result is retrieved
+func (c *CachedSecretClient) renew(key cacheKey, whenModified time.Time) (interface{}, *http.Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !entry.whenModified.Equal(whenModified) {
+		return nil, nil, false
+	}
+	entry.expires = c.now().Add(c.ttl)
+	return cachedValue(entry.value), cachedResponse(entry.response), true
+}
+
+// startFetch records that the result of 'key' is being retrieved from the secret store.  endFetch
+// must be called when the request returns.
+func (c *CachedSecretClient) startFetch(key cacheKey) *cacheFetch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fetches, ok := c.pending[key]
+	if !ok {
+		fetches = &cacheFetches{}
+		c.pending[key] = fetches
+	}
+	fetches.requests++
+	return &cacheFetch{key: key, fetches: fetches, invalidations: fetches.invalidations}
+}
+
+// endFetch records that the request of 'fetch' has returned
+func (c *CachedSecretClient) endFetch(fetch *cacheFetch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fetch.fetches.requests--
+	if fetch.fetches.requests == 0 {
+		delete(c.pending, fetch.key)
+	}
+}
+
+// add caches 'value' retrieved by 'fetch', and evicts the least recently used results if the
+// cache is full.  Values of unknown types are not cached, and a value is not cached if the result
+// is invalidated while it is retrieved, as it may be older than the change that invalidates it.
+func (c *CachedSecretClient) add(fetch *cacheFetch, value interface{}, resp *http.Response, whenModified time.Time) {
+	stored, ok := cacheValue(value)
+	key := fetch.key
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+	if !ok || fetch.fetches.invalidations != fetch.invalidations {
+		return
+	}
+	entry := &cacheEntry{
+		key:          key,
+		value:        stored,
+		response:     cachedResponse(resp),
+		whenModified: whenModified,
+		expires:      c.now().Add(c.ttl),
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.removeLocked(c.lru.Back().Value.(*cacheEntry).key)
+	}
+}
+
+// remove removes the cached result of 'key'
+func (c *CachedSecretClient) remove(key cacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+}
+
+// invalidateLocked removes the cached result of 'key', and prevents the results being retrieved
+// from being cached.  c.mu must be held.
+func (c *CachedSecretClient) invalidateLocked(key cacheKey) {
+	c.removeLocked(key)
+	if fetches, ok := c.pending[key]; ok {
+		fetches.invalidations++
+	}
+}
+
+// removeLocked removes the cached result of 'key' and zeroes its value.  c.mu must be held.
+func (c *CachedSecretClient) removeLocked(key cacheKey) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	c.lru.Remove(elem)
+	zeroCachedValue(elem.Value.(*cacheEntry).value)
+}
+
+// cachePath returns the path used in cache keys, so that paths that differ only in leading
+// or trailing slashes share the same results
+func cachePath(path string) string {
+	return strings.Trim(path, "/")
+}
+
+// cachedBinary is the stored value of a binary secret, so that it is not confused with the stored
+// value of a text secret
+type cachedBinary []byte
+
+// cacheValue returns a copy of 'value' to be stored in the cache.  Secret values are stored
+// in byte slices so that they can be zeroed.
+func cacheValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), true
+	case []byte:
+		return cachedBinary(append([]byte(nil), v...)), true
+	case map[string]string:
+		m := make(map[string][]byte, len(v))
+		for k, s := range v {
+			m[k] = []byte(s)
+		}
+		return m, true
+	case *MetaData:
+		if v == nil {
+			return nil, false
+		}
+		metadata := *v
+		metadata.Attributes = copyAttributes(v.Attributes)
+		return &metadata, true
+	case []Item:
+		return append([]Item(nil), v...), true
+	}
+	return nil, false
+}
+
+// cachedValue returns a copy of the stored value 'value' in the type returned by Secret
+func cachedValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case cachedBinary:
+		return append([]byte(nil), v...)
+	case map[string][]byte:
+		m := make(map[string]string, len(v))
+		for k, b := range v {
+			m[k] = string(b)
+		}
+		return m
+	case *MetaData:
+		metadata := *v
+		metadata.Attributes = copyAttributes(v.Attributes)
+		return &metadata
+	case []Item:
+		return append([]Item(nil), v...)
+	}
+	return value
+}
+
+// zeroCachedValue overwrites the secret value stored in 'value' with zeroes
+func zeroCachedValue(value interface{}) {
+	switch v := value.(type) {
+	case []byte:
+		zeroBytes(v)
+	case cachedBinary:
+		zeroBytes(v)
+	case map[string][]byte:
+		for k, b := range v {
+			zeroBytes(b)
+			delete(v, k)
+		}
+	}
+}
+
+// zeroBytes overwrites 'b' with zeroes
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// cachedResponse returns a copy of 'resp' with an empty body
+func cachedResponse(resp *http.Response) *http.Response {
+	if resp == nil {
+		return nil
+	}
+	copied := *resp
+	copied.Header = resp.Header.Clone()
+	copied.Body = ioutil.NopCloser(strings.NewReader(""))
+	copied.ContentLength = 0
+	return &copied
+}
@@ -0,0 +1,290 @@
+package secret
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretCacheTestSuite struct {
+	testutils.CfyTestSuite
+	counter *countingClient     // client that is cached
+	other   Secret              // another client of the same secrets
+	handle  *CachedSecretClient // interface to secret API
+	now     time.Time           // current time of handle
+}
+
+// countingClient counts the requests sent to the underlying secret client
+type countingClient struct {
+	Secret
+	gets      int
+	metadatas int
+	lists     int
+	inFlight  func() // called before each result is returned, while the request is in flight
+}
+
+func (c *countingClient) GetContext(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	c.gets++
+	defer c.returning()
+	return c.Secret.GetContext(ctx, path)
+}
+
+func (c *countingClient) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	c.metadatas++
+	defer c.returning()
+	return c.Secret.GetMetaDataContext(ctx, path)
+}
+
+func (c *countingClient) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	c.lists++
+	defer c.returning()
+	return c.Secret.ListContext(ctx, path)
+}
+
+func (c *countingClient) returning() {
+	if c.inFlight != nil {
+		c.inFlight()
+	}
+}
+
+func TestSecretCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretCacheTestSuite))
+}
+
+func (s *SecretCacheTestSuite) SetupTest() {
+	server := s.T().Name()
+	cl, err := NewSecretClient(server, ServerMemory, "", nil)
+	s.Require().NoError(err)
+	cl.(*MemorySecretClient).Clear()
+	s.other, _ = NewSecretClient(server, ServerMemory, "", nil)
+	s.counter = &countingClient{Secret: cl}
+	s.newHandle(&CacheOptions{TTL: time.Minute})
+}
+
+// newHandle creates the cached client with 'opts' and a clock that is controlled by the test
+func (s *SecretCacheTestSuite) newHandle(opts *CacheOptions) {
+	s.handle = NewCachedSecretClient(s.counter, opts)
+	s.now = time.Now()
+	s.handle.now = func() time.Time { return s.now }
+}
+
+func (s *SecretCacheTestSuite) TestGet() {
+	s.other.Create("app/text", "", "value")
+	s.other.Create("app/kv", "", map[string]string{"user": "admin"})
+
+	for i := 0; i < 3; i++ {
+		value, r, err := s.handle.Get("app/text")
+		s.Require().NoError(err)
+		s.Assert().Equal("value", value)
+		s.Assert().Equal(200, r.StatusCode)
+	}
+	s.Assert().Equal(1, s.counter.gets, "Should get secret once")
+
+	value, _, _ := s.handle.Get("/app/kv/")
+	value.(map[string]string)["user"] = "changed"
+	value, _, err := s.handle.Get("app/kv")
+	s.Require().NoError(err)
+	s.Assert().Equal(map[string]string{"user": "admin"}, value, "Cached value should not be changed by caller")
+	s.Assert().Equal(2, s.counter.gets)
+
+	s.other.Modify("app/text", "", "new value")
+	value, _, _ = s.handle.Get("app/text")
+	s.Assert().Equal("value", value, "Should return cached value before TTL expires")
+	s.now = s.now.Add(time.Minute)
+	value, _, _ = s.handle.Get("app/text")
+	s.Assert().Equal("new value", value, "Should get secret again after TTL expires")
+	s.Assert().Equal(3, s.counter.gets)
+
+	_, _, err = s.handle.Get("app/missing")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, _, err = s.handle.Get("app/missing")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	s.Assert().Equal(5, s.counter.gets, "Errors should not be cached")
+}
+
+func (s *SecretCacheTestSuite) TestMetaDataAndList() {
+	s.other.Create("app/text", "description", "value")
+
+	metadata, _, err := s.handle.GetMetaData("app/text")
+	s.Require().NoError(err)
+	metadata.Description = "changed"
+	metadata, _, _ = s.handle.GetMetaData("app/text")
+	s.Assert().Equal("description", metadata.Description)
+	s.Assert().Equal(1, s.counter.metadatas)
+
+	items, _, err := s.handle.List("app")
+	s.Require().NoError(err)
+	s.Assert().Len(items, 1)
+	items, _, _ = s.handle.List("app/")
+	s.Assert().Len(items, 1)
+	s.Assert().Equal(1, s.counter.lists)
+
+	_, _, err = s.handle.List("app/text")
+	s.Assert().ErrorIs(err, ErrNotSecretFolder)
+}
+
+func (s *SecretCacheTestSuite) TestInvalidate() {
+	s.handle.Create("app/text", "", "value")
+	s.handle.Get("app/text")
+	s.handle.GetMetaData("app/text")
+	items, _, _ := s.handle.List("app")
+	s.Require().Len(items, 1)
+	s.handle.List("")
+
+	_, _, _, err := s.handle.Modify("app/text", "", "new value")
+	s.Require().NoError(err)
+	value, _, _ := s.handle.Get("app/text")
+	s.Assert().Equal("new value", value, "Modify should invalidate cached value")
+	s.Assert().Equal(2, s.counter.gets)
+	s.handle.GetMetaData("app/text")
+	s.Assert().Equal(2, s.counter.metadatas, "Modify should invalidate cached metadata")
+	_, err = s.handle.ModifyMetaData("app/text", "changed", nil)
+	s.Require().NoError(err)
+	info, _, _ := s.handle.GetMetaData("app/text")
+	s.Assert().Equal("changed", info.Description, "ModifyMetaData should invalidate cached metadata")
+
+	s.handle.Create("app/folder/text", "", "value")
+	items, _, _ = s.handle.List("app")
+	s.Assert().Len(items, 2, "Create should invalidate listing of parent folders")
+	s.handle.List("")
+	s.Assert().Equal(4, s.counter.lists, "Create should invalidate listing of top level folder")
+
+	_, err = s.handle.Delete("app/text")
+	s.Require().NoError(err)
+	_, _, err = s.handle.Get("app/text")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Delete should invalidate cached value")
+	items, _, _ = s.handle.List("app")
+	s.Assert().Len(items, 1, "Delete should invalidate listing of parent folder")
+
+	s.Assert().NotZero(s.handle.Len())
+	s.handle.Purge()
+	s.Assert().Zero(s.handle.Len())
+}
+
+func (s *SecretCacheTestSuite) TestInvalidateInFlight() {
+	s.other.Create("app/text", "", "value")
+	s.counter.inFlight = func() {
+		// the old value is already read when another change is made through the cached client
+		s.counter.inFlight = nil
+		s.handle.Modify("app/text", "", "new value")
+	}
+	value, _, err := s.handle.Get("app/text")
+	s.Require().NoError(err)
+	s.Assert().Equal("value", value)
+	value, _, _ = s.handle.Get("app/text")
+	s.Assert().Equal("new value", value, "Value read before invalidation should not be cached")
+
+	s.counter.inFlight = func() {
+		s.counter.inFlight = nil
+		s.handle.Create("app/other", "", "value")
+	}
+	items, _, _ := s.handle.List("app")
+	s.Assert().Len(items, 1)
+	items, _, _ = s.handle.List("app")
+	s.Assert().Len(items, 2, "Listing read before invalidation should not be cached")
+
+	s.handle.GetMetaData("app/text")
+	s.counter.inFlight = func() {
+		s.counter.inFlight = nil
+		s.handle.Purge()
+	}
+	s.handle.Invalidate("app/text")
+	s.handle.GetMetaData("app/text")
+	s.Assert().Zero(s.handle.Len(), "Result read before Purge should not be cached")
+	s.Assert().Empty(s.handle.pending)
+}
+
+func (s *SecretCacheTestSuite) TestMove() {
+	s.handle.Create("app/folder/text", "", "value")
+	s.handle.Get("app/folder/text")
+	s.handle.List("app")
+	_, _, err := s.handle.Get("new/text")
+	s.Require().ErrorIs(err, ErrSecretNotFound)
+
+	_, _, err = s.handle.Rename("app/folder", "new")
+	s.Require().NoError(err)
+	_, _, err = s.handle.Get("app/folder/text")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Move should invalidate cached contents of folder")
+	items, _, _ := s.handle.List("app")
+	s.Require().Len(items, 1, "Move should invalidate listing of parent folder")
+	s.Assert().Equal("new", items[0].Name)
+
+	_, _, err = s.handle.Move("app/new", "new")
+	s.Require().NoError(err)
+	value, _, err := s.handle.Get("new/text")
+	s.Assert().NoError(err, "Move should invalidate cached results of destination")
+	s.Assert().Equal("value", value)
+}
+
+func (s *SecretCacheTestSuite) TestRevalidate() {
+	s.newHandle(&CacheOptions{TTL: time.Minute, Revalidate: true})
+	s.other.Create("app/text", "", "value")
+
+	value, _, err := s.handle.Get("app/text")
+	s.Require().NoError(err)
+	s.Assert().Equal("value", value)
+	s.Assert().Equal(1, s.counter.gets)
+	s.Assert().Equal(1, s.counter.metadatas)
+
+	s.now = s.now.Add(time.Minute)
+	value, _, _ = s.handle.Get("app/text")
+	s.Assert().Equal("value", value)
+	s.Assert().Equal(1, s.counter.gets, "Should not get unmodified secret again")
+	s.Assert().Equal(2, s.counter.metadatas)
+	s.handle.Get("app/text")
+	s.Assert().Equal(2, s.counter.metadatas, "TTL should be extended after revalidation")
+
+	time.Sleep(time.Millisecond)
+	s.other.Modify("app/text", "", "new value")
+	s.now = s.now.Add(time.Minute)
+	value, _, _ = s.handle.Get("app/text")
+	s.Assert().Equal("new value", value, "Should get modified secret")
+	s.Assert().Equal(2, s.counter.gets)
+}
+
+func (s *SecretCacheTestSuite) TestRevalidateInvalidateInFlight() {
+	s.newHandle(&CacheOptions{TTL: time.Minute, Revalidate: true})
+	s.other.Create("app/text", "old", "value")
+	s.counter.inFlight = func() {
+		// the old metadata is already read when the secret is changed through the cached client
+		s.counter.inFlight = nil
+		s.handle.ModifyMetaData("app/text", "new", nil)
+	}
+	value, _, err := s.handle.Get("app/text")
+	s.Require().NoError(err)
+	s.Assert().Equal("value", value)
+	info, _, _ := s.handle.GetMetaData("app/text")
+	s.Assert().Equal("new", info.Description, "Metadata read before invalidation should not be cached")
+	s.Assert().Equal(2, s.counter.metadatas)
+	s.Assert().Empty(s.handle.pending)
+}
+
+func (s *SecretCacheTestSuite) TestEviction() {
+	s.newHandle(&CacheOptions{MaxEntries: 2})
+	s.other.Create("one", "", "1")
+	s.other.Create("two", "", map[string]string{"k": "2"})
+	s.other.Create("three", "", "3")
+
+	s.handle.Get("one")
+	s.handle.Get("two")
+	s.handle.Get("one")
+	stored := s.handle.entries[cacheKey{op: cacheOpGet, path: "two"}].Value.(*cacheEntry).value.(map[string][]byte)
+	b := stored["k"]
+	s.handle.Get("three")
+	s.Assert().Equal(2, s.handle.Len())
+	s.Assert().Equal([]byte{0}, b, "Evicted value should be zeroed")
+	s.Assert().Empty(stored)
+
+	s.handle.Get("one")
+	s.Assert().Equal(3, s.counter.gets, "Recently used secret should not be evicted")
+	s.handle.Get("two")
+	s.Assert().Equal(4, s.counter.gets, "Least recently used secret should be evicted")
+
+	stored2 := s.handle.entries[cacheKey{op: cacheOpGet, path: "one"}].Value.(*cacheEntry).value.([]byte)
+	s.handle.Invalidate("one")
+	s.Assert().Equal([]byte{0}, stored2, "Invalidated value should be zeroed")
+}
@@ -0,0 +1,163 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// versionModifier is implemented by the secret clients that can modify a secret only if it is
+// still in the version observed in its metadata, in a single request
+type versionModifier interface {
+	modifyIfVersion(ctx context.Context, path string, observed *MetaData, description string, value interface{}) (bool, string, *http.Response, error)
+}
+
+// ModifyIfUnchanged is the same as Modify, but the secret in 'path' is only modified if it has not
+// changed since 'observed' is returned by GetMetaData.  Otherwise ErrConflict is returned, and the
+// caller can get the secret again and decide whether to retry.  This prevents lost updates when
+// several clients read and modify the same secret.
+//
+// A secret is considered changed if its ID, type, version or modification time is different, e.g.,
+// when it has been modified, or deleted and created again.  HashiCorp Vault checks the version in
+// the write request itself.  PAS does not support preconditions in its REST API, so for PAS and
+// the other secret stores the metadata is read again and compared before the secret is modified,
+// which cannot detect a modification made in the short time between the check and the write.
+//
+// The metadata is not read from the caches of CachedSecretClient in 'cl', including one under a
+// VersionedSecretClient, and their results of 'path' are invalidated after the change.  A cache under
+// another Secret implementation that wraps a client is still read, so its metadata may be stale.
+//
+// The following errors may be returned, in addition to those returned by Modify and GetMetaData:
+//	ErrConflict:  The secret has been changed or deleted since 'observed' is returned.
+//	ErrMetaDataNotSupported:  The secret store reports neither the version nor the modification
+//		time, e.g., TSS, so a change cannot be detected.
+func ModifyIfUnchanged(cl Secret, path string, observed *MetaData, description string, value interface{}) (bool, string, *http.Response, error) {
+	return ModifyIfUnchangedContext(context.Background(), cl, path, observed, description, value)
+}
+
+// ModifyIfUnchangedContext is the same as ModifyIfUnchanged, but uses 'ctx' for the requests.
+func ModifyIfUnchangedContext(ctx context.Context, cl Secret, path string, observed *MetaData, description string, value interface{}) (bool, string, *http.Response, error) {
+	path = strings.Trim(path, "/")
+	cl, done := directClient(cl, path)
+	defer done()
+	return modifyIfUnchanged(ctx, cl, path, observed, nil, description, value)
+}
+
+// DeleteIfUnchanged is the same as Delete, but the secret or folder in 'path' is only deleted if it
+// has not changed since 'observed' is returned by GetMetaData.  The metadata is compared before the
+// secret is deleted, as in ModifyIfUnchanged, and the same errors may be returned.
+func DeleteIfUnchanged(cl Secret, path string, observed *MetaData) (*http.Response, error) {
+	return DeleteIfUnchangedContext(context.Background(), cl, path, observed)
+}
+
+// DeleteIfUnchangedContext is the same as DeleteIfUnchanged, but uses 'ctx' for the requests.
+func DeleteIfUnchangedContext(ctx context.Context, cl Secret, path string, observed *MetaData) (*http.Response, error) {
+	path = strings.Trim(path, "/")
+	cl, done := directClient(cl, path)
+	defer done()
+	if err := checkUnchanged(ctx, cl, path, observed, nil); err != nil {
+		return nil, err
+	}
+	return cl.DeleteContext(ctx, path)
+}
+
+// directClient returns 'cl' without the CachedSecretClients in it, so that the current metadata is
+// compared instead of the cached one.  The returned function must be called after the secret is
+// changed.  It invalidates the results of 'path' cached by the clients removed.
+//
+// Caches are removed through CachedSecretClient and VersionedSecretClient.  A VersionedSecretClient
+// is copied onto the client under it without caches, so that the previous value is still archived.
+// Caches under other Secret implementations that wrap a client are still used.
+func directClient(cl Secret, path string) (Secret, func()) {
+	direct, invalidations := withoutCache(cl, []string{path})
+	return direct, func() {
+		for _, invalidate := range invalidations {
+			invalidate()
+		}
+	}
+}
+
+// withoutCache returns 'cl' without the CachedSecretClients in it, and the functions that invalidate
+// the results of 'paths' cached by them.  'cl' is returned with no function if it has no cache.
+func withoutCache(cl Secret, paths []string) (Secret, []func()) {
+	switch c := cl.(type) {
+	case *CachedSecretClient:
+		direct, invalidations := withoutCache(c.Secret, paths)
+		return direct, append(invalidations, func() {
+			for _, path := range paths {
+				c.Invalidate(path)
+			}
+		})
+	case *VersionedSecretClient:
+		// the previous value is archived in the versions folder under the client
+		paths = append(paths[:len(paths):len(paths)], c.versionsPath(paths[0]))
+		direct, invalidations := withoutCache(c.Secret, paths)
+		if invalidations == nil {
+			return cl, nil
+		}
+		versioned := *c
+		versioned.Secret = direct
+		return &versioned, invalidations
+	}
+	return cl, nil
+}
+
+// modifyIfUnchanged modifies the secret in 'path' if it has not changed since 'observed' and
+// 'observedValue' are returned by GetMetaData and Get.  'observedValue' is only needed when the
+// secret store reports neither the version nor the modification time, and can be nil.
+func modifyIfUnchanged(ctx context.Context, cl Secret, path string, observed *MetaData, observedValue interface{}, description string, value interface{}) (bool, string, *http.Response, error) {
+	if m, ok := cl.(versionModifier); ok && observed.Version != "" {
+		success, id, r, err := m.modifyIfVersion(ctx, path, observed, description, value)
+		switch {
+		case errors.Is(err, ErrConflict):
+			err = fmt.Errorf("[%s] has been changed: %w", path, ErrConflict)
+		case errors.Is(err, ErrSecretNotFound):
+			err = fmt.Errorf("[%s] has been deleted: %w", path, ErrConflict)
+		}
+		return success, id, r, err
+	}
+	if err := checkUnchanged(ctx, cl, path, observed, observedValue); err != nil {
+		return false, "", nil, err
+	}
+	return cl.ModifyContext(ctx, path, description, value)
+}
+
+// checkUnchanged returns ErrConflict if the secret in 'path' has changed since 'observed' is
+// returned by GetMetaData.  If the secret store reports neither the version nor the modification
+// time, the current value is compared with 'value' instead, or ErrMetaDataNotSupported is returned
+// if 'value' is nil.
+func checkUnchanged(ctx context.Context, cl Secret, path string, observed *MetaData, value interface{}) error {
+	info, _, err := cl.GetMetaDataContext(ctx, path)
+	if errors.Is(err, ErrSecretNotFound) {
+		return fmt.Errorf("[%s] has been deleted: %w", path, ErrConflict)
+	}
+	if err != nil {
+		return err
+	}
+	if info.ID != observed.ID || info.Type != observed.Type || info.Version != observed.Version ||
+		!info.WhenModified.Equal(observed.WhenModified) {
+		return fmt.Errorf("[%s] has been changed: %w", path, ErrConflict)
+	}
+	if info.Version != "" || !info.WhenModified.IsZero() {
+		return nil
+	}
+	if value == nil {
+		return fmt.Errorf("cannot tell whether [%s] has changed: %w", path, ErrMetaDataNotSupported)
+	}
+
+	// the metadata cannot tell whether the secret has changed
+	current, _, err := cl.GetContext(ctx, path)
+	if errors.Is(err, ErrSecretNotFound) {
+		return fmt.Errorf("[%s] has been deleted: %w", path, ErrConflict)
+	}
+	if err != nil {
+		return err
+	}
+	if current, _, _ = normalizeValue(current); !reflect.DeepEqual(current, value) {
+		return fmt.Errorf("[%s] has been changed: %w", path, ErrConflict)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretConditionalTestSuite struct {
+	memorySuite
+}
+
+func TestSecretConditionalTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretConditionalTestSuite))
+}
+
+func (s *SecretConditionalTestSuite) TestModifyIfUnchanged() {
+	s.handle.Create("app/db", "", "v1")
+	observed, _, _ := s.handle.GetMetaData("app/db")
+
+	success, _, _, err := ModifyIfUnchanged(s.handle, "/app/db", observed, "", "v2")
+	s.Require().NoError(err)
+	s.Assert().True(success)
+	value, _, _ := s.handle.Get("app/db")
+	s.Assert().Equal("v2", value)
+
+	_, _, _, err = ModifyIfUnchanged(s.handle, "app/db", observed, "", "v3")
+	s.Assert().ErrorIs(err, ErrConflict, "Stale metadata should be rejected")
+	value, _, _ = s.handle.Get("app/db")
+	s.Assert().Equal("v2", value)
+
+	observed, _, _ = s.handle.GetMetaData("app/db")
+	s.handle.ModifyMetaData("app/db", "changed", nil)
+	_, _, _, err = ModifyIfUnchanged(s.handle, "app/db", observed, "", "v3")
+	s.Assert().ErrorIs(err, ErrConflict, "Change of metadata should be detected")
+
+	observed, _, _ = s.handle.GetMetaData("app/db")
+	s.handle.Delete("app/db")
+	_, _, _, err = ModifyIfUnchanged(s.handle, "app/db", observed, "", "v3")
+	s.Assert().ErrorIs(err, ErrConflict, "Deleted secret should be detected")
+	s.handle.Create("app/db", "", "v1")
+	_, _, _, err = ModifyIfUnchanged(s.handle, "app/db", observed, "", "v3")
+	s.Assert().ErrorIs(err, ErrConflict, "Secret created again should be detected")
+}
+
+func (s *SecretConditionalTestSuite) TestDeleteIfUnchanged() {
+	s.handle.Create("app/db", "", "v1")
+	observed, _, _ := s.handle.GetMetaData("app/db")
+	s.handle.Modify("app/db", "", "v2")
+
+	_, err := DeleteIfUnchanged(s.handle, "app/db", observed)
+	s.Assert().ErrorIs(err, ErrConflict)
+	_, _, err = s.handle.Get("app/db")
+	s.Require().NoError(err, "Secret should not be deleted")
+
+	observed, _, _ = s.handle.GetMetaData("app/db")
+	_, err = DeleteIfUnchanged(s.handle, "app/db", observed)
+	s.Require().NoError(err)
+	_, _, err = s.handle.Get("app/db")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+
+	_, err = DeleteIfUnchanged(s.handle, "app/db", observed)
+	s.Assert().ErrorIs(err, ErrConflict)
+}
+
+func (s *SecretConditionalTestSuite) TestCachedAndUnsupported() {
+	cached := NewCachedSecretClient(s.handle, nil)
+	s.handle.Create("db", "", "v1")
+	observed, _, _ := cached.GetMetaData("db")
+	s.handle.Modify("db", "", "v2")
+	_, _, _, err := ModifyIfUnchanged(cached, "db", observed, "", "v3")
+	s.Assert().ErrorIs(err, ErrConflict, "Cached metadata should not be compared")
+
+	observed, _, _ = cached.GetMetaData("db")
+	_, _, _, err = ModifyIfUnchanged(cached, "db", observed, "", "v3")
+	s.Require().NoError(err)
+	value, _, _ := cached.Get("db")
+	s.Assert().Equal("v3", value, "Cached value should be invalidated")
+
+	// cache under another decorator
+	versioned := NewVersionedSecretClient(NewCachedSecretClient(s.handle, nil), nil)
+	observed, _, _ = versioned.GetMetaData("db")
+	s.handle.Modify("db", "", "v4")
+	_, _, _, err = ModifyIfUnchanged(versioned, "db", observed, "", "v5")
+	s.Assert().ErrorIs(err, ErrConflict, "Metadata cached under VersionedSecretClient should not be compared")
+	observed, _, _ = s.handle.GetMetaData("db")
+	_, _, _, err = ModifyIfUnchanged(versioned, "db", observed, "", "v5")
+	s.Require().NoError(err)
+	value, _, _ = versioned.Get("db")
+	s.Assert().Equal("v5", value, "Cached value should be invalidated")
+	versions, _ := versioned.ListVersions("db")
+	s.Assert().Len(versions, 1, "Previous value should be archived")
+
+	cl := &racingClient{Secret: s.handle, noModified: true}
+	observed, _, _ = cl.GetMetaDataContext(context.Background(), "db")
+	_, _, _, err = ModifyIfUnchanged(cl, "db", observed, "", "v4")
+	s.Assert().ErrorIs(err, ErrMetaDataNotSupported)
+	_, err = DeleteIfUnchanged(cl, "db", observed)
+	s.Assert().ErrorIs(err, ErrMetaDataNotSupported)
+}
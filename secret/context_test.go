@@ -0,0 +1,103 @@
+package secret
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+// SecretContextTestSuite tests the context-aware APIs of PASSecretClient against a
+// local HTTP server.  It does not require a PAS tenant.
+type SecretContextTestSuite struct {
+	testutils.CfyTestSuite
+	server  *httptest.Server // local HTTP server that stands in for PAS
+	handle  Secret           // interface to secret API
+	release chan struct{}    // closed to release all blocked requests
+}
+
+func TestSecretContextTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretContextTestSuite))
+}
+
+func (s *SecretContextTestSuite) SetupTest() {
+	s.release = make(chan struct{})
+	release := s.release
+
+	// the handler blocks until either the request is canceled or the test releases it
+	s.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	s.handle = newTestPASClient(s.server, "token")
+}
+
+func (s *SecretContextTestSuite) TearDownTest() {
+	close(s.release)
+	s.server.Close()
+}
+
+func (s *SecretContextTestSuite) TestDeadlineExceeded() {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, _, err := s.handle.GetContext(ctx, "folder/secret")
+	s.Assert().ErrorIs(err, context.DeadlineExceeded, "GetContext should return DeadlineExceeded")
+	s.Assert().NotErrorIs(err, ErrUnexpectedResponse, "Timeout should not be reported as unexpected response")
+
+	_, _, err = s.handle.GetMetaDataContext(ctx, "folder/secret")
+	s.Assert().ErrorIs(err, context.DeadlineExceeded, "GetMetaDataContext should return DeadlineExceeded")
+
+	_, _, err = s.handle.ListContext(ctx, "folder")
+	s.Assert().ErrorIs(err, context.DeadlineExceeded, "ListContext should return DeadlineExceeded")
+}
+
+func (s *SecretContextTestSuite) TestCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	success, _, _, err := s.handle.CreateContext(ctx, "folder/secret", "", "value")
+	s.Assert().False(success, "Create should fail when request is canceled")
+	s.Assert().ErrorIs(err, context.Canceled, "CreateContext should return Canceled")
+
+	// context is already canceled for the following requests
+	success, _, _, err = s.handle.CreateFolderContext(ctx, "folder", "")
+	s.Assert().False(success, "CreateFolder should fail when request is canceled")
+	s.Assert().ErrorIs(err, context.Canceled, "CreateFolderContext should return Canceled")
+
+	success, _, _, err = s.handle.ModifyContext(ctx, "folder/secret", "", "new value")
+	s.Assert().False(success, "Modify should fail when request is canceled")
+	s.Assert().ErrorIs(err, context.Canceled, "ModifyContext should return Canceled")
+
+	_, err = s.handle.DeleteContext(ctx, "folder/secret")
+	s.Assert().ErrorIs(err, context.Canceled, "DeleteContext should return Canceled")
+}
+
+func (s *SecretContextTestSuite) TestNoDeadline() {
+	// release the request so that the server responds immediately
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		s.release <- struct{}{}
+	}()
+
+	_, r, err := s.handle.GetContext(context.Background(), "folder/secret")
+	s.Assert().ErrorIs(err, ErrUnexpectedResponse, "Server error should be returned as ErrUnexpectedResponse")
+	s.Require().NotNil(r, "HTTP response should be returned")
+	s.Assert().Equal(500, r.StatusCode, "HTTP status should be 500")
+}
+
+// newTestPASClient returns a PAS secret client that sends all requests to the local
+// HTTPS server 'ts'
+func newTestPASClient(ts *httptest.Server, token string) Secret {
+	return newPASSecretClient(ts.URL, token, ts.Client)
+}
@@ -0,0 +1,352 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// CopyAction describes the outcome of copying a secret or folder in Copy.
+type CopyAction string
+
+// constant definition for copy actions
+const (
+	CopyCreated     CopyAction = "created"     // secret/folder is created in the destination
+	CopyOverwritten CopyAction = "overwritten" // existing secret is replaced
+	CopySkipped     CopyAction = "skipped"     // existing secret/folder is different and is not changed
+	CopyUnchanged   CopyAction = "unchanged"   // secret/folder already exists with the same description and value
+	CopyFailed      CopyAction = "failed"      // secret/folder cannot be copied.  See CopyResult.Err
+)
+
+// CopyOptions specifies the options for Copy.
+type CopyOptions struct {
+	// OnConflict specifies what to do when a secret in the destination has a different value or
+	// description.  Default is ConflictSkip.
+	OnConflict ConflictPolicy
+
+	// Preview specifies to only compare the source with the destination, without changing the
+	// destination.  The results report what would be done, and the differences found.
+	Preview bool
+}
+
+// CopyResult is the outcome of copying a secret or folder in Copy.
+type CopyResult struct {
+	SrcPath string     // full path of secret/folder in the source
+	DstPath string     // full path of secret/folder in the destination
+	Type    string     // type of secret: SecretTypeFolder, SecretTypeText, SecretTypeKV or SecretTypeFile
+	Action  CopyAction // what is done, or would be done in a preview, for the secret/folder
+	Diff    []string   // differences between an existing secret/folder and the source
+	Err     error      // reason of failure if Action is CopyFailed
+}
+
+// Copy copies the secret or folder in 'srcPath' of 'src' to 'dstPath' of 'dst', including all the
+// secrets and folders under a folder.  'src' and 'dst' can be clients of different tenants or
+// secret stores.  The description and value of each text, keyvalue and file secret, and the
+// description of each folder, are copied.  'srcPath' can be "" or "/" to copy all secrets that the caller can
+// access, and 'dstPath' can be "" or "/" to copy the contents of a folder to the top level.
+//
+// A secret that already exists in the destination with the same description and value is
+// unchanged.  'opts' specifies what to do when it is different, and whether to only preview
+// the changes.  It can be nil.  Existing folders are not modified, and an empty description in the
+// source does not replace the description of an existing secret or folder.
+//
+// Copy returns the result of each secret or folder copied.  A secret or folder that cannot be copied
+// does not stop the copy, unless it already exists and the conflict policy is ConflictFail.  The
+// contents of a folder that cannot be copied are skipped.  Each difference in Diff is "description",
+// "type", "value", or "key [name] added/removed/changed" for a keyvalue secret.  Values are not
+// included.
+//
+// The following errors may be returned, in addition to those returned by Walk:
+//	ErrBadPathName:  'dstPath' is in 'srcPath' of the same client.
+//	ErrCopyIncomplete:  Some secrets/folders cannot be copied.  Check the results for details.
+//	ErrExists:  A secret is different in the destination and the conflict policy is ConflictFail.
+//	ErrInvalidExportOption:  The conflict policy in 'opts' is not supported.
+func Copy(src Secret, srcPath string, dst Secret, dstPath string, opts *CopyOptions) ([]CopyResult, error) {
+	return CopyContext(context.Background(), src, srcPath, dst, dstPath, opts)
+}
+
+// CopyContext is the same as Copy, but uses 'ctx' for all the requests.
+// If 'ctx' is canceled, CopyContext stops and returns the results so far and the error in 'ctx'.
+func CopyContext(ctx context.Context, src Secret, srcPath string, dst Secret, dstPath string, opts *CopyOptions) ([]CopyResult, error) {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	policy, err := checkConflictPolicy(opts.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+	srcRoot := strings.Trim(srcPath, "/")
+	dstRoot := strings.Trim(dstPath, "/")
+	if sameClient(src, dst) && srcRoot != dstRoot && (srcRoot == "" || strings.HasPrefix(dstRoot, srcRoot+"/")) {
+		return nil, fmt.Errorf("Cannot copy [%s] into itself: %w", srcRoot, ErrBadPathName)
+	}
+
+	c := &copier{ctx: ctx, src: src, dst: dst, policy: policy, preview: opts.Preview}
+	err = WalkContext(ctx, src, srcRoot, &WalkOptions{MetaData: true}, func(path string, info *MetaData, err error) error {
+		isFolder := info != nil && strings.EqualFold(info.Type, SecretTypeFolder)
+		target := dstRoot
+		if path != srcRoot {
+			target = joinPath(dstRoot, relativePath(srcRoot, path))
+		}
+		if err != nil {
+			if info == nil || ctx.Err() != nil {
+				return err
+			}
+			c.fail(CopyResult{SrcPath: path, DstPath: target, Type: itemType(info), Err: err})
+			return SkipFolder
+		}
+		if target == "" {
+			// contents of the source are copied to the top level
+			if !isFolder {
+				return fmt.Errorf("Cannot copy secret [%s] to top level folder: %w", path, ErrBadPathName)
+			}
+			return nil
+		}
+
+		result := c.copyItem(path, target, info)
+		if result.Err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.fail(result)
+			if errors.Is(result.Err, ErrExists) && policy == ConflictFail {
+				return fmt.Errorf("copy stopped at [%s]: %w", target, result.Err)
+			}
+			if isFolder {
+				return SkipFolder
+			}
+			return nil
+		}
+		c.results = append(c.results, result)
+		return nil
+	})
+	if err != nil {
+		return c.results, err
+	}
+	if c.failed > 0 {
+		return c.results, fmt.Errorf("%d of %d secrets/folders cannot be copied: %w", c.failed, len(c.results), ErrCopyIncomplete)
+	}
+	return c.results, nil
+}
+
+// copier keeps the state of Copy
+type copier struct {
+	ctx     context.Context
+	src     Secret
+	dst     Secret
+	policy  ConflictPolicy
+	preview bool
+	results []CopyResult
+	failed  int // number of results that are CopyFailed
+}
+
+// fail adds 'result' as a failed result
+func (c *copier) fail(result CopyResult) {
+	result.Action = CopyFailed
+	c.results = append(c.results, result)
+	c.failed++
+}
+
+// copyItem copies the secret or folder in 'path' described by 'info' to 'target', and returns
+// the result.  Err is set in the result if it fails.
+func (c *copier) copyItem(path string, target string, info *MetaData) CopyResult {
+	result := CopyResult{SrcPath: path, DstPath: target, Type: itemType(info), Action: CopyCreated}
+
+	var value interface{}
+	if !strings.EqualFold(info.Type, SecretTypeFolder) {
+		v, _, err := c.src.GetContext(c.ctx, path)
+		if err == nil {
+			value, result.Type, err = normalizeValue(v)
+		}
+		if err != nil {
+			result.Err = fmt.Errorf("cannot get value of [%s]: %w", path, err)
+			return result
+		}
+	}
+
+	existing, _, err := c.dst.GetMetaDataContext(c.ctx, target)
+	if errors.Is(err, ErrSecretNotFound) || errors.Is(err, ErrFolderNotFound) {
+		if c.preview {
+			return result
+		}
+		if value == nil {
+			_, _, _, err = c.dst.CreateFolderContext(c.ctx, target, info.Description)
+		} else {
+			_, _, _, err = c.dst.CreateContext(c.ctx, target, info.Description, value)
+		}
+		if err != nil {
+			result.Err = err
+		}
+		return result
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	// compare with the existing secret or folder.  An empty description is not copied, as Modify
+	// keeps the existing description.
+	description := info.Description
+	if description == "" {
+		description = existing.Description
+	}
+	existingFolder := strings.EqualFold(existing.Type, SecretTypeFolder)
+	if value == nil {
+		if !existingFolder {
+			result.Err = ErrNotSecretFolder
+			return result
+		}
+		if description != existing.Description {
+			result.Diff = []string{"description"}
+			result.Action = CopySkipped
+		} else {
+			result.Action = CopyUnchanged
+		}
+		return result
+	}
+	if existingFolder {
+		result.Err = ErrNotSecretObject
+		return result
+	}
+	v, _, err := c.dst.GetContext(c.ctx, target)
+	var existingValue interface{}
+	if err == nil {
+		existingValue, _, err = normalizeValue(v)
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("cannot get value of [%s]: %w", target, err)
+		return result
+	}
+	result.Diff = diffSecrets(description, value, existing.Description, existingValue)
+	switch {
+	case len(result.Diff) == 0:
+		result.Action = CopyUnchanged
+	case c.policy == ConflictSkip:
+		result.Action = CopySkipped
+	case c.policy == ConflictFail:
+		result.Err = ErrExists
+	case result.Diff[len(result.Diff)-1] == "type":
+		result.Err = ErrCannotModifySecretType
+	default:
+		result.Action = CopyOverwritten
+		if !c.preview {
+			_, _, _, result.Err = c.dst.ModifyContext(c.ctx, target, info.Description, value)
+		}
+	}
+	return result
+}
+
+// sameClient reports whether 'a' and 'b' are the same client.  Only pointers are compared, as
+// comparing clients of types that are not comparable, e.g., structs with slices, panics.
+func sameClient(a Secret, b Secret) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.Kind() == reflect.Ptr && vb.Kind() == reflect.Ptr && va.Type() == vb.Type() && va.Pointer() == vb.Pointer()
+}
+
+// itemType returns the type of the secret or folder described by 'info' in lower case
+func itemType(info *MetaData) string {
+	if strings.EqualFold(info.Type, SecretTypeFolder) {
+		return SecretTypeFolder
+	}
+	return strings.ToLower(info.Type)
+}
+
+// checkConflictPolicy checks whether 'policy' is supported, and returns the policy to use
+func checkConflictPolicy(policy ConflictPolicy) (ConflictPolicy, error) {
+	switch policy {
+	case "":
+		return ConflictSkip, nil
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy [%s]: %w", policy, ErrInvalidExportOption)
+}
+
+// normalizeValue returns the value of a text, keyvalue or file secret returned by Get as a string,
+// map[string]string or []byte, and the type of the secret.
+func normalizeValue(value interface{}) (interface{}, string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, SecretTypeText, nil
+	case []byte:
+		return v, SecretTypeFile, nil
+	case map[string]string:
+		return v, SecretTypeKV, nil
+	case map[string]interface{}:
+		kv := make(map[string]string, len(v))
+		for key, val := range v {
+			kv[key] = fmt.Sprint(val)
+		}
+		return kv, SecretTypeKV, nil
+	}
+	return nil, "", ErrSecretTypeNotSupported
+}
+
+// diffSecrets returns the differences between the secret with 'srcDesc' and 'srcValue', and the
+// existing secret with 'dstDesc' and 'dstValue'.  A different type is always the last difference.
+func diffSecrets(srcDesc string, srcValue interface{}, dstDesc string, dstValue interface{}) []string {
+	var diff []string
+	if srcDesc != dstDesc {
+		diff = append(diff, "description")
+	}
+	// a binary value is returned as text by a client without WithBinaryValues
+	if s, ok := srcValue.([]byte); ok {
+		if _, ok := dstValue.(string); ok {
+			srcValue = binaryText(s)
+		}
+	} else if d, ok := dstValue.([]byte); ok {
+		if _, ok := srcValue.(string); ok {
+			dstValue = binaryText(d)
+		}
+	}
+	switch s := srcValue.(type) {
+	case string:
+		d, ok := dstValue.(string)
+		if !ok {
+			return append(diff, "type")
+		}
+		if s != d {
+			diff = append(diff, "value")
+		}
+	case []byte:
+		d, ok := dstValue.([]byte)
+		if !ok {
+			return append(diff, "type")
+		}
+		if !bytes.Equal(s, d) {
+			diff = append(diff, "value")
+		}
+	case map[string]string:
+		d, ok := dstValue.(map[string]string)
+		if !ok {
+			return append(diff, "type")
+		}
+		keys := make([]string, 0, len(s)+len(d))
+		for key := range s {
+			keys = append(keys, key)
+		}
+		for key := range d {
+			if _, ok := s[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sv, inSrc := s[key]
+			dv, inDst := d[key]
+			switch {
+			case !inDst:
+				diff = append(diff, fmt.Sprintf("key [%s] added", key))
+			case !inSrc:
+				diff = append(diff, fmt.Sprintf("key [%s] removed", key))
+			case sv != dv:
+				diff = append(diff, fmt.Sprintf("key [%s] changed", key))
+			}
+		}
+	}
+	return diff
+}
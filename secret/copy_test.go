@@ -0,0 +1,172 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretCopyTestSuite struct {
+	testutils.CfyTestSuite
+	src *MemorySecretClient
+	dst *MemorySecretClient
+}
+
+func TestSecretCopyTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretCopyTestSuite))
+}
+
+func (s *SecretCopyTestSuite) SetupTest() {
+	s.src = newMemorySecretClient("")
+	s.dst = newMemorySecretClient("")
+	s.src.CreateFolder("staging", "staging secrets")
+	s.src.Create("staging/db", "database", "password")
+	s.src.Create("staging/api/keys", "", map[string]string{"id": "admin", "key": "secret"})
+}
+
+// copyActions returns the destination path and action of each result
+func copyActions(results []CopyResult) []string {
+	var result []string
+	for _, r := range results {
+		result = append(result, r.DstPath+" "+string(r.Action))
+	}
+	return result
+}
+
+func (s *SecretCopyTestSuite) TestCopy() {
+	results, err := Copy(s.src, "/staging/", s.dst, "prod", nil)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"prod created", "prod/api created", "prod/api/keys created", "prod/db created"}, copyActions(results))
+	s.Assert().Equal("staging/api/keys", results[2].SrcPath)
+	s.Assert().Equal(SecretTypeKV, results[2].Type)
+	s.Assert().Equal(SecretTypeText, results[3].Type)
+
+	metadata, _, err := s.dst.GetMetaData("prod")
+	s.Require().NoError(err)
+	s.Assert().Equal("staging secrets", metadata.Description, "Folder description should be copied")
+	value, _, _ := s.dst.Get("prod/db")
+	s.Assert().Equal("password", value)
+	metadata, _, _ = s.dst.GetMetaData("prod/db")
+	s.Assert().Equal("database", metadata.Description)
+	value, _, _ = s.dst.Get("prod/api/keys")
+	s.Assert().Equal(map[string]string{"id": "admin", "key": "secret"}, value)
+
+	results, err = Copy(s.src, "staging", s.dst, "prod", nil)
+	s.Require().NoError(err)
+	for _, result := range results {
+		s.Assert().Equal(CopyUnchanged, result.Action, "Copying again should not change [%s]", result.DstPath)
+	}
+
+	results, err = Copy(s.src, "staging/db", s.dst, "other/db", nil)
+	s.Require().NoError(err, "Should copy single secret")
+	s.Assert().Equal([]string{"other/db created"}, copyActions(results))
+
+	_, err = Copy(s.src, "missing", s.dst, "prod", nil)
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, err = Copy(s.src, "staging", s.src, "staging/copy", nil)
+	s.Assert().ErrorIs(err, ErrBadPathName, "Folder cannot be copied into itself")
+	_, err = Copy(s.src, "staging/db", s.dst, "/", nil)
+	s.Assert().ErrorIs(err, ErrBadPathName, "Secret cannot be copied to top level folder")
+	_, err = Copy(s.src, "staging", s.dst, "prod", &CopyOptions{OnConflict: "replace"})
+	s.Assert().ErrorIs(err, ErrInvalidExportOption)
+}
+
+func (s *SecretCopyTestSuite) TestTopLevel() {
+	results, err := Copy(s.src, "staging", s.dst, "", nil)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"api created", "api/keys created", "db created"}, copyActions(results))
+
+	results, err = Copy(s.src, "", s.dst, "all", nil)
+	s.Require().NoError(err)
+	s.Assert().Equal("all created", copyActions(results)[0])
+	_, _, err = s.dst.Get("all/staging/api/keys")
+	s.Assert().NoError(err)
+}
+
+func (s *SecretCopyTestSuite) TestConflicts() {
+	s.dst.CreateFolder("prod", "staging secrets")
+	s.dst.Create("prod/db", "old database", "old password")
+	s.dst.Create("prod/api/keys", "", map[string]string{"id": "admin", "key": "old", "extra": "value"})
+
+	results, err := Copy(s.src, "staging", s.dst, "prod", &CopyOptions{Preview: true, OnConflict: ConflictOverwrite})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"prod unchanged", "prod/api unchanged", "prod/api/keys overwritten", "prod/db overwritten"}, copyActions(results))
+	s.Assert().Equal([]string{"key [extra] removed", "key [key] changed"}, results[2].Diff)
+	s.Assert().Equal([]string{"description", "value"}, results[3].Diff)
+	value, _, _ := s.dst.Get("prod/db")
+	s.Assert().Equal("old password", value, "Preview should not change destination")
+
+	results, err = Copy(s.src, "staging", s.dst, "prod", nil)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"prod unchanged", "prod/api unchanged", "prod/api/keys skipped", "prod/db skipped"}, copyActions(results))
+	value, _, _ = s.dst.Get("prod/db")
+	s.Assert().Equal("old password", value)
+
+	results, err = Copy(s.src, "staging", s.dst, "prod", &CopyOptions{OnConflict: ConflictFail})
+	s.Assert().ErrorIs(err, ErrExists)
+	s.Assert().Equal("copy stopped at [prod/api/keys]: Secret/folder already exists", err.Error())
+	s.Assert().Equal([]string{"prod unchanged", "prod/api unchanged", "prod/api/keys failed"}, copyActions(results))
+
+	_, err = Copy(s.src, "staging", s.dst, "prod", &CopyOptions{OnConflict: ConflictOverwrite})
+	s.Require().NoError(err)
+	value, _, _ = s.dst.Get("prod/db")
+	s.Assert().Equal("password", value, "Existing secret should be overwritten")
+	value, _, _ = s.dst.Get("prod/api/keys")
+	s.Assert().Equal(map[string]string{"id": "admin", "key": "secret"}, value)
+}
+
+func (s *SecretCopyTestSuite) TestEmptyDescription() {
+	s.dst.Create("prod/api/keys", "api keys", map[string]string{"id": "admin", "key": "secret"})
+
+	results, err := Copy(s.src, "staging/api", s.dst, "prod/api", &CopyOptions{OnConflict: ConflictOverwrite})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"prod/api unchanged", "prod/api/keys unchanged"}, copyActions(results), "Empty description should not be copied")
+	metadata, _, _ := s.dst.GetMetaData("prod/api/keys")
+	s.Assert().Equal("api keys", metadata.Description)
+}
+
+// valueClient is a client whose type cannot be compared with ==
+type valueClient struct {
+	Secret
+	tags []string
+}
+
+func (s *SecretCopyTestSuite) TestClientNotComparable() {
+	cl := valueClient{Secret: s.src, tags: []string{"src"}}
+	results, err := Copy(cl, "staging", valueClient{Secret: s.dst}, "prod", nil)
+	s.Require().NoError(err, "Clients that are not comparable should not panic")
+	s.Assert().Len(results, 4)
+
+	_, err = Copy(s.src, "staging", s.src, "staging/copy", nil)
+	s.Assert().ErrorIs(err, ErrBadPathName, "Should not copy into itself")
+}
+
+func (s *SecretCopyTestSuite) TestFailures() {
+	s.dst.Create("prod/api", "", "not a folder")
+	s.dst.Create("prod/db", "", map[string]string{"k": "v"})
+
+	results, err := Copy(s.src, "staging", s.dst, "prod", &CopyOptions{OnConflict: ConflictOverwrite})
+	s.Assert().ErrorIs(err, ErrCopyIncomplete)
+	s.Assert().Equal([]string{"prod skipped", "prod/api failed", "prod/db failed"}, copyActions(results), "Contents of failed folder should be skipped")
+	s.Assert().Equal([]string{"description"}, results[0].Diff, "Different description of folder should be reported")
+	s.Assert().ErrorIs(results[1].Err, ErrNotSecretFolder)
+	s.Assert().ErrorIs(results[2].Err, ErrCannotModifySecretType)
+	s.Assert().Equal([]string{"description", "type"}, results[2].Diff)
+
+	cl := &failingClient{Secret: s.dst, failCreate: map[string]bool{"new/db": true}}
+	results, err = Copy(s.src, "staging", cl, "new", nil)
+	s.Assert().ErrorIs(err, ErrCopyIncomplete)
+	s.Assert().Equal("1 of 4 secrets/folders cannot be copied: Some secrets/folders cannot be copied", err.Error())
+	s.Assert().ErrorIs(results[3].Err, ErrNoCreatePermission)
+	_, _, err = s.dst.Get("new/api/keys")
+	s.Assert().NoError(err, "Other secrets should be copied")
+}
+
+func (s *SecretCopyTestSuite) TestCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := CopyContext(ctx, s.src, "staging", s.dst, "prod", nil)
+	s.Assert().ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,245 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DeleteAction describes the outcome of deleting a secret or folder in DeleteTree.
+type DeleteAction string
+
+// constant definition for delete actions
+const (
+	DeleteDeleted DeleteAction = "deleted" // secret/folder is deleted
+	DeleteDryRun  DeleteAction = "dry-run" // secret/folder would be deleted.  Only used in a dry run
+	DeleteSkipped DeleteAction = "skipped" // folder is not deleted as some of its contents are not deleted
+	DeleteFailed  DeleteAction = "failed"  // secret/folder cannot be deleted.  See DeleteResult.Err
+)
+
+// DeleteTreeOptions specifies the options for DeleteTree.
+type DeleteTreeOptions struct {
+	// DryRun specifies to only report the secrets and folders that would be deleted, without
+	// deleting any of them.
+	DryRun bool
+
+	// Concurrency is the maximum number of folders that are listed, and secrets and folders that
+	// are deleted, concurrently.  If it is 0 or 1, objects are deleted one at a time.
+	Concurrency int
+
+	// ContinueOnError specifies whether to continue deleting other secrets and folders when a
+	// secret or folder cannot be deleted, or a folder cannot be listed.  By default, DeleteTree
+	// stops at the first failure.
+	ContinueOnError bool
+}
+
+// DeleteResult is the outcome of deleting a secret or folder in DeleteTree.
+type DeleteResult struct {
+	Path   string       // full path of secret/folder
+	Type   string       // type of secret
+	Action DeleteAction // what is done for the secret/folder
+	Err    error        // reason of failure if Action is DeleteFailed
+}
+
+// deleteItem is a secret or folder to be deleted by DeleteTree
+type deleteItem struct {
+	path    string
+	typ     string
+	depth   int   // number of folders between the item and the root of the tree
+	listErr error // error in listing the contents of a folder
+}
+
+// DeleteTree deletes the secret or folder in 'path', including all the secrets and folders under
+// it.  The contents of a folder are deleted before the folder, and a folder is not deleted if any
+// of its contents is not deleted.  The top level folder cannot be deleted.  'opts' specifies
+// whether it is a dry run, the concurrency and whether to continue after a failure.  It can be nil.
+//
+// DeleteTree returns the result of each secret or folder, in the order they are deleted.  A
+// secret or folder is not in the results if DeleteTree stops before reaching it.
+//
+// The following errors may be returned, in addition to those returned by Walk and Delete:
+//	ErrBadPathName:  'path' is the top level folder.
+//	ErrDeleteIncomplete:  Some secrets/folders cannot be deleted.  Check the results for details.
+func DeleteTree(cl Secret, path string, opts *DeleteTreeOptions) ([]DeleteResult, error) {
+	return DeleteTreeContext(context.Background(), cl, path, opts)
+}
+
+// DeleteTreeContext is the same as DeleteTree, but uses 'ctx' for all the requests.
+// If 'ctx' is canceled, DeleteTreeContext stops and returns the results so far and the error in 'ctx'.
+func DeleteTreeContext(ctx context.Context, cl Secret, path string, opts *DeleteTreeOptions) ([]DeleteResult, error) {
+	if opts == nil {
+		opts = &DeleteTreeOptions{}
+	}
+	root := strings.Trim(path, "/")
+	if root == "" {
+		return nil, fmt.Errorf("Cannot delete top level folder: %w", ErrBadPathName)
+	}
+
+	// find all objects in the tree before deleting any of them
+	var items []deleteItem
+	var stoppedAt string
+	walkOpts := &WalkOptions{Concurrency: opts.Concurrency}
+	err := WalkContext(ctx, cl, root, walkOpts, func(path string, info *MetaData, err error) error {
+		if err != nil {
+			if info == nil {
+				// root cannot be found
+				return err
+			}
+			if !opts.ContinueOnError {
+				stoppedAt = path
+				return err
+			}
+			for i := range items {
+				if items[i].path == path {
+					items[i].listErr = err
+				}
+			}
+			return SkipFolder
+		}
+		items = append(items, deleteItem{path: path, typ: info.Type, depth: strings.Count(path, "/")})
+		return nil
+	})
+	if err != nil {
+		if stoppedAt != "" && ctx.Err() == nil {
+			return nil, fmt.Errorf("delete stopped at [%s]: %w", stoppedAt, err)
+		}
+		return nil, err
+	}
+
+	// delete deepest objects first
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].depth != items[j].depth {
+			return items[i].depth > items[j].depth
+		}
+		return items[i].path < items[j].path
+	})
+	d := &treeDeleter{
+		ctx:     ctx,
+		cl:      cl,
+		opts:    opts,
+		root:    root,
+		items:   items,
+		results: make([]DeleteResult, len(items)),
+		blocked: make(map[string]bool),
+	}
+	d.run()
+
+	results := make([]DeleteResult, 0, len(items))
+	for _, result := range d.results {
+		if result.Action != "" {
+			results = append(results, result)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	if d.stopErr != nil {
+		return results, d.stopErr
+	}
+	if d.failed > 0 {
+		return results, fmt.Errorf("%d of %d secrets/folders cannot be deleted: %w", d.failed, len(items), ErrDeleteIncomplete)
+	}
+	return results, nil
+}
+
+// treeDeleter keeps the state of DeleteTree
+type treeDeleter struct {
+	ctx     context.Context
+	cl      Secret
+	opts    *DeleteTreeOptions
+	root    string
+	items   []deleteItem   // objects to delete, deepest first
+	results []DeleteResult // result of each item.  Action is empty if the item is not processed
+
+	mu      sync.Mutex      // protects the fields below
+	blocked map[string]bool // folders with contents that are not deleted
+	failed  int             // number of objects not deleted
+	stopErr error           // error that stops the delete
+}
+
+// run deletes the items one depth at a time, so that a folder is only considered after all its
+// contents are processed
+func (d *treeDeleter) run() {
+	concurrency := d.opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for start := 0; start < len(d.items); {
+		end := start
+		for end < len(d.items) && d.items[end].depth == d.items[start].depth {
+			end++
+		}
+		for i := start; i < end; i++ {
+			slots <- struct{}{}
+			if d.stopped() {
+				<-slots
+				break
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				d.deleteItem(i)
+			}(i)
+		}
+		wg.Wait()
+		if d.stopped() {
+			return
+		}
+		start = end
+	}
+}
+
+// stopped returns whether no more item is to be processed
+func (d *treeDeleter) stopped() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stopErr != nil || d.ctx.Err() != nil
+}
+
+// deleteItem deletes the item 'i' and saves its result
+func (d *treeDeleter) deleteItem(i int) {
+	item := &d.items[i]
+	result := DeleteResult{Path: item.path, Type: item.typ}
+
+	d.mu.Lock()
+	blocked := d.blocked[item.path]
+	d.mu.Unlock()
+	switch {
+	case item.listErr != nil:
+		result.Action = DeleteFailed
+		result.Err = item.listErr
+	case blocked:
+		result.Action = DeleteSkipped
+	case d.opts.DryRun:
+		result.Action = DeleteDryRun
+	default:
+		if _, err := d.cl.DeleteContext(d.ctx, item.path); err != nil {
+			result.Action = DeleteFailed
+			result.Err = err
+		} else {
+			result.Action = DeleteDeleted
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.results[i] = result
+	if result.Action != DeleteFailed && result.Action != DeleteSkipped {
+		return
+	}
+	d.failed++
+	if item.path != d.root {
+		d.blocked[item.path[:strings.LastIndex(item.path, "/")]] = true
+	}
+	if result.Action == DeleteFailed && !d.opts.ContinueOnError && d.stopErr == nil && d.ctx.Err() == nil {
+		d.stopErr = fmt.Errorf("delete stopped at [%s]: %w", item.path, result.Err)
+	}
+}
@@ -0,0 +1,132 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretDeleteTreeTestSuite struct {
+	memorySuite
+}
+
+func TestSecretDeleteTreeTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretDeleteTreeTestSuite))
+}
+
+func (s *SecretDeleteTreeTestSuite) SetupTest() {
+	s.memorySuite.SetupTest()
+	s.handle.Create("app/db", "", "password")
+	s.handle.Create("app/config/api", "", map[string]string{"key": "value"})
+	s.handle.Create("app/config/web", "", "value")
+	s.handle.CreateFolder("app/empty", "")
+	s.handle.Create("other", "", "value")
+}
+
+// paths returns the path and action of each result
+func paths(results []DeleteResult) []string {
+	var result []string
+	for _, r := range results {
+		result = append(result, r.Path+" "+string(r.Action))
+	}
+	return result
+}
+
+func (s *SecretDeleteTreeTestSuite) TestDeleteTree() {
+	results, err := DeleteTree(s.handle, "/app/", nil)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{
+		"app/config/api deleted",
+		"app/config/web deleted",
+		"app/config deleted",
+		"app/db deleted",
+		"app/empty deleted",
+		"app deleted",
+	}, paths(results), "Contents should be deleted before folders")
+	_, _, err = s.handle.GetMetaData("app")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, _, err = s.handle.Get("other")
+	s.Assert().NoError(err, "Secret outside of tree should be kept")
+
+	results, err = DeleteTree(s.handle, "other", nil)
+	s.Require().NoError(err, "Should delete single secret")
+	s.Assert().Equal([]string{"other deleted"}, paths(results))
+
+	_, err = DeleteTree(s.handle, "app", nil)
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, err = DeleteTree(s.handle, "/", nil)
+	s.Assert().ErrorIs(err, ErrBadPathName, "Top level folder cannot be deleted")
+}
+
+func (s *SecretDeleteTreeTestSuite) TestDryRun() {
+	results, err := DeleteTree(s.handle, "app/config", &DeleteTreeOptions{DryRun: true})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"app/config/api dry-run", "app/config/web dry-run", "app/config dry-run"}, paths(results))
+	s.Assert().Equal(SecretTypeKV, results[0].Type)
+	_, _, err = s.handle.Get("app/config/api")
+	s.Assert().NoError(err, "Dry run should not delete anything")
+}
+
+func (s *SecretDeleteTreeTestSuite) TestConcurrency() {
+	for i := 0; i < 20; i++ {
+		s.handle.Create("app/many/"+string(rune('a'+i)), "", "value")
+	}
+	results, err := DeleteTree(s.handle, "app", &DeleteTreeOptions{Concurrency: 4})
+	s.Require().NoError(err)
+	s.Assert().Len(results, 27)
+	s.Assert().Equal("app/config/api deleted", paths(results)[0], "Results should be in a stable order")
+	s.Assert().Equal("app deleted", paths(results)[26])
+	_, _, err = s.handle.GetMetaData("app")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretDeleteTreeTestSuite) TestStopOnError() {
+	cl := &failingClient{Secret: s.handle, failDelete: map[string]bool{"app/config/web": true}}
+	results, err := DeleteTree(cl, "app", nil)
+	s.Assert().ErrorIs(err, ErrNoDeletePermission)
+	s.Assert().NotErrorIs(err, ErrDeleteIncomplete)
+	s.Assert().Equal("delete stopped at [app/config/web]: No permission to delete secret/folder", err.Error())
+	s.Assert().Equal([]string{"app/config/api deleted", "app/config/web failed"}, paths(results))
+	s.Assert().ErrorIs(results[1].Err, ErrNoDeletePermission)
+	_, _, err = s.handle.Get("app/db")
+	s.Assert().NoError(err, "Should stop at first failure")
+
+	cl = &failingClient{Secret: s.handle, failList: map[string]bool{"app/config": true}}
+	results, err = DeleteTree(cl, "app", nil)
+	s.Assert().ErrorIs(err, ErrNoRetrievePermission)
+	s.Assert().Empty(results, "Nothing should be deleted if tree cannot be listed")
+}
+
+func (s *SecretDeleteTreeTestSuite) TestContinueOnError() {
+	cl := &failingClient{
+		Secret:     s.handle,
+		failDelete: map[string]bool{"app/config/web": true},
+		failList:   map[string]bool{"app/empty": true},
+	}
+	results, err := DeleteTree(cl, "app", &DeleteTreeOptions{ContinueOnError: true, Concurrency: 2})
+	s.Assert().ErrorIs(err, ErrDeleteIncomplete)
+	s.Assert().Equal("4 of 6 secrets/folders cannot be deleted: Some secrets/folders cannot be deleted", err.Error())
+	s.Assert().Equal([]string{
+		"app/config/api deleted",
+		"app/config/web failed",
+		"app/config skipped",
+		"app/db deleted",
+		"app/empty failed",
+		"app skipped",
+	}, paths(results))
+	s.Assert().ErrorIs(results[4].Err, ErrNoRetrievePermission)
+	_, _, err = s.handle.Get("app/db")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Other secrets should be deleted")
+	_, _, err = s.handle.Get("app/config/web")
+	s.Assert().NoError(err)
+}
+
+func (s *SecretDeleteTreeTestSuite) TestCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := DeleteTreeContext(ctx, s.handle, "app", nil)
+	s.Assert().ErrorIs(err, context.Canceled)
+	_, _, err = s.handle.Get("app/db")
+	s.Assert().NoError(err)
+}
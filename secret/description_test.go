@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+// SecretDescriptionTestSuite tests that PASSecretClient sends and returns the description
+// of secrets, using a local HTTP server that stands in for PAS.
+type SecretDescriptionTestSuite struct {
+	testutils.CfyTestSuite
+	server *httptest.Server         // local HTTP server that stands in for PAS
+	handle Secret                   // interface to secret API
+	bodies []map[string]interface{} // bodies of requests received by server
+}
+
+func TestSecretDescriptionTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretDescriptionTestSuite))
+}
+
+func (s *SecretDescriptionTestSuite) SetupTest() {
+	s.bodies = nil
+	s.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			body := make(map[string]interface{})
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			s.bodies = append(s.bodies, body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"name": "folder/secret",
+			"type": "text",
+			"description": "my description",
+			"meta": {"id": "id-1", "crn": "crn-1", "createdBy": "admin@example.com", "modifiedBy": "app@example.com", "revision": 3}
+		}`)
+	}))
+	s.handle = newTestPASClient(s.server, "token")
+}
+
+func (s *SecretDescriptionTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *SecretDescriptionTestSuite) TestSendDescription() {
+	_, _, _, err := s.handle.Create("folder/secret", "text secret", "value")
+	s.Require().NoError(err, "Create should not return error")
+	_, _, _, err = s.handle.Create("folder/bag", "bag secret", map[string]string{"key": "value"})
+	s.Require().NoError(err, "Create should not return error")
+	_, _, _, err = s.handle.CreateFolder("folder", "folder description")
+	s.Require().NoError(err, "CreateFolder should not return error")
+	_, _, _, err = s.handle.Modify("folder/secret", "new description", "new value")
+	s.Require().NoError(err, "Modify should not return error")
+	_, _, _, err = s.handle.Modify("folder/secret", "", "newer value")
+	s.Require().NoError(err, "Modify should not return error")
+
+	s.Require().Len(s.bodies, 5)
+	s.Assert().Equal("text secret", s.bodies[0]["description"])
+	s.Assert().Equal("bag secret", s.bodies[1]["description"])
+	s.Assert().Equal("folder description", s.bodies[2]["description"])
+	s.Assert().Equal("new description", s.bodies[3]["description"])
+	s.Assert().NotContains(s.bodies[4], "description", "Empty description should not be sent")
+}
+
+func (s *SecretDescriptionTestSuite) TestGetDescription() {
+	metadata, _, err := s.handle.GetMetaData("folder/secret")
+	s.Require().NoError(err, "GetMetaData should not return error")
+	s.Assert().Equal("my description", metadata.Description)
+	s.Assert().Equal("id-1", metadata.ID)
+	s.Assert().Equal("admin@example.com", metadata.CreatedBy)
+	s.Assert().Equal("app@example.com", metadata.ModifiedBy)
+	s.Assert().Equal(map[string]string{"revision": "3"}, metadata.Attributes, "Other metadata should be returned as attributes")
+}
+
+func (s *SecretDescriptionTestSuite) TestModifyMetaData() {
+	_, err := s.handle.ModifyMetaData("folder/secret", "new description", nil)
+	s.Require().NoError(err, "ModifyMetaData should not return error")
+	s.Require().Len(s.bodies, 1)
+	s.Assert().Equal(map[string]interface{}{"type": "text", "description": "new description"}, s.bodies[0], "Value should not be sent")
+
+	_, err = s.handle.ModifyMetaData("folder/secret", "", nil)
+	s.Require().NoError(err)
+	s.Assert().Len(s.bodies, 1, "Nothing should be modified")
+}
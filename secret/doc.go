@@ -6,12 +6,34 @@ Obtain a client handle
 
 The application must call NewSecretClient() to obtain a client handle to Centrify PAS.
 
+Secrets stored in the KV version 2 secrets engine of HashiCorp Vault can be accessed with the same
+APIs by specifying the server type ServerHCVault.  As KV has no folders, a folder in Vault is a prefix
+of the path of secrets.  It exists only when it contains secrets.
+
+Secrets stored in DevOps Secrets Vault (DSV) are accessed by specifying the server type ServerDSV.  Like
+Vault, a folder in DSV is a prefix of the path of secrets.
+
+Secrets stored in Thycotic Secret Server (TSS) are accessed by specifying the server type ServerTSS.  Text
+and keyvalue secrets are mapped to secrets of secret templates, which can be changed by
+TSSSecretClient.SetTemplates().
+
+Secrets can also be stored in memory by specifying the server type ServerMemory, which is useful
+for unit testing applications without a secret store.  MemorySecretClient follows the semantics of PAS
+and returns the same errors.
+
 Access credential
 
-You can specify an OAuth access token in NewSecretClient().  It will be used for all subsequent calls
+You can specify an OAuth access token (or Vault token for ServerHCVault, DSV or TSS access token for ServerDSV and ServerTSS) in NewSecretClient().  It will be used for all subsequent calls
 to access the secrets.  Altenatively, you can setup the necessary authorization header by calling
 the method AddDefaultHeaders().
 
+An access token expires after some time.  A long-lived client can get tokens on demand from an
+oauth2.TokenSource specified by the option WithTokenSource in NewSecretClient.  The token is refreshed
+before it expires, and a request rejected with HTTP status 401 is sent again once with a new token.
+NewDMCTokenSource and NewResourceOwnerTokenSource return token sources that get tokens from Centrify
+Client with dmc.GetDMCToken and oauthhelper.GetResourceOwnerToken respectively.  They are only available
+on Linux.
+
 Custom HTTP Client
 
 You can specify to use a custom HTTP client by providing a HTTPFactory in NewSecretClient().  If this is not
@@ -27,13 +49,166 @@ Accessing secrets
 
 The following methods are provided:
 
-  Create:       Create a secret
-  CreateFolder: Create a secret folder
-  Delete:       Delete a secret/folder
-  Get:          Get value of a secret
-  GetMetaData:  Get metadata associated with a secret
-  List:         List secrets in a secret folder
-  Modify:       Modify a secert
+  Create:         Create a secret
+  CreateFolder:   Create a secret folder
+  Delete:         Delete a secret/folder
+  Get:            Get value of a secret
+  GetMetaData:    Get metadata associated with a secret
+  List:           List secrets in a secret folder
+  ListSecrets:    List secrets that match search/filter options, one page at a time
+  Modify:         Modify a secert
+  ModifyMetaData: Modify description/attributes of a secret/folder
+  Move:           Move a secret/folder to another path
+  PathOfID:       Get path of a secret/folder from its ID
+  Rename:         Rename a secret/folder
+
+Move and Rename move a secret or folder, including the contents of a folder, to another path.  PAS
+renames the secret or folder so that it keeps its ID.  In other secret stores, it is copied to the
+new path and deleted from the old path.  If that fails part way and cannot be undone, a MoveError
+reports what is left in each path.
+
+Accessing secrets by ID
+
+PathOfID returns the current path of a secret or folder from its ID, e.g., Item.ID returned by
+List.  A SecretRef created by ByID or ByPath can be passed to GetRef, GetMetaDataRef, ModifyRef and
+DeleteRef, so that a reference stored by an application keeps working after the secret is moved or
+renamed, and a name that looks like an ID is never mistaken for one.  In HashiCorp Vault, the ID of
+a secret is its path.
+
+Secret metadata
+
+GetMetaData returns the description, creation and modification time, and, where the secret store
+keeps them, who created and last modified the secret, its version and other attributes.
+ModifyMetaData changes the description and attributes of a secret or folder without changing its
+value.  Attributes can be written in HashiCorp Vault and DSV, while PAS only allows the
+description to be changed and TSS neither.  ErrMetaDataNotSupported is returned for metadata that
+the secret store does not keep.
+
+Binary secrets
+
+Create and Modify also accept a []byte or io.Reader value, e.g., a TLS key, a keystore or a
+kubeconfig file.  The bytes are saved as base64 text with a marker in a text secret.  Get returns
+them as []byte only for a client created with WithBinaryValues, as a text secret saved by other
+tools may also start with the marker.  Otherwise, the saved text is returned as string.  Export,
+Import, Copy and manifests handle such secrets as SecretTypeFile.  Values larger than
+DefaultMaxBinarySize, or the limit specified by WithMaxBinarySize, are rejected with
+ErrSecretTooLarge before any request is sent.
+
+Keyvalue secrets as structs
+
+GetInto stores the values of a keyvalue secret in the fields of a struct, so that a secret can be
+used like configuration.  Fields are mapped to keys by the "secret" struct tag, and values are
+converted to integers, booleans, durations, or nested JSON according to the type of each field.
+Keys marked as required must be in the secret.  CreateFrom and ModifyFrom save the fields of a
+struct as a keyvalue secret.
+
+Conditional modification
+
+ModifyIfUnchanged and DeleteIfUnchanged take the MetaData returned by GetMetaData earlier, and only
+modify or delete the secret if it has not changed since.  Otherwise ErrConflict is returned, so
+that a client does not overwrite a change made by another client that it has not seen.  HashiCorp
+Vault checks the version of the secret in the write request.  For the other secret stores, the
+metadata is read again and compared just before the secret is changed.  The metadata is read
+past CachedSecretClient, also when it is under a VersionedSecretClient.
+
+Updating keys of keyvalue secrets
+
+SetKeys and DeleteKeys change some keys of a keyvalue secret and keep the others, so that clients
+updating different keys of the same secret do not overwrite each other's changes.  The secret is
+read and written back with ModifyIfUnchanged, so ErrConflict is returned if another client has
+changed it in between, and the caller can retry.
+
+Cancellation and timeouts
+
+Each method above has a counterpart in the SecretContext interface (e.g., GetContext for Get) that
+takes a context.Context as its first parameter.  The context is used for the REST API requests so
+that the caller can cancel a request or set a deadline for it.  If the context is canceled or its deadline
+expires, the method returns context.Canceled or context.DeadlineExceeded.
+
+Error details
+
+When a method of PASSecretClient fails, the error is an Error that wraps the error describing the
+failure, so it can still be checked with errors.Is, e.g., errors.Is(err, ErrSecretNotFound).  Use
+errors.As to get the operation, path, HTTP status, transaction ID and the error details reported
+by PAS, which are useful when reporting a problem to the PAS administrator.
+
+Walking a secret tree
+
+Walk and WalkContext visit every folder and secret under a root folder, calling a WalkFunc for each
+of them.  The function can return SkipFolder to skip the contents of a folder.  WalkContext can
+list folders concurrently and retrieve the full metadata of each object (see WalkOptions).
+
+Deleting a secret tree
+
+DeleteTree and DeleteTreeContext delete a folder including all the secrets and folders under it.
+The contents of a folder are deleted before the folder.  DeleteTreeOptions specifies a dry run that
+only reports what would be deleted, the number of concurrent requests, and whether to continue
+deleting other objects after a failure.  The result of each secret or folder is returned in
+DeleteResult.
+
+Exporting and importing secrets
+
+Export writes the folders and secrets under a folder, including their descriptions and values, to
+a versioned JSON or YAML document that can be encrypted with a passphrase.  Import creates the
+folders and secrets in such a document under a folder in the same or another secret store.
+ImportOptions.OnConflict specifies whether an existing secret is skipped, overwritten or stops the
+import.  The result of each item is returned in ImportResult.
+
+Copying secrets
+
+Copy and CopyContext copy a secret or folder, including the contents of a folder, to a path in the
+same or another Secret, e.g., from a staging tenant to a production tenant.  Secrets that already
+exist with a different value or description are skipped, overwritten or stop the copy, as specified
+in CopyOptions.OnConflict.  CopyOptions.Preview only compares the source with the destination.
+The result of each secret or folder, including the differences found, is returned in CopyResult.
+
+Applying a manifest
+
+A Manifest describes the desired folders and secrets under a folder in JSON or YAML, in the same
+format as the items of an export document.  Values can refer to environment variables or files, so
+that the manifest can be kept in source control without the values.  PlanManifest compares the
+manifest with the secret store and returns a Plan of the secrets and folders to create, modify and,
+if PlanOptions.Prune is set, delete.  The plan can be reviewed before ApplyPlan makes the changes.
+
+Retrying transient failures
+
+The WithRetryPolicy option of NewSecretClient, or PASSecretClient.SetRetryPolicy, enables retrying
+requests that fail with a transport error or a transient HTTP status, such as 503 (Service
+Unavailable).  RetryPolicy specifies the maximum number of attempts and the exponential backoff
+between them.  The Retry-After header of the response is honored,
+and Create and CreateFolder are only retried when PAS has not processed the request, unless
+RetryPolicy.RetryNonIdempotent is set.  If a request still fails after it is retried, the error is a
+RetryError that has the number of attempts and the error of the last attempt.
+
+Caching secrets
+
+NewCachedSecretClient wraps a Secret with a cache of the results of Get, GetMetaData and List, so
+that secrets that are used repeatedly are not retrieved from the secret store every time.  The
+time-to-live and maximum number of cached results are specified in CacheOptions.  When
+CacheOptions.Revalidate is set, an expired secret value is kept if the modification time in its
+metadata is unchanged.  Create, CreateFolder, Delete, Modify and ModifyMetaData through the cached
+client invalidate the affected results.  Cached secret values are zeroed in memory when they are
+evicted.
+
+Versioning secrets
+
+NewVersionedSecretClient wraps a Secret so that Modify archives the previous value and description
+of a secret in a hidden history folder before changing it.  Each archived value has a version
+number that increases monotonically.  ListVersions lists the versions of a secret, GetVersion
+returns the value of a version, and Rollback restores a version, archiving the current value so
+that the rollback can be reverted.  VersionOptions specifies the history folder and how many
+versions are kept for each secret.  Older versions are pruned after a modification on a best-effort
+basis: errors in deleting them are ignored.  The history folder is hidden from List, so Walk,
+DeleteTree, Export and PlanManifest skip it when they are given the VersionedSecretClient, but
+include it when they are given the underlying Secret.
+
+Watching secrets
+
+Watch polls a secret, or all secrets and folders under a folder, at an interval and sends the
+secrets and folders that are created, modified or deleted to a channel, with the new value of each
+secret, so that a daemon can reload its credentials when they change.  Changes are detected by
+comparing the metadata returned by GetMetaData, including the modification time.  The interval is
+jittered, and it is backed off while polls fail.
 
 Additional customizations
 
@@ -85,5 +260,16 @@ of the file:
 You can pass the configuration information in the go test command.  An example is <br>
 `go test -args -config=/tmp/gotest.json`
 
+Running go tests without a tenant
+
+The PAS emulator in github.com/centrify/platform-go-sdk/examples/pasemu implements the secrets API with
+secrets stored in memory.  Start it with a test user, and let go test trust its self-signed certificate:
+
+ pasemu -addr 127.0.0.1:8443 -user test@example.com -password testpass -certout /tmp/pasemu.pem &
+ SSL_CERT_FILE=/tmp/pasemu.pem go test -args -config=/tmp/gotest.json
+
+where TenantURL in the test configuration file is 127.0.0.1:8443, and PASuser is the test user.  AppID
+and Scope can be any non-empty values.
+
 */
 package secret
@@ -0,0 +1,760 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dsvSearchLimit is the number of secrets that DSV returns in each page of search results
+const dsvSearchLimit = 100
+
+// DSVSecretClient implements the Secret interface where the secret is stored in DevOps Secrets Vault (DSV).
+//
+// DSV has no folders.  A folder is a prefix of the path of secrets, and it exists as long as
+// there are secrets in it.  The ID of a folder is its path.  The ID of a secret is the ID assigned by DSV,
+// and the CRN of a secret is its path.
+// The type of a secret is saved in the "secret_type" attribute of the secret.  The value of a
+// text secret is saved in the key "text" of the secret data.  A secret without type attribute is treated
+// as a keyvalue secret.  DSV paths separated by ":" are returned with "/".
+type DSVSecretClient struct {
+	*restClient
+}
+
+// dsvSecret is a secret returned by DSV
+type dsvSecret struct {
+	ID           string                 `json:"id"`
+	Path         string                 `json:"path"`
+	Description  string                 `json:"description"`
+	Attributes   map[string]interface{} `json:"attributes"`
+	Data         map[string]interface{} `json:"data"`
+	Created      time.Time              `json:"created"`
+	CreatedBy    string                 `json:"createdBy"`
+	LastModified time.Time              `json:"lastModified"`
+	ModifiedBy   string                 `json:"lastModifiedBy"`
+	Version      string                 `json:"version"`
+}
+
+// dsvSearchResult is a page of search results returned by DSV
+type dsvSearchResult struct {
+	Data   []dsvSecret `json:"data"`
+	Cursor string      `json:"cursor"`
+}
+
+// newDSVSecretClient creates a new client handle to access secrets in the DSV tenant specified
+// in 'server' with the access token 'accessToken'.  'server' is the URL or host name of the
+// tenant, e.g., mytenant.secretsvaultcloud.com.
+func newDSVSecretClient(server string, accessToken string, httpFactory HTTPClientFactory) *DSVSecretClient {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	server = strings.TrimSuffix(strings.TrimSuffix(server, "/"), "/v1")
+	cl := &DSVSecretClient{
+		restClient: newRESTClient(server, httpFactory),
+	}
+	if accessToken != "" {
+		cl.headers["Authorization"] = "Bearer " + accessToken
+	}
+	return cl
+}
+
+// Get returns the secret content.
+// If the secret is a keyvalue secret, it returns the secret as map[string]string
+// If the secret is a text string, it returns the secret as string.
+// The following errors may be returned:
+//	 ErrNoRetrievePermission: No permission to read the secret.
+//	 ErrSecretNotFound: Secret specified in path cannot be found.
+//	 ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *DSVSecretClient) Get(path string) (interface{}, *http.Response, error) {
+	return c.GetContext(context.Background(), path)
+}
+
+// GetContext is the same as Get, but uses 'ctx' for the REST API request.
+func (c *DSVSecretClient) GetContext(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	data, r, err := c.getSecret(ctx, path)
+	if err != nil {
+		if err == ErrNoGetMetaDataPermission {
+			err = ErrNoRetrievePermission
+		}
+		return nil, r, err
+	}
+	value, err := secretValue(data.secretType(), data.Data, c.decodeBinary)
+	return value, r, err
+}
+
+// Create creates a secret in 'path'. 'description' is an optional description
+// of the secret.  If 'value' is a string, it saves the secret as a
+// secret text string.  If 'value' is type map[string]string, the secret
+// is stored as 'keyvalue' secret.
+// Returns the following information:
+//  bool: whether the secret is created or not.
+//  id: the ID of the secret
+//  response: the actual HTTP response
+//
+// The following errors may be returned:
+//
+//   ErrBadPathName: Invalid secret path name
+//	 ErrExists: Secret or folder already exists
+//	 ErrNoCreatePermission: No permission to create secret.
+//	 ErrSecretTypeNotSupported:  Cannot create secret for the specified type.
+//	 ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *DSVSecretClient) Create(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.CreateContext(context.Background(), path, description, value)
+}
+
+// CreateContext is the same as Create, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) CreateContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, err
+	}
+	secretType, data, err := secretData(value)
+	if err != nil {
+		return false, "", nil, err
+	}
+	path, err = cleanPath(path)
+	if err != nil {
+		return false, "", nil, err
+	}
+
+	// a secret cannot have the same path as a folder
+	children, r, err := c.search(ctx, path, 1)
+	if err != nil {
+		return false, "", r, err
+	}
+	if len(children) > 0 {
+		return false, "", r, ErrExists
+	}
+
+	body := map[string]interface{}{
+		"data":       data,
+		"attributes": map[string]interface{}{secretTypeKey: secretType},
+	}
+	if description != "" {
+		body["description"] = description
+	}
+	var result dsvSecret
+	r, err = c.doRequest(ctx, http.MethodPost, c.apiPath(path), body, &result)
+	if err != nil {
+		return false, "", r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return true, result.ID, r, nil
+	case http.StatusConflict:
+		return false, "", r, ErrExists
+	case http.StatusBadRequest:
+		if strings.Contains(strings.ToLower(c.errorMessage(r)), "exist") {
+			return false, "", r, ErrExists
+		}
+		return false, "", r, ErrBadPathName
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, "", r, ErrNoCreatePermission
+	default:
+		return false, "", r, ErrUnexpectedResponse
+	}
+}
+
+// CreateFolder creates a secret folder in 'path'.  DSV has no folders, so nothing is saved in DSV
+// and 'description' is ignored.  The folder exists once a secret is created in it.
+// Returns the following information:
+//  bool: whether the secret folder is created or not.
+//  id: the path of the secret folder
+//  response: the actual HTTP response
+//
+// The following errors may be returned:
+//   ErrBadPathName: Invalid secret path name
+//	 ErrExists: Secret or folder already exists
+//	 ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *DSVSecretClient) CreateFolder(path string, description string) (bool, string, *http.Response, error) {
+	return c.CreateFolderContext(context.Background(), path, description)
+}
+
+// CreateFolderContext is the same as CreateFolder, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) CreateFolderContext(ctx context.Context, path string, description string) (bool, string, *http.Response, error) {
+	path, err := cleanPath(path)
+	if err != nil {
+		return false, "", nil, err
+	}
+	children, r, err := c.search(ctx, path, 1)
+	if err != nil {
+		return false, "", r, err
+	}
+	if len(children) > 0 {
+		return false, "", r, ErrExists
+	}
+	_, r, err = c.getSecret(ctx, path)
+	if err == nil {
+		return false, "", r, ErrExists
+	}
+	if err != ErrSecretNotFound {
+		return false, "", r, err
+	}
+	return true, path, r, nil
+}
+
+// List lists all secrets in a folder specified in 'path'
+// Returns the following:
+//  items: an array of Item. If the folder is empty, nil is returned.
+//  response: the actual HTTP response
+// the following errors may be returned:
+//	ErrFolderNotFound: Secret specified in path cannot be found.  It is possible that
+//		the caller may not have permission to access the folder.
+//	ErrNotSecretFolder: The path specifies a secret.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *DSVSecretClient) List(path string) ([]Item, *http.Response, error) {
+	return c.ListContext(context.Background(), path)
+}
+
+// ListContext is the same as List, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	folder := strings.Trim(path, "/")
+	secrets, r, err := c.search(ctx, folder, 0)
+	if err != nil {
+		return nil, r, err
+	}
+	if len(secrets) == 0 {
+		if folder == "" {
+			// no secret in tenant
+			return nil, r, nil
+		}
+		if _, _, err := c.getSecret(ctx, folder); err == nil {
+			return nil, r, ErrNotSecretFolder
+		}
+		return nil, r, ErrFolderNotFound
+	}
+
+	var items []Item
+	folders := make(map[string]bool)
+	for _, secret := range secrets {
+		name := strings.TrimPrefix(secret.Path, folder)
+		name = strings.TrimPrefix(name, "/")
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[:i]
+			if !folders[name] {
+				folders[name] = true
+				items = append(items, Item{Name: name, Type: SecretTypeFolder, ID: joinPath(folder, name)})
+			}
+			continue
+		}
+		items = append(items, Item{Name: name, Type: secret.secretType(), ID: secret.ID})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, r, nil
+}
+
+// ListSecrets lists the secrets that match the search and ordering options in 'opts'.
+// 'opts' can be nil, which lists all secrets that the caller can access.
+// The name of each item is the full path of the secret.  Search matches any part of the path,
+// ignoring case.  OrderBy can only be "name" or "name desc", and Filter is not supported.
+// If 'fn' returns an error, the listing stops and the error is returned.
+// Returns the following:
+//  response: the HTTP response of the last page of search results
+// the following errors may be returned:
+//	ErrInvalidListOption: The options in 'opts' are not supported.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *DSVSecretClient) ListSecrets(opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	return c.ListSecretsContext(context.Background(), opts, fn)
+}
+
+// ListSecretsContext is the same as ListSecrets, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) ListSecretsContext(ctx context.Context, opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	if opts.Limit < 0 || opts.Limit > maxListLimit || opts.Filter != "" {
+		return nil, ErrInvalidListOption
+	}
+	descending := false
+	for _, order := range opts.OrderBy {
+		switch strings.ToLower(strings.Join(strings.Fields(order), " ")) {
+		case "name", "name asc":
+			descending = false
+		case "name desc":
+			descending = true
+		default:
+			return nil, fmt.Errorf("Cannot order by [%s]: %w", order, ErrInvalidListOption)
+		}
+	}
+
+	// DSV returns search results in no particular order, so all pages are read before sorting
+	var items []Item
+	search := strings.ToLower(opts.Search)
+	r, err := c.searchPages(ctx, opts.Search, int(opts.Limit), func(secret *dsvSecret) bool {
+		if strings.Contains(strings.ToLower(secret.Path), search) {
+			items = append(items, Item{Name: secret.Path, Type: secret.secretType(), ID: secret.ID})
+		}
+		return true
+	})
+	if err != nil {
+		return r, err
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if descending {
+			return items[i].Name > items[j].Name
+		}
+		return items[i].Name < items[j].Name
+	})
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// Delete deletes the folder/secret specified in 'path'.  The secret is deleted permanently.
+// Returns the following information:
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrFolderNotEmpty: Folder is not empty
+//	ErrNoDeletePermission: No permission to delete secret/folder
+//	ErrSecretNotFound: Secret specified in path cannot be found.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *DSVSecretClient) Delete(path string) (*http.Response, error) {
+	return c.DeleteContext(context.Background(), path)
+}
+
+// DeleteContext is the same as Delete, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) DeleteContext(ctx context.Context, path string) (*http.Response, error) {
+	path = strings.Trim(path, "/")
+	_, r, err := c.getSecret(ctx, path)
+	switch err {
+	case nil:
+	case ErrSecretNotFound:
+		children, r, err := c.search(ctx, path, 1)
+		if err != nil {
+			return r, err
+		}
+		if len(children) > 0 {
+			return r, ErrFolderNotEmpty
+		}
+		return r, ErrSecretNotFound
+	case ErrNoGetMetaDataPermission:
+		return r, ErrNoDeletePermission
+	default:
+		return r, err
+	}
+
+	r, err = c.doRequest(ctx, http.MethodDelete, c.apiPath(path)+"?force=true", nil, nil)
+	if err != nil {
+		return r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return r, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return r, ErrNoDeletePermission
+	case http.StatusNotFound:
+		return r, ErrSecretNotFound
+	default:
+		return r, ErrUnexpectedResponse
+	}
+}
+
+// Modify modifies a secret in 'path'.
+// If 'description' is not an empty string, it replaces the current secret description.
+// If 'value' is a string, it saves the secret as a
+// secret text string.  If 'value' is type map[string]string, the secret
+// is stored as 'keyvalue' secret.
+//
+// Returns the following information:
+//  bool: whether the secret is modified or not.
+//  id: the ID of the secret
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrCannotModifySecretFolder:  Modification of a secret folder is not supported.
+//	ErrCannotModifySecretType:  Modification of keyvalue secret to text or vice versa is not supported.
+//	ErrNoModifyPermission: No permission to modify secret
+//	ErrSecretNotFound: secret cannot be found
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *DSVSecretClient) Modify(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.ModifyContext(context.Background(), path, description, value)
+}
+
+// ModifyContext is the same as Modify, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, err
+	}
+	secretType, data, err := secretData(value)
+	if err != nil {
+		return false, "", nil, err
+	}
+	path = strings.Trim(path, "/")
+
+	current, r, err := c.getSecret(ctx, path)
+	switch err {
+	case nil:
+	case ErrSecretNotFound:
+		children, r, err := c.search(ctx, path, 1)
+		if err != nil {
+			return false, "", r, err
+		}
+		if len(children) > 0 {
+			return false, "", r, ErrCannotModifySecretFolder
+		}
+		return false, "", r, ErrSecretNotFound
+	case ErrNoGetMetaDataPermission:
+		return false, "", r, ErrNoModifyPermission
+	default:
+		return false, "", r, err
+	}
+	if current.secretType() != secretType {
+		return false, "", r, ErrCannotModifySecretType
+	}
+
+	// PUT replaces the whole secret, so keep the current attributes and description
+	attributes := current.Attributes
+	if attributes == nil {
+		attributes = make(map[string]interface{})
+	}
+	attributes[secretTypeKey] = secretType
+	if description == "" {
+		description = current.Description
+	}
+	body := map[string]interface{}{
+		"data":        data,
+		"attributes":  attributes,
+		"description": description,
+	}
+	r, err = c.doRequest(ctx, http.MethodPut, c.apiPath(path), body, nil)
+	if err != nil {
+		return false, "", r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return true, current.ID, r, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, "", r, ErrNoModifyPermission
+	case http.StatusNotFound:
+		return false, "", r, ErrSecretNotFound
+	default:
+		return false, "", r, ErrUnexpectedResponse
+	}
+}
+
+// ModifyMetaData modifies the description and attributes of the secret in 'path' without changing
+// its value.  If 'description' is not an empty string, it replaces the current description.  Each
+// key in 'attributes' is set to its value, or removed if the value is empty.  The key "secret_type"
+// is used to save the type of secret, and cannot be used as an attribute.
+//
+// DSV replaces the whole secret in an update, so the current value is sent back with the new
+// metadata.  A modification of the value made by another client in between is lost.
+// The following errors may be returned:
+//	ErrCannotModifySecretFolder: 'path' is a folder, which has no metadata in DSV
+//	ErrMetaDataNotSupported: an attribute uses a reserved key
+//	ErrNoModifyPermission: No permission to modify secret
+//	ErrSecretNotFound: secret cannot be found
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *DSVSecretClient) ModifyMetaData(path string, description string, attributes map[string]string) (*http.Response, error) {
+	return c.ModifyMetaDataContext(context.Background(), path, description, attributes)
+}
+
+// ModifyMetaDataContext is the same as ModifyMetaData, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) ModifyMetaDataContext(ctx context.Context, path string, description string, attributes map[string]string) (*http.Response, error) {
+	if _, ok := attributes[secretTypeKey]; ok {
+		return nil, fmt.Errorf("attribute [%s] is reserved: %w", secretTypeKey, ErrMetaDataNotSupported)
+	}
+	path = strings.Trim(path, "/")
+
+	current, r, err := c.getSecret(ctx, path)
+	switch err {
+	case nil:
+	case ErrSecretNotFound:
+		children, r, err := c.search(ctx, path, 1)
+		if err != nil {
+			return r, err
+		}
+		if len(children) > 0 {
+			return r, ErrCannotModifySecretFolder
+		}
+		return r, ErrSecretNotFound
+	case ErrNoGetMetaDataPermission:
+		return r, ErrNoModifyPermission
+	default:
+		return r, err
+	}
+
+	merged := make(map[string]interface{}, len(current.Attributes)+len(attributes))
+	for k, v := range current.Attributes {
+		merged[k] = v
+	}
+	for k, v := range attributes {
+		if v == "" {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	if description == "" {
+		description = current.Description
+	}
+	body := map[string]interface{}{
+		"data":        current.Data,
+		"attributes":  merged,
+		"description": description,
+	}
+	r, err = c.doRequest(ctx, http.MethodPut, c.apiPath(path), body, nil)
+	if err != nil {
+		return r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return r, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return r, ErrNoModifyPermission
+	case http.StatusNotFound:
+		return r, ErrSecretNotFound
+	default:
+		return r, ErrUnexpectedResponse
+	}
+}
+
+// Move moves the secret or folder in 'srcPath', including the contents of a folder, to 'dstPath'.
+// As DSV cannot move secrets, the secret or folder is copied to 'dstPath' and deleted from
+// 'srcPath', which gives the copies new IDs.  If copying fails, the copies already created are
+// deleted.  If the copy cannot be completed or undone, a MoveError is returned that describes what
+// is left in the source and destination.
+// Returns the following information:
+//  id: the unique ID of the secret or folder in 'dstPath'
+//  response: the HTTP response of the last request
+// The following errors may be returned, in addition to those returned by Get, Create, CreateFolder
+// and Delete:
+//	ErrBadPathName: 'srcPath' or 'dstPath' is invalid, or 'dstPath' is in the folder 'srcPath'
+//	ErrMoveIncomplete: The secret or folder is only partly moved.  The error is a MoveError.
+func (c *DSVSecretClient) Move(srcPath string, dstPath string) (string, *http.Response, error) {
+	return c.MoveContext(context.Background(), srcPath, dstPath)
+}
+
+// MoveContext is the same as Move, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) MoveContext(ctx context.Context, srcPath string, dstPath string) (string, *http.Response, error) {
+	return moveByCopy(ctx, c, srcPath, dstPath)
+}
+
+// Rename renames the secret or folder in 'path' to 'newName' in the same folder.  It is the same as
+// Move to the new path.  ErrBadPathName is returned if 'newName' is empty or has "/".
+func (c *DSVSecretClient) Rename(path string, newName string) (string, *http.Response, error) {
+	return c.RenameContext(context.Background(), path, newName)
+}
+
+// RenameContext is the same as Rename, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) RenameContext(ctx context.Context, path string, newName string) (string, *http.Response, error) {
+	newPath, err := renamePath(path, newName)
+	if err != nil {
+		return "", nil, err
+	}
+	return c.MoveContext(ctx, path, newPath)
+}
+
+// errDSVIDFound stops the listing of secrets by PathOfID when the ID is found
+var errDSVIDFound = errors.New("ID found")
+
+// PathOfID returns the current path of the secret or folder with the ID 'id'.  The ID of a
+// folder is its path.  The ID of a secret is found by searching all the secrets that the caller
+// can access, which needs a request for each page of secrets.
+// The following errors may be returned:
+//	ErrSecretNotFound: No secret or folder has the ID.
+func (c *DSVSecretClient) PathOfID(id string) (string, *http.Response, error) {
+	return c.PathOfIDContext(context.Background(), id)
+}
+
+// PathOfIDContext is the same as PathOfID, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) PathOfIDContext(ctx context.Context, id string) (string, *http.Response, error) {
+	var path string
+	r, err := c.ListSecretsContext(ctx, nil, func(item Item) error {
+		if item.ID == id {
+			path = item.Name
+			return errDSVIDFound
+		}
+		return nil
+	})
+	if path != "" {
+		return path, r, nil
+	}
+	if err != nil {
+		return "", r, err
+	}
+
+	// folders are identified by their paths
+	info, r, err := c.GetMetaDataContext(ctx, id)
+	if err != nil || info.ID != id {
+		return "", r, ErrSecretNotFound
+	}
+	return strings.Trim(id, "/"), r, nil
+}
+
+// GetMetaData returns the metadata of a secret.
+// Returns the following information:
+//  MetaData: metadata information for the secret
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrNoGetMetaDataPermission:  The caller has no permission to get metadata information
+//	ErrSecretNotFound: Secret specified in path cannot be found.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *DSVSecretClient) GetMetaData(path string) (*MetaData, *http.Response, error) {
+	return c.GetMetaDataContext(context.Background(), path)
+}
+
+// GetMetaDataContext is the same as GetMetaData, but uses 'ctx' for the REST API requests.
+func (c *DSVSecretClient) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	path = strings.Trim(path, "/")
+	name := baseName(path)
+
+	secret, r, err := c.getSecret(ctx, path)
+	if err == ErrSecretNotFound {
+		// check if it is a folder
+		children, r, err := c.search(ctx, path, 1)
+		if err != nil {
+			return nil, r, err
+		}
+		if len(children) == 0 {
+			return nil, r, ErrSecretNotFound
+		}
+		result := &MetaData{}
+		result.Name = name
+		result.Type = SecretTypeFolder
+		result.ID = path
+		result.CRN = path
+		return result, r, nil
+	}
+	if err != nil {
+		return nil, r, err
+	}
+
+	result := &MetaData{}
+	result.Name = name
+	result.Type = secret.secretType()
+	result.ID = secret.ID
+	result.CRN = path
+	result.Description = secret.Description
+	result.WhenCreated = secret.Created
+	result.WhenModified = secret.LastModified
+	result.CreatedBy = secret.CreatedBy
+	result.ModifiedBy = secret.ModifiedBy
+	result.Version = secret.Version
+	for k, v := range secret.Attributes {
+		if k != secretTypeKey {
+			if result.Attributes == nil {
+				result.Attributes = make(map[string]string)
+			}
+			result.Attributes[k] = attributeText(v)
+		}
+	}
+	return result, r, nil
+}
+
+// getSecret returns the secret in 'path'
+func (c *DSVSecretClient) getSecret(ctx context.Context, path string) (*dsvSecret, *http.Response, error) {
+	var result dsvSecret
+	r, err := c.doRequest(ctx, http.MethodGet, c.apiPath(path), nil, &result)
+	if err != nil {
+		return nil, r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK:
+		result.Path = dsvPath(result.Path)
+		return &result, r, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, r, ErrNoGetMetaDataPermission
+	case http.StatusNotFound:
+		return nil, r, ErrSecretNotFound
+	default:
+		return nil, r, ErrUnexpectedResponse
+	}
+}
+
+// search returns the secrets in the folder 'path' and its subfolders.  At most 'max' secrets are
+// returned if 'max' is larger than 0.  If the caller has no permission to search secrets, no secret
+// is returned.
+func (c *DSVSecretClient) search(ctx context.Context, path string, max int) ([]dsvSecret, *http.Response, error) {
+	prefix := ""
+	if path != "" {
+		prefix = path + "/"
+	}
+	var secrets []dsvSecret
+	r, err := c.searchPages(ctx, path, 0, func(secret *dsvSecret) bool {
+		// search text matches any part of the path
+		if !strings.HasPrefix(secret.Path, prefix) {
+			return true
+		}
+		secrets = append(secrets, *secret)
+		return max <= 0 || len(secrets) < max
+	})
+	if err == ErrNoGetMetaDataPermission {
+		return nil, r, nil
+	}
+	return secrets, r, err
+}
+
+// searchPages searches secrets that contain 'text' in their path, and calls 'fn' for each secret
+// found.  'limit' is the number of secrets in each page.  The search stops when 'fn' returns false.
+func (c *DSVSecretClient) searchPages(ctx context.Context, text string, limit int, fn func(secret *dsvSecret) bool) (*http.Response, error) {
+	if limit <= 0 {
+		limit = dsvSearchLimit
+	}
+	query := url.Values{}
+	query.Set("searchText", text)
+	query.Set("limit", strconv.Itoa(limit))
+	for {
+		var result dsvSearchResult
+		r, err := c.doRequest(ctx, http.MethodGet, "/v1/secrets?"+query.Encode(), nil, &result)
+		if err != nil {
+			return r, err
+		}
+		switch r.StatusCode {
+		case http.StatusOK:
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return r, ErrNoGetMetaDataPermission
+		default:
+			return r, ErrUnexpectedResponse
+		}
+		for i := range result.Data {
+			result.Data[i].Path = dsvPath(result.Data[i].Path)
+			if !fn(&result.Data[i]) {
+				return r, nil
+			}
+		}
+		if result.Cursor == "" || len(result.Data) == 0 {
+			return r, nil
+		}
+		query.Set("cursor", result.Cursor)
+	}
+}
+
+// apiPath returns the path of the DSV API for the secret 'path'
+func (c *DSVSecretClient) apiPath(path string) string {
+	return "/v1/secrets/" + escapePath(strings.Trim(path, "/"))
+}
+
+// errorMessage returns the error message in a DSV error response
+func (c *DSVSecretClient) errorMessage(r *http.Response) string {
+	var result struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(responseBody(r), &result) != nil {
+		return ""
+	}
+	return result.Message
+}
+
+// secretType returns the type of secret saved in its attributes
+func (s *dsvSecret) secretType() string {
+	if secretType, ok := s.Attributes[secretTypeKey].(string); ok && secretType == SecretTypeText {
+		return SecretTypeText
+	}
+	return SecretTypeKV
+}
+
+// dsvPath converts the path of a secret returned by DSV to a path separated by "/"
+func dsvPath(path string) string {
+	return strings.Trim(strings.ReplaceAll(path, ":", "/"), "/")
+}
@@ -0,0 +1,375 @@
+package secret
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testDSVToken = "test-dsv-token"
+	testDSVUser  = "app-user"
+)
+
+// dsvServer is a minimal stand-in of the secrets API of DSV.  It supports reading, creating,
+// updating, searching and deleting secrets.  Like DSV, it returns paths separated by ":".
+type dsvServer struct {
+	mu        sync.Mutex                        // protects secrets
+	secrets   map[string]map[string]interface{} // secrets indexed by path
+	forbidden map[string]bool                   // paths that return 403
+	nextID    int                               // ID of next secret
+}
+
+func newDSVServer() *dsvServer {
+	return &dsvServer{
+		secrets:   make(map[string]map[string]interface{}),
+		forbidden: make(map[string]bool),
+	}
+}
+
+func (d *dsvServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+testDSVToken {
+		d.reply(w, http.StatusUnauthorized, map[string]interface{}{"code": 401, "message": "unauthorized"})
+		return
+	}
+	if r.URL.Path == "/v1/secrets" && r.Method == http.MethodGet {
+		d.search(w, r)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/v1/secrets/") {
+		d.reply(w, http.StatusNotFound, map[string]interface{}{"code": 404, "message": "not found"})
+		return
+	}
+	path := strings.ReplaceAll(strings.TrimPrefix(r.URL.Path, "/v1/secrets/"), "/", ":")
+	if d.forbidden[path] {
+		d.reply(w, http.StatusForbidden, map[string]interface{}{"code": 403, "message": "forbidden"})
+		return
+	}
+
+	var body map[string]interface{}
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+	secret, exists := d.secrets[path]
+	now := time.Now().UTC().Format(time.RFC3339)
+	switch {
+	case r.Method == http.MethodGet && exists:
+		d.reply(w, http.StatusOK, secret)
+	case r.Method == http.MethodPost && exists:
+		d.reply(w, http.StatusBadRequest, map[string]interface{}{"code": 400, "message": "the secret already exists"})
+	case r.Method == http.MethodPost:
+		d.nextID++
+		body["id"] = "id-" + strconv.Itoa(d.nextID)
+		body["path"] = path
+		body["created"] = now
+		body["lastModified"] = now
+		body["createdBy"] = testDSVUser
+		body["lastModifiedBy"] = testDSVUser
+		body["version"] = "0"
+		d.secrets[path] = body
+		d.reply(w, http.StatusOK, body)
+	case r.Method == http.MethodPut && exists:
+		for _, key := range []string{"data", "attributes", "description"} {
+			secret[key] = body[key]
+		}
+		version, _ := strconv.Atoi(secret["version"].(string))
+		secret["lastModified"] = now
+		secret["lastModifiedBy"] = testDSVUser
+		secret["version"] = strconv.Itoa(version + 1)
+		d.reply(w, http.StatusOK, secret)
+	case r.Method == http.MethodDelete && exists:
+		delete(d.secrets, path)
+		w.WriteHeader(http.StatusOK)
+	default:
+		d.reply(w, http.StatusNotFound, map[string]interface{}{"code": 404, "message": "unable to find item"})
+	}
+}
+
+func (d *dsvServer) reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// search returns the secrets whose path contains the search text, using the index of the
+// next secret as cursor
+func (d *dsvServer) search(w http.ResponseWriter, r *http.Request) {
+	text := strings.ReplaceAll(r.URL.Query().Get("searchText"), "/", ":")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+
+	var paths []string
+	for path := range d.secrets {
+		if strings.Contains(path, text) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	result := map[string]interface{}{}
+	var data []interface{}
+	for i := start; i < len(paths) && len(data) < limit; i++ {
+		data = append(data, d.secrets[paths[i]])
+		if i+1 < len(paths) {
+			result["cursor"] = strconv.Itoa(i + 1)
+		} else {
+			delete(result, "cursor")
+		}
+	}
+	result["data"] = data
+	d.reply(w, http.StatusOK, result)
+}
+
+// SecretDSVTestSuite tests DSVSecretClient against a local stand-in of the DSV secrets API.
+type SecretDSVTestSuite struct {
+	testutils.CfyTestSuite
+	dsv    *dsvServer
+	server *httptest.Server
+	handle Secret
+}
+
+func TestSecretDSVTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretDSVTestSuite))
+}
+
+func (s *SecretDSVTestSuite) SetupTest() {
+	s.dsv = newDSVServer()
+	s.server = httptest.NewTLSServer(s.dsv)
+	var err error
+	s.handle, err = NewSecretClient(s.server.URL, ServerDSV, testDSVToken, s.server.Client)
+	s.Require().NoError(err, "Should create client for dsv")
+}
+
+func (s *SecretDSVTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *SecretDSVTestSuite) TestTextSecret() {
+	success, id, _, err := s.handle.Create("app/password", "db password", "pa$$word")
+	s.Require().NoError(err, "Should create text secret")
+	s.Assert().True(success)
+	s.Assert().Equal("id-1", id, "ID should be assigned by DSV")
+
+	value, r, err := s.handle.Get("app/password")
+	s.Require().NoError(err, "Should get text secret")
+	s.Assert().Equal(200, r.StatusCode)
+	s.Assert().Equal("pa$$word", value)
+
+	metadata, _, err := s.handle.GetMetaData("app/password")
+	s.Require().NoError(err, "Should get metadata")
+	s.Assert().Equal("password", metadata.Name)
+	s.Assert().Equal(SecretTypeText, metadata.Type)
+	s.Assert().Equal("id-1", metadata.ID)
+	s.Assert().Equal("app/password", metadata.CRN)
+	s.Assert().Equal("db password", metadata.Description)
+	s.Assert().False(metadata.WhenCreated.IsZero(), "Should return creation time")
+}
+
+func (s *SecretDSVTestSuite) TestKeyValueSecret() {
+	kv := map[string]string{"user": "admin", "password": "secret"}
+	_, _, _, err := s.handle.Create("app/creds", "", kv)
+	s.Require().NoError(err, "Should create keyvalue secret")
+
+	value, _, err := s.handle.Get("app/creds")
+	s.Require().NoError(err, "Should get keyvalue secret")
+	s.Assert().Equal(kv, value)
+
+	// secret written by other DSV clients without type is keyvalue
+	s.dsv.secrets["external"] = map[string]interface{}{
+		"id":   "external-id",
+		"path": "external",
+		"data": map[string]interface{}{"port": 5432, "host": "db"},
+	}
+	value, _, err = s.handle.Get("external")
+	s.Require().NoError(err, "Should get secret without type")
+	s.Assert().Equal(map[string]string{"port": "5432", "host": "db"}, value)
+}
+
+func (s *SecretDSVTestSuite) TestCreateErrors() {
+	_, _, _, err := s.handle.Create("app/secret", "", "value")
+	s.Require().NoError(err)
+
+	_, _, _, err = s.handle.Create("app/secret", "", "value")
+	s.Assert().ErrorIs(err, ErrExists, "Duplicate secret")
+	_, _, _, err = s.handle.Create("app", "", "value")
+	s.Assert().ErrorIs(err, ErrExists, "Secret with same path as folder")
+	_, _, _, err = s.handle.CreateFolder("app", "")
+	s.Assert().ErrorIs(err, ErrExists, "Existing folder")
+	_, _, _, err = s.handle.CreateFolder("app/secret", "")
+	s.Assert().ErrorIs(err, ErrExists, "Folder with same path as secret")
+	_, _, _, err = s.handle.Create("app/ /bad", "", "value")
+	s.Assert().ErrorIs(err, ErrBadPathName)
+	_, _, _, err = s.handle.Create("app/number", "", 123)
+	s.Assert().ErrorIs(err, ErrSecretTypeNotSupported)
+
+	s.dsv.forbidden["locked:secret"] = true
+	_, _, _, err = s.handle.Create("locked/secret", "", "value")
+	s.Assert().ErrorIs(err, ErrNoCreatePermission)
+}
+
+func (s *SecretDSVTestSuite) TestList() {
+	s.handle.Create("app/db/password", "", "pw")
+	s.handle.Create("app/db/user", "", "admin")
+	s.handle.Create("app/api", "", map[string]string{"key": "value"})
+	s.handle.Create("top", "", "value")
+
+	items, _, err := s.handle.List("app")
+	s.Require().NoError(err, "Should list folder")
+	s.Assert().Equal([]Item{
+		{Name: "api", Type: SecretTypeKV, ID: "id-3"},
+		{Name: "db", Type: SecretTypeFolder, ID: "app/db"},
+	}, items)
+
+	items, _, err = s.handle.List("/")
+	s.Require().NoError(err, "Should list top level")
+	s.Assert().Equal([]Item{
+		{Name: "app", Type: SecretTypeFolder, ID: "app"},
+		{Name: "top", Type: SecretTypeText, ID: "id-4"},
+	}, items)
+
+	_, _, err = s.handle.List("missing")
+	s.Assert().ErrorIs(err, ErrFolderNotFound)
+	_, _, err = s.handle.List("top")
+	s.Assert().ErrorIs(err, ErrNotSecretFolder)
+
+	metadata, _, err := s.handle.GetMetaData("app/db")
+	s.Require().NoError(err, "Should get metadata of folder")
+	s.Assert().Equal(SecretTypeFolder, metadata.Type)
+	s.Assert().Equal("db", metadata.Name)
+}
+
+func (s *SecretDSVTestSuite) TestListSecrets() {
+	s.handle.Create("app/db/password", "", "pw")
+	s.handle.Create("app/api", "", "key")
+	s.handle.Create("other", "", "value")
+
+	var names []string
+	_, err := s.handle.ListSecrets(&ListOptions{Limit: 1, Search: "app", OrderBy: []string{"name desc"}}, func(item Item) error {
+		names = append(names, item.Name)
+		return nil
+	})
+	s.Require().NoError(err, "Should list secrets across pages")
+	s.Assert().Equal([]string{"app/db/password", "app/api"}, names)
+
+	_, err = s.handle.ListSecrets(&ListOptions{Filter: "type eq 'text'"}, func(item Item) error { return nil })
+	s.Assert().ErrorIs(err, ErrInvalidListOption)
+	_, err = s.handle.ListSecrets(&ListOptions{OrderBy: []string{"type"}}, func(item Item) error { return nil })
+	s.Assert().ErrorIs(err, ErrInvalidListOption)
+}
+
+func (s *SecretDSVTestSuite) TestModify() {
+	_, _, _, err := s.handle.Create("app/secret", "first", "value")
+	s.Require().NoError(err)
+	s.handle.Create("app/sub/secret", "", "value")
+
+	success, id, _, err := s.handle.Modify("app/secret", "", "new value")
+	s.Require().NoError(err, "Should modify secret")
+	s.Assert().True(success)
+	s.Assert().Equal("id-1", id)
+	value, _, _ := s.handle.Get("app/secret")
+	s.Assert().Equal("new value", value)
+	metadata, _, _ := s.handle.GetMetaData("app/secret")
+	s.Assert().Equal("first", metadata.Description, "Description should be kept")
+
+	_, _, _, err = s.handle.Modify("app/secret", "second", "newer value")
+	s.Require().NoError(err)
+	metadata, _, _ = s.handle.GetMetaData("app/secret")
+	s.Assert().Equal("second", metadata.Description)
+
+	_, _, _, err = s.handle.Modify("app/secret", "", map[string]string{"k": "v"})
+	s.Assert().ErrorIs(err, ErrCannotModifySecretType)
+	_, _, _, err = s.handle.Modify("app/sub", "", "value")
+	s.Assert().ErrorIs(err, ErrCannotModifySecretFolder)
+	_, _, _, err = s.handle.Modify("app/missing", "", "value")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretDSVTestSuite) TestModifyMetaData() {
+	s.handle.Create("app/secret", "original", map[string]string{"password": "pw"})
+
+	_, err := s.handle.ModifyMetaData("app/secret", "", map[string]string{"owner": "ops"})
+	s.Require().NoError(err, "Should modify attributes")
+	metadata, _, err := s.handle.GetMetaData("app/secret")
+	s.Require().NoError(err)
+	s.Assert().Equal("original", metadata.Description, "Description should not change")
+	s.Assert().Equal(map[string]string{"owner": "ops"}, metadata.Attributes)
+	s.Assert().Equal(testDSVUser, metadata.CreatedBy)
+	s.Assert().Equal(testDSVUser, metadata.ModifiedBy)
+	s.Assert().Equal("1", metadata.Version)
+	value, _, _ := s.handle.Get("app/secret")
+	s.Assert().Equal(map[string]string{"password": "pw"}, value, "Value should not change")
+
+	_, err = s.handle.ModifyMetaData("app/secret", "changed", map[string]string{"owner": ""})
+	s.Require().NoError(err)
+	metadata, _, _ = s.handle.GetMetaData("app/secret")
+	s.Assert().Equal("changed", metadata.Description)
+	s.Assert().Nil(metadata.Attributes)
+
+	_, err = s.handle.ModifyMetaData("app/secret", "", map[string]string{"secret_type": "text"})
+	s.Assert().ErrorIs(err, ErrMetaDataNotSupported)
+	_, err = s.handle.ModifyMetaData("app", "", nil)
+	s.Assert().ErrorIs(err, ErrCannotModifySecretFolder)
+	_, err = s.handle.ModifyMetaData("missing", "", nil)
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretDSVTestSuite) TestDelete() {
+	s.handle.Create("app/secret", "", "value")
+
+	_, err := s.handle.Delete("app")
+	s.Assert().ErrorIs(err, ErrFolderNotEmpty)
+	_, err = s.handle.Delete("app/secret")
+	s.Require().NoError(err, "Should delete secret")
+	_, err = s.handle.Delete("app/secret")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, _, err = s.handle.GetMetaData("app")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Folder should be gone with its last secret")
+}
+
+func (s *SecretDSVTestSuite) TestPermission() {
+	s.handle.Create("locked", "", "value")
+	s.dsv.forbidden["locked"] = true
+
+	_, _, err := s.handle.Get("locked")
+	s.Assert().ErrorIs(err, ErrNoRetrievePermission)
+	_, _, err = s.handle.GetMetaData("locked")
+	s.Assert().ErrorIs(err, ErrNoGetMetaDataPermission)
+	_, _, _, err = s.handle.Modify("locked", "", "value")
+	s.Assert().ErrorIs(err, ErrNoModifyPermission)
+	_, err = s.handle.Delete("locked")
+	s.Assert().ErrorIs(err, ErrNoDeletePermission)
+
+	handle, err := NewSecretClient(s.server.URL, ServerDSV, "bad token", s.server.Client)
+	s.Require().NoError(err)
+	_, _, err = handle.Get("locked")
+	s.Assert().ErrorIs(err, ErrNoRetrievePermission, "Should reject bad token")
+}
+
+func (s *SecretDSVTestSuite) TestPathOfID() {
+	_, id, _, err := s.handle.Create("app/db/password", "", "pw")
+	s.Require().NoError(err)
+	s.handle.Create("other", "", "value")
+
+	path, _, err := s.handle.PathOfID(id)
+	s.Require().NoError(err, "Should find secret by ID")
+	s.Assert().Equal("app/db/password", path)
+	path, _, err = s.handle.PathOfID("app/db")
+	s.Require().NoError(err, "Should find folder by path")
+	s.Assert().Equal("app/db", path)
+
+	_, _, err = s.handle.PathOfID("app/db/password")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Path of secret should not be taken for ID")
+	_, _, err = s.handle.PathOfID("missing")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
@@ -0,0 +1,110 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// parameters for encrypting export documents with a passphrase
+const (
+	exportCipher     = "aes-256-gcm"
+	exportKDF        = "pbkdf2-sha256"
+	exportIterations = 100000
+	exportSaltSize   = 16
+	exportKeySize    = 32
+
+	// limit of the iterations in documents that are read, as deriving the key takes time in
+	// proportion to them
+	exportMaxIterations = 10 * exportIterations
+)
+
+// exportEncryption describes how the content of an export document is encrypted
+type exportEncryption struct {
+	Cipher     string `json:"cipher" yaml:"cipher"`
+	KDF        string `json:"kdf" yaml:"kdf"`
+	Iterations int    `json:"iterations" yaml:"iterations"`
+	Salt       string `json:"salt" yaml:"salt"`   // base64 encoded
+	Nonce      string `json:"nonce" yaml:"nonce"` // base64 encoded
+}
+
+// encryptExport encrypts 'plaintext' with a key derived from 'passphrase'.
+// It returns the encryption parameters and the base64 encoded ciphertext.
+func encryptExport(plaintext []byte, passphrase string) (*exportEncryption, string, error) {
+	salt := make([]byte, exportSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, "", err
+	}
+	gcm, err := newExportGCM(passphrase, salt, exportIterations)
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	info := &exportEncryption{
+		Cipher:     exportCipher,
+		KDF:        exportKDF,
+		Iterations: exportIterations,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+	}
+	ciphertext := gcm.Seal(nil, nonce, plaintext, []byte(info.Cipher+info.KDF))
+	return info, base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptExport decrypts the base64 encoded 'data' that is encrypted as described in 'info'.
+func decryptExport(info *exportEncryption, data string, passphrase string) ([]byte, error) {
+	if info.Cipher != exportCipher || info.KDF != exportKDF {
+		return nil, fmt.Errorf("unsupported encryption %s/%s: %w", info.Cipher, info.KDF, ErrInvalidExportDocument)
+	}
+	if info.Iterations <= 0 || info.Iterations > exportMaxIterations {
+		return nil, fmt.Errorf("bad iterations %d: %w", info.Iterations, ErrInvalidExportDocument)
+	}
+	salt, err := base64.StdEncoding.DecodeString(info.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("bad salt: %w", ErrInvalidExportDocument)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(info.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("bad nonce: %w", ErrInvalidExportDocument)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("bad encrypted data: %w", ErrInvalidExportDocument)
+	}
+	gcm, err := newExportGCM(passphrase, salt, info.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("bad nonce: %w", ErrInvalidExportDocument)
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(info.Cipher+info.KDF))
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return plaintext, nil
+}
+
+// newExportGCM returns the AES-GCM cipher keyed with the key derived from 'passphrase'
+func newExportGCM(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key := exportKey(passphrase, salt, iterations)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// exportKey derives the key of export documents from 'passphrase' with PBKDF2, using HMAC-SHA256
+// as the pseudorandom function
+func exportKey(passphrase string, salt []byte, iterations int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, iterations, exportKeySize, sha256.New)
+}
@@ -0,0 +1,91 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error is the error returned by PASSecretClient when an operation fails.  It has the details of the
+// failure that are reported by PAS, and wraps the error that describes the failure, e.g.,
+// ErrSecretNotFound, so that the error can still be checked with errors.Is:
+//
+//	if errors.Is(err, secret.ErrSecretNotFound) {
+//		...
+//	}
+//	var secretErr *secret.Error
+//	if errors.As(err, &secretErr) {
+//		log.Printf("transaction ID: %s", secretErr.TxID)
+//	}
+//
+// The wrapped error can also be an error from the transport, the error in the context of the request,
+// or a RetryError.
+type Error struct {
+	Op         string // operation that fails, e.g., "Get"
+	Path       string // path of the secret or folder.  Empty for ListSecrets
+	StatusCode int    // HTTP status of the response.  0 if there is no response
+	TxID       string // transaction ID of the request in PAS, from the header X-CFY-TX-ID of the response
+	Title      string // title in the error response of PAS
+	Detail     string // detail in the error response of PAS
+	Type       string // URL that identifies the type of error in the error response of PAS
+	Err        error  // underlying error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.Path != "" {
+		fmt.Fprintf(&b, " [%s]", e.Path)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+
+	var details []string
+	if e.StatusCode != 0 {
+		details = append(details, fmt.Sprintf("HTTP status %d", e.StatusCode))
+	}
+	switch {
+	case e.Title != "" && e.Detail != "":
+		details = append(details, e.Title+": "+e.Detail)
+	case e.Title != "":
+		details = append(details, e.Title)
+	case e.Detail != "":
+		details = append(details, e.Detail)
+	}
+	if e.TxID != "" {
+		details = append(details, "transaction ID "+e.TxID)
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// pasError returns 'err' in operation 'op' on 'path' as an Error, with the details in the response 'r'.
+// nil is returned if 'err' is nil.
+func pasError(op string, path string, r *http.Response, err error) error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{Op: op, Path: path, Err: err}
+	if r == nil {
+		return e
+	}
+	e.StatusCode = r.StatusCode
+	e.TxID = r.Header.Get("X-CFY-TX-ID")
+	if r.StatusCode >= 400 && r.Body != nil {
+		// PAS returns the details of the error as problem details (RFC 7807)
+		var problem map[string]interface{}
+		if json.Unmarshal(responseBody(r), &problem) == nil {
+			e.Title, _ = problem["title"].(string)
+			e.Detail, _ = problem["detail"].(string)
+			e.Type, _ = problem["type"].(string)
+		}
+	}
+	return e
+}
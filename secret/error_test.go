@@ -0,0 +1,106 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+// SecretErrorTestSuite tests the errors returned by PASSecretClient, using a local HTTP server that
+// stands in for PAS and returns an error response.
+type SecretErrorTestSuite struct {
+	testutils.CfyTestSuite
+	server *httptest.Server // local HTTP server that stands in for PAS
+	handle Secret           // interface to secret API
+	status int              // HTTP status returned by server
+}
+
+func TestSecretErrorTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretErrorTestSuite))
+}
+
+func (s *SecretErrorTestSuite) SetupTest() {
+	s.status = http.StatusNotFound
+	s.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.Header().Set("X-CFY-TX-ID", "tx-1")
+		w.WriteHeader(s.status)
+		fmt.Fprintf(w, `{
+			"type": "https://example.com/errors/%d",
+			"title": "%s",
+			"status": %d,
+			"detail": "something is wrong"
+		}`, s.status, http.StatusText(s.status), s.status)
+	}))
+	s.handle = newTestPASClient(s.server, "token")
+}
+
+func (s *SecretErrorTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// checkError checks that 'err' is an Error of operation 'op' that wraps 'target'
+func (s *SecretErrorTestSuite) checkError(err error, op string, path string, target error) {
+	s.Assert().ErrorIs(err, target, "%s should return [%v]", op, target)
+	var secretErr *Error
+	if !s.Assert().True(errors.As(err, &secretErr), "%s should return Error", op) {
+		return
+	}
+	s.Assert().Equal(op, secretErr.Op)
+	s.Assert().Equal(path, secretErr.Path)
+	s.Assert().Equal(s.status, secretErr.StatusCode)
+	s.Assert().Equal("tx-1", secretErr.TxID)
+	s.Assert().Equal(http.StatusText(s.status), secretErr.Title)
+	s.Assert().Equal("something is wrong", secretErr.Detail)
+	s.Assert().Equal(fmt.Sprintf("https://example.com/errors/%d", s.status), secretErr.Type)
+}
+
+func (s *SecretErrorTestSuite) TestAllMethods() {
+	_, _, err := s.handle.Get("folder/secret")
+	s.checkError(err, "Get", "folder/secret", ErrSecretNotFound)
+	_, _, err = s.handle.GetMetaData("folder/secret")
+	s.checkError(err, "GetMetaData", "folder/secret", ErrSecretNotFound)
+	_, _, err = s.handle.List("folder")
+	s.checkError(err, "List", "folder", ErrFolderNotFound)
+	_, err = s.handle.Delete("folder/secret")
+	s.checkError(err, "Delete", "folder/secret", ErrSecretNotFound)
+	_, _, _, err = s.handle.Modify("folder/secret", "", "value")
+	s.checkError(err, "Modify", "folder/secret", ErrSecretNotFound)
+
+	s.status = http.StatusConflict
+	_, _, _, err = s.handle.Create("folder/secret", "", "value")
+	s.checkError(err, "Create", "folder/secret", ErrExists)
+	_, _, _, err = s.handle.CreateFolder("folder", "")
+	s.checkError(err, "CreateFolder", "folder", ErrExists)
+
+	s.status = http.StatusUnprocessableEntity
+	_, err = s.handle.ListSecrets(&ListOptions{Filter: "bad"}, func(item Item) error { return nil })
+	s.checkError(err, "ListSecrets", "", ErrInvalidListOption)
+}
+
+func (s *SecretErrorTestSuite) TestErrorMessage() {
+	_, _, err := s.handle.Get("folder/secret")
+	s.Assert().Equal("Get [folder/secret]: Secret cannot be found (HTTP status 404, Not Found: something is wrong, transaction ID tx-1)",
+		err.Error())
+
+	_, _, _, err = s.handle.Create("folder/secret", "", 42)
+	s.Assert().ErrorIs(err, ErrSecretTypeNotSupported)
+	s.Assert().Equal("Create [folder/secret]: Cannot created secret for input type", err.Error(),
+		"Error without response should not have HTTP details")
+}
+
+func (s *SecretErrorTestSuite) TestContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := s.handle.GetContext(ctx, "folder/secret")
+	s.Assert().ErrorIs(err, context.Canceled)
+	var secretErr *Error
+	s.Require().True(errors.As(err, &secretErr))
+	s.Assert().Zero(secretErr.StatusCode)
+}
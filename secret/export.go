@@ -0,0 +1,435 @@
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExportFormatVersion is the version of the export document written by Export.  Import accepts
+// documents of this version or earlier.
+const ExportFormatVersion = 1
+
+// constant definition for export document formats
+const (
+	ExportFormatJSON = "json"
+	ExportFormatYAML = "yaml"
+)
+
+// ConflictPolicy specifies what Import does when a secret or folder in the export document
+// already exists in the destination.
+type ConflictPolicy string
+
+// constant definition for conflict policies
+const (
+	ConflictSkip      ConflictPolicy = "skip"      // keep the existing secret.  This is the default
+	ConflictOverwrite ConflictPolicy = "overwrite" // replace the value and description of the existing secret using Modify
+	ConflictFail      ConflictPolicy = "fail"      // stop the import
+)
+
+// ImportAction describes the outcome of importing an item in an export document.
+type ImportAction string
+
+// constant definition for import actions
+const (
+	ImportCreated     ImportAction = "created"     // secret/folder is created
+	ImportSkipped     ImportAction = "skipped"     // secret/folder already exists and is not changed
+	ImportOverwritten ImportAction = "overwritten" // existing secret is replaced
+	ImportFailed      ImportAction = "failed"      // secret/folder cannot be imported.  See ImportResult.Err
+)
+
+// ExportDocument is the portable representation of a tree of secrets.  It is written by Export and
+// read by Import.
+type ExportDocument struct {
+	Version  int          `json:"version" yaml:"version"`                       // format version.  See ExportFormatVersion
+	Root     string       `json:"root,omitempty" yaml:"root,omitempty"`         // path of the exported folder in the source
+	Exported time.Time    `json:"exported,omitempty" yaml:"exported,omitempty"` // time of export
+	Items    []ExportItem `json:"items,omitempty" yaml:"items,omitempty"`       // folders and secrets, parent folders first
+}
+
+// ExportItem is a secret or folder in an ExportDocument.
+type ExportItem struct {
+	Path        string            `json:"path" yaml:"path"` // path relative to the exported folder
+	Type        string            `json:"type" yaml:"type"` // one of SecretTypeFolder, SecretTypeText, SecretTypeKV or SecretTypeFile
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Text        string            `json:"text,omitempty" yaml:"text,omitempty"`         // value of text secret
+	KeyValue    map[string]string `json:"keyvalue,omitempty" yaml:"keyvalue,omitempty"` // value of keyvalue secret
+	Data        string            `json:"data,omitempty" yaml:"data,omitempty"`         // base64 encoded value of file secret
+}
+
+// ExportOptions specifies the options for Export and WriteExport.
+type ExportOptions struct {
+	// Format is the format of the document, either ExportFormatJSON (default) or ExportFormatYAML
+	Format string
+
+	// Passphrase is used to encrypt the folders and secrets in the document.  If it is empty,
+	// the document is not encrypted.
+	Passphrase string
+}
+
+// ImportOptions specifies the options for Import.
+type ImportOptions struct {
+	// Passphrase is used to decrypt an encrypted document
+	Passphrase string
+
+	// OnConflict specifies what to do when a secret or folder already exists.  Default is ConflictSkip.
+	OnConflict ConflictPolicy
+}
+
+// ImportResult is the outcome of importing an item in an export document.
+type ImportResult struct {
+	Path   string       // full path of secret/folder in the destination
+	Type   string       // type of secret
+	Action ImportAction // what is done for the secret/folder
+	Err    error        // reason of failure if Action is ImportFailed
+}
+
+// exportEnvelope is the document that is actually written.  When the document is encrypted, only
+// the version, the encryption parameters and the encrypted document are present.
+type exportEnvelope struct {
+	ExportDocument `yaml:",inline"`
+	Encryption     *exportEncryption `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	Data           string            `json:"data,omitempty" yaml:"data,omitempty"` // encrypted ExportDocument in JSON
+}
+
+// Export writes the folders and secrets under the folder 'root' to 'w'.  The document
+// includes the description of each folder and secret, and the value of each text and keyvalue
+// secret.  'root' can be "" or "/" to export all secrets that the caller can access.
+// 'opts' specifies the format of the document and whether it is encrypted.  It can be nil.
+//
+// The following errors may be returned, in addition to those returned by Walk and Get:
+//	ErrInvalidExportOption:  The format in 'opts' is not supported.
+//	ErrNotSecretFolder:  'root' is not a folder.
+func Export(cl Secret, root string, w io.Writer, opts *ExportOptions) error {
+	return ExportContext(context.Background(), cl, root, w, opts)
+}
+
+// ExportContext is the same as Export, but uses 'ctx' for all the requests.
+func ExportContext(ctx context.Context, cl Secret, root string, w io.Writer, opts *ExportOptions) error {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	if err := checkExportFormat(opts.Format); err != nil {
+		return err
+	}
+
+	doc := &ExportDocument{
+		Version:  ExportFormatVersion,
+		Root:     root,
+		Exported: time.Now().UTC().Truncate(time.Second),
+	}
+	err := WalkContext(ctx, cl, root, &WalkOptions{MetaData: true}, func(path string, info *MetaData, err error) error {
+		if err != nil {
+			return err
+		}
+		isFolder := strings.EqualFold(info.Type, SecretTypeFolder)
+		if path == root {
+			if !isFolder {
+				return ErrNotSecretFolder
+			}
+			return nil
+		}
+
+		item := ExportItem{
+			Path:        relativePath(root, path),
+			Description: info.Description,
+		}
+		if isFolder {
+			item.Type = SecretTypeFolder
+			doc.Items = append(doc.Items, item)
+			return nil
+		}
+
+		value, _, err := cl.GetContext(ctx, path)
+		if err != nil {
+			return fmt.Errorf("cannot get value of [%s]: %w", path, err)
+		}
+		switch v := value.(type) {
+		case string:
+			item.Type = SecretTypeText
+			item.Text = v
+		case []byte:
+			item.Type = SecretTypeFile
+			item.Data = base64.StdEncoding.EncodeToString(v)
+		case map[string]string:
+			item.Type = SecretTypeKV
+			item.KeyValue = v
+		case map[string]interface{}:
+			item.Type = SecretTypeKV
+			item.KeyValue = make(map[string]string, len(v))
+			for key, val := range v {
+				item.KeyValue[key] = fmt.Sprint(val)
+			}
+		default:
+			return fmt.Errorf("cannot export [%s] of type %s: %w", path, info.Type, ErrSecretTypeNotSupported)
+		}
+		doc.Items = append(doc.Items, item)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return WriteExport(w, doc, opts)
+}
+
+// WriteExport writes 'doc' to 'w' in the format specified in 'opts', and encrypts it if
+// a passphrase is specified.  'opts' can be nil.
+func WriteExport(w io.Writer, doc *ExportDocument, opts *ExportOptions) error {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	if err := checkExportFormat(opts.Format); err != nil {
+		return err
+	}
+
+	envelope := &exportEnvelope{ExportDocument: *doc}
+	if opts.Passphrase != "" {
+		plaintext, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		envelope.ExportDocument = ExportDocument{
+			Version:  doc.Version,
+			Exported: doc.Exported,
+		}
+		envelope.Encryption, envelope.Data, err = encryptExport(plaintext, opts.Passphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.Format == ExportFormatYAML {
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(envelope); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(envelope)
+}
+
+// ReadExport reads an export document in JSON or YAML format from 'r'.  If the document is
+// encrypted, it is decrypted using 'passphrase'.
+//
+// The following errors may be returned:
+//	ErrBadPassphrase:  The document cannot be decrypted with 'passphrase'.
+//	ErrInvalidExportDocument:  The document is not valid, or its version is not supported.
+//	ErrPassphraseRequired:  The document is encrypted but 'passphrase' is empty.
+func ReadExport(r io.Reader, passphrase string) (*ExportDocument, error) {
+	content, err := ioutil.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &exportEnvelope{}
+	if err = decodeDocument(content, envelope, ErrInvalidExportDocument); err != nil {
+		return nil, err
+	}
+	if envelope.Encryption != nil {
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		plaintext, err := decryptExport(envelope.Encryption, envelope.Data, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		envelope = &exportEnvelope{}
+		if err = decodeDocument(plaintext, envelope, ErrInvalidExportDocument); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := &envelope.ExportDocument
+	if err = validateExport(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Import creates the folders and secrets in the export document read from 'r' under the folder
+// 'root', which is created if it does not exist.  'root' can be "" or "/" to import the secrets
+// to the top level.  'opts' specifies the passphrase of an encrypted document, and what to do
+// when a secret already exists.  It can be nil.
+//
+// Import returns the result of each item imported.  An item that cannot be imported does not stop
+// the import, unless it already exists and the conflict policy is ConflictFail.
+//
+// The following errors may be returned, in addition to those returned by ReadExport:
+//	ErrExists:  A secret/folder already exists and the conflict policy is ConflictFail.
+//	ErrImportIncomplete:  Some items cannot be imported.  Check the results for details.
+//	ErrInvalidExportOption:  The conflict policy in 'opts' is not supported.
+func Import(cl Secret, root string, r io.Reader, opts *ImportOptions) ([]ImportResult, error) {
+	return ImportContext(context.Background(), cl, root, r, opts)
+}
+
+// ImportContext is the same as Import, but uses 'ctx' for all the requests.
+// If 'ctx' is canceled, ImportContext stops and returns the results so far and the error in 'ctx'.
+func ImportContext(ctx context.Context, cl Secret, root string, r io.Reader, opts *ImportOptions) ([]ImportResult, error) {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+	policy, err := checkConflictPolicy(opts.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := ReadExport(r, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isTopLevel(root) {
+		_, _, _, err = cl.CreateFolderContext(ctx, root, "")
+		if err != nil && !errors.Is(err, ErrExists) {
+			return nil, fmt.Errorf("cannot create folder [%s]: %w", root, err)
+		}
+	}
+
+	var results []ImportResult
+	failed := 0
+	for _, item := range doc.Items {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result := importItem(ctx, cl, joinPath(root, item.Path), &item, policy)
+		results = append(results, result)
+		if result.Action != ImportFailed {
+			continue
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return results, ctxErr
+		}
+		if errors.Is(result.Err, ErrExists) && policy == ConflictFail {
+			return results, fmt.Errorf("import stopped at [%s]: %w", result.Path, result.Err)
+		}
+		failed++
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d items cannot be imported: %w", failed, len(doc.Items), ErrImportIncomplete)
+	}
+	return results, nil
+}
+
+// importItem imports 'item' to 'path' and returns the result.
+func importItem(ctx context.Context, cl Secret, path string, item *ExportItem, policy ConflictPolicy) ImportResult {
+	result := ImportResult{Path: path, Type: item.Type, Action: ImportCreated}
+
+	var value interface{}
+	var err error
+	switch item.Type {
+	case SecretTypeFolder:
+		_, _, _, err = cl.CreateFolderContext(ctx, path, item.Description)
+	case SecretTypeText:
+		value = item.Text
+	case SecretTypeKV:
+		kv := item.KeyValue
+		if kv == nil {
+			// an empty keyvalue secret is written without value
+			kv = map[string]string{}
+		}
+		value = kv
+	case SecretTypeFile:
+		// data is checked by validateItems
+		data, _ := base64.StdEncoding.DecodeString(item.Data)
+		value = data
+	}
+	if value != nil {
+		_, _, _, err = cl.CreateContext(ctx, path, item.Description, value)
+	}
+
+	if errors.Is(err, ErrExists) {
+		switch {
+		case policy == ConflictFail:
+			// handled below
+		case policy == ConflictSkip || item.Type == SecretTypeFolder:
+			// an existing folder has nothing to overwrite
+			result.Action = ImportSkipped
+			return result
+		default:
+			_, _, _, err = cl.ModifyContext(ctx, path, item.Description, value)
+			if err == nil {
+				result.Action = ImportOverwritten
+			}
+		}
+	}
+	if err != nil {
+		result.Action = ImportFailed
+		result.Err = err
+	}
+	return result
+}
+
+// checkExportFormat checks whether 'format' is a supported export document format
+func checkExportFormat(format string) error {
+	switch format {
+	case "", ExportFormatJSON, ExportFormatYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown export format [%s]: %w", format, ErrInvalidExportOption)
+}
+
+// decodeDocument decodes 'content' in JSON or YAML format into 'v'.  If it fails, the error
+// wraps 'invalid'.
+func decodeDocument(content []byte, v interface{}, invalid error) error {
+	var err error
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(content, v)
+	} else {
+		err = yaml.Unmarshal(content, v)
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, invalid)
+	}
+	return nil
+}
+
+// validateExport checks that 'doc' is a document that can be imported
+func validateExport(doc *ExportDocument) error {
+	if doc.Version < 1 || doc.Version > ExportFormatVersion {
+		return fmt.Errorf("unsupported version %d: %w", doc.Version, ErrInvalidExportDocument)
+	}
+	return validateItems(doc.Items, ErrInvalidExportDocument)
+}
+
+// validateItems checks the path and type of each item in 'items'.  If an item is not valid, the
+// error wraps 'invalid'.
+func validateItems(items []ExportItem, invalid error) error {
+	for _, item := range items {
+		// "." and ".." are rejected, so that items cannot be written outside the root folder
+		for _, segment := range strings.Split(strings.TrimSuffix(item.Path, "/"), "/") {
+			if strings.TrimSpace(segment) == "" || segment == "." || segment == ".." {
+				return fmt.Errorf("bad path [%s]: %w", item.Path, invalid)
+			}
+		}
+		switch item.Type {
+		case SecretTypeFolder, SecretTypeText, SecretTypeKV:
+		case SecretTypeFile:
+			if _, err := base64.StdEncoding.DecodeString(item.Data); err != nil {
+				return fmt.Errorf("bad data for [%s]: %w", item.Path, invalid)
+			}
+		default:
+			return fmt.Errorf("unknown type [%s] for [%s]: %w", item.Type, item.Path, invalid)
+		}
+	}
+	return nil
+}
+
+// relativePath returns 'path' relative to the folder 'root'
+func relativePath(root string, path string) string {
+	if isTopLevel(root) {
+		return path
+	}
+	return strings.TrimPrefix(path, strings.TrimSuffix(root, "/")+"/")
+}
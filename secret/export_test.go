@@ -0,0 +1,301 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+// mapSecret is a Secret that keeps secrets in a map, indexed by path.  Parent folders are
+// created implicitly as in PAS.  Methods not used in export/import are not supported.
+type mapSecret struct {
+	Secret                                // not set, other methods are not supported
+	objects   map[string]*mapSecretObject // secrets and folders
+	failPaths map[string]error            // error returned when creating/modifying the path
+}
+
+type mapSecretObject struct {
+	typ         string
+	description string
+	value       interface{}
+}
+
+func newMapSecret() *mapSecret {
+	return &mapSecret{
+		objects:   make(map[string]*mapSecretObject),
+		failPaths: make(map[string]error),
+	}
+}
+
+func (m *mapSecret) create(path string, obj *mapSecretObject) (bool, string, *http.Response, error) {
+	if err := m.failPaths[path]; err != nil {
+		return false, "", nil, err
+	}
+	if _, ok := m.objects[path]; ok {
+		return false, "", nil, ErrExists
+	}
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], "/")
+		if _, ok := m.objects[parent]; !ok {
+			m.objects[parent] = &mapSecretObject{typ: SecretTypeFolder}
+		}
+	}
+	m.objects[path] = obj
+	return true, "id:" + path, nil, nil
+}
+
+func (m *mapSecret) CreateContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	typ := SecretTypeText
+	if _, ok := value.(map[string]string); ok {
+		typ = SecretTypeKV
+	}
+	return m.create(path, &mapSecretObject{typ: typ, description: description, value: value})
+}
+
+func (m *mapSecret) CreateFolderContext(ctx context.Context, path string, description string) (bool, string, *http.Response, error) {
+	return m.create(path, &mapSecretObject{typ: SecretTypeFolder, description: description})
+}
+
+func (m *mapSecret) GetContext(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	obj, ok := m.objects[path]
+	if !ok || obj.typ == SecretTypeFolder {
+		return nil, nil, ErrSecretNotFound
+	}
+	return obj.value, nil, nil
+}
+
+func (m *mapSecret) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	obj, ok := m.objects[path]
+	if !ok {
+		return nil, nil, ErrSecretNotFound
+	}
+	name := path[strings.LastIndex(path, "/")+1:]
+	return &MetaData{Item: Item{Name: name, Type: obj.typ, ID: "id:" + path}, Description: obj.description}, nil, nil
+}
+
+func (m *mapSecret) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	prefix := ""
+	if !isTopLevel(path) {
+		if obj, ok := m.objects[path]; !ok || obj.typ != SecretTypeFolder {
+			return nil, nil, ErrFolderNotFound
+		}
+		prefix = path + "/"
+	}
+	var items []Item
+	for p, obj := range m.objects {
+		if strings.HasPrefix(p, prefix) && !strings.Contains(p[len(prefix):], "/") {
+			items = append(items, Item{Name: p[len(prefix):], Type: obj.typ, ID: "id:" + p})
+		}
+	}
+	return items, nil, nil
+}
+
+func (m *mapSecret) ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	if err := m.failPaths[path]; err != nil {
+		return false, "", nil, err
+	}
+	obj, ok := m.objects[path]
+	if !ok {
+		return false, "", nil, ErrSecretNotFound
+	}
+	if obj.typ == SecretTypeFolder {
+		return false, "", nil, ErrCannotModifySecretFolder
+	}
+	obj.value = value
+	if description != "" {
+		obj.description = description
+	}
+	return true, "id:" + path, nil, nil
+}
+
+type SecretExportTestSuite struct {
+	testutils.CfyTestSuite
+	source *mapSecret
+	dest   *mapSecret
+}
+
+func TestSecretExportTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretExportTestSuite))
+}
+
+func (s *SecretExportTestSuite) SetupTest() {
+	s.source = newMapSecret()
+	s.source.CreateFolderContext(context.Background(), "app", "application secrets")
+	s.source.CreateContext(context.Background(), "app/db/password", "database password", "pa$$word")
+	s.source.CreateContext(context.Background(), "app/api", "", map[string]string{"key": "k1", "secret": "s1"})
+	s.source.CreateContext(context.Background(), "other", "", "not exported")
+	s.dest = newMapSecret()
+}
+
+// export exports "app" in source and returns the document
+func (s *SecretExportTestSuite) export(opts *ExportOptions) []byte {
+	var buf bytes.Buffer
+	err := Export(s.source, "app", &buf, opts)
+	s.Require().NoError(err, "Export should not return error")
+	return buf.Bytes()
+}
+
+func (s *SecretExportTestSuite) TestExportDocument() {
+	doc, err := ReadExport(bytes.NewReader(s.export(nil)), "")
+	s.Require().NoError(err, "Should read exported document")
+	s.Assert().Equal(ExportFormatVersion, doc.Version)
+	s.Assert().Equal("app", doc.Root)
+	s.Assert().Equal([]ExportItem{
+		{Path: "api", Type: SecretTypeKV, KeyValue: map[string]string{"key": "k1", "secret": "s1"}},
+		{Path: "db", Type: SecretTypeFolder},
+		{Path: "db/password", Type: SecretTypeText, Description: "database password", Text: "pa$$word"},
+	}, doc.Items)
+}
+
+func (s *SecretExportTestSuite) TestRoundTrip() {
+	for _, format := range []string{ExportFormatJSON, ExportFormatYAML} {
+		s.dest = newMapSecret()
+		data := s.export(&ExportOptions{Format: format})
+
+		results, err := Import(s.dest, "copy", bytes.NewReader(data), nil)
+		s.Require().NoError(err, "Import of %s document should not return error", format)
+		s.Assert().Len(results, 3)
+		for _, result := range results {
+			s.Assert().Equal(ImportCreated, result.Action, "%s should be created", result.Path)
+		}
+		s.Assert().Equal("pa$$word", s.dest.objects["copy/db/password"].value)
+		s.Assert().Equal("database password", s.dest.objects["copy/db/password"].description)
+		s.Assert().Equal(map[string]string{"key": "k1", "secret": "s1"}, s.dest.objects["copy/api"].value)
+		s.Assert().Equal(SecretTypeFolder, s.dest.objects["copy/db"].typ)
+		s.Assert().NotContains(s.dest.objects, "copy/other")
+	}
+}
+
+func (s *SecretExportTestSuite) TestYAMLFormat() {
+	data := s.export(&ExportOptions{Format: ExportFormatYAML})
+	s.Assert().Contains(string(data), "version: 1\n", "Should be written in YAML")
+	s.Assert().Contains(string(data), "path: db/password", "Should be written in YAML")
+}
+
+func (s *SecretExportTestSuite) TestEncrypted() {
+	for _, format := range []string{ExportFormatJSON, ExportFormatYAML} {
+		data := s.export(&ExportOptions{Format: format, Passphrase: "correct horse"})
+		s.Assert().NotContains(string(data), "pa$$word", "Secret value should be encrypted")
+		s.Assert().NotContains(string(data), "db/password", "Secret path should be encrypted")
+
+		_, err := ReadExport(bytes.NewReader(data), "")
+		s.Assert().ErrorIs(err, ErrPassphraseRequired)
+		_, err = ReadExport(bytes.NewReader(data), "wrong")
+		s.Assert().ErrorIs(err, ErrBadPassphrase)
+
+		doc, err := ReadExport(bytes.NewReader(data), "correct horse")
+		s.Require().NoError(err, "Should decrypt %s document with passphrase", format)
+		s.Assert().Len(doc.Items, 3)
+		s.Assert().Equal("app", doc.Root)
+	}
+}
+
+func (s *SecretExportTestSuite) TestEncryptionIterations() {
+	data := s.export(&ExportOptions{Passphrase: "correct horse"})
+	for _, iterations := range []int{0, -1, exportMaxIterations + 1, 1 << 40} {
+		var doc map[string]interface{}
+		s.Require().NoError(json.Unmarshal(data, &doc))
+		doc["encryption"].(map[string]interface{})["iterations"] = iterations
+		changed, err := json.Marshal(doc)
+		s.Require().NoError(err)
+
+		_, err = ReadExport(bytes.NewReader(changed), "correct horse")
+		s.Assert().ErrorIs(err, ErrInvalidExportDocument, "Should reject %d iterations", iterations)
+	}
+}
+
+func (s *SecretExportTestSuite) TestConflictPolicies() {
+	data := s.export(nil)
+	s.dest.CreateContext(context.Background(), "copy/db/password", "", "old")
+
+	// skip
+	results, err := Import(s.dest, "copy", bytes.NewReader(data), nil)
+	s.Require().NoError(err, "Conflicts should be skipped by default")
+	s.Assert().Equal([]ImportAction{ImportCreated, ImportSkipped, ImportSkipped}, actions(results))
+	s.Assert().Equal("old", s.dest.objects["copy/db/password"].value)
+
+	// overwrite
+	results, err = Import(s.dest, "copy", bytes.NewReader(data), &ImportOptions{OnConflict: ConflictOverwrite})
+	s.Require().NoError(err, "Overwrite should not return error")
+	s.Assert().Equal([]ImportAction{ImportOverwritten, ImportSkipped, ImportOverwritten}, actions(results))
+	s.Assert().Equal("pa$$word", s.dest.objects["copy/db/password"].value)
+
+	// fail
+	results, err = Import(s.dest, "copy", bytes.NewReader(data), &ImportOptions{OnConflict: ConflictFail})
+	s.Assert().ErrorIs(err, ErrExists, "Import should stop on conflict")
+	s.Assert().Equal([]ImportAction{ImportFailed}, actions(results))
+	s.Assert().Equal("copy/api", results[0].Path)
+
+	_, err = Import(s.dest, "copy", bytes.NewReader(data), &ImportOptions{OnConflict: "ignore"})
+	s.Assert().ErrorIs(err, ErrInvalidExportOption)
+}
+
+func (s *SecretExportTestSuite) TestPartialFailure() {
+	data := s.export(nil)
+	s.dest.failPaths["copy/api"] = ErrNoCreatePermission
+
+	results, err := Import(s.dest, "copy", bytes.NewReader(data), nil)
+	s.Assert().ErrorIs(err, ErrImportIncomplete, "Should report incomplete import")
+	s.Assert().Equal([]ImportAction{ImportFailed, ImportCreated, ImportCreated}, actions(results))
+	s.Assert().ErrorIs(results[0].Err, ErrNoCreatePermission)
+}
+
+func (s *SecretExportTestSuite) TestImportTopLevel() {
+	results, err := Import(s.dest, "", bytes.NewReader(s.export(nil)), nil)
+	s.Require().NoError(err, "Import should not return error")
+	s.Assert().Equal("db/password", results[2].Path)
+	s.Assert().Contains(s.dest.objects, "db/password")
+}
+
+func (s *SecretExportTestSuite) TestExportErrors() {
+	var buf bytes.Buffer
+	err := Export(s.source, "other", &buf, nil)
+	s.Assert().ErrorIs(err, ErrNotSecretFolder, "Cannot export a secret")
+
+	err = Export(s.source, "missing", &buf, nil)
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+
+	err = Export(s.source, "app", &buf, &ExportOptions{Format: "xml"})
+	s.Assert().ErrorIs(err, ErrInvalidExportOption)
+}
+
+func (s *SecretExportTestSuite) TestInvalidDocument() {
+	docs := []string{
+		`not a document`,
+		`{"version": 2, "items": []}`,
+		`{"version": 1, "items": [{"path": "/abs", "type": "text"}]}`,
+		`{"version": 1, "items": [{"path": "file", "type": "certificate"}]}`,
+		"version: 1\nitems:\n  - path: a//b\n    type: folder\n",
+		`{"version": 1, "items": [{"path": "../outside", "type": "text"}]}`,
+		`{"version": 1, "items": [{"path": "a/./b", "type": "text"}]}`,
+	}
+	for _, doc := range docs {
+		_, err := Import(s.dest, "copy", strings.NewReader(doc), nil)
+		s.Assert().ErrorIs(err, ErrInvalidExportDocument, "Should reject [%s]", doc)
+	}
+	s.Assert().Empty(s.dest.objects, "Nothing should be imported")
+}
+
+func actions(results []ImportResult) []ImportAction {
+	var list []ImportAction
+	for _, result := range results {
+		list = append(list, result.Action)
+	}
+	return list
+}
+
+func (s *SecretExportTestSuite) TestPBKDF2() {
+	// test vectors for PBKDF2-HMAC-SHA256 from RFC 7914, truncated to the key size
+	key := exportKey("passwd", []byte("salt"), 1)
+	s.Assert().Equal("55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc", hex.EncodeToString(key))
+	key = exportKey("Password", []byte("NaCl"), 80000)
+	s.Assert().Equal("4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56", hex.EncodeToString(key))
+}
@@ -0,0 +1,751 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHCVaultMount is the mount path of the KV version 2 secrets engine that is used when
+// the server URL does not specify one
+const defaultHCVaultMount = "secret"
+
+// hcvaultDescriptionKey is the key in the custom metadata of a Vault secret that stores the
+// description of the secret
+const hcvaultDescriptionKey = "description"
+
+// HCVaultSecretClient implements the Secret interface where the secret is stored in the KV version 2
+// secrets engine of HashiCorp Vault.
+//
+// KV has no folders.  A folder is a prefix of the path of secrets, and it exists as long as
+// there are secrets in it.  The ID and CRN of a secret or folder are its path.
+// The type and description of a secret are saved in the custom metadata of the secret.  The
+// value of a text secret is saved in the key "text".  A secret without type in the custom
+// metadata is treated as a keyvalue secret.
+//
+// Headers such as X-Vault-Namespace can be added with AddDefaultHeaders to access secrets in a
+// Vault namespace.
+type HCVaultSecretClient struct {
+	*restClient
+	mount string // mount path of KV secrets engine
+}
+
+// hcvaultMetadata is the metadata of a secret returned by Vault
+type hcvaultMetadata struct {
+	CreatedTime    time.Time         `json:"created_time"`
+	UpdatedTime    time.Time         `json:"updated_time"`
+	CurrentVersion int               `json:"current_version"`
+	CustomMetadata map[string]string `json:"custom_metadata"`
+}
+
+// newHCVaultSecretClient creates a new client handle to access secrets in the Vault server specified
+// in 'server' with the Vault token 'accessToken'.  'server' is the URL of the Vault server,
+// optionally followed by the mount path of the KV secrets engine (e.g.,
+// https://vault.example.com:8200/kv).  The mount path "secret" is used if it is not specified.
+func newHCVaultSecretClient(server string, accessToken string, httpFactory HTTPClientFactory) *HCVaultSecretClient {
+	mount := defaultHCVaultMount
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	if u, err := url.Parse(server); err == nil {
+		if path := strings.TrimPrefix(strings.TrimPrefix(strings.Trim(u.Path, "/"), "v1"), "/"); path != "" {
+			mount = path
+		}
+		u.Path = ""
+		u.RawPath = ""
+		server = u.String()
+	}
+	// if the URL cannot be parsed, let the requests fail with the bad URL
+
+	cl := &HCVaultSecretClient{
+		restClient: newRESTClient(server, httpFactory),
+		mount:      mount,
+	}
+	if accessToken != "" {
+		cl.headers["X-Vault-Token"] = accessToken
+	}
+	return cl
+}
+
+// Get returns the secret content.
+// If the secret is a keyvalue secret, it returns the secret as map[string]string
+// If the secret is a text string, it returns the secret as string.
+// The following errors may be returned:
+//	 ErrNoRetrievePermission: No permission to read the secret.
+//	 ErrSecretNotFound: Secret specified in path cannot be found.
+//	 ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *HCVaultSecretClient) Get(path string) (interface{}, *http.Response, error) {
+	return c.GetContext(context.Background(), path)
+}
+
+// GetContext is the same as Get, but uses 'ctx' for the REST API request.
+func (c *HCVaultSecretClient) GetContext(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	var result struct {
+		Data struct {
+			Data     map[string]interface{} `json:"data"`
+			Metadata hcvaultMetadata        `json:"metadata"`
+		} `json:"data"`
+	}
+	r, err := c.doRequest(ctx, http.MethodGet, c.apiPath("data", path), nil, &result)
+	if err != nil {
+		return nil, r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return nil, r, ErrNoRetrievePermission
+	case http.StatusNotFound:
+		return nil, r, ErrSecretNotFound
+	default:
+		return nil, r, ErrUnexpectedResponse
+	}
+
+	value, err := secretValue(secretTypeOf(&result.Data.Metadata), result.Data.Data, c.decodeBinary)
+	return value, r, err
+}
+
+// Create creates a secret in 'path'. 'description' is an optional description
+// of the secret.  If 'value' is a string, it saves the secret as a
+// secret text string.  If 'value' is type map[string]string, the secret
+// is stored as 'keyvalue' secret.
+// Returns the following information:
+//  bool: whether the secret is created or not.
+//  id: the path of the secret
+//  response: the actual HTTP response
+//
+// The following errors may be returned:
+//
+//   ErrBadPathName: Invalid secret path name
+//	 ErrExists: Secret or folder already exists
+//	 ErrNoCreatePermission: No permission to create secret.
+//	 ErrSecretTypeNotSupported:  Cannot create secret for the specified type.
+//	 ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *HCVaultSecretClient) Create(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.CreateContext(context.Background(), path, description, value)
+}
+
+// CreateContext is the same as Create, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) CreateContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, err
+	}
+	secretType, data, err := secretData(value)
+	if err != nil {
+		return false, "", nil, err
+	}
+	path, err = cleanPath(path)
+	if err != nil {
+		return false, "", nil, err
+	}
+
+	// a secret cannot have the same path as a folder
+	keys, r, err := c.listKeys(ctx, path)
+	if err != nil {
+		return false, "", r, err
+	}
+	if len(keys) > 0 {
+		return false, "", r, ErrExists
+	}
+
+	// check-and-set version 0 only allows the write if the secret does not exist
+	body := map[string]interface{}{
+		"options": map[string]interface{}{"cas": 0},
+		"data":    data,
+	}
+	r, err = c.doRequest(ctx, http.MethodPost, c.apiPath("data", path), body, nil)
+	if err != nil {
+		return false, "", r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+	case http.StatusBadRequest:
+		if strings.Contains(c.errorMessage(r), "check-and-set") {
+			return false, "", r, ErrExists
+		}
+		return false, "", r, ErrBadPathName
+	case http.StatusForbidden:
+		return false, "", r, ErrNoCreatePermission
+	default:
+		return false, "", r, ErrUnexpectedResponse
+	}
+
+	r, err = c.writeCustomMetadata(ctx, path, secretType, description, nil)
+	if err != nil {
+		// without its type, a text secret would be read as a keyvalue secret.  The secret did not
+		// exist before the check-and-set write, so all of it is deleted.
+		c.doRequest(ctx, http.MethodDelete, c.apiPath("metadata", path), nil, nil)
+		if err == ErrNoModifyPermission {
+			err = ErrNoCreatePermission
+		}
+		return false, "", r, err
+	}
+	return true, path, r, nil
+}
+
+// CreateFolder creates a secret folder in 'path'.  KV has no folders, so nothing is saved in Vault
+// and 'description' is ignored.  The folder exists once a secret is created in it.
+// Returns the following information:
+//  bool: whether the secret folder is created or not.
+//  id: the path of the secret folder
+//  response: the actual HTTP response
+//
+// The following errors may be returned:
+//   ErrBadPathName: Invalid secret path name
+//	 ErrExists: Secret or folder already exists
+//	 ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *HCVaultSecretClient) CreateFolder(path string, description string) (bool, string, *http.Response, error) {
+	return c.CreateFolderContext(context.Background(), path, description)
+}
+
+// CreateFolderContext is the same as CreateFolder, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) CreateFolderContext(ctx context.Context, path string, description string) (bool, string, *http.Response, error) {
+	path, err := cleanPath(path)
+	if err != nil {
+		return false, "", nil, err
+	}
+	keys, r, err := c.listKeys(ctx, path)
+	if err != nil {
+		return false, "", r, err
+	}
+	if len(keys) > 0 {
+		return false, "", r, ErrExists
+	}
+	_, r, err = c.getMetadata(ctx, path)
+	if err == nil {
+		return false, "", r, ErrExists
+	}
+	if err != ErrSecretNotFound {
+		return false, "", r, err
+	}
+	return true, path, r, nil
+}
+
+// List lists all secrets in a folder specified in 'path'
+// Returns the following:
+//  items: an array of Item. If the folder is empty, nil is returned.
+//  response: the actual HTTP response
+// the following errors may be returned:
+//	ErrFolderNotFound: Secret specified in path cannot be found.  It is possible that
+//		the caller may not have permission to access the folder.
+//	ErrNotSecretFolder: The path specifies a secret.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *HCVaultSecretClient) List(path string) ([]Item, *http.Response, error) {
+	return c.ListContext(context.Background(), path)
+}
+
+// ListContext is the same as List, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	folder := strings.Trim(path, "/")
+	keys, r, err := c.listKeys(ctx, folder)
+	if err != nil {
+		return nil, r, err
+	}
+	if len(keys) == 0 {
+		if folder == "" {
+			// nothing in the secrets engine
+			return nil, r, nil
+		}
+		if _, _, err := c.getMetadata(ctx, folder); err == nil {
+			return nil, r, ErrNotSecretFolder
+		}
+		return nil, r, ErrFolderNotFound
+	}
+
+	var items []Item
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			name := strings.TrimSuffix(key, "/")
+			items = append(items, Item{Name: name, Type: SecretTypeFolder, ID: joinPath(folder, name)})
+			continue
+		}
+		itemPath := joinPath(folder, key)
+		meta, _, err := c.getMetadata(ctx, itemPath)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, r, ctxErr
+			}
+			// the secret is listed but its metadata cannot be read
+			meta = &hcvaultMetadata{}
+		}
+		items = append(items, Item{Name: key, Type: secretTypeOf(meta), ID: itemPath})
+	}
+	return items, r, nil
+}
+
+// ListSecrets lists the secrets that match the search and ordering options in 'opts'.
+// 'opts' can be nil, which lists all secrets that the caller can access.
+// KV cannot search secrets, so ListSecrets lists all folders recursively.  The name of each item is
+// the full path of the secret.  Search matches any part of the path, ignoring case.
+// OrderBy can only be "name" or "name desc", and Filter is not supported.
+// If 'fn' returns an error, the listing stops and the error is returned.
+// Returns the following:
+//  response: nil as the secrets are retrieved in multiple requests
+// the following errors may be returned:
+//	ErrInvalidListOption: The options in 'opts' are not supported.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *HCVaultSecretClient) ListSecrets(opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	return c.ListSecretsContext(context.Background(), opts, fn)
+}
+
+// ListSecretsContext is the same as ListSecrets, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) ListSecretsContext(ctx context.Context, opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	if opts.Limit < 0 || opts.Limit > maxListLimit || opts.Filter != "" {
+		return nil, ErrInvalidListOption
+	}
+	descending := false
+	for _, order := range opts.OrderBy {
+		switch strings.ToLower(strings.Join(strings.Fields(order), " ")) {
+		case "name", "name asc":
+			descending = false
+		case "name desc":
+			descending = true
+		default:
+			return nil, fmt.Errorf("Cannot order by [%s]: %w", order, ErrInvalidListOption)
+		}
+	}
+
+	var items []Item
+	search := strings.ToLower(opts.Search)
+	err := WalkContext(ctx, c, "/", nil, func(path string, info *MetaData, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(info.Type, SecretTypeFolder) {
+			return nil
+		}
+		if strings.Contains(strings.ToLower(path), search) {
+			items = append(items, Item{Name: path, Type: info.Type, ID: info.ID})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if descending {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Name > items[j].Name })
+	}
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// Delete deletes the folder/secret specified in 'path'.  All versions of the secret are deleted.
+// Returns the following information:
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrFolderNotEmpty: Folder is not empty
+//	ErrNoDeletePermission: No permission to delete secret/folder
+//	ErrSecretNotFound: Secret specified in path cannot be found.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *HCVaultSecretClient) Delete(path string) (*http.Response, error) {
+	return c.DeleteContext(context.Background(), path)
+}
+
+// DeleteContext is the same as Delete, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) DeleteContext(ctx context.Context, path string) (*http.Response, error) {
+	path = strings.Trim(path, "/")
+	_, r, err := c.getMetadata(ctx, path)
+	switch err {
+	case nil:
+	case ErrSecretNotFound:
+		keys, r, err := c.listKeys(ctx, path)
+		if err != nil {
+			return r, err
+		}
+		if len(keys) > 0 {
+			return r, ErrFolderNotEmpty
+		}
+		return r, ErrSecretNotFound
+	case ErrNoGetMetaDataPermission:
+		return r, ErrNoDeletePermission
+	default:
+		return r, err
+	}
+
+	r, err = c.doRequest(ctx, http.MethodDelete, c.apiPath("metadata", path), nil, nil)
+	if err != nil {
+		return r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return r, nil
+	case http.StatusForbidden:
+		return r, ErrNoDeletePermission
+	case http.StatusNotFound:
+		return r, ErrSecretNotFound
+	default:
+		return r, ErrUnexpectedResponse
+	}
+}
+
+// Modify modifies a secret in 'path'.  A new version of the secret is created.
+// If 'description' is not an empty string, it replaces the current secret description.
+// If 'value' is a string, it saves the secret as a
+// secret text string.  If 'value' is type map[string]string, the secret
+// is stored as 'keyvalue' secret.
+//
+// Returns the following information:
+//  bool: whether the secret is modified or not.
+//  id: the path of the secret
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrCannotModifySecretFolder:  Modification of a secret folder is not supported.
+//	ErrCannotModifySecretType:  Modification of keyvalue secret to text or vice versa is not supported.
+//	ErrNoModifyPermission: No permission to modify secret
+//	ErrSecretNotFound: secret cannot be found
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *HCVaultSecretClient) Modify(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.ModifyContext(context.Background(), path, description, value)
+}
+
+// ModifyContext is the same as Modify, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.modify(ctx, path, description, value, nil)
+}
+
+// modifyIfVersion modifies the secret in 'path' only if it is still in 'observed.Version', using
+// the check-and-set option of the write.  The creation time is compared as well, as a secret that
+// is deleted and created again starts from version 1.
+func (c *HCVaultSecretClient) modifyIfVersion(ctx context.Context, path string, observed *MetaData, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.modify(ctx, path, description, value, observed)
+}
+
+// modify modifies the secret in 'path'.  If 'observed' is not nil, the secret is only modified if
+// its version and creation time are the same as in 'observed'.
+func (c *HCVaultSecretClient) modify(ctx context.Context, path string, description string, value interface{}, observed *MetaData) (bool, string, *http.Response, error) {
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, err
+	}
+	secretType, data, err := secretData(value)
+	if err != nil {
+		return false, "", nil, err
+	}
+	path = strings.Trim(path, "/")
+
+	meta, r, err := c.getMetadata(ctx, path)
+	switch err {
+	case nil:
+	case ErrSecretNotFound:
+		keys, r, err := c.listKeys(ctx, path)
+		if err != nil {
+			return false, "", r, err
+		}
+		if len(keys) > 0 {
+			return false, "", r, ErrCannotModifySecretFolder
+		}
+		return false, "", r, ErrSecretNotFound
+	case ErrNoGetMetaDataPermission:
+		return false, "", r, ErrNoModifyPermission
+	default:
+		return false, "", r, err
+	}
+	if secretTypeOf(meta) != secretType {
+		return false, "", r, ErrCannotModifySecretType
+	}
+
+	body := map[string]interface{}{"data": data}
+	if observed != nil {
+		if strconv.Itoa(meta.CurrentVersion) != observed.Version || !meta.CreatedTime.Equal(observed.WhenCreated) {
+			return false, "", r, ErrConflict
+		}
+		body["options"] = map[string]interface{}{"cas": meta.CurrentVersion}
+	}
+	r, err = c.doRequest(ctx, http.MethodPost, c.apiPath("data", path), body, nil)
+	if err != nil {
+		return false, "", r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+	case http.StatusBadRequest:
+		if observed != nil && strings.Contains(c.errorMessage(r), "check-and-set") {
+			// modified after the version is checked
+			return false, "", r, ErrConflict
+		}
+		return false, "", r, ErrUnexpectedResponse
+	case http.StatusForbidden:
+		return false, "", r, ErrNoModifyPermission
+	case http.StatusNotFound:
+		return false, "", r, ErrSecretNotFound
+	default:
+		return false, "", r, ErrUnexpectedResponse
+	}
+
+	if description != "" {
+		r, err = c.writeCustomMetadata(ctx, path, secretType, description, nil)
+		if err != nil {
+			return false, path, r, err
+		}
+	}
+	return true, path, r, nil
+}
+
+// ModifyMetaData modifies the description and attributes of the secret in 'path' without changing
+// its value.  The description and attributes are saved in the custom metadata of the secret, so the
+// keys "description" and "secret_type" cannot be used as attributes.  If 'description' is not an
+// empty string, it replaces the current description.  Each key in 'attributes' is set to its
+// value, or removed if the value is empty.
+// The following errors may be returned:
+//	ErrCannotModifySecretFolder: 'path' is a folder, which has no metadata in Vault
+//	ErrMetaDataNotSupported: an attribute uses a reserved key
+//	ErrNoModifyPermission: No permission to modify secret
+//	ErrSecretNotFound: secret cannot be found
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *HCVaultSecretClient) ModifyMetaData(path string, description string, attributes map[string]string) (*http.Response, error) {
+	return c.ModifyMetaDataContext(context.Background(), path, description, attributes)
+}
+
+// ModifyMetaDataContext is the same as ModifyMetaData, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) ModifyMetaDataContext(ctx context.Context, path string, description string, attributes map[string]string) (*http.Response, error) {
+	for k := range attributes {
+		if k == secretTypeKey || k == hcvaultDescriptionKey {
+			return nil, fmt.Errorf("attribute [%s] is reserved: %w", k, ErrMetaDataNotSupported)
+		}
+	}
+	path = strings.Trim(path, "/")
+
+	meta, r, err := c.getMetadata(ctx, path)
+	switch err {
+	case nil:
+	case ErrSecretNotFound:
+		keys, r, err := c.listKeys(ctx, path)
+		if err != nil {
+			return r, err
+		}
+		if len(keys) > 0 {
+			return r, ErrCannotModifySecretFolder
+		}
+		return r, ErrSecretNotFound
+	case ErrNoGetMetaDataPermission:
+		return r, ErrNoModifyPermission
+	default:
+		return r, err
+	}
+	return c.writeCustomMetadata(ctx, path, secretTypeOf(meta), description, attributes)
+}
+
+// Move moves the secret or folder in 'srcPath', including the contents of a folder, to 'dstPath'.
+// As Vault cannot move secrets, the secret or folder is copied to 'dstPath' and deleted from
+// 'srcPath', which gives the copies new IDs.  If copying fails, the copies already created are
+// deleted.  If the copy cannot be completed or undone, a MoveError is returned that describes what
+// is left in the source and destination.
+// Returns the following information:
+//  id: the unique ID of the secret or folder in 'dstPath'
+//  response: the HTTP response of the last request
+// The following errors may be returned, in addition to those returned by Get, Create, CreateFolder
+// and Delete:
+//	ErrBadPathName: 'srcPath' or 'dstPath' is invalid, or 'dstPath' is in the folder 'srcPath'
+//	ErrMoveIncomplete: The secret or folder is only partly moved.  The error is a MoveError.
+func (c *HCVaultSecretClient) Move(srcPath string, dstPath string) (string, *http.Response, error) {
+	return c.MoveContext(context.Background(), srcPath, dstPath)
+}
+
+// MoveContext is the same as Move, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) MoveContext(ctx context.Context, srcPath string, dstPath string) (string, *http.Response, error) {
+	return moveByCopy(ctx, c, srcPath, dstPath)
+}
+
+// Rename renames the secret or folder in 'path' to 'newName' in the same folder.  It is the same as
+// Move to the new path.  ErrBadPathName is returned if 'newName' is empty or has "/".
+func (c *HCVaultSecretClient) Rename(path string, newName string) (string, *http.Response, error) {
+	return c.RenameContext(context.Background(), path, newName)
+}
+
+// RenameContext is the same as Rename, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) RenameContext(ctx context.Context, path string, newName string) (string, *http.Response, error) {
+	newPath, err := renamePath(path, newName)
+	if err != nil {
+		return "", nil, err
+	}
+	return c.MoveContext(ctx, path, newPath)
+}
+
+// PathOfID returns the current path of the secret or folder with the ID 'id'.  In Vault, the ID
+// of a secret or folder is its path, so it is returned if the secret or folder exists.
+// The following errors may be returned:
+//	ErrSecretNotFound: No secret or folder has the ID.
+func (c *HCVaultSecretClient) PathOfID(id string) (string, *http.Response, error) {
+	return c.PathOfIDContext(context.Background(), id)
+}
+
+// PathOfIDContext is the same as PathOfID, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) PathOfIDContext(ctx context.Context, id string) (string, *http.Response, error) {
+	info, r, err := c.GetMetaDataContext(ctx, id)
+	if err != nil {
+		return "", r, err
+	}
+	return info.ID, r, nil
+}
+
+// GetMetaData returns the metadata of a secret.
+// Returns the following information:
+//  MetaData: metadata information for the secret
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrNoGetMetaDataPermission:  The caller has no permission to get metadata information
+//	ErrSecretNotFound: Secret specified in path cannot be found.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *HCVaultSecretClient) GetMetaData(path string) (*MetaData, *http.Response, error) {
+	return c.GetMetaDataContext(context.Background(), path)
+}
+
+// GetMetaDataContext is the same as GetMetaData, but uses 'ctx' for the REST API requests.
+func (c *HCVaultSecretClient) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	path = strings.Trim(path, "/")
+	name := baseName(path)
+
+	meta, r, err := c.getMetadata(ctx, path)
+	if err == ErrSecretNotFound {
+		// check if it is a folder
+		keys, r, err := c.listKeys(ctx, path)
+		if err != nil {
+			return nil, r, err
+		}
+		if len(keys) == 0 {
+			return nil, r, ErrSecretNotFound
+		}
+		result := &MetaData{}
+		result.Name = name
+		result.Type = SecretTypeFolder
+		result.ID = path
+		result.CRN = path
+		return result, r, nil
+	}
+	if err != nil {
+		return nil, r, err
+	}
+
+	result := &MetaData{}
+	result.Name = name
+	result.Type = secretTypeOf(meta)
+	result.ID = path
+	result.CRN = path
+	result.Description = meta.CustomMetadata[hcvaultDescriptionKey]
+	result.WhenCreated = meta.CreatedTime
+	result.WhenModified = meta.UpdatedTime
+	if meta.CurrentVersion > 0 {
+		result.Version = strconv.Itoa(meta.CurrentVersion)
+	}
+	for k, v := range meta.CustomMetadata {
+		if k != secretTypeKey && k != hcvaultDescriptionKey {
+			if result.Attributes == nil {
+				result.Attributes = make(map[string]string)
+			}
+			result.Attributes[k] = v
+		}
+	}
+	return result, r, nil
+}
+
+// getMetadata returns the KV metadata of the secret in 'path'
+func (c *HCVaultSecretClient) getMetadata(ctx context.Context, path string) (*hcvaultMetadata, *http.Response, error) {
+	var result struct {
+		Data hcvaultMetadata `json:"data"`
+	}
+	r, err := c.doRequest(ctx, http.MethodGet, c.apiPath("metadata", path), nil, &result)
+	if err != nil {
+		return nil, r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK:
+		return &result.Data, r, nil
+	case http.StatusForbidden:
+		return nil, r, ErrNoGetMetaDataPermission
+	case http.StatusNotFound:
+		return nil, r, ErrSecretNotFound
+	default:
+		return nil, r, ErrUnexpectedResponse
+	}
+}
+
+// writeCustomMetadata saves the type and description of the secret in 'path' in its custom metadata.
+// The current description is kept if 'description' is empty.  The current attributes are kept, and
+// updated with 'attributes' as in ModifyMetaData.
+func (c *HCVaultSecretClient) writeCustomMetadata(ctx context.Context, path string, secretType string, description string, attributes map[string]string) (*http.Response, error) {
+	var current map[string]string
+	if meta, _, err := c.getMetadata(ctx, path); err == nil {
+		current = meta.CustomMetadata
+	}
+	custom := mergeAttributes(current, attributes)
+	custom[secretTypeKey] = secretType
+	if description != "" {
+		custom[hcvaultDescriptionKey] = description
+	}
+	r, err := c.doRequest(ctx, http.MethodPost, c.apiPath("metadata", path), map[string]interface{}{"custom_metadata": custom}, nil)
+	if err != nil {
+		return r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return r, nil
+	case http.StatusForbidden:
+		return r, ErrNoModifyPermission
+	default:
+		return r, ErrUnexpectedResponse
+	}
+}
+
+// listKeys returns the keys in the folder 'path'.  Folders end with "/".  If the folder does not
+// exist or the caller has no permission to list it, no key is returned.
+func (c *HCVaultSecretClient) listKeys(ctx context.Context, path string) ([]string, *http.Response, error) {
+	var result struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	apiPath := c.apiPath("metadata", path) + "/"
+	r, err := c.doRequest(ctx, http.MethodGet, apiPath+"?list=true", nil, &result)
+	if err != nil {
+		return nil, r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK:
+		return result.Data.Keys, r, nil
+	case http.StatusNotFound, http.StatusForbidden:
+		return nil, r, nil
+	default:
+		return nil, r, ErrUnexpectedResponse
+	}
+}
+
+// apiPath returns the path of the Vault API for the secret 'path'.  'kind' is either "data" or
+// "metadata".
+func (c *HCVaultSecretClient) apiPath(kind string, path string) string {
+	apiPath := "/v1/" + c.mount + "/" + kind
+	if path = strings.Trim(path, "/"); path != "" {
+		apiPath += "/" + escapePath(path)
+	}
+	return apiPath
+}
+
+// errorMessage returns the error messages in a Vault error response
+func (c *HCVaultSecretClient) errorMessage(r *http.Response) string {
+	var result struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal(responseBody(r), &result) != nil {
+		return ""
+	}
+	return strings.Join(result.Errors, "; ")
+}
+
+// secretTypeOf returns the type of secret saved in its custom metadata
+func secretTypeOf(meta *hcvaultMetadata) string {
+	if meta.CustomMetadata[secretTypeKey] == SecretTypeText {
+		return SecretTypeText
+	}
+	return SecretTypeKV
+}
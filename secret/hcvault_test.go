@@ -0,0 +1,444 @@
+package secret
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+const testVaultToken = "test-vault-token"
+
+// kvServer is a minimal stand-in of the KV version 2 secrets engine of HashiCorp Vault.
+// It supports reading and writing secret data and metadata, listing and deleting secrets.
+type kvServer struct {
+	mount          string              // mount path of KV engine
+	mu             sync.Mutex          // protects secrets
+	secrets        map[string]*kvEntry // secrets indexed by path
+	forbidden      map[string]bool     // paths that return 403
+	lockedMetadata map[string]bool     // paths whose metadata cannot be written, which return 403
+}
+
+type kvEntry struct {
+	versions []map[string]interface{}
+	custom   map[string]string
+	created  time.Time
+	updated  time.Time
+}
+
+func newKVServer(mount string) *kvServer {
+	return &kvServer{
+		mount:          mount,
+		secrets:        make(map[string]*kvEntry),
+		forbidden:      make(map[string]bool),
+		lockedMetadata: make(map[string]bool),
+	}
+}
+
+func (kv *kvServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != testVaultToken {
+		kv.reply(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+	prefix := "/v1/" + kv.mount + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		kv.reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{"no handler for route"}})
+		return
+	}
+	rest := strings.TrimPrefix(r.URL.Path, prefix)
+	kind, path := rest, ""
+	if i := strings.Index(rest, "/"); i >= 0 {
+		kind, path = rest[:i], rest[i+1:]
+	}
+	if kv.forbidden[strings.TrimSuffix(path, "/")] {
+		kv.reply(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+
+	var body map[string]interface{}
+	if r.Method == http.MethodPost {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+	switch {
+	case kind == "data" && r.Method == http.MethodGet:
+		kv.readData(w, path)
+	case kind == "data" && r.Method == http.MethodPost:
+		kv.writeData(w, path, body)
+	case kind == "metadata" && r.Method == http.MethodGet && r.URL.Query().Get("list") == "true":
+		kv.list(w, path)
+	case kind == "metadata" && r.Method == http.MethodGet:
+		kv.readMetadata(w, path)
+	case kind == "metadata" && r.Method == http.MethodPost && kv.lockedMetadata[path]:
+		kv.reply(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+	case kind == "metadata" && r.Method == http.MethodPost:
+		kv.writeMetadata(w, path, body)
+	case kind == "metadata" && r.Method == http.MethodDelete:
+		delete(kv.secrets, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		kv.reply(w, http.StatusMethodNotAllowed, map[string]interface{}{"errors": []string{"unsupported"}})
+	}
+}
+
+func (kv *kvServer) reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (kv *kvServer) metadata(entry *kvEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"created_time":    entry.created,
+		"updated_time":    entry.updated,
+		"current_version": len(entry.versions),
+		"custom_metadata": entry.custom,
+	}
+}
+
+func (kv *kvServer) readData(w http.ResponseWriter, path string) {
+	entry, ok := kv.secrets[path]
+	if !ok || len(entry.versions) == 0 {
+		kv.reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+	kv.reply(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"data":     entry.versions[len(entry.versions)-1],
+			"metadata": kv.metadata(entry),
+		},
+	})
+}
+
+func (kv *kvServer) writeData(w http.ResponseWriter, path string, body map[string]interface{}) {
+	entry, ok := kv.secrets[path]
+	if options, ok := body["options"].(map[string]interface{}); ok {
+		if cas, ok := options["cas"].(float64); ok && (entry != nil && len(entry.versions) != int(cas) || entry == nil && cas != 0) {
+			kv.reply(w, http.StatusBadRequest, map[string]interface{}{
+				"errors": []string{"check-and-set parameter did not match the current version"},
+			})
+			return
+		}
+	}
+	now := time.Now().UTC()
+	if !ok {
+		entry = &kvEntry{created: now}
+		kv.secrets[path] = entry
+	}
+	data, _ := body["data"].(map[string]interface{})
+	entry.versions = append(entry.versions, data)
+	entry.updated = now
+	kv.reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"version": len(entry.versions)}})
+}
+
+func (kv *kvServer) readMetadata(w http.ResponseWriter, path string) {
+	entry, ok := kv.secrets[path]
+	if !ok {
+		kv.reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+	kv.reply(w, http.StatusOK, map[string]interface{}{"data": kv.metadata(entry)})
+}
+
+func (kv *kvServer) writeMetadata(w http.ResponseWriter, path string, body map[string]interface{}) {
+	entry, ok := kv.secrets[path]
+	if !ok {
+		entry = &kvEntry{created: time.Now().UTC()}
+		kv.secrets[path] = entry
+	}
+	if custom, ok := body["custom_metadata"].(map[string]interface{}); ok {
+		entry.custom = make(map[string]string)
+		for k, v := range custom {
+			entry.custom[k], _ = v.(string)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (kv *kvServer) list(w http.ResponseWriter, path string) {
+	keys := make(map[string]bool)
+	for p := range kv.secrets {
+		if !strings.HasPrefix(p, path) {
+			continue
+		}
+		key := strings.TrimPrefix(p, path)
+		if i := strings.Index(key, "/"); i >= 0 {
+			key = key[:i+1]
+		}
+		keys[key] = true
+	}
+	if len(keys) == 0 {
+		kv.reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+	var list []string
+	for key := range keys {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	kv.reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"keys": list}})
+}
+
+// SecretHCVaultTestSuite tests HCVaultSecretClient against a local stand-in of the KV version 2 API.
+type SecretHCVaultTestSuite struct {
+	testutils.CfyTestSuite
+	kv     *kvServer
+	server *httptest.Server
+	handle Secret
+}
+
+func TestSecretHCVaultTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretHCVaultTestSuite))
+}
+
+func (s *SecretHCVaultTestSuite) SetupTest() {
+	s.kv = newKVServer("kv")
+	s.server = httptest.NewTLSServer(s.kv)
+	var err error
+	s.handle, err = NewSecretClient(s.server.URL+"/kv", ServerHCVault, testVaultToken, s.server.Client)
+	s.Require().NoError(err, "Should create client for hcvault")
+}
+
+func (s *SecretHCVaultTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *SecretHCVaultTestSuite) TestTextSecret() {
+	success, id, _, err := s.handle.Create("app/password", "db password", "pa$$word")
+	s.Require().NoError(err, "Should create text secret")
+	s.Assert().True(success)
+	s.Assert().Equal("app/password", id, "ID should be the path")
+
+	value, r, err := s.handle.Get("app/password")
+	s.Require().NoError(err, "Should get text secret")
+	s.Assert().Equal(200, r.StatusCode)
+	s.Assert().Equal("pa$$word", value)
+
+	metadata, _, err := s.handle.GetMetaData("app/password")
+	s.Require().NoError(err, "Should get metadata")
+	s.Assert().Equal("password", metadata.Name)
+	s.Assert().Equal(SecretTypeText, metadata.Type)
+	s.Assert().Equal("app/password", metadata.ID)
+	s.Assert().Equal("db password", metadata.Description)
+	s.Assert().False(metadata.WhenCreated.IsZero(), "Should return creation time")
+}
+
+func (s *SecretHCVaultTestSuite) TestKeyValueSecret() {
+	kv := map[string]string{"user": "admin", "password": "secret"}
+	_, _, _, err := s.handle.Create("app/creds", "", kv)
+	s.Require().NoError(err, "Should create keyvalue secret")
+
+	value, _, err := s.handle.Get("app/creds")
+	s.Require().NoError(err, "Should get keyvalue secret")
+	s.Assert().Equal(kv, value)
+
+	// secret written by other Vault clients without type is keyvalue
+	s.kv.secrets["external"] = &kvEntry{versions: []map[string]interface{}{{"port": 5432.0, "host": "db"}}}
+	value, _, err = s.handle.Get("external")
+	s.Require().NoError(err, "Should get secret without type")
+	s.Assert().Equal(map[string]string{"port": "5432", "host": "db"}, value)
+}
+
+func (s *SecretHCVaultTestSuite) TestCreateErrors() {
+	_, _, _, err := s.handle.Create("app/secret", "", "value")
+	s.Require().NoError(err)
+
+	_, _, _, err = s.handle.Create("app/secret", "", "value")
+	s.Assert().ErrorIs(err, ErrExists, "Duplicate secret")
+	_, _, _, err = s.handle.Create("app", "", "value")
+	s.Assert().ErrorIs(err, ErrExists, "Secret with same path as folder")
+	_, _, _, err = s.handle.CreateFolder("app", "")
+	s.Assert().ErrorIs(err, ErrExists, "Existing folder")
+	_, _, _, err = s.handle.CreateFolder("app/secret", "")
+	s.Assert().ErrorIs(err, ErrExists, "Folder with same path as secret")
+	_, _, _, err = s.handle.Create("app/ /bad", "", "value")
+	s.Assert().ErrorIs(err, ErrBadPathName)
+	_, _, _, err = s.handle.Create("app/number", "", 123)
+	s.Assert().ErrorIs(err, ErrSecretTypeNotSupported)
+
+	s.kv.forbidden["locked/secret"] = true
+	_, _, _, err = s.handle.Create("locked/secret", "", "value")
+	s.Assert().ErrorIs(err, ErrNoCreatePermission)
+
+	s.kv.lockedMetadata["app/typeless"] = true
+	_, _, _, err = s.handle.Create("app/typeless", "", "value")
+	s.Assert().ErrorIs(err, ErrNoCreatePermission, "Metadata cannot be written")
+	_, _, err = s.handle.Get("app/typeless")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Secret without type should be deleted")
+}
+
+func (s *SecretHCVaultTestSuite) TestList() {
+	s.handle.Create("app/db/password", "", "pw")
+	s.handle.Create("app/api", "", map[string]string{"key": "value"})
+	s.handle.Create("top", "", "value")
+
+	items, _, err := s.handle.List("app")
+	s.Require().NoError(err, "Should list folder")
+	s.Assert().Equal([]Item{
+		{Name: "api", Type: SecretTypeKV, ID: "app/api"},
+		{Name: "db", Type: SecretTypeFolder, ID: "app/db"},
+	}, items)
+
+	items, _, err = s.handle.List("/")
+	s.Require().NoError(err, "Should list top level")
+	s.Assert().Equal([]Item{
+		{Name: "app", Type: SecretTypeFolder, ID: "app"},
+		{Name: "top", Type: SecretTypeText, ID: "top"},
+	}, items)
+
+	_, _, err = s.handle.List("missing")
+	s.Assert().ErrorIs(err, ErrFolderNotFound)
+	_, _, err = s.handle.List("top")
+	s.Assert().ErrorIs(err, ErrNotSecretFolder)
+
+	metadata, _, err := s.handle.GetMetaData("app/db")
+	s.Require().NoError(err, "Should get metadata of folder")
+	s.Assert().Equal(SecretTypeFolder, metadata.Type)
+	s.Assert().Equal("db", metadata.Name)
+}
+
+func (s *SecretHCVaultTestSuite) TestListSecrets() {
+	s.handle.Create("app/db/password", "", "pw")
+	s.handle.Create("app/api", "", map[string]string{"key": "value"})
+	s.handle.Create("other/password", "", "value")
+
+	var names []string
+	_, err := s.handle.ListSecrets(&ListOptions{Search: "PASSWORD", OrderBy: []string{"name desc"}}, func(item Item) error {
+		names = append(names, item.Name)
+		return nil
+	})
+	s.Require().NoError(err, "Should list secrets")
+	s.Assert().Equal([]string{"other/password", "app/db/password"}, names)
+
+	_, err = s.handle.ListSecrets(&ListOptions{Filter: "type eq 'text'"}, func(item Item) error { return nil })
+	s.Assert().ErrorIs(err, ErrInvalidListOption, "Filter is not supported")
+}
+
+func (s *SecretHCVaultTestSuite) TestModify() {
+	s.handle.Create("app/secret", "original", "value")
+	s.handle.Create("app/kv", "", map[string]string{"a": "b"})
+
+	success, _, _, err := s.handle.Modify("app/secret", "", "new value")
+	s.Require().NoError(err, "Should modify secret")
+	s.Assert().True(success)
+	value, _, _ := s.handle.Get("app/secret")
+	s.Assert().Equal("new value", value)
+	metadata, _, _ := s.handle.GetMetaData("app/secret")
+	s.Assert().Equal("original", metadata.Description, "Description should not change")
+
+	_, _, _, err = s.handle.Modify("app/secret", "changed", "newer value")
+	s.Require().NoError(err, "Should modify secret")
+	metadata, _, _ = s.handle.GetMetaData("app/secret")
+	s.Assert().Equal("changed", metadata.Description, "Description should be replaced")
+
+	_, _, _, err = s.handle.Modify("app/secret", "", map[string]string{"a": "b"})
+	s.Assert().ErrorIs(err, ErrCannotModifySecretType)
+	_, _, _, err = s.handle.Modify("app/kv", "", "text")
+	s.Assert().ErrorIs(err, ErrCannotModifySecretType)
+	_, _, _, err = s.handle.Modify("app", "", "text")
+	s.Assert().ErrorIs(err, ErrCannotModifySecretFolder)
+	_, _, _, err = s.handle.Modify("app/missing", "", "text")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretHCVaultTestSuite) TestModifyMetaData() {
+	s.handle.Create("app/secret", "original", "value")
+
+	_, err := s.handle.ModifyMetaData("app/secret", "", map[string]string{"owner": "ops", "env": "prod"})
+	s.Require().NoError(err, "Should modify attributes")
+	metadata, _, _ := s.handle.GetMetaData("app/secret")
+	s.Assert().Equal("original", metadata.Description, "Description should not change")
+	s.Assert().Equal(map[string]string{"owner": "ops", "env": "prod"}, metadata.Attributes)
+	s.Assert().Equal("1", metadata.Version)
+
+	_, err = s.handle.ModifyMetaData("app/secret", "changed", map[string]string{"env": ""})
+	s.Require().NoError(err)
+	_, _, _, err = s.handle.Modify("app/secret", "modified", "new value")
+	s.Require().NoError(err)
+	metadata, _, _ = s.handle.GetMetaData("app/secret")
+	s.Assert().Equal("modified", metadata.Description)
+	s.Assert().Equal(map[string]string{"owner": "ops"}, metadata.Attributes, "Modify should keep attributes")
+	s.Assert().Equal("2", metadata.Version, "Metadata should not create versions")
+	value, _, _ := s.handle.Get("app/secret")
+	s.Assert().Equal("new value", value)
+
+	_, err = s.handle.ModifyMetaData("app/secret", "", map[string]string{"description": "x"})
+	s.Assert().ErrorIs(err, ErrMetaDataNotSupported)
+	_, err = s.handle.ModifyMetaData("app", "folder", nil)
+	s.Assert().ErrorIs(err, ErrCannotModifySecretFolder)
+	_, err = s.handle.ModifyMetaData("app/missing", "", nil)
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretHCVaultTestSuite) TestModifyIfUnchanged() {
+	s.handle.Create("app/secret", "", "v1")
+	observed, _, _ := s.handle.GetMetaData("app/secret")
+	s.Require().Equal("1", observed.Version)
+
+	_, _, _, err := ModifyIfUnchanged(s.handle, "app/secret", observed, "", "v2")
+	s.Require().NoError(err)
+	_, _, _, err = ModifyIfUnchanged(s.handle, "app/secret", observed, "", "v3")
+	s.Assert().ErrorIs(err, ErrConflict, "Version should be checked")
+	value, _, _ := s.handle.Get("app/secret")
+	s.Assert().Equal("v2", value)
+
+	s.handle.Delete("app/secret")
+	s.handle.Create("app/secret", "", "v1")
+	_, _, _, err = ModifyIfUnchanged(s.handle, "app/secret", observed, "", "v3")
+	s.Assert().ErrorIs(err, ErrConflict, "Secret created again should be detected")
+	s.handle.Delete("app/secret")
+	_, _, _, err = ModifyIfUnchanged(s.handle, "app/secret", observed, "", "v3")
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	s.handle.Create("app/secret", "", map[string]string{"user": "admin"})
+	s.Require().NoError(SetKeys(s.handle, "app/secret", map[string]string{"password": "pw"}))
+	value, _, _ = s.handle.Get("app/secret")
+	s.Assert().Equal(map[string]string{"user": "admin", "password": "pw"}, value)
+}
+
+func (s *SecretHCVaultTestSuite) TestDelete() {
+	s.handle.Create("app/secret", "", "value")
+
+	_, err := s.handle.Delete("app")
+	s.Assert().ErrorIs(err, ErrFolderNotEmpty)
+	_, err = s.handle.Delete("app/secret")
+	s.Assert().NoError(err, "Should delete secret")
+	_, _, err = s.handle.Get("app/secret")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, err = s.handle.Delete("app/secret")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretHCVaultTestSuite) TestPermission() {
+	s.handle.Create("app/secret", "", "value")
+
+	cl, err := NewSecretClient(s.server.URL+"/kv", ServerHCVault, "bad-token", s.server.Client)
+	s.Require().NoError(err)
+	_, _, err = cl.Get("app/secret")
+	s.Assert().ErrorIs(err, ErrNoRetrievePermission)
+	_, _, err = cl.GetMetaData("app/secret")
+	s.Assert().ErrorIs(err, ErrNoGetMetaDataPermission)
+	_, _, _, err = cl.Modify("app/secret", "", "new")
+	s.Assert().ErrorIs(err, ErrNoModifyPermission)
+	_, err = cl.Delete("app/secret")
+	s.Assert().ErrorIs(err, ErrNoDeletePermission)
+}
+
+func (s *SecretHCVaultTestSuite) TestDefaultMount() {
+	cl := newHCVaultSecretClient("vault.example.com:8200", "", nil)
+	s.Assert().Equal("https://vault.example.com:8200", cl.serverURL)
+	s.Assert().Equal("secret", cl.mount)
+
+	cl = newHCVaultSecretClient("http://localhost:8200/v1/team/kv/", "", nil)
+	s.Assert().Equal("http://localhost:8200", cl.serverURL)
+	s.Assert().Equal("team/kv", cl.mount)
+}
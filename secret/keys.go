@@ -0,0 +1,93 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SetKeys sets the keys in 'keys' of the keyvalue secret in 'path' to their values, adding the keys
+// that are not in the secret.  The other keys and the description of the secret are not changed.
+//
+// The secret is read, updated and written back with ModifyIfUnchanged, so ErrConflict is returned
+// without modifying the secret if another client has changed it in between.  The caller can then
+// retry.  If the secret store reports neither the version nor the modification time of a secret,
+// e.g., TSS, the value is read again and compared instead.
+//
+// The following errors may be returned, in addition to those returned by Get, GetMetaData and
+// ModifyIfUnchanged:
+//	ErrCannotModifySecretFolder:  'path' is a folder.
+//	ErrConflict:  The secret has been changed or deleted by another client.
+//	ErrSecretTypeNotSupported:  The secret is not a keyvalue secret.
+func SetKeys(cl Secret, path string, keys map[string]string) error {
+	return SetKeysContext(context.Background(), cl, path, keys)
+}
+
+// SetKeysContext is the same as SetKeys, but uses 'ctx' for the requests.
+func SetKeysContext(ctx context.Context, cl Secret, path string, keys map[string]string) error {
+	return patchKeys(ctx, cl, path, func(kv map[string]string) bool {
+		changed := false
+		for k, v := range keys {
+			if current, ok := kv[k]; !ok || current != v {
+				kv[k] = v
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// DeleteKeys removes the keys in 'keys' from the keyvalue secret in 'path'.  Keys that are not in
+// the secret are ignored.  The secret is updated as in SetKeys, and the same errors may be returned.
+func DeleteKeys(cl Secret, path string, keys []string) error {
+	return DeleteKeysContext(context.Background(), cl, path, keys)
+}
+
+// DeleteKeysContext is the same as DeleteKeys, but uses 'ctx' for the requests.
+func DeleteKeysContext(ctx context.Context, cl Secret, path string, keys []string) error {
+	return patchKeys(ctx, cl, path, func(kv map[string]string) bool {
+		changed := false
+		for _, k := range keys {
+			if _, ok := kv[k]; ok {
+				delete(kv, k)
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// patchKeys reads the keyvalue secret in 'path', calls 'update' to change a copy of its value, and
+// writes it back if 'update' returns true and the secret has not changed since it is read
+func patchKeys(ctx context.Context, cl Secret, path string, update func(kv map[string]string) bool) error {
+	path = strings.Trim(path, "/")
+	cl, done := directClient(cl, path)
+	defer done()
+
+	info, _, err := cl.GetMetaDataContext(ctx, path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(info.Type, SecretTypeFolder) {
+		return fmt.Errorf("[%s]: %w", path, ErrCannotModifySecretFolder)
+	}
+	value, _, err := cl.GetContext(ctx, path)
+	if err != nil {
+		return err
+	}
+	value, typ, err := normalizeValue(value)
+	if err != nil || typ != SecretTypeKV {
+		return fmt.Errorf("[%s] is not a keyvalue secret: %w", path, ErrSecretTypeNotSupported)
+	}
+
+	current := value.(map[string]string)
+	kv := make(map[string]string, len(current))
+	for k, v := range current {
+		kv[k] = v
+	}
+	if !update(kv) {
+		return nil
+	}
+	_, _, _, err = modifyIfUnchanged(ctx, cl, path, info, current, "", kv)
+	return err
+}
@@ -0,0 +1,116 @@
+package secret
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretKeysTestSuite struct {
+	memorySuite
+}
+
+// racingClient calls 'onGet' after the first Get, to change the secret before it is written back
+type racingClient struct {
+	Secret
+	onGet      func()
+	noModified bool // whether GetMetaData reports no modification time
+}
+
+func (c *racingClient) GetContext(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	value, r, err := c.Secret.GetContext(ctx, path)
+	if c.onGet != nil {
+		c.onGet()
+		c.onGet = nil
+	}
+	return value, r, err
+}
+
+func (c *racingClient) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	info, r, err := c.Secret.GetMetaDataContext(ctx, path)
+	if err == nil && c.noModified {
+		info.WhenModified = time.Time{}
+	}
+	return info, r, err
+}
+
+func TestSecretKeysTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretKeysTestSuite))
+}
+
+func (s *SecretKeysTestSuite) TestSetAndDeleteKeys() {
+	s.handle.Create("app/db", "database", map[string]string{"user": "admin", "password": "pw"})
+
+	s.Require().NoError(SetKeys(s.handle, "/app/db/", map[string]string{"password": "new", "host": "db"}))
+	value, _, _ := s.handle.Get("app/db")
+	s.Assert().Equal(map[string]string{"user": "admin", "password": "new", "host": "db"}, value)
+	metadata, _, _ := s.handle.GetMetaData("app/db")
+	s.Assert().Equal("database", metadata.Description, "Description should not change")
+
+	s.Require().NoError(DeleteKeys(s.handle, "app/db", []string{"host", "missing"}))
+	value, _, _ = s.handle.Get("app/db")
+	s.Assert().Equal(map[string]string{"user": "admin", "password": "new"}, value)
+
+	modified := metadata.WhenModified
+	s.Require().NoError(SetKeys(s.handle, "app/db", map[string]string{"user": "admin"}))
+	metadata, _, _ = s.handle.GetMetaData("app/db")
+	s.Assert().NotEqual(modified, metadata.WhenModified)
+	modified = metadata.WhenModified
+	s.Require().NoError(DeleteKeys(s.handle, "app/db", []string{"missing"}))
+	metadata, _, _ = s.handle.GetMetaData("app/db")
+	s.Assert().Equal(modified, metadata.WhenModified, "Secret should not be written without changes")
+
+	s.handle.Create("app/text", "", "value")
+	s.Assert().ErrorIs(SetKeys(s.handle, "app/text", map[string]string{"k": "v"}), ErrSecretTypeNotSupported)
+	s.Assert().ErrorIs(SetKeys(s.handle, "app", map[string]string{"k": "v"}), ErrCannotModifySecretFolder)
+	s.Assert().ErrorIs(DeleteKeys(s.handle, "app/missing", []string{"k"}), ErrSecretNotFound)
+}
+
+func (s *SecretKeysTestSuite) TestConflict() {
+	s.handle.Create("db", "", map[string]string{"user": "admin"})
+	cl := &racingClient{Secret: s.handle}
+	cl.onGet = func() {
+		s.handle.Modify("db", "", map[string]string{"user": "other"})
+	}
+	err := SetKeys(cl, "db", map[string]string{"password": "pw"})
+	s.Assert().ErrorIs(err, ErrConflict)
+	value, _, _ := s.handle.Get("db")
+	s.Assert().Equal(map[string]string{"user": "other"}, value, "Concurrent change should be kept")
+
+	cl.onGet = func() {
+		s.handle.Delete("db")
+		s.handle.Create("db", "", map[string]string{"user": "admin"})
+	}
+	err = DeleteKeys(cl, "db", []string{"user"})
+	s.Assert().ErrorIs(err, ErrConflict, "Secret created again should be detected")
+
+	s.Require().NoError(DeleteKeys(cl, "db", []string{"user"}), "Retry should succeed")
+}
+
+func (s *SecretKeysTestSuite) TestConflictWithoutModificationTime() {
+	s.handle.Create("db", "", map[string]string{"user": "admin"})
+	cl := &racingClient{Secret: s.handle, noModified: true}
+	cl.onGet = func() {
+		s.handle.Modify("db", "", map[string]string{"user": "other"})
+	}
+	s.Assert().ErrorIs(SetKeys(cl, "db", map[string]string{"password": "pw"}), ErrConflict)
+
+	s.Require().NoError(SetKeys(cl, "db", map[string]string{"password": "pw"}))
+	value, _, _ := s.handle.Get("db")
+	s.Assert().Equal(map[string]string{"user": "other", "password": "pw"}, value)
+}
+
+func (s *SecretKeysTestSuite) TestCachedClient() {
+	cached := NewCachedSecretClient(s.handle, nil)
+	s.handle.Create("db", "", map[string]string{"user": "admin"})
+	cached.Get("db")
+	cached.GetMetaData("db")
+	s.handle.Modify("db", "", map[string]string{"user": "other"})
+
+	s.Require().NoError(SetKeys(cached, "db", map[string]string{"password": "pw"}))
+	value, _, _ := cached.Get("db")
+	s.Assert().Equal(map[string]string{"user": "other", "password": "pw"}, value, "Cached value should not be written back")
+}
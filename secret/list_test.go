@@ -0,0 +1,186 @@
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+// SecretListTestSuite tests ListSecrets of PASSecretClient against a local HTTP server
+// that returns the secret list in pages.
+type SecretListTestSuite struct {
+	testutils.CfyTestSuite
+	server   *httptest.Server // local HTTP server that stands in for PAS
+	handle   Secret           // interface to secret API
+	secrets  []Item           // secrets returned by the server
+	pageSize int              // number of secrets in each page
+	queries  []url.Values     // query parameters received by server
+	pageHost string           // scheme and host of the next_url returned.  "" for relative URLs
+}
+
+func TestSecretListTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretListTestSuite))
+}
+
+func (s *SecretListTestSuite) SetupTest() {
+	s.secrets = nil
+	for i := 0; i < 25; i++ {
+		s.secrets = append(s.secrets, Item{
+			Name: fmt.Sprintf("folder/secret%02d", i),
+			Type: SecretTypeText,
+			ID:   fmt.Sprintf("id-%02d", i),
+		})
+	}
+	s.pageSize = 10
+	s.queries = nil
+	s.pageHost = ""
+
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.serveList))
+	s.handle = newTestPASClient(s.server, "token")
+}
+
+func (s *SecretListTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// serveList returns the secrets in pages.  The page number is specified in the query
+// parameter "page".  The next_url returned is relative to the host.
+func (s *SecretListTestSuite) serveList(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v1.0/secrets" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	s.queries = append(s.queries, query)
+	if query.Get("orderBy") == "bad" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprint(w, `{"title": "Invalid parameter", "status": 422}`)
+		return
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	start := page * s.pageSize
+	end := start + s.pageSize
+	if end > len(s.secrets) {
+		end = len(s.secrets)
+	}
+	items := make([]map[string]string, 0, s.pageSize)
+	for _, item := range s.secrets[start:end] {
+		items = append(items, map[string]string{"id": item.ID, "name": item.Name, "type": item.Type})
+	}
+	result := map[string]interface{}{
+		"object":       "list",
+		"items":        items,
+		"next_url":     nil,
+		"previous_url": nil,
+	}
+	if end < len(s.secrets) {
+		query.Set("page", strconv.Itoa(page+1))
+		result["next_url"] = s.pageHost + "/api/v1.0/secrets?" + query.Encode()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (s *SecretListTestSuite) TestListAllPages() {
+	var items []Item
+	r, err := s.handle.ListSecrets(nil, func(item Item) error {
+		items = append(items, item)
+		return nil
+	})
+	s.Assert().NoError(err, "Should not return error when listing secrets")
+	s.Assert().Equal(200, r.StatusCode, "HTTP status should be 200")
+	s.Assert().Equal(s.secrets, items, "Should return all secrets in order")
+	s.Assert().Len(s.queries, 3, "Should retrieve 3 pages")
+}
+
+func (s *SecretListTestSuite) TestListOptions() {
+	opts := &ListOptions{
+		Limit:   10,
+		OrderBy: []string{"name desc", "created"},
+		Search:  "secret",
+		Filter:  "type eq 'text'",
+	}
+	count := 0
+	_, err := s.handle.ListSecrets(opts, func(item Item) error {
+		count++
+		return nil
+	})
+	s.Assert().NoError(err, "Should not return error when listing secrets")
+	s.Assert().Equal(len(s.secrets), count, "Should return all secrets")
+	s.Require().NotEmpty(s.queries, "Server should receive requests")
+	for _, query := range s.queries {
+		s.Assert().Equal("10", query.Get("limit"), "limit should be passed in every page")
+		s.Assert().Equal("name desc,created", query.Get("orderBy"), "orderBy should be passed in every page")
+		s.Assert().Equal("secret", query.Get("search"), "search should be passed in every page")
+		s.Assert().Equal("type eq 'text'", query.Get("filter"), "filter should be passed in every page")
+	}
+}
+
+func (s *SecretListTestSuite) TestAbsolutePageURL() {
+	s.pageHost = s.server.URL
+	count := 0
+	_, err := s.handle.ListSecrets(nil, func(item Item) error {
+		count++
+		return nil
+	})
+	s.Require().NoError(err, "Should follow next_url on the same server")
+	s.Assert().Equal(len(s.secrets), count)
+
+	foreign := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Fail("Request should not be sent to another server", "Authorization: %s", r.Header.Get("Authorization"))
+	}))
+	defer foreign.Close()
+	for _, host := range []string{foreign.URL, "//" + foreign.Listener.Addr().String(), "http://" + s.server.Listener.Addr().String()} {
+		s.pageHost = host
+		s.queries = nil
+		_, err = s.handle.ListSecrets(nil, func(item Item) error { return nil })
+		s.Assert().ErrorIs(err, ErrUnexpectedResponse, "next_url on [%s] should be rejected", host)
+		s.Assert().Len(s.queries, 1, "Only the first page should be retrieved")
+	}
+}
+
+func (s *SecretListTestSuite) TestStopListing() {
+	errStop := errors.New("stop")
+	count := 0
+	_, err := s.handle.ListSecrets(nil, func(item Item) error {
+		count++
+		if count == 12 {
+			return errStop
+		}
+		return nil
+	})
+	s.Assert().ErrorIs(err, errStop, "Should return error from ListFunc")
+	s.Assert().Equal(12, count, "Should stop listing after error")
+	s.Assert().Len(s.queries, 2, "Should not retrieve more pages after error")
+}
+
+func (s *SecretListTestSuite) TestInvalidOptions() {
+	_, err := s.handle.ListSecrets(&ListOptions{Limit: 101}, func(item Item) error { return nil })
+	s.Assert().ErrorIs(err, ErrInvalidListOption, "Limit over 100 should be rejected")
+	s.Assert().Empty(s.queries, "Request should not be sent")
+
+	r, err := s.handle.ListSecrets(&ListOptions{OrderBy: []string{"bad"}}, func(item Item) error { return nil })
+	s.Assert().ErrorIs(err, ErrInvalidListOption, "Should return ErrInvalidListOption when server rejects options")
+	s.Assert().Equal(422, r.StatusCode, "HTTP status should be 422")
+}
+
+func (s *SecretListTestSuite) TestEmptyList() {
+	s.secrets = nil
+	count := 0
+	_, err := s.handle.ListSecrets(nil, func(item Item) error {
+		count++
+		return nil
+	})
+	s.Assert().NoError(err, "Should not return error when there is no secret")
+	s.Assert().Zero(count, "Should not call ListFunc")
+}
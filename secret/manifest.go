@@ -0,0 +1,357 @@
+package secret
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ManifestFormatVersion is the version of the manifest read by ReadManifest.
+const ManifestFormatVersion = 1
+
+// Manifest describes the desired state of the folders and secrets under a folder.  Its items have
+// the same format as the items in an export document, so an export document that is not
+// encrypted can also be used as a manifest.
+//
+// The value of a text secret, and each value of a keyvalue secret, can refer to an environment
+// variable or a file instead of containing the value:
+//	${env:NAME}   the value of the environment variable NAME
+//	${file:PATH}  the content of the file PATH.  A relative path is relative to Dir.
+// The value of a file secret is either base64 encoded in Data, or referred to in Text.
+type Manifest struct {
+	Version int          `json:"version" yaml:"version"`                 // format version.  See ManifestFormatVersion
+	Items   []ExportItem `json:"items,omitempty" yaml:"items,omitempty"` // folders and secrets, with paths relative to the root
+
+	// Dir is the directory that relative file references are resolved against.  It is set by
+	// ReadManifestFile.  If it is empty, the current directory is used.
+	Dir string `json:"-" yaml:"-"`
+}
+
+// PlanAction is a change in a Plan.
+type PlanAction string
+
+// constant definition for plan actions
+const (
+	PlanCreate PlanAction = "create" // secret/folder is created
+	PlanModify PlanAction = "modify" // description or value of secret is modified
+	PlanDelete PlanAction = "delete" // secret/folder is deleted
+)
+
+// PlanOptions specifies the options for PlanManifest.
+type PlanOptions struct {
+	// Prune specifies whether to delete the secrets and folders under the root that are not in
+	// the manifest.  By default, they are kept.
+	Prune bool
+}
+
+// PlanChange is a change to a secret or folder in a Plan.
+type PlanChange struct {
+	Path   string     // full path of secret/folder
+	Type   string     // type of secret: SecretTypeFolder, SecretTypeText, SecretTypeKV or SecretTypeFile
+	Action PlanAction // what is to be done for the secret/folder
+	Diff   []string   // differences of a modified secret.  See Copy for the format
+
+	description string      // description to set
+	value       interface{} // value of secret to create or modify
+}
+
+// Plan is the list of changes that make the secrets under a folder match a manifest.  It is
+// computed by PlanManifest and applied by ApplyPlan.
+type Plan struct {
+	Root    string       // path of the folder
+	Changes []PlanChange // changes in the order they are applied
+}
+
+// manifestRef matches a reference to an environment variable or a file in a manifest
+var manifestRef = regexp.MustCompile(`^\$\{(env|file):(.+)\}$`)
+
+// ReadManifest reads a manifest in JSON or YAML format from 'r'.
+//
+// The following errors may be returned:
+//	ErrInvalidManifest:  The manifest is not valid, or its version is not supported.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	content, err := ioutil.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err = decodeDocument(content, m, ErrInvalidManifest); err != nil {
+		return nil, err
+	}
+	if m.Version < 1 || m.Version > ManifestFormatVersion {
+		return nil, fmt.Errorf("unsupported version %d: %w", m.Version, ErrInvalidManifest)
+	}
+	if err = validateItems(m.Items, ErrInvalidManifest); err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool, len(m.Items))
+	for _, item := range m.Items {
+		path := strings.TrimSuffix(item.Path, "/")
+		if paths[path] {
+			return nil, fmt.Errorf("duplicate path [%s]: %w", item.Path, ErrInvalidManifest)
+		}
+		paths[path] = true
+	}
+	return m, nil
+}
+
+// ReadManifestFile reads a manifest from the file in 'path'.  Relative file references in the
+// manifest are resolved against the directory of the file.
+func ReadManifestFile(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ReadManifest(f)
+	if err != nil {
+		return nil, err
+	}
+	m.Dir = filepath.Dir(path)
+	return m, nil
+}
+
+// PlanManifest compares the folders and secrets in the manifest 'm' with those under the folder
+// 'root', and returns the changes that make them match the manifest.  'root' is created if it does
+// not exist, and can be "" or "/" for the top level folder.  'opts' specifies whether secrets that
+// are not in the manifest are deleted.  It can be nil.
+//
+// A secret is modified if its value, or its description specified in the manifest, is different.
+// The description of an existing folder is not changed.  Folders are created before their contents,
+// and deleted after their contents.  References to environment variables and files are resolved
+// when the plan is computed.
+//
+// The following errors may be returned, in addition to those returned by Walk and Get:
+//	ErrCannotModifySecretType:  A secret in the manifest has a different type in the secret store.
+//	ErrInvalidManifest:  A referenced environment variable is not set, or a file cannot be read.
+//	ErrNotSecretFolder:  A folder in the manifest, or 'root', is a secret in the secret store.
+//	ErrNotSecretObject:  A secret in the manifest is a folder in the secret store.
+func PlanManifest(cl Secret, root string, m *Manifest, opts *PlanOptions) (*Plan, error) {
+	return PlanManifestContext(context.Background(), cl, root, m, opts)
+}
+
+// PlanManifestContext is the same as PlanManifest, but uses 'ctx' for all the requests.
+func PlanManifestContext(ctx context.Context, cl Secret, root string, m *Manifest, opts *PlanOptions) (*Plan, error) {
+	if opts == nil {
+		opts = &PlanOptions{}
+	}
+	root = strings.Trim(root, "/")
+	desired, err := desiredState(root, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// read what is in the secret store
+	live := make(map[string]*MetaData)
+	err = WalkContext(ctx, cl, root, &WalkOptions{MetaData: true}, func(path string, info *MetaData, err error) error {
+		if info == nil && (errors.Is(err, ErrSecretNotFound) || errors.Is(err, ErrFolderNotFound)) {
+			// root is to be created
+			desired[root] = &PlanChange{Path: root, Type: SecretTypeFolder}
+			return SkipFolder
+		}
+		if err != nil {
+			return err
+		}
+		if path == root {
+			if !strings.EqualFold(info.Type, SecretTypeFolder) {
+				return fmt.Errorf("[%s]: %w", root, ErrNotSecretFolder)
+			}
+			return nil
+		}
+		live[path] = info
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Root: root}
+	paths := make([]string, 0, len(desired))
+	for path := range desired {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		change := desired[path]
+		info, ok := live[path]
+		if !ok {
+			change.Action = PlanCreate
+			plan.Changes = append(plan.Changes, *change)
+			continue
+		}
+		modified, err := compareLive(ctx, cl, change, info)
+		if err != nil {
+			return nil, err
+		}
+		if modified {
+			change.Action = PlanModify
+			plan.Changes = append(plan.Changes, *change)
+		}
+	}
+
+	if opts.Prune {
+		var deleted []string
+		for path := range live {
+			if _, ok := desired[path]; !ok {
+				deleted = append(deleted, path)
+			}
+		}
+		// delete contents of folders before the folders
+		sort.Sort(sort.Reverse(sort.StringSlice(deleted)))
+		for _, path := range deleted {
+			plan.Changes = append(plan.Changes, PlanChange{Path: path, Type: itemType(live[path]), Action: PlanDelete})
+		}
+	}
+	return plan, nil
+}
+
+// ApplyPlan makes the changes in 'plan' in order.  It stops at the first change that fails, and
+// returns the number of changes made.
+func ApplyPlan(cl Secret, plan *Plan) (int, error) {
+	return ApplyPlanContext(context.Background(), cl, plan)
+}
+
+// ApplyPlanContext is the same as ApplyPlan, but uses 'ctx' for all the requests.
+func ApplyPlanContext(ctx context.Context, cl Secret, plan *Plan) (int, error) {
+	for i, change := range plan.Changes {
+		var err error
+		switch {
+		case change.Action == PlanDelete:
+			_, err = cl.DeleteContext(ctx, change.Path)
+		case change.Action == PlanModify:
+			_, _, _, err = cl.ModifyContext(ctx, change.Path, change.description, change.value)
+		case change.Type == SecretTypeFolder:
+			_, _, _, err = cl.CreateFolderContext(ctx, change.Path, change.description)
+		default:
+			_, _, _, err = cl.CreateContext(ctx, change.Path, change.description, change.value)
+		}
+		if err != nil {
+			return i, fmt.Errorf("apply stopped at %s [%s]: %w", change.Action, change.Path, err)
+		}
+	}
+	return len(plan.Changes), nil
+}
+
+// desiredState returns the secrets and folders in 'm' under 'root', including parent folders that
+// are not in the manifest, indexed by their full path.
+func desiredState(root string, m *Manifest) (map[string]*PlanChange, error) {
+	desired := make(map[string]*PlanChange, len(m.Items))
+	for _, item := range m.Items {
+		path := joinPath(root, strings.TrimSuffix(item.Path, "/"))
+		change := &PlanChange{Path: path, Type: item.Type, description: item.Description}
+		switch item.Type {
+		case SecretTypeText:
+			text, err := resolveManifestValue(item.Text, m.Dir)
+			if err != nil {
+				return nil, fmt.Errorf("[%s]: %w", item.Path, err)
+			}
+			change.value = text
+		case SecretTypeKV:
+			kv := make(map[string]string, len(item.KeyValue))
+			for key, value := range item.KeyValue {
+				resolved, err := resolveManifestValue(value, m.Dir)
+				if err != nil {
+					return nil, fmt.Errorf("[%s] key [%s]: %w", item.Path, key, err)
+				}
+				kv[key] = resolved
+			}
+			change.value = kv
+		case SecretTypeFile:
+			// data is checked by validateItems
+			data, _ := base64.StdEncoding.DecodeString(item.Data)
+			if item.Text != "" {
+				text, err := resolveManifestValue(item.Text, m.Dir)
+				if err != nil {
+					return nil, fmt.Errorf("[%s]: %w", item.Path, err)
+				}
+				data = []byte(text)
+			}
+			change.value = data
+		}
+		desired[path] = change
+	}
+
+	// parent folders of each item
+	for path := range desired {
+		for i := strings.LastIndex(path, "/"); i > len(root); i = strings.LastIndex(path, "/") {
+			path = path[:i]
+			if _, ok := desired[path]; ok {
+				break
+			}
+			desired[path] = &PlanChange{Path: path, Type: SecretTypeFolder}
+		}
+	}
+	return desired, nil
+}
+
+// resolveManifestValue returns 'value', or the value it refers to if it is a reference to an
+// environment variable or a file.  Relative file paths are resolved against 'dir'.
+func resolveManifestValue(value string, dir string) (string, error) {
+	match := manifestRef.FindStringSubmatch(value)
+	if match == nil {
+		return value, nil
+	}
+	if match[1] == "env" {
+		resolved, ok := os.LookupEnv(match[2])
+		if !ok {
+			return "", fmt.Errorf("environment variable [%s] is not set: %w", match[2], ErrInvalidManifest)
+		}
+		return resolved, nil
+	}
+	path := match[2]
+	if !filepath.IsAbs(path) && dir != "" {
+		path = filepath.Join(dir, path)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", err, ErrInvalidManifest)
+	}
+	return string(content), nil
+}
+
+// compareLive compares the desired secret or folder in 'change' with the existing object described
+// by 'info', and returns whether it is to be modified.  The differences are saved in 'change'.
+func compareLive(ctx context.Context, cl Secret, change *PlanChange, info *MetaData) (bool, error) {
+	liveFolder := strings.EqualFold(info.Type, SecretTypeFolder)
+	if change.Type == SecretTypeFolder {
+		if !liveFolder {
+			return false, fmt.Errorf("[%s]: %w", change.Path, ErrNotSecretFolder)
+		}
+		return false, nil
+	}
+	if liveFolder {
+		return false, fmt.Errorf("[%s]: %w", change.Path, ErrNotSecretObject)
+	}
+
+	v, _, err := cl.GetContext(ctx, change.Path)
+	if err != nil {
+		return false, fmt.Errorf("cannot get value of [%s]: %w", change.Path, err)
+	}
+	value, typ, err := normalizeValue(v)
+	if err != nil {
+		return false, fmt.Errorf("[%s]: %w", change.Path, err)
+	}
+	if text, ok := value.(string); ok && change.Type == SecretTypeFile && strings.HasPrefix(text, binaryPrefix) {
+		// binary value returned as text by a client without WithBinaryValues
+		typ = SecretTypeFile
+	}
+	if typ != change.Type {
+		return false, fmt.Errorf("[%s] is a %s secret: %w", change.Path, typ, ErrCannotModifySecretType)
+	}
+	description := change.description
+	if description == "" {
+		// description is not managed by the manifest
+		description = info.Description
+	}
+	change.Diff = diffSecrets(description, change.value, info.Description, value)
+	return len(change.Diff) > 0, nil
+}
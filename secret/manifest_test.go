@@ -0,0 +1,215 @@
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretManifestTestSuite struct {
+	memorySuite
+}
+
+const testManifest = `
+version: 1
+items:
+  - path: db
+    type: text
+    description: database
+    text: ${env:SECRET_TEST_DB_PASSWORD}
+  - path: api/keys
+    type: keyvalue
+    keyvalue:
+      id: admin
+      key: ${file:key.txt}
+  - path: empty
+    type: folder
+    description: empty folder
+`
+
+func TestSecretManifestTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretManifestTestSuite))
+}
+
+func (s *SecretManifestTestSuite) SetupTest() {
+	s.memorySuite.SetupTest()
+	os.Setenv("SECRET_TEST_DB_PASSWORD", "password")
+}
+
+func (s *SecretManifestTestSuite) TearDownTest() {
+	os.Unsetenv("SECRET_TEST_DB_PASSWORD")
+}
+
+// readManifest writes 'content' and the file referenced in testManifest to a temporary
+// directory, and reads the manifest from it
+func (s *SecretManifestTestSuite) readManifest(content string) *Manifest {
+	dir := s.T().TempDir()
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "key.txt"), []byte("secret"), 0600))
+	path := filepath.Join(dir, "manifest.yaml")
+	s.Require().NoError(ioutil.WriteFile(path, []byte(content), 0600))
+	m, err := ReadManifestFile(path)
+	s.Require().NoError(err)
+	return m
+}
+
+// changes returns the action and path of each change in 'plan'
+func changes(plan *Plan) []string {
+	var result []string
+	for _, change := range plan.Changes {
+		result = append(result, string(change.Action)+" "+change.Path)
+	}
+	return result
+}
+
+func (s *SecretManifestTestSuite) TestPlanAndApply() {
+	m := s.readManifest(testManifest)
+	plan, err := PlanManifest(s.handle, "/app/", m, nil)
+	s.Require().NoError(err)
+	s.Assert().Equal("app", plan.Root)
+	s.Assert().Equal([]string{"create app", "create app/api", "create app/api/keys", "create app/db", "create app/empty"}, changes(plan))
+	_, _, err = s.handle.GetMetaData("app")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Plan should not change anything")
+
+	n, err := ApplyPlan(s.handle, plan)
+	s.Require().NoError(err)
+	s.Assert().Equal(5, n)
+	value, _, _ := s.handle.Get("app/db")
+	s.Assert().Equal("password", value, "Environment variable should be resolved")
+	value, _, _ = s.handle.Get("app/api/keys")
+	s.Assert().Equal(map[string]string{"id": "admin", "key": "secret"}, value, "File should be resolved")
+	metadata, _, _ := s.handle.GetMetaData("app/empty")
+	s.Assert().Equal("empty folder", metadata.Description)
+
+	plan, err = PlanManifest(s.handle, "app", m, &PlanOptions{Prune: true})
+	s.Require().NoError(err)
+	s.Assert().Empty(plan.Changes, "Nothing should be changed after plan is applied")
+}
+
+func (s *SecretManifestTestSuite) TestModifyAndPrune() {
+	s.handle.Create("app/db", "old description", "old password")
+	s.handle.Create("app/api/keys", "keys", map[string]string{"id": "admin", "key": "old", "extra": "x"})
+	s.handle.Create("app/old/secret", "", "value")
+	s.handle.Create("other", "", "value")
+
+	m := s.readManifest(testManifest)
+	plan, err := PlanManifest(s.handle, "app", m, nil)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"modify app/api/keys", "modify app/db", "create app/empty"}, changes(plan), "Secrets should not be deleted without prune")
+	s.Assert().Equal([]string{"key [extra] removed", "key [key] changed"}, plan.Changes[0].Diff, "Description not in manifest should not be compared")
+	s.Assert().Equal([]string{"description", "value"}, plan.Changes[1].Diff)
+
+	plan, err = PlanManifest(s.handle, "app", m, &PlanOptions{Prune: true})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{
+		"modify app/api/keys",
+		"modify app/db",
+		"create app/empty",
+		"delete app/old/secret",
+		"delete app/old",
+	}, changes(plan))
+	_, err = ApplyPlan(s.handle, plan)
+	s.Require().NoError(err)
+	_, _, err = s.handle.GetMetaData("app/old")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, _, err = s.handle.Get("other")
+	s.Assert().NoError(err, "Secret outside of root should be kept")
+	metadata, _, _ := s.handle.GetMetaData("app/db")
+	s.Assert().Equal("database", metadata.Description)
+	metadata, _, _ = s.handle.GetMetaData("app/api/keys")
+	s.Assert().Equal("keys", metadata.Description)
+}
+
+func (s *SecretManifestTestSuite) TestFileSecrets() {
+	m := s.readManifest(`
+version: 1
+items:
+  - path: tls/key
+    type: file
+    text: ${file:key.txt}
+  - path: tls/cert
+    type: file
+    data: AAEC
+`)
+	plan, err := PlanManifest(s.handle, "app", m, nil)
+	s.Require().NoError(err)
+	_, err = ApplyPlan(s.handle, plan)
+	s.Require().NoError(err)
+	plan, err = PlanManifest(s.handle, "app", m, nil)
+	s.Require().NoError(err)
+	s.Assert().Empty(plan.Changes, "Binary values returned as text should not be changed")
+
+	s.handle.setBinaryOptions(0, true)
+	plan, err = PlanManifest(s.handle, "app", m, nil)
+	s.Require().NoError(err)
+	s.Assert().Empty(plan.Changes)
+	value, _, _ := s.handle.Get("app/tls/key")
+	s.Assert().Equal([]byte("secret"), value, "File should be read as binary value")
+	value, _, _ = s.handle.Get("app/tls/cert")
+	s.Assert().Equal([]byte{0, 1, 2}, value)
+
+	s.handle.Modify("app/tls/cert", "", []byte{0})
+	plan, err = PlanManifest(s.handle, "app", m, nil)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"modify app/tls/cert"}, changes(plan))
+	s.Assert().Equal([]string{"value"}, plan.Changes[0].Diff)
+}
+
+func (s *SecretManifestTestSuite) TestConflicts() {
+	m := s.readManifest(testManifest)
+	s.handle.Create("app/db", "", map[string]string{"k": "v"})
+	_, err := PlanManifest(s.handle, "app", m, nil)
+	s.Assert().ErrorIs(err, ErrCannotModifySecretType)
+
+	s.handle.Delete("app/db")
+	s.handle.CreateFolder("app/db", "")
+	_, err = PlanManifest(s.handle, "app", m, nil)
+	s.Assert().ErrorIs(err, ErrNotSecretObject)
+
+	s.handle.Create("app/empty", "", "text")
+	_, err = PlanManifest(s.handle, "app/empty", m, nil)
+	s.Assert().ErrorIs(err, ErrNotSecretFolder, "Root should be a folder")
+}
+
+func (s *SecretManifestTestSuite) TestApplyFails() {
+	m := s.readManifest(testManifest)
+	plan, err := PlanManifest(s.handle, "app", m, nil)
+	s.Require().NoError(err)
+	cl := &failingClient{Secret: s.handle, failCreate: map[string]bool{"app/db": true}}
+	n, err := ApplyPlan(cl, plan)
+	s.Assert().ErrorIs(err, ErrNoCreatePermission)
+	s.Assert().Equal("apply stopped at create [app/db]: No permission to create secret", err.Error())
+	s.Assert().Equal(3, n)
+	_, _, err = s.handle.GetMetaData("app/empty")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Should stop at first failure")
+}
+
+func (s *SecretManifestTestSuite) TestReadManifest() {
+	m, err := ReadManifest(strings.NewReader(`{"version": 1, "items": [{"path": "a", "type": "text", "text": "v"}]}`))
+	s.Require().NoError(err, "Should read JSON manifest")
+	s.Assert().Equal("v", m.Items[0].Text)
+
+	badManifests := []string{
+		`version: 2`,
+		`items: []`,
+		`{"version": 1, "items": [{"path": "/a", "type": "text"}]}`,
+		`{"version": 1, "items": [{"path": "a", "type": "certificate"}]}`,
+		`{"version": 1, "items": [{"path": "a/../../b", "type": "text"}]}`,
+		`{"version": 1, "items": [{"path": "a", "type": "text"}, {"path": "a/", "type": "folder"}]}`,
+		`{"version": 1`,
+	}
+	for _, content := range badManifests {
+		_, err = ReadManifest(strings.NewReader(content))
+		s.Assert().ErrorIs(err, ErrInvalidManifest, "Manifest [%s] should be rejected", content)
+	}
+
+	os.Unsetenv("SECRET_TEST_DB_PASSWORD")
+	_, err = PlanManifest(s.handle, "app", s.readManifest(testManifest), nil)
+	s.Assert().ErrorIs(err, ErrInvalidManifest, "Missing environment variable should be reported")
+	m.Items[0].Text = "${file:missing.txt}"
+	_, err = PlanManifest(s.handle, "app", m, nil)
+	s.Assert().ErrorIs(err, ErrInvalidManifest, "Missing file should be reported")
+}
@@ -0,0 +1,280 @@
+package secret
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// structField is a field of a struct that is mapped to a key of a keyvalue secret
+type structField struct {
+	index     int    // index of field in struct
+	key       string // key in secret
+	required  bool   // whether GetInto fails when the key is missing
+	omitEmpty bool   // whether CreateFrom and ModifyFrom omit a zero value
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// GetInto gets the keyvalue secret in 'path' and stores its values in the fields of the struct
+// pointed to by 'v'.  Each exported field is mapped to a key by the "secret" struct tag, or by its
+// name if it has no tag.  The tag specifies the key, followed by comma separated options:
+//	required   the key must be in the secret
+//	omitempty  the key is omitted by CreateFrom and ModifyFrom if the field has a zero value
+// A field with the tag "-" is ignored.  For example:
+//	type DBConfig struct {
+//		Host    string        `secret:"host,required"`
+//		Port    int           `secret:"port"`
+//		Timeout time.Duration `secret:"timeout,omitempty"`
+//		Labels  []string      `secret:"labels,omitempty"`
+//	}
+//
+// Values are converted to the type of the field.  Booleans, integers and floating point numbers
+// are parsed by the strconv package, and time.Duration by time.ParseDuration.  Types that implement
+// encoding.TextUnmarshaler, e.g., time.Time, are decoded from text.  Structs, maps, slices and
+// arrays are decoded from JSON.  A pointer field is allocated when its key is set.  Fields of keys
+// that are missing, or have an empty value, are not changed.
+//
+// The following errors may be returned, in addition to those returned by Get:
+//	ErrInvalidKeyValue:  A value cannot be converted to the type of its field.
+//	ErrMissingRequiredKey:  Some required keys are missing.  All of them are listed in the error.
+//	ErrSecretTypeNotSupported:  The secret is not a keyvalue secret, or 'v' is not a pointer to a struct.
+func GetInto(cl Secret, path string, v interface{}) error {
+	return GetIntoContext(context.Background(), cl, path, v)
+}
+
+// GetIntoContext is the same as GetInto, but uses 'ctx' for the request.
+func GetIntoContext(ctx context.Context, cl Secret, path string, v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("GetInto needs a pointer to a struct, not %T: %w", v, ErrSecretTypeNotSupported)
+	}
+	value, _, err := cl.GetContext(ctx, path)
+	if err != nil {
+		return err
+	}
+	value, typ, err := normalizeValue(value)
+	if err != nil || typ != SecretTypeKV {
+		return fmt.Errorf("[%s] is not a keyvalue secret: %w", path, ErrSecretTypeNotSupported)
+	}
+	return unmarshalKeyValue(value.(map[string]string), target.Elem())
+}
+
+// CreateFrom creates a keyvalue secret in 'path' from the fields of the struct 'v', or of the
+// struct that 'v' points to.  The fields are mapped to keys as in GetInto.  Nil pointers, and zero
+// values of fields with the omitempty option, are omitted.  It returns the ID of the secret.
+//
+// The following errors may be returned, in addition to those returned by Create:
+//	ErrInvalidKeyValue:  A field cannot be converted to text.
+//	ErrSecretTypeNotSupported:  'v' is not a struct or a pointer to a struct.
+func CreateFrom(cl Secret, path string, description string, v interface{}) (string, error) {
+	return CreateFromContext(context.Background(), cl, path, description, v)
+}
+
+// CreateFromContext is the same as CreateFrom, but uses 'ctx' for the request.
+func CreateFromContext(ctx context.Context, cl Secret, path string, description string, v interface{}) (string, error) {
+	kv, err := marshalKeyValue(v)
+	if err != nil {
+		return "", err
+	}
+	_, id, _, err := cl.CreateContext(ctx, path, description, kv)
+	return id, err
+}
+
+// ModifyFrom replaces the value of the keyvalue secret in 'path' with the fields of the struct 'v',
+// or of the struct that 'v' points to, as in CreateFrom.  Keys that are not in the result are
+// removed from the secret.  If 'description' is not empty, it replaces the current description.
+// It returns the ID of the secret.
+//
+// The following errors may be returned, in addition to those returned by Modify:
+//	ErrInvalidKeyValue:  A field cannot be converted to text.
+//	ErrSecretTypeNotSupported:  'v' is not a struct or a pointer to a struct.
+func ModifyFrom(cl Secret, path string, description string, v interface{}) (string, error) {
+	return ModifyFromContext(context.Background(), cl, path, description, v)
+}
+
+// ModifyFromContext is the same as ModifyFrom, but uses 'ctx' for the request.
+func ModifyFromContext(ctx context.Context, cl Secret, path string, description string, v interface{}) (string, error) {
+	kv, err := marshalKeyValue(v)
+	if err != nil {
+		return "", err
+	}
+	_, id, _, err := cl.ModifyContext(ctx, path, description, kv)
+	return id, err
+}
+
+// structFields returns the fields of struct type 't' that are mapped to keys
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		tag := f.Tag.Get("secret")
+		if tag == "-" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		field := structField{index: i, key: options[0]}
+		if field.key == "" {
+			field.key = f.Name
+		}
+		for _, option := range options[1:] {
+			switch option {
+			case "required":
+				field.required = true
+			case "omitempty":
+				field.omitEmpty = true
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// unmarshalKeyValue stores the values in 'kv' in the fields of the struct 'target'
+func unmarshalKeyValue(kv map[string]string, target reflect.Value) error {
+	var missing []string
+	for _, field := range structFields(target.Type()) {
+		text, ok := kv[field.key]
+		if !ok || text == "" {
+			if field.required {
+				missing = append(missing, field.key)
+			}
+			continue
+		}
+		if err := setField(target.Field(field.index), text); err != nil {
+			return fmt.Errorf("key [%s]: %v: %w", field.key, err, ErrInvalidKeyValue)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("keys [%s]: %w", strings.Join(missing, ", "), ErrMissingRequiredKey)
+	}
+	return nil
+}
+
+// setField converts 'text' to the type of the field 'f' and stores it in the field
+func setField(f reflect.Value, text string) error {
+	if f.Kind() == reflect.Ptr {
+		value := reflect.New(f.Type().Elem())
+		if err := setField(value.Elem(), text); err != nil {
+			return err
+		}
+		f.Set(value)
+		return nil
+	}
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	if reflect.PtrTo(f.Type()).Implements(textUnmarshalerType) {
+		return f.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		return json.Unmarshal([]byte(text), f.Addr().Interface())
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
+
+// marshalKeyValue converts the fields of the struct 'v', or of the struct that 'v' points to, into
+// the value of a keyvalue secret
+func marshalKeyValue(v interface{}) (map[string]string, error) {
+	source := reflect.ValueOf(v)
+	if source.Kind() == reflect.Ptr && !source.IsNil() {
+		source = source.Elem()
+	}
+	if source.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("keyvalue secret cannot be created from %T: %w", v, ErrSecretTypeNotSupported)
+	}
+	kv := make(map[string]string)
+	for _, field := range structFields(source.Type()) {
+		f := source.Field(field.index)
+		if (field.omitEmpty && f.IsZero()) || (f.Kind() == reflect.Ptr && f.IsNil()) {
+			continue
+		}
+		text, err := fieldText(f)
+		if err != nil {
+			return nil, fmt.Errorf("key [%s]: %v: %w", field.key, err, ErrInvalidKeyValue)
+		}
+		kv[field.key] = text
+	}
+	return kv, nil
+}
+
+// fieldText converts the value of the field 'f' to text
+func fieldText(f reflect.Value) (string, error) {
+	if f.Kind() == reflect.Ptr {
+		return fieldText(f.Elem())
+	}
+	if f.Type() == durationType {
+		return time.Duration(f.Int()).String(), nil
+	}
+	if f.Type().Implements(textMarshalerType) {
+		text, err := f.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		return f.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(f.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(f.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'g', -1, f.Type().Bits()), nil
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		encoded, err := json.Marshal(f.Interface())
+		return string(encoded), err
+	}
+	return "", fmt.Errorf("unsupported type %s", f.Type())
+}
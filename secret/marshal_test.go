@@ -0,0 +1,127 @@
+package secret
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretMarshalTestSuite struct {
+	memorySuite
+}
+
+type testEndpoint struct {
+	URL     string `json:"url"`
+	Retries int    `json:"retries"`
+}
+
+type testConfig struct {
+	Host      string            `secret:"host,required"`
+	Port      int               `secret:"port"`
+	Debug     bool              `secret:"debug,omitempty"`
+	Ratio     float64           `secret:"ratio,omitempty"`
+	Timeout   time.Duration     `secret:"timeout"`
+	Expires   time.Time         `secret:"expires,omitempty"`
+	MaxConns  *uint16           `secret:"maxconns"`
+	Endpoint  testEndpoint      `secret:"endpoint,omitempty"`
+	Labels    map[string]string `secret:"labels,omitempty"`
+	User      string
+	Ignored   string `secret:"-"`
+	unexposed string
+}
+
+func TestSecretMarshalTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretMarshalTestSuite))
+}
+
+func (s *SecretMarshalTestSuite) TestGetInto() {
+	s.handle.Create("db", "", map[string]string{
+		"host":     "db.example.com",
+		"port":     "5432",
+		"debug":    "true",
+		"ratio":    "0.5",
+		"timeout":  "1m30s",
+		"expires":  "2030-01-02T03:04:05Z",
+		"maxconns": "20",
+		"endpoint": `{"url": "https://api.example.com", "retries": 3}`,
+		"labels":   `{"env": "prod"}`,
+		"User":     "admin",
+		"Ignored":  "value",
+	})
+
+	config := testConfig{Ignored: "kept"}
+	s.Require().NoError(GetInto(s.handle, "db", &config))
+	maxConns := uint16(20)
+	s.Assert().Equal(testConfig{
+		Host:     "db.example.com",
+		Port:     5432,
+		Debug:    true,
+		Ratio:    0.5,
+		Timeout:  90 * time.Second,
+		Expires:  time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		MaxConns: &maxConns,
+		Endpoint: testEndpoint{URL: "https://api.example.com", Retries: 3},
+		Labels:   map[string]string{"env": "prod"},
+		User:     "admin",
+		Ignored:  "kept",
+	}, config)
+}
+
+func (s *SecretMarshalTestSuite) TestGetIntoErrors() {
+	s.handle.Create("db", "", map[string]string{"port": "not a number"})
+	var config testConfig
+	err := GetInto(s.handle, "db", &config)
+	s.Assert().ErrorIs(err, ErrInvalidKeyValue)
+	s.Assert().Contains(err.Error(), "key [port]")
+
+	s.handle.Modify("db", "", map[string]string{"port": "1"})
+	var required struct {
+		Name string `secret:"name,required"`
+		ID   string `secret:"id,required"`
+		Port int    `secret:"port,required"`
+	}
+	err = GetInto(s.handle, "db", &required)
+	s.Assert().ErrorIs(err, ErrMissingRequiredKey)
+	s.Assert().Contains(err.Error(), "keys [id, name]", "All missing keys should be reported")
+
+	s.handle.Create("text", "", "value")
+	s.Assert().ErrorIs(GetInto(s.handle, "text", &config), ErrSecretTypeNotSupported)
+	s.Assert().ErrorIs(GetInto(s.handle, "db", config), ErrSecretTypeNotSupported, "Should need a pointer")
+	s.Assert().ErrorIs(GetInto(s.handle, "missing", &config), ErrSecretNotFound)
+}
+
+func (s *SecretMarshalTestSuite) TestCreateAndModifyFrom() {
+	config := testConfig{
+		Host:    "db.example.com",
+		Port:    5432,
+		Timeout: time.Minute,
+		User:    "admin",
+		Ignored: "value",
+	}
+	_, err := CreateFrom(s.handle, "db", "database", &config)
+	s.Require().NoError(err)
+	value, _, _ := s.handle.Get("db")
+	s.Assert().Equal(map[string]string{
+		"host":    "db.example.com",
+		"port":    "5432",
+		"timeout": "1m0s",
+		"User":    "admin",
+	}, value, "Empty values with omitempty and nil pointers should be omitted")
+
+	var read testConfig
+	s.Require().NoError(GetInto(s.handle, "db", &read))
+	config.Ignored = ""
+	s.Assert().Equal(config, read)
+
+	config.Endpoint = testEndpoint{URL: "https://api.example.com"}
+	config.Debug = true
+	_, err = ModifyFrom(s.handle, "db", "", config)
+	s.Require().NoError(err)
+	value, _, _ = s.handle.Get("db")
+	s.Assert().Equal(`{"url":"https://api.example.com","retries":0}`, value.(map[string]string)["endpoint"])
+	s.Assert().Equal("true", value.(map[string]string)["debug"])
+
+	_, err = CreateFrom(s.handle, "other", "", "not a struct")
+	s.Assert().ErrorIs(err, ErrSecretTypeNotSupported)
+}
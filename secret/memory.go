@@ -0,0 +1,482 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/centrify/platform-go-sdk/internal/memstore"
+)
+
+// memoryStores are the in-memory secret stores shared by clients with the same server name
+var memoryStores = struct {
+	sync.Mutex
+	stores map[string]*memstore.Store
+}{stores: make(map[string]*memstore.Store)}
+
+// MemorySecretClient implements the Secret interface where the secret is stored in memory.  It is
+// intended for unit testing code that uses the Secret interface without a PAS tenant.
+//
+// It follows the semantics of PAS: parent folders are created when a secret is created, a path can
+// only be used by one secret or folder, only empty folders can be deleted, and the type of a secret
+// cannot be changed.  The same errors as PASSecretClient are returned, and each secret and folder has a
+// unique ID and CRN, and its creation and modification time.  The HTTP response returned has the
+// status code that PAS returns, and an empty body.
+//
+// Clients created by NewSecretClient with the same server name share the same secrets.  A client
+// created with an empty server name has its own secrets.  The access token is not checked.
+type MemorySecretClient struct {
+	store *memstore.Store // secret store
+	debug bool            // whether debug is on/off
+
+	maxBinarySize int  // maximum size of binary values.  0 means DefaultMaxBinarySize
+	decodeBinary  bool // whether Get returns binary values as []byte.  See WithBinaryValues
+}
+
+// newMemorySecretClient creates a new client handle to access the in-memory secret store 'server'
+func newMemorySecretClient(server string) *MemorySecretClient {
+	if server == "" {
+		return &MemorySecretClient{store: memstore.New()}
+	}
+	memoryStores.Lock()
+	defer memoryStores.Unlock()
+	store, ok := memoryStores.stores[server]
+	if !ok {
+		store = memstore.New()
+		memoryStores.stores[server] = store
+	}
+	return &MemorySecretClient{store: store}
+}
+
+// Get returns the secret content.
+// If the secret is a keyvalue secret, it returns the secret as map[string]string
+// If the secret is a text string, it returns the secret as string.
+// The following errors may be returned:
+//	 ErrNotSecretObject: The path specifies a folder.
+//	 ErrSecretNotFound: Secret specified in path cannot be found.
+func (c *MemorySecretClient) Get(path string) (interface{}, *http.Response, error) {
+	return c.GetContext(context.Background(), path)
+}
+
+// GetContext is the same as Get, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) GetContext(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	c.logf("Get [%s]", path)
+	obj, err := c.store.Get(path)
+	if err != nil {
+		return nil, memoryResponse(http.StatusNotFound), ErrSecretNotFound
+	}
+	switch obj.Type {
+	case memstore.TypeText:
+		return decodeValue(obj.Text, c.decodeBinary), memoryResponse(http.StatusOK), nil
+	case memstore.TypeKeyValue:
+		return obj.KeyValue, memoryResponse(http.StatusOK), nil
+	}
+	return nil, memoryResponse(http.StatusOK), ErrNotSecretObject
+}
+
+// Create creates a secret in 'path'. 'description' is an optional description
+// of the secret.  If 'value' is a string, it saves the secret as a
+// secret text string.  If 'value' is type map[string]string, the secret
+// is stored as 'keyvalue' secret.  Parent folders are created if they do not exist.
+// Returns the following information:
+//  bool: whether the secret is created or not.
+//  id: a unique ID of the secret
+//  response: the HTTP response that PAS returns
+//
+// The following errors may be returned:
+//
+//   ErrBadPathName: Invalid secret path name, or a parent is not a folder
+//	 ErrExists: Secret or folder already exists
+//	 ErrSecretTypeNotSupported:  Cannot create secret for the specified type.
+func (c *MemorySecretClient) Create(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.CreateContext(context.Background(), path, description, value)
+}
+
+// CreateContext is the same as Create, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) CreateContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return false, "", nil, err
+	}
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, err
+	}
+	obj, err := memoryObject(description, value)
+	if err != nil {
+		return false, "", nil, err
+	}
+	return c.create(path, obj)
+}
+
+// CreateFolder creates a secret folder in 'path', with optional description.
+// Parent folders are created if they do not exist.
+// Returns the following information:
+//  bool: whether the secret folder is created or not.
+//  id: a unique ID of the secret folder
+//  response: the HTTP response that PAS returns
+//
+// The following errors may be returned:
+//   ErrBadPathName: Invalid secret path name, or a parent is not a folder
+//	 ErrExists: Secret or folder already exists
+func (c *MemorySecretClient) CreateFolder(path string, description string) (bool, string, *http.Response, error) {
+	return c.CreateFolderContext(context.Background(), path, description)
+}
+
+// CreateFolderContext is the same as CreateFolder, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) CreateFolderContext(ctx context.Context, path string, description string) (bool, string, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return false, "", nil, err
+	}
+	return c.create(path, &memstore.Object{Type: memstore.TypeFolder, Description: description})
+}
+
+// List lists all secrets in a folder specified in 'path'
+// Returns the following:
+//  items: an array of Item sorted by name. If the folder is empty, an empty array is returned.
+//  response: the HTTP response that PAS returns
+// the following errors may be returned:
+//	ErrFolderNotFound: Secret specified in path cannot be found.
+//	ErrNotSecretFolder: The path specifies a secret.
+func (c *MemorySecretClient) List(path string) ([]Item, *http.Response, error) {
+	return c.ListContext(context.Background(), path)
+}
+
+// ListContext is the same as List, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	c.logf("List [%s]", path)
+	objects, err := c.store.List(path)
+	switch err {
+	case nil:
+	case memstore.ErrNotFolder:
+		return nil, memoryResponse(http.StatusOK), ErrNotSecretFolder
+	default:
+		return nil, memoryResponse(http.StatusNotFound), ErrFolderNotFound
+	}
+	items := make([]Item, len(objects))
+	for i, obj := range objects {
+		items[i] = Item{Name: obj.Name, Type: obj.Type, ID: obj.ID}
+	}
+	return items, memoryResponse(http.StatusOK), nil
+}
+
+// ListSecrets lists the secrets that match the search, filter and ordering options in 'opts'.
+// 'opts' can be nil, which lists all secrets.  The name of each item is the full path of the secret,
+// and folders are not listed.
+// Search matches any part of the path, ignoring case.  Filter is a list of conditions joined by "and",
+// where each condition has the form <property> <operator> '<value>'.  The property is name, type or
+// description, and the operator is eq, ne, co (contains), sw (starts with) or ew (ends with).
+// OrderBy can be name, type, created or modified, optionally followed by asc or desc.
+// If 'fn' returns an error, the listing stops and the error is returned.
+// Returns the following:
+//  response: the HTTP response that PAS returns
+// the following errors may be returned:
+//	ErrInvalidListOption: The options in 'opts' are not valid.
+func (c *MemorySecretClient) ListSecrets(opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	return c.ListSecretsContext(context.Background(), opts, fn)
+}
+
+// ListSecretsContext is the same as ListSecrets, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) ListSecretsContext(ctx context.Context, opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	if opts.Limit < 0 || opts.Limit > maxListLimit {
+		return memoryResponse(http.StatusBadRequest), fmt.Errorf("Limit must be between 1 and %d: %w", maxListLimit, ErrInvalidListOption)
+	}
+	c.logf("ListSecrets %+v", *opts)
+	objects, err := c.store.Search(&memstore.Query{Search: opts.Search, Filter: opts.Filter, OrderBy: opts.OrderBy})
+	if err != nil {
+		return memoryResponse(http.StatusBadRequest), fmt.Errorf("%v: %w", err, ErrInvalidListOption)
+	}
+	for _, obj := range objects {
+		if err := fn(Item{Name: obj.Path, Type: obj.Type, ID: obj.ID}); err != nil {
+			return memoryResponse(http.StatusOK), err
+		}
+		if err := ctx.Err(); err != nil {
+			return memoryResponse(http.StatusOK), err
+		}
+	}
+	return memoryResponse(http.StatusOK), nil
+}
+
+// Delete deletes the folder/secret specified in 'path'
+// Returns the following information:
+//  response: the HTTP response that PAS returns
+// The following errors may be returned:
+//	ErrFolderNotEmpty: Folder is not empty
+//	ErrSecretNotFound: Secret specified in path cannot be found.
+func (c *MemorySecretClient) Delete(path string) (*http.Response, error) {
+	return c.DeleteContext(context.Background(), path)
+}
+
+// DeleteContext is the same as Delete, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) DeleteContext(ctx context.Context, path string) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.logf("Delete [%s]", path)
+	switch c.store.Delete(path) {
+	case nil:
+		return memoryResponse(http.StatusNoContent), nil
+	case memstore.ErrFolderNotEmpty:
+		return memoryResponse(http.StatusConflict), ErrFolderNotEmpty
+	default:
+		return memoryResponse(http.StatusNotFound), ErrSecretNotFound
+	}
+}
+
+// Modify modifies a secret in 'path'.
+// If 'description' is not an empty string, it replaces the current secret description.
+// If 'value' is a string, it saves the secret as a
+// secret text string.  If 'value' is type map[string]string, the secret
+// is stored as 'keyvalue' secret.
+//
+// Returns the following information:
+//  bool: whether the secret is modified or not.
+//  id: the unique ID of the secret
+//  response: the HTTP response that PAS returns
+// The following errors may be returned:
+//	ErrCannotModifySecretFolder:  Modification of a secret folder is not supported.
+//	ErrCannotModifySecretType:  Modification of keyvalue secret to text or vice versa is not supported.
+//	ErrSecretNotFound: secret cannot be found
+//	ErrSecretTypeNotSupported:  The type of 'value' is not supported.
+func (c *MemorySecretClient) Modify(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.ModifyContext(context.Background(), path, description, value)
+}
+
+// ModifyContext is the same as Modify, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return false, "", nil, err
+	}
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, err
+	}
+	update, err := memoryObject(description, value)
+	if err != nil {
+		return false, "", nil, err
+	}
+	c.logf("Modify [%s]", path)
+	obj, err := c.store.Modify(path, update)
+	switch err {
+	case nil:
+		return true, obj.ID, memoryResponse(http.StatusOK), nil
+	case memstore.ErrIsFolder:
+		return false, "", memoryResponse(http.StatusNotFound), ErrCannotModifySecretFolder
+	case memstore.ErrTypeChanged:
+		return false, "", memoryResponse(http.StatusConflict), ErrCannotModifySecretType
+	default:
+		return false, "", memoryResponse(http.StatusNotFound), ErrSecretNotFound
+	}
+}
+
+// ModifyMetaData modifies the description and attributes of the secret or folder in 'path' without
+// changing its value.  If 'description' is not an empty string, it replaces the current description.
+// Each key in 'attributes' is set to its value, or removed if the value is empty.
+// The following errors may be returned:
+//	ErrSecretNotFound: secret or folder cannot be found
+func (c *MemorySecretClient) ModifyMetaData(path string, description string, attributes map[string]string) (*http.Response, error) {
+	return c.ModifyMetaDataContext(context.Background(), path, description, attributes)
+}
+
+// ModifyMetaDataContext is the same as ModifyMetaData, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) ModifyMetaDataContext(ctx context.Context, path string, description string, attributes map[string]string) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.logf("ModifyMetaData [%s]", path)
+	if _, err := c.store.ModifyMetaData(path, description, attributes); err != nil {
+		return memoryResponse(http.StatusNotFound), ErrSecretNotFound
+	}
+	return memoryResponse(http.StatusOK), nil
+}
+
+// Move moves the secret or folder in 'srcPath', including the contents of a folder, to 'dstPath'.
+// Missing parent folders of 'dstPath' are created.  The secret or folder keeps its ID.
+// Returns the following information:
+//  id: the unique ID of the secret or folder
+//  response: the HTTP response that PAS returns
+// The following errors may be returned:
+//	ErrBadPathName: 'srcPath' or 'dstPath' is invalid, 'dstPath' is in the folder 'srcPath', or a
+//		parent of 'dstPath' is not a folder
+//	ErrExists: A secret or folder already exists in 'dstPath'
+//	ErrSecretNotFound: Secret or folder specified in 'srcPath' cannot be found.
+func (c *MemorySecretClient) Move(srcPath string, dstPath string) (string, *http.Response, error) {
+	return c.MoveContext(context.Background(), srcPath, dstPath)
+}
+
+// MoveContext is the same as Move, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) MoveContext(ctx context.Context, srcPath string, dstPath string) (string, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
+	}
+	src, dst, err := checkMove(srcPath, dstPath)
+	if err != nil {
+		return "", nil, err
+	}
+	c.logf("Move [%s] to [%s]", src, dst)
+	obj, err := c.store.Move(src, dst)
+	switch {
+	case err == nil:
+		return obj.ID, memoryResponse(http.StatusOK), nil
+	case errors.Is(err, memstore.ErrNotFound):
+		return "", memoryResponse(http.StatusNotFound), ErrSecretNotFound
+	case errors.Is(err, memstore.ErrExists):
+		return "", memoryResponse(http.StatusConflict), ErrExists
+	}
+	return "", memoryResponse(http.StatusBadRequest), ErrBadPathName
+}
+
+// Rename renames the secret or folder in 'path' to 'newName' in the same folder.  It is the same as
+// Move to the new path.  ErrBadPathName is returned if 'newName' is empty or has "/".
+func (c *MemorySecretClient) Rename(path string, newName string) (string, *http.Response, error) {
+	return c.RenameContext(context.Background(), path, newName)
+}
+
+// RenameContext is the same as Rename, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) RenameContext(ctx context.Context, path string, newName string) (string, *http.Response, error) {
+	newPath, err := renamePath(path, newName)
+	if err != nil {
+		return "", nil, err
+	}
+	return c.MoveContext(ctx, path, newPath)
+}
+
+// PathOfID returns the current path of the secret or folder with the ID 'id'.
+// The following errors may be returned:
+//	ErrSecretNotFound: No secret or folder has the ID.
+func (c *MemorySecretClient) PathOfID(id string) (string, *http.Response, error) {
+	return c.PathOfIDContext(context.Background(), id)
+}
+
+// PathOfIDContext is the same as PathOfID, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) PathOfIDContext(ctx context.Context, id string) (string, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
+	}
+	c.logf("PathOfID [%s]", id)
+	obj, err := c.store.GetByID(id)
+	if err != nil {
+		return "", memoryResponse(http.StatusNotFound), ErrSecretNotFound
+	}
+	return obj.Path, memoryResponse(http.StatusOK), nil
+}
+
+// GetMetaData returns the metadata of a secret.
+// Returns the following information:
+//  MetaData: metadata information for the secret
+//  response: the HTTP response that PAS returns
+// The following errors may be returned:
+//	ErrSecretNotFound: Secret specified in path cannot be found.
+func (c *MemorySecretClient) GetMetaData(path string) (*MetaData, *http.Response, error) {
+	return c.GetMetaDataContext(context.Background(), path)
+}
+
+// GetMetaDataContext is the same as GetMetaData, but returns the error of 'ctx' if it is done.
+func (c *MemorySecretClient) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	c.logf("GetMetaData [%s]", path)
+	obj, err := c.store.Get(path)
+	if err != nil {
+		return nil, memoryResponse(http.StatusNotFound), ErrSecretNotFound
+	}
+	result := &MetaData{}
+	result.Name = obj.Path
+	result.Type = obj.Type
+	result.ID = obj.ID
+	result.CRN = obj.CRN
+	result.Description = obj.Description
+	result.WhenCreated = obj.Created
+	result.WhenModified = obj.Modified
+	result.Attributes = copyAttributes(obj.Attributes)
+	return result, memoryResponse(http.StatusOK), nil
+}
+
+// SetDebug enables/disables debug messages.  For MemorySecretClient, it logs the
+// operations and paths to the standard logger.
+func (c *MemorySecretClient) SetDebug(onoff bool) {
+	c.debug = onoff
+}
+
+// setBinaryOptions sets the maximum size of binary values, and whether Get decodes them
+func (c *MemorySecretClient) setBinaryOptions(maxSize int, decode bool) {
+	c.maxBinarySize = maxSize
+	c.decodeBinary = decode
+}
+
+// SetUserAgent does nothing as there are no HTTP requests
+func (c *MemorySecretClient) SetUserAgent(agent string) {
+}
+
+// AddDefaultHeaders does nothing as there are no HTTP requests
+func (c *MemorySecretClient) AddDefaultHeaders(hdrs map[string]string) {
+}
+
+// Clear deletes all secrets and folders in the secret store, which is shared by all clients
+// with the same server name.
+func (c *MemorySecretClient) Clear() {
+	c.store.Clear()
+}
+
+// create creates the secret or folder 'obj' in 'path'
+func (c *MemorySecretClient) create(path string, obj *memstore.Object) (bool, string, *http.Response, error) {
+	c.logf("Create %s [%s]", obj.Type, path)
+	created, err := c.store.Create(path, obj)
+	switch {
+	case err == nil:
+		return true, created.ID, memoryResponse(http.StatusCreated), nil
+	case errors.Is(err, memstore.ErrExists):
+		return false, "", memoryResponse(http.StatusConflict), ErrExists
+	case errors.Is(err, memstore.ErrBadPath), errors.Is(err, memstore.ErrNotFolder):
+		return false, "", memoryResponse(http.StatusBadRequest), ErrBadPathName
+	}
+	return false, "", memoryResponse(http.StatusInternalServerError), fmt.Errorf("%v: %w", err, ErrUnexpectedResponse)
+}
+
+// logf logs a message when debug is on
+func (c *MemorySecretClient) logf(format string, args ...interface{}) {
+	if c.debug {
+		log.Printf("MemorySecretClient: "+format+"\n", args...)
+	}
+}
+
+// memoryObject converts 'value' into a secret in the in-memory store
+func memoryObject(description string, value interface{}) (*memstore.Object, error) {
+	switch v := value.(type) {
+	case string:
+		return &memstore.Object{Type: memstore.TypeText, Description: description, Text: v}, nil
+	case map[string]string:
+		return &memstore.Object{Type: memstore.TypeKeyValue, Description: description, KeyValue: v}, nil
+	}
+	return nil, ErrSecretTypeNotSupported
+}
+
+// memoryResponse returns a HTTP response with status code 'status' and an empty body
+func memoryResponse(status int) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}
+}
@@ -0,0 +1,244 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretMemoryTestSuite struct {
+	testutils.CfyTestSuite
+	handle Secret
+}
+
+// memorySuite is embedded in the test suites that need a private MemorySecretClient, which is created
+// for each test
+type memorySuite struct {
+	testutils.CfyTestSuite
+	handle *MemorySecretClient
+}
+
+func (s *memorySuite) SetupTest() {
+	s.handle = newMemorySecretClient("")
+}
+
+func TestSecretMemoryTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretMemoryTestSuite))
+}
+
+func (s *SecretMemoryTestSuite) SetupTest() {
+	var err error
+	s.handle, err = NewSecretClient("", ServerMemory, "", nil)
+	s.Require().NoError(err, "Should create client for memory")
+}
+
+func (s *SecretMemoryTestSuite) TestTextSecret() {
+	success, id, r, err := s.handle.Create("/app/note", "my description", "my note")
+	s.Require().NoError(err, "Should create text secret")
+	s.Assert().True(success)
+	s.Assert().NotEmpty(id)
+	s.Assert().Equal(201, r.StatusCode)
+
+	value, r, err := s.handle.Get("app/note")
+	s.Require().NoError(err, "Should get text secret")
+	s.Assert().Equal(200, r.StatusCode)
+	s.Assert().Equal("my note", value)
+
+	metadata, _, err := s.handle.GetMetaData("app/note")
+	s.Require().NoError(err, "Should get metadata")
+	s.Assert().Equal("app/note", metadata.Name, "Name should be full path as in PAS")
+	s.Assert().Equal(SecretTypeText, metadata.Type)
+	s.Assert().Equal(id, metadata.ID)
+	s.Assert().Equal("crn:secret:"+id, metadata.CRN)
+	s.Assert().Equal("my description", metadata.Description)
+	s.Assert().False(metadata.WhenCreated.IsZero())
+	s.Assert().Equal(metadata.WhenCreated, metadata.WhenModified)
+
+	metadata, _, err = s.handle.GetMetaData("app")
+	s.Require().NoError(err, "Parent folder should be created")
+	s.Assert().Equal(SecretTypeFolder, metadata.Type)
+
+	_, _, err = s.handle.Get("app")
+	s.Assert().ErrorIs(err, ErrNotSecretObject)
+	_, r, err = s.handle.Get("app/missing")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	s.Assert().Equal(404, r.StatusCode)
+}
+
+func (s *SecretMemoryTestSuite) TestKeyValueSecret() {
+	kv := map[string]string{"username": "admin", "password": "secret"}
+	_, _, _, err := s.handle.Create("app/creds", "", kv)
+	s.Require().NoError(err, "Should create keyvalue secret")
+	kv["password"] = "changed"
+
+	value, _, err := s.handle.Get("app/creds")
+	s.Require().NoError(err, "Should get keyvalue secret")
+	s.Assert().Equal(map[string]string{"username": "admin", "password": "secret"}, value,
+		"Secret should not be changed by caller")
+}
+
+func (s *SecretMemoryTestSuite) TestCreateErrors() {
+	_, _, _, err := s.handle.Create("app/secret", "", "value")
+	s.Require().NoError(err)
+
+	_, _, r, err := s.handle.Create("app/secret", "", "value")
+	s.Assert().ErrorIs(err, ErrExists, "Duplicate secret")
+	s.Assert().Equal(409, r.StatusCode)
+	_, _, _, err = s.handle.Create("app", "", "value")
+	s.Assert().ErrorIs(err, ErrExists, "Secret with same name as folder")
+	_, _, _, err = s.handle.CreateFolder("app/", "")
+	s.Assert().ErrorIs(err, ErrExists, "Existing folder")
+	_, _, _, err = s.handle.CreateFolder("app/secret", "")
+	s.Assert().ErrorIs(err, ErrExists, "Folder with same name as secret")
+	_, _, _, err = s.handle.Create("app/secret/child", "", "value")
+	s.Assert().ErrorIs(err, ErrBadPathName, "Parent is a secret")
+	_, _, _, err = s.handle.Create("app/number", "", 123)
+	s.Assert().ErrorIs(err, ErrSecretTypeNotSupported)
+
+	for _, path := range []string{"&#", "abc<def", "   ", "  /empty_parent"} {
+		_, _, r, err = s.handle.Create(path, "", "value")
+		s.Assert().ErrorIs(err, ErrBadPathName, "Path [%s] should be invalid", path)
+		s.Assert().Equal(400, r.StatusCode)
+	}
+}
+
+func (s *SecretMemoryTestSuite) TestList() {
+	s.handle.Create("app/db/password", "", "pw")
+	_, id, _, _ := s.handle.Create("app/api", "", map[string]string{"key": "value"})
+	s.handle.Create("top", "", "value")
+
+	items, _, err := s.handle.List("app")
+	s.Require().NoError(err, "Should list folder")
+	s.Require().Len(items, 2)
+	s.Assert().Equal(Item{Name: "api", Type: SecretTypeKV, ID: id}, items[0])
+	s.Assert().Equal("db", items[1].Name)
+	s.Assert().Equal(SecretTypeFolder, items[1].Type)
+
+	items, _, err = s.handle.List("/")
+	s.Require().NoError(err, "Should list top level")
+	s.Assert().Len(items, 2)
+
+	_, r, err := s.handle.List("missing")
+	s.Assert().ErrorIs(err, ErrFolderNotFound)
+	s.Assert().Equal(404, r.StatusCode)
+	_, _, err = s.handle.List("top")
+	s.Assert().ErrorIs(err, ErrNotSecretFolder)
+}
+
+func (s *SecretMemoryTestSuite) TestListSecrets() {
+	s.handle.Create("app/db/password", "", "pw")
+	s.handle.Create("app/api-key", "", map[string]string{"key": "value"})
+	s.handle.Create("other", "", "value")
+
+	var names []string
+	listFn := func(item Item) error {
+		names = append(names, item.Name)
+		return nil
+	}
+	_, err := s.handle.ListSecrets(nil, listFn)
+	s.Require().NoError(err, "Should list all secrets")
+	s.Assert().Equal([]string{"app/api-key", "app/db/password", "other"}, names)
+
+	names = nil
+	_, err = s.handle.ListSecrets(&ListOptions{Filter: "type eq 'text'", OrderBy: []string{"name desc"}}, listFn)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"other", "app/db/password"}, names)
+
+	names = nil
+	_, err = s.handle.ListSecrets(&ListOptions{Search: "KEY"}, listFn)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"app/api-key"}, names)
+
+	_, err = s.handle.ListSecrets(&ListOptions{Filter: "size eq '1'"}, listFn)
+	s.Assert().ErrorIs(err, ErrInvalidListOption)
+	_, err = s.handle.ListSecrets(&ListOptions{Limit: maxListLimit + 1}, listFn)
+	s.Assert().ErrorIs(err, ErrInvalidListOption)
+}
+
+func (s *SecretMemoryTestSuite) TestModify() {
+	_, id, _, _ := s.handle.Create("app/creds", "desc", map[string]string{"username": "admin"})
+	s.handle.Create("app/sub/note", "", "value")
+
+	success, modifiedID, _, err := s.handle.Modify("app/creds", "", map[string]string{"username": "root"})
+	s.Require().NoError(err, "Should modify secret")
+	s.Assert().True(success)
+	s.Assert().Equal(id, modifiedID)
+	value, _, _ := s.handle.Get("app/creds")
+	s.Assert().Equal(map[string]string{"username": "root"}, value)
+	metadata, _, _ := s.handle.GetMetaData("app/creds")
+	s.Assert().Equal("desc", metadata.Description, "Empty description should not change description")
+	s.Assert().False(metadata.WhenModified.Before(metadata.WhenCreated))
+
+	_, _, _, err = s.handle.Modify("app/creds", "", "text")
+	s.Assert().ErrorIs(err, ErrCannotModifySecretType)
+	_, _, _, err = s.handle.Modify("app/sub", "", "value")
+	s.Assert().ErrorIs(err, ErrCannotModifySecretFolder)
+	_, _, _, err = s.handle.Modify("app/missing", "", "value")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretMemoryTestSuite) TestModifyMetaData() {
+	s.handle.Create("app/secret", "original", "value")
+
+	_, err := s.handle.ModifyMetaData("app/secret", "changed", map[string]string{"owner": "ops"})
+	s.Require().NoError(err)
+	metadata, _, _ := s.handle.GetMetaData("app/secret")
+	s.Assert().Equal("changed", metadata.Description)
+	s.Assert().Equal(map[string]string{"owner": "ops"}, metadata.Attributes)
+	metadata.Attributes["owner"] = "changed by caller"
+	metadata, _, _ = s.handle.GetMetaData("app/secret")
+	s.Assert().Equal("ops", metadata.Attributes["owner"], "Attributes should be copied")
+
+	_, err = s.handle.ModifyMetaData("app", "folder", nil)
+	s.Require().NoError(err, "Should modify metadata of folder")
+	_, err = s.handle.ModifyMetaData("missing", "", nil)
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretMemoryTestSuite) TestDelete() {
+	s.handle.Create("app/secret", "", "value")
+
+	r, err := s.handle.Delete("app")
+	s.Assert().ErrorIs(err, ErrFolderNotEmpty)
+	s.Assert().Equal(409, r.StatusCode)
+	_, err = s.handle.Delete("app/secret")
+	s.Require().NoError(err, "Should delete secret")
+	_, err = s.handle.Delete("app/secret")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, err = s.handle.Delete("app")
+	s.Require().NoError(err, "Should delete empty folder")
+	_, _, err = s.handle.GetMetaData("app")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretMemoryTestSuite) TestSharedStore() {
+	first, _ := NewSecretClient("shared-memory-test", ServerMemory, "", nil)
+	second, _ := NewSecretClient("shared-memory-test", ServerMemory, "", nil)
+	defer first.(*MemorySecretClient).Clear()
+
+	_, _, _, err := first.Create("shared", "", "value")
+	s.Require().NoError(err)
+	value, _, err := second.Get("shared")
+	s.Require().NoError(err, "Clients with same server name should share secrets")
+	s.Assert().Equal("value", value)
+
+	_, _, err = s.handle.Get("shared")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Client with empty server name should have its own secrets")
+
+	second.(*MemorySecretClient).Clear()
+	_, _, err = first.Get("shared")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Clear should delete secrets of all clients")
+}
+
+func (s *SecretMemoryTestSuite) TestContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := s.handle.GetContext(ctx, "app")
+	s.Assert().ErrorIs(err, context.Canceled)
+	_, _, _, err = s.handle.CreateContext(ctx, "app", "", "value")
+	s.Assert().ErrorIs(err, context.Canceled)
+	_, _, err = s.handle.Get("app")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Secret should not be created")
+}
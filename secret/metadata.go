@@ -0,0 +1,51 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// copyAttributes returns a copy of 'attributes'.  nil is returned if it is empty.
+func copyAttributes(attributes map[string]string) map[string]string {
+	if len(attributes) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		c[k] = v
+	}
+	return c
+}
+
+// mergeAttributes returns a copy of 'current' where each key in 'update' is set to its value, or
+// removed if the value is empty
+func mergeAttributes(current map[string]string, update map[string]string) map[string]string {
+	result := make(map[string]string, len(current)+len(update))
+	for k, v := range current {
+		result[k] = v
+	}
+	for k, v := range update {
+		if v == "" {
+			delete(result, k)
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// attributeText converts the value of an attribute returned by a secret store to text.  Values
+// that are not strings are converted to JSON.
+func attributeText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,161 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MoveError is the error returned by Move when a secret or folder is moved by copying it to the
+// destination and deleting it from the source, and the move can be neither completed nor undone.
+// It wraps the error that stops the move.  errors.Is(err, ErrMoveIncomplete) reports whether an
+// error is a MoveError.
+//
+// If copying fails, the objects already created in the destination are deleted, and Created lists
+// those that cannot be deleted.  If deleting the source fails after it is copied, the destination is
+// complete, and NotDeleted lists the objects that remain in the source.
+type MoveError struct {
+	Src        string   // path of the secret/folder moved
+	Dst        string   // path of the destination
+	Created    []string // paths in the destination that are created and not deleted
+	NotDeleted []string // paths in the source that are not deleted
+	Err        error    // error that stops the move
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("%v: [%s] to [%s]: %v", ErrMoveIncomplete, e.Src, e.Dst, e.Err)
+}
+
+// Unwrap returns the error that stops the move.
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether 'target' is ErrMoveIncomplete
+func (e *MoveError) Is(target error) bool {
+	return target == ErrMoveIncomplete
+}
+
+// moveItem is a secret or folder that is moved by copying it
+type moveItem struct {
+	src   string      // path in source
+	dst   string      // path in destination
+	info  *MetaData   // metadata of object
+	value interface{} // value of secret.  nil for folder
+}
+
+// checkMove cleans 'srcPath' and 'dstPath', and checks that a secret or folder can be moved from
+// 'srcPath' to 'dstPath'
+func checkMove(srcPath string, dstPath string) (string, string, error) {
+	src := strings.Trim(srcPath, "/")
+	dst := strings.Trim(dstPath, "/")
+	if src == "" || dst == "" {
+		return "", "", ErrBadPathName
+	}
+	if strings.HasPrefix(dst, src+"/") {
+		return "", "", fmt.Errorf("Cannot move [%s] into itself: %w", src, ErrBadPathName)
+	}
+	return src, dst, nil
+}
+
+// renamePath returns the path of the secret or folder in 'path' after it is renamed to 'newName'
+func renamePath(path string, newName string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" || strings.TrimSpace(newName) == "" || strings.Contains(newName, "/") {
+		return "", ErrBadPathName
+	}
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i+1] + newName, nil
+	}
+	return newName, nil
+}
+
+// moveByCopy moves the secret or folder in 'srcPath' to 'dstPath' in 'cl', by copying it and its
+// contents to 'dstPath' and deleting it from 'srcPath'.  It is used by secret stores that cannot
+// move secrets.  It returns the ID of the secret or folder created in 'dstPath'.
+func moveByCopy(ctx context.Context, cl Secret, srcPath string, dstPath string) (string, *http.Response, error) {
+	src, dst, err := checkMove(srcPath, dstPath)
+	if err != nil {
+		return "", nil, err
+	}
+	if src == dst {
+		info, r, err := cl.GetMetaDataContext(ctx, src)
+		if err != nil {
+			return "", r, err
+		}
+		return info.ID, r, nil
+	}
+
+	// read the source before making any change
+	var items []moveItem
+	err = WalkContext(ctx, cl, src, &WalkOptions{MetaData: true}, func(path string, info *MetaData, err error) error {
+		if err != nil {
+			return err
+		}
+		item := moveItem{src: path, dst: dst, info: info}
+		if path != src {
+			item.dst = joinPath(dst, relativePath(src, path))
+		}
+		if !strings.EqualFold(info.Type, SecretTypeFolder) {
+			item.value, _, err = cl.GetContext(ctx, path)
+			if err != nil {
+				return err
+			}
+		}
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	// copy folders before their contents
+	var id string
+	var r *http.Response
+	created := make([]string, 0, len(items))
+	for _, item := range items {
+		var itemID string
+		if item.value == nil {
+			_, itemID, r, err = cl.CreateFolderContext(ctx, item.dst, item.info.Description)
+		} else {
+			_, itemID, r, err = cl.CreateContext(ctx, item.dst, item.info.Description, item.value)
+		}
+		if err != nil {
+			return "", r, undoCopy(ctx, cl, src, dst, created, err)
+		}
+		if id == "" {
+			id = itemID
+		}
+		created = append(created, item.dst)
+	}
+
+	// delete contents of folders before the folders.  A folder is not deleted if any of its
+	// contents is not deleted.
+	var moveErr *MoveError
+	for i := len(items) - 1; i >= 0; i-- {
+		var deleteErr error
+		if r, deleteErr = cl.DeleteContext(ctx, items[i].src); deleteErr == nil {
+			continue
+		}
+		if moveErr == nil {
+			moveErr = &MoveError{Src: src, Dst: dst, Err: deleteErr}
+		}
+		moveErr.NotDeleted = append([]string{items[i].src}, moveErr.NotDeleted...)
+	}
+	if moveErr != nil {
+		return id, r, moveErr
+	}
+	return id, r, nil
+}
+
+// undoCopy deletes the objects in 'created' after copying fails with 'err'.  It returns 'err' if all
+// of them are deleted.  Otherwise a MoveError is returned.
+func undoCopy(ctx context.Context, cl Secret, src string, dst string, created []string, err error) error {
+	for i := len(created) - 1; i >= 0; i-- {
+		if _, deleteErr := cl.DeleteContext(ctx, created[i]); deleteErr != nil {
+			return &MoveError{Src: src, Dst: dst, Created: created[:i+1], Err: err}
+		}
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// SecretMoveTestSuite tests Move and Rename, and the copy-and-delete fallback used by secret stores
+// that cannot move secrets.
+type SecretMoveTestSuite struct {
+	memorySuite
+}
+
+// failingClient is a Secret that fails to create, delete or list some paths
+type failingClient struct {
+	Secret
+	failCreate map[string]bool // paths that cannot be created
+	failDelete map[string]bool // paths that cannot be deleted
+	failList   map[string]bool // folders that cannot be listed
+}
+
+func (c *failingClient) CreateContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	if c.failCreate[path] {
+		return false, "", nil, ErrNoCreatePermission
+	}
+	return c.Secret.CreateContext(ctx, path, description, value)
+}
+
+func (c *failingClient) DeleteContext(ctx context.Context, path string) (*http.Response, error) {
+	if c.failDelete[path] {
+		return nil, ErrNoDeletePermission
+	}
+	return c.Secret.DeleteContext(ctx, path)
+}
+
+func (c *failingClient) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	if c.failList[path] {
+		return nil, nil, ErrNoRetrievePermission
+	}
+	return c.Secret.ListContext(ctx, path)
+}
+
+func TestSecretMoveTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretMoveTestSuite))
+}
+
+func (s *SecretMoveTestSuite) SetupTest() {
+	s.memorySuite.SetupTest()
+	s.handle.CreateFolder("app", "application")
+	s.handle.Create("app/db", "database", "password")
+	s.handle.Create("app/config/api", "", map[string]string{"key": "value"})
+}
+
+// checkMoved checks that the tree created in SetupTest is in 'path' and not in "app"
+func (s *SecretMoveTestSuite) checkMoved(path string) {
+	metadata, _, err := s.handle.GetMetaData(path)
+	s.Require().NoError(err, "Folder should be moved to [%s]", path)
+	s.Assert().Equal("application", metadata.Description)
+	value, _, err := s.handle.Get(path + "/db")
+	s.Require().NoError(err, "Secret should be moved with folder")
+	s.Assert().Equal("password", value)
+	metadata, _, _ = s.handle.GetMetaData(path + "/db")
+	s.Assert().Equal("database", metadata.Description)
+	value, _, _ = s.handle.Get(path + "/config/api")
+	s.Assert().Equal(map[string]string{"key": "value"}, value)
+	_, _, err = s.handle.GetMetaData("app")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Source should be deleted")
+}
+
+func (s *SecretMoveTestSuite) TestMove() {
+	metadata, _, _ := s.handle.GetMetaData("app/db")
+	id, r, err := s.handle.Move("app/db", "/other/db/")
+	s.Require().NoError(err, "Should move secret")
+	s.Assert().Equal(metadata.ID, id, "Secret should keep its ID")
+	s.Assert().Equal(200, r.StatusCode)
+	value, _, _ := s.handle.Get("other/db")
+	s.Assert().Equal("password", value)
+
+	_, _, err = s.handle.Move("app", "other")
+	s.Assert().ErrorIs(err, ErrExists)
+	_, _, err = s.handle.Move("app", "app/config/app")
+	s.Assert().ErrorIs(err, ErrBadPathName, "Folder cannot be moved into itself")
+	_, _, err = s.handle.Move("/", "root")
+	s.Assert().ErrorIs(err, ErrBadPathName)
+	_, _, err = s.handle.Move("missing", "found")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretMoveTestSuite) TestRename() {
+	metadata, _, _ := s.handle.GetMetaData("app/config")
+	id, _, err := s.handle.Rename("app/config", "settings")
+	s.Require().NoError(err, "Should rename folder")
+	s.Assert().Equal(metadata.ID, id)
+	_, _, err = s.handle.Get("app/settings/api")
+	s.Assert().NoError(err, "Contents should be in renamed folder")
+
+	_, _, err = s.handle.Rename("app", "renamed")
+	s.Require().NoError(err, "Should rename top level folder")
+	_, _, err = s.handle.Get("renamed/db")
+	s.Assert().NoError(err)
+
+	for _, name := range []string{"", "a/b", "  "} {
+		_, _, err = s.handle.Rename("renamed/db", name)
+		s.Assert().ErrorIs(err, ErrBadPathName, "Name [%s] should be rejected", name)
+	}
+}
+
+func (s *SecretMoveTestSuite) TestMoveByCopy() {
+	metadata, _, _ := s.handle.GetMetaData("app")
+	id, _, err := moveByCopy(context.Background(), s.handle, "app", "copied/app")
+	s.Require().NoError(err, "Should move folder by copying")
+	s.Assert().NotEqual(metadata.ID, id, "Copy should have new ID")
+	created, _, _ := s.handle.GetMetaData("copied/app")
+	s.Assert().Equal(created.ID, id, "ID of copy should be returned")
+	s.checkMoved("copied/app")
+
+	id, _, err = moveByCopy(context.Background(), s.handle, "copied/app", "copied/app")
+	s.Require().NoError(err, "Moving to the same path should do nothing")
+	s.Assert().Equal(created.ID, id)
+	_, _, err = moveByCopy(context.Background(), s.handle, "copied", "copied/app/x")
+	s.Assert().ErrorIs(err, ErrBadPathName)
+}
+
+func (s *SecretMoveTestSuite) TestCopyFails() {
+	cl := &failingClient{Secret: s.handle, failCreate: map[string]bool{"new/db": true}}
+	_, _, err := moveByCopy(context.Background(), cl, "app", "new")
+	s.Assert().ErrorIs(err, ErrNoCreatePermission)
+	s.Assert().NotErrorIs(err, ErrMoveIncomplete, "Copy should be undone")
+	_, _, err = s.handle.GetMetaData("new/config")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Copies should be deleted")
+	_, _, err = s.handle.Get("app/db")
+	s.Assert().NoError(err, "Source should be kept")
+
+	cl.failDelete = map[string]bool{"new": true}
+	_, _, err = moveByCopy(context.Background(), cl, "app", "new")
+	s.Assert().ErrorIs(err, ErrMoveIncomplete)
+	var moveErr *MoveError
+	s.Require().True(errors.As(err, &moveErr))
+	s.Assert().ErrorIs(moveErr.Err, ErrNoCreatePermission)
+	s.Assert().Equal([]string{"new"}, moveErr.Created, "Copy that cannot be deleted should be reported")
+	s.Assert().Empty(moveErr.NotDeleted)
+}
+
+func (s *SecretMoveTestSuite) TestDeleteFails() {
+	cl := &failingClient{Secret: s.handle, failDelete: map[string]bool{"app/db": true}}
+	id, _, err := moveByCopy(context.Background(), cl, "app", "new")
+	s.Assert().ErrorIs(err, ErrMoveIncomplete)
+	s.Assert().ErrorIs(err, ErrNoDeletePermission)
+	s.Assert().NotEmpty(id, "ID of copy should be returned")
+	var moveErr *MoveError
+	s.Require().True(errors.As(err, &moveErr))
+	s.Assert().Equal("app", moveErr.Src)
+	s.Assert().Equal("new", moveErr.Dst)
+	s.Assert().Equal([]string{"app", "app/db"}, moveErr.NotDeleted)
+	s.Assert().Empty(moveErr.Created)
+	s.Assert().Equal("Secret/folder is only partly moved: [app] to [new]: No permission to delete secret/folder", err.Error())
+
+	_, _, err = s.handle.Get("new/db")
+	s.Assert().NoError(err, "Destination should be complete")
+	_, _, err = s.handle.Get("app/config/api")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Objects that can be deleted should be deleted")
+}
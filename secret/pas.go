@@ -3,14 +3,21 @@ package secret
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/centrify/platform-go-sdk/internal/secretinternal"
 )
 
+// maxListLimit is the maximum number of items that PAS returns in a page
+const maxListLimit = 100
+
 // PASSecretClient implements the Secrets interface where the secret is stored in PAS
 type PASSecretClient struct {
 	apiClient   *secretinternal.APIClient
@@ -18,6 +25,14 @@ type PASSecretClient struct {
 	accessToken string       // access token
 	tenantURL   string       // tenant URL
 	debug       bool         // whether debug is on/off
+	retryPolicy *RetryPolicy // policy to retry transient errors.  nil means no retry
+
+	maxBinarySize int  // maximum size of binary values.  0 means DefaultMaxBinarySize
+	decodeBinary  bool // whether Get returns binary values as []byte.  See WithBinaryValues
+
+	// noPatchMove is set to 1 when PAS does not rename secrets in PATCH requests.  It is accessed
+	// atomically.
+	noPatchMove int32
 }
 
 // newPASSecretClient creates a new client handle for calling other functions in the secret package to
@@ -45,7 +60,9 @@ func newPASSecretClient(tenantURL string, accessToken string, httpFactory HTTPCl
 	cfg.AddDefaultHeader("X-CENTRIFY-NATIVE-CLIENT", "Yes")
 
 	// add Oauth token
-	cfg.AddDefaultHeader("Authorization", "Bearer "+accessToken)
+	if accessToken != "" {
+		cfg.AddDefaultHeader("Authorization", "Bearer "+accessToken)
+	}
 
 	apiClient := secretinternal.NewAPIClient(cfg)
 	cl := &PASSecretClient{
@@ -66,7 +83,22 @@ func newPASSecretClient(tenantURL string, accessToken string, httpFactory HTTPCl
 //	 ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
 //				technical support.
 func (c *PASSecretClient) Get(path string) (interface{}, *http.Response, error) {
-	data, r, err := c.apiClient.SecretsApi.RetrieveExecute(c.apiClient.SecretsApi.Retrieve(context.Background(), path))
+	return c.GetContext(context.Background(), path)
+}
+
+// GetContext is the same as Get, but uses 'ctx' for the REST API request.
+func (c *PASSecretClient) GetContext(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	var value interface{}
+	r, err := c.retry(ctx, true, func() (r *http.Response, err error) {
+		value, r, err = c.get(ctx, path)
+		return r, err
+	})
+	return decodeValue(value, c.decodeBinary), r, pasError("Get", path, r, err)
+}
+
+// get returns the secret content in a single attempt
+func (c *PASSecretClient) get(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	data, r, err := c.apiClient.SecretsApi.RetrieveExecute(c.apiClient.SecretsApi.Retrieve(ctx, path))
 	if err != nil {
 		if r != nil {
 			// handle common error cases
@@ -79,7 +111,7 @@ func (c *PASSecretClient) Get(path string) (interface{}, *http.Response, error)
 				return nil, r, ErrUnexpectedResponse
 			}
 		}
-		return nil, r, err
+		return nil, r, contextError(ctx, err)
 	}
 
 	switch data.Type {
@@ -120,7 +152,26 @@ func (c *PASSecretClient) Get(path string) (interface{}, *http.Response, error)
 //	 ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
 //				technical support.
 func (c *PASSecretClient) Create(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.CreateContext(context.Background(), path, description, value)
+}
+
+// CreateContext is the same as Create, but uses 'ctx' for the REST API request.
+func (c *PASSecretClient) CreateContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, pasError("Create", path, nil, err)
+	}
+	var success bool
+	var id string
+	r, err := c.retry(ctx, false, func() (r *http.Response, err error) {
+		success, id, r, err = c.create(ctx, path, description, value)
+		return r, err
+	})
+	return success, id, r, pasError("Create", path, r, err)
+}
 
+// create creates a secret in a single attempt
+func (c *PASSecretClient) create(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
 	var secretType secretinternal.Secrettypes
 
 	switch value.(type) {
@@ -132,12 +183,14 @@ func (c *PASSecretClient) Create(path string, description string, value interfac
 		return false, "", nil, ErrSecretTypeNotSupported
 	}
 
-	req := c.apiClient.SecretsApi.SecretsCreate(context.Background())
+	req := c.apiClient.SecretsApi.SecretsCreate(ctx)
 	if secretType == secretinternal.TEXT {
 		textSecret := secretinternal.NewSecretTextWritable(value.(string), secretType, path)
+		textSecret.AdditionalProperties = descriptionProperty(description)
 		req = req.SecretWritable(textSecret)
 	} else {
 		bagSecret := secretinternal.NewSecretBagWritable(value.(map[string]string), secretType, path)
+		bagSecret.AdditionalProperties = descriptionProperty(description)
 		req = req.SecretWritable(bagSecret)
 	}
 	resp, r, err := req.Execute()
@@ -174,7 +227,7 @@ func (c *PASSecretClient) Create(path string, description string, value interfac
 		}
 	}
 
-	return false, "", r, err
+	return false, "", r, contextError(ctx, err)
 }
 
 // CreateFolder creates a secret folder in 'path', with optional description.
@@ -191,10 +244,26 @@ func (c *PASSecretClient) Create(path string, description string, value interfac
 //	 ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
 //				technical support.
 func (c *PASSecretClient) CreateFolder(path string, description string) (bool, string, *http.Response, error) {
+	return c.CreateFolderContext(context.Background(), path, description)
+}
+
+// CreateFolderContext is the same as CreateFolder, but uses 'ctx' for the REST API request.
+func (c *PASSecretClient) CreateFolderContext(ctx context.Context, path string, description string) (bool, string, *http.Response, error) {
+	var success bool
+	var id string
+	r, err := c.retry(ctx, false, func() (r *http.Response, err error) {
+		success, id, r, err = c.createFolder(ctx, path, description)
+		return r, err
+	})
+	return success, id, r, pasError("CreateFolder", path, r, err)
+}
 
+// createFolder creates a secret folder in a single attempt
+func (c *PASSecretClient) createFolder(ctx context.Context, path string, description string) (bool, string, *http.Response, error) {
 	secretType := secretinternal.FOLDER
-	req := c.apiClient.SecretsApi.SecretsCreate(context.Background())
+	req := c.apiClient.SecretsApi.SecretsCreate(ctx)
 	writable := secretinternal.NewSecretFolderWritable(secretType, path)
+	writable.AdditionalProperties = descriptionProperty(description)
 	req = req.SecretWritable(writable)
 
 	resp, r, err := req.Execute()
@@ -224,7 +293,7 @@ func (c *PASSecretClient) CreateFolder(path string, description string) (bool, s
 			}
 		}
 
-		return false, "", r, err
+		return false, "", r, contextError(ctx, err)
 	}
 
 	if resp.Meta.Id != nil {
@@ -248,7 +317,22 @@ func (c *PASSecretClient) CreateFolder(path string, description string) (bool, s
 //	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
 //		technical support.
 func (c *PASSecretClient) List(path string) ([]Item, *http.Response, error) {
-	req := c.apiClient.SecretsApi.Get(context.Background(), path)
+	return c.ListContext(context.Background(), path)
+}
+
+// ListContext is the same as List, but uses 'ctx' for the REST API request.
+func (c *PASSecretClient) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	var items []Item
+	r, err := c.retry(ctx, true, func() (r *http.Response, err error) {
+		items, r, err = c.list(ctx, path)
+		return r, err
+	})
+	return items, r, pasError("List", path, r, err)
+}
+
+// list lists the secrets in a folder in a single attempt
+func (c *PASSecretClient) list(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	req := c.apiClient.SecretsApi.Get(ctx, path)
 
 	resp, r, err := c.apiClient.SecretsApi.GetExecute(req)
 	if err != nil {
@@ -262,7 +346,7 @@ func (c *PASSecretClient) List(path string) ([]Item, *http.Response, error) {
 			}
 		}
 
-		return nil, r, err
+		return nil, r, contextError(ctx, err)
 	}
 	res := resp.SecretWritable
 	if res.Type != secretinternal.FOLDER {
@@ -321,6 +405,111 @@ func (c *PASSecretClient) List(path string) ([]Item, *http.Response, error) {
 	return retItems, r, nil
 }
 
+// ListSecrets lists the secrets that match the search, filter and ordering options in 'opts'.
+// 'opts' can be nil, which lists all secrets that the caller can access.
+// The results are returned in pages by PAS.  ListSecrets retrieves the pages one at a time
+// by following the next_url in each page, and calls 'fn' for each item.
+// If 'fn' returns an error, the listing stops and the error is returned.
+// Returns the following:
+//  response: the HTTP response of the last page retrieved
+// the following errors may be returned:
+//	ErrInvalidListOption: PAS rejects the options in 'opts'.
+//	ErrNoGetMetaDataPermission:  The caller has no permission to list secrets.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
+//		technical support.
+func (c *PASSecretClient) ListSecrets(opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	return c.ListSecretsContext(context.Background(), opts, fn)
+}
+
+// ListSecretsContext is the same as ListSecrets, but uses 'ctx' for the REST API requests.
+func (c *PASSecretClient) ListSecretsContext(ctx context.Context, opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	if opts.Limit < 0 || opts.Limit > maxListLimit {
+		return nil, pasError("ListSecrets", "", nil, fmt.Errorf("Limit must be between 1 and %d: %w", maxListLimit, ErrInvalidListOption))
+	}
+
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(int(opts.Limit)))
+	}
+	if len(opts.OrderBy) > 0 {
+		query.Set("orderBy", strings.Join(opts.OrderBy, ","))
+	}
+	if opts.Search != "" {
+		query.Set("search", opts.Search)
+	}
+	if opts.Filter != "" {
+		query.Set("filter", opts.Filter)
+	}
+	var page secretinternal.SecretList
+	r, err := c.retry(ctx, true, func() (r *http.Response, err error) {
+		page, r, err = c.apiClient.SecretsListQuery(ctx, query)
+		return r, listSecretsError(ctx, r, err)
+	})
+
+	// pages already retrieved, used to detect paging loop
+	visited := make(map[string]bool)
+	for {
+		if err != nil {
+			return r, pasError("ListSecrets", "", r, err)
+		}
+		if c.debug {
+			log.Printf("Number of items returned in page: %d\n", len(page.Items))
+		}
+
+		for _, sparse := range page.Items {
+			item := Item{
+				Name: sparse.Name,
+				ID:   sparse.Id,
+			}
+			if t, ok := sparse.AdditionalProperties["type"].(string); ok {
+				item.Type = t
+			}
+			if err = fn(item); err != nil {
+				return r, err
+			}
+		}
+
+		next := page.GetNextUrl()
+		if next == "" {
+			// no more pages
+			return r, nil
+		}
+		if visited[next] {
+			return r, pasError("ListSecrets", "", r, fmt.Errorf("Page [%s] is returned more than once: %w", next, ErrUnexpectedResponse))
+		}
+		visited[next] = true
+		r, err = c.retry(ctx, true, func() (r *http.Response, err error) {
+			page, r, err = c.apiClient.SecretsListPage(ctx, next)
+			return r, listSecretsError(ctx, r, err)
+		})
+	}
+}
+
+// listSecretsError maps the error in retrieving a page of secrets to the error returned by ListSecrets
+func listSecretsError(ctx context.Context, r *http.Response, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, secretinternal.ErrPageURLNotOnServer) {
+		return fmt.Errorf("%v: %w", err, ErrUnexpectedResponse)
+	}
+	if r != nil {
+		// map HTTP status into specific error
+		switch r.StatusCode {
+		case 400, 422: // bad request or invalid parameter
+			return ErrInvalidListOption
+		case 401: // unauthorized
+			return ErrNoGetMetaDataPermission
+		default:
+			return ErrUnexpectedResponse
+		}
+	}
+	return contextError(ctx, err)
+}
+
 // Delete deletes the folder/secret specified in 'path'
 // Returns the following information:
 //  response: the actual HTTP response
@@ -329,11 +518,28 @@ func (c *PASSecretClient) List(path string) ([]Item, *http.Response, error) {
 //	ErrNoDeletePermission: No permission to delete secret/folder
 //	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact technical support.
 func (c *PASSecretClient) Delete(path string) (*http.Response, error) {
-	req := c.apiClient.SecretsApi.Delete(context.Background(), path)
+	return c.DeleteContext(context.Background(), path)
+}
+
+// DeleteContext is the same as Delete, but uses 'ctx' for the REST API request.
+func (c *PASSecretClient) DeleteContext(ctx context.Context, path string) (*http.Response, error) {
+	r, err := c.retry(ctx, true, func() (*http.Response, error) {
+		return c.delete(ctx, path)
+	})
+	return r, pasError("Delete", path, r, err)
+}
+
+// delete deletes a secret or folder in a single attempt
+func (c *PASSecretClient) delete(ctx context.Context, path string) (*http.Response, error) {
+	req := c.apiClient.SecretsApi.Delete(ctx, path)
 	resp, err := c.apiClient.SecretsApi.DeleteExecute(req)
 	if err == nil {
 		return resp, err
 	}
+	if resp == nil {
+		// no response from server
+		return resp, contextError(ctx, err)
+	}
 
 	switch resp.StatusCode {
 	case 401: // unauthorized
@@ -366,7 +572,26 @@ func (c *PASSecretClient) Delete(path string) (*http.Response, error) {
 //	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
 //				technical support.
 func (c *PASSecretClient) Modify(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.ModifyContext(context.Background(), path, description, value)
+}
+
+// ModifyContext is the same as Modify, but uses 'ctx' for the REST API request.
+func (c *PASSecretClient) ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, pasError("Modify", path, nil, err)
+	}
+	var success bool
+	var id string
+	r, err := c.retry(ctx, true, func() (r *http.Response, err error) {
+		success, id, r, err = c.modify(ctx, path, description, value)
+		return r, err
+	})
+	return success, id, r, pasError("Modify", path, r, err)
+}
 
+// modify modifies a secret in a single attempt
+func (c *PASSecretClient) modify(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
 	var secretType secretinternal.Secrettypes
 
 	switch value.(type) {
@@ -378,12 +603,14 @@ func (c *PASSecretClient) Modify(path string, description string, value interfac
 		return false, "", nil, ErrSecretTypeNotSupported
 	}
 
-	req := c.apiClient.SecretsApi.Modify(context.Background(), path)
+	req := c.apiClient.SecretsApi.Modify(ctx, path)
 	if secretType == secretinternal.TEXT {
 		textSecret := secretinternal.NewSecretTextPatchable(value.(string), secretType)
+		textSecret.AdditionalProperties = descriptionProperty(description)
 		req = req.SecretPatchable(textSecret)
 	} else {
 		bagSecret := secretinternal.NewSecretBagPatchable(value.(map[string]string), secretType)
+		bagSecret.AdditionalProperties = descriptionProperty(description)
 		req = req.SecretPatchable(bagSecret)
 	}
 	resp, r, err := req.Execute()
@@ -397,7 +624,7 @@ func (c *PASSecretClient) Modify(path string, description string, value interfac
 		case 401: // unauthorized
 			return false, "", r, ErrNoModifyPermission
 		case 404: // not found, or it may be that the path specifies a folder
-			metadata, _, err2 := c.GetMetaData(path)
+			metadata, _, err2 := c.GetMetaDataContext(ctx, path)
 			if err2 != nil {
 				// cannot get metadata for the object
 				return false, "", r, ErrSecretNotFound
@@ -413,7 +640,296 @@ func (c *PASSecretClient) Modify(path string, description string, value interfac
 			return false, "", r, ErrCannotModifySecretType
 		}
 	}
-	return false, "", r, err
+	return false, "", r, contextError(ctx, err)
+}
+
+// ModifyMetaData modifies the description of the secret or folder in 'path' without changing its
+// value.  If 'description' is not an empty string, it replaces the current description.  PAS does
+// not keep attributes, so the attributes in MetaData cannot be modified.
+//
+// The description is sent in a PATCH request without the value.  If PAS requires the value of a
+// secret in the request, the current value is sent back with the new description.
+// Returns the following information:
+//  response: the HTTP response of the last request
+// The following errors may be returned:
+//	ErrMetaDataNotSupported: 'attributes' is not empty, or PAS cannot modify the description of the folder
+//	ErrNoModifyPermission: No permission to modify secret
+//	ErrSecretNotFound: secret cannot be found
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
+//				technical support.
+func (c *PASSecretClient) ModifyMetaData(path string, description string, attributes map[string]string) (*http.Response, error) {
+	return c.ModifyMetaDataContext(context.Background(), path, description, attributes)
+}
+
+// ModifyMetaDataContext is the same as ModifyMetaData, but uses 'ctx' for the REST API requests.
+func (c *PASSecretClient) ModifyMetaDataContext(ctx context.Context, path string, description string, attributes map[string]string) (*http.Response, error) {
+	if len(attributes) > 0 {
+		return nil, pasError("ModifyMetaData", path, nil, ErrMetaDataNotSupported)
+	}
+	r, err := c.retry(ctx, true, func() (*http.Response, error) {
+		return c.modifyMetaData(ctx, path, description)
+	})
+	return r, pasError("ModifyMetaData", path, r, err)
+}
+
+// modifyMetaData modifies the description of a secret or folder in a single attempt
+func (c *PASSecretClient) modifyMetaData(ctx context.Context, path string, description string) (*http.Response, error) {
+	metadata, r, err := c.getMetaData(ctx, path)
+	if err != nil || description == "" {
+		return r, err
+	}
+
+	patch := secretinternal.NewSecretPatchable(secretinternal.Secrettypes(metadata.Type))
+	patch.AdditionalProperties = descriptionProperty(description)
+	_, r, err = c.apiClient.SecretsApi.Modify(ctx, path).SecretPatchable(patch).Execute()
+	if err == nil {
+		return r, nil
+	}
+	if r != nil {
+		switch r.StatusCode {
+		case 400, 422: // value is required
+			if metadata.Type == SecretTypeFolder {
+				return r, ErrMetaDataNotSupported
+			}
+			value, r, err := c.get(ctx, path)
+			if err != nil {
+				return r, err
+			}
+			_, _, r, err = c.modify(ctx, path, description, value)
+			return r, err
+		case 401: // unauthorized
+			return r, ErrNoModifyPermission
+		case 404: // not found
+			return r, ErrSecretNotFound
+		default:
+			return r, ErrUnexpectedResponse
+		}
+	}
+	return r, contextError(ctx, err)
+}
+
+// errPatchMoveNotSupported is returned by patchMove when PAS does not rename secrets in PATCH requests
+var errPatchMoveNotSupported = errors.New("PAS does not rename secrets in PATCH requests")
+
+// Move moves the secret or folder in 'srcPath', including the contents of a folder, to 'dstPath'.
+// Missing parent folders of 'dstPath' are created.
+//
+// The secret or folder is renamed with the name field of the PATCH request, so it keeps its ID.
+// If PAS does not support it, i.e., it answers with status 405 or 501, or ignores the name, the
+// secret or folder is copied to 'dstPath' and deleted from 'srcPath' instead, which gives the copies
+// new IDs.  Parent folders created for the PATCH request are deleted if it fails.  If copying fails,
+// the copies already created are deleted.  If the copy cannot be completed or undone, a MoveError is
+// returned that describes what is left in the source and destination.
+//
+// Returns the following information:
+//  id: the unique ID of the secret or folder in 'dstPath'
+//  response: the HTTP response of the last request
+// The following errors may be returned:
+//	ErrBadPathName: 'srcPath' or 'dstPath' is invalid or not accepted by PAS, or 'dstPath' is in the
+//		folder 'srcPath'
+//	ErrExists: A secret or folder already exists in 'dstPath'
+//	ErrMoveIncomplete: The secret or folder is only partly moved.  The error is a MoveError.
+//	ErrNoModifyPermission: No permission to modify the secret or folder
+//	ErrSecretNotFound: Secret or folder specified in 'srcPath' cannot be found
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
+//		technical support.
+func (c *PASSecretClient) Move(srcPath string, dstPath string) (string, *http.Response, error) {
+	return c.MoveContext(context.Background(), srcPath, dstPath)
+}
+
+// MoveContext is the same as Move, but uses 'ctx' for the REST API requests.
+func (c *PASSecretClient) MoveContext(ctx context.Context, srcPath string, dstPath string) (string, *http.Response, error) {
+	src, dst, err := checkMove(srcPath, dstPath)
+	if err != nil {
+		return "", nil, pasError("Move", srcPath, nil, err)
+	}
+	if atomic.LoadInt32(&c.noPatchMove) == 0 {
+		var id string
+		r, err := c.retry(ctx, false, func() (r *http.Response, err error) {
+			id, r, err = c.patchMove(ctx, src, dst)
+			return r, err
+		})
+		if !errors.Is(err, errPatchMoveNotSupported) {
+			return id, r, pasError("Move", srcPath, r, err)
+		}
+		if c.debug {
+			log.Printf("Move [%s] by copying it: %v\n", srcPath, err)
+		}
+		atomic.StoreInt32(&c.noPatchMove, 1)
+	}
+	// errors of the requests already have the details of the responses
+	id, r, err := moveByCopy(ctx, c, src, dst)
+	return id, r, pasError("Move", srcPath, nil, err)
+}
+
+// patchMove renames a secret or folder in a PATCH request in a single attempt
+func (c *PASSecretClient) patchMove(ctx context.Context, src string, dst string) (string, *http.Response, error) {
+	metadata, r, err := c.getMetaData(ctx, src)
+	if err != nil {
+		return "", r, err
+	}
+	if src == dst {
+		return metadata.ID, r, nil
+	}
+	created, r, err := c.createParents(ctx, dst)
+	if err != nil {
+		return "", r, err
+	}
+
+	patch := secretinternal.NewSecretPatchable(secretinternal.Secrettypes(metadata.Type))
+	patch.AdditionalProperties = map[string]interface{}{"name": dst}
+	resp, r, err := c.apiClient.SecretsApi.Modify(ctx, src).SecretPatchable(patch).Execute()
+	if err == nil && strings.Trim(resp.Name, "/") == dst {
+		return metadata.ID, r, nil
+	}
+	// the parent folders are not needed if the secret is not moved, or is moved by copying it
+	c.deleteFolders(ctx, created)
+	if err == nil {
+		// name is ignored
+		return "", r, errPatchMoveNotSupported
+	}
+	if r != nil {
+		switch r.StatusCode {
+		case 405, 501: // PATCH or name is not supported
+			return "", r, errPatchMoveNotSupported
+		case 400, 422: // name is not accepted
+			return "", r, ErrBadPathName
+		case 401: // unauthorized
+			return "", r, ErrNoModifyPermission
+		case 404: // not found
+			return "", r, ErrSecretNotFound
+		case 409: // conflict
+			return "", r, ErrExists
+		default:
+			return "", r, ErrUnexpectedResponse
+		}
+	}
+	return "", r, contextError(ctx, err)
+}
+
+// createParents creates the missing parent folders of 'path', as PAS does not create them when a
+// secret or folder is renamed.  It returns the paths of the folders it created, parents first.  If it
+// fails, the folders it created are deleted.
+func (c *PASSecretClient) createParents(ctx context.Context, path string) ([]string, *http.Response, error) {
+	segments := strings.Split(path, "/")
+	// find the nearest parent that exists
+	n := len(segments) - 1
+	var r *http.Response
+	for ; n > 0; n-- {
+		var metadata *MetaData
+		var err error
+		metadata, r, err = c.getMetaData(ctx, strings.Join(segments[:n], "/"))
+		if err == nil {
+			if !strings.EqualFold(metadata.Type, SecretTypeFolder) {
+				return nil, r, fmt.Errorf("[%s] is not a folder: %w", strings.Join(segments[:n], "/"), ErrBadPathName)
+			}
+			break
+		}
+		if !errors.Is(err, ErrSecretNotFound) {
+			return nil, r, err
+		}
+	}
+	var created []string
+	for n++; n < len(segments); n++ {
+		folder := strings.Join(segments[:n], "/")
+		_, _, r, err := c.createFolder(ctx, folder, "")
+		if err == nil {
+			created = append(created, folder)
+		} else if !errors.Is(err, ErrExists) {
+			c.deleteFolders(ctx, created)
+			return nil, r, err
+		}
+	}
+	return created, r, nil
+}
+
+// deleteFolders deletes the folders in 'paths' created by createParents, children first.  Errors are
+// ignored, as the folders are only left behind if they cannot be deleted.
+func (c *PASSecretClient) deleteFolders(ctx context.Context, paths []string) {
+	for i := len(paths) - 1; i >= 0; i-- {
+		if _, err := c.DeleteContext(ctx, paths[i]); err != nil && c.debug {
+			log.Printf("Delete folder [%s] created for move: %v\n", paths[i], err)
+		}
+	}
+}
+
+// Rename renames the secret or folder in 'path' to 'newName' in the same folder.  It is the same as
+// Move to the new path.  ErrBadPathName is returned if 'newName' is empty or has "/".
+func (c *PASSecretClient) Rename(path string, newName string) (string, *http.Response, error) {
+	return c.RenameContext(context.Background(), path, newName)
+}
+
+// RenameContext is the same as Rename, but uses 'ctx' for the REST API requests.
+func (c *PASSecretClient) RenameContext(ctx context.Context, path string, newName string) (string, *http.Response, error) {
+	newPath, err := renamePath(path, newName)
+	if err != nil {
+		return "", nil, pasError("Rename", path, nil, err)
+	}
+	return c.MoveContext(ctx, path, newPath)
+}
+
+// PathOfID returns the current path of the secret or folder with the ID 'id'.  As PAS looks up
+// names before IDs, the ID is looked up by listing secrets if a secret or folder is named 'id'.
+// Returns the following information:
+//  path: the full path of the secret or folder
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrNoGetMetaDataPermission: No permission to get the secret or folder.
+//	ErrSecretNotFound: No secret or folder has the ID.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
+//		technical support.
+func (c *PASSecretClient) PathOfID(id string) (string, *http.Response, error) {
+	return c.PathOfIDContext(context.Background(), id)
+}
+
+// PathOfIDContext is the same as PathOfID, but uses 'ctx' for the REST API request.
+func (c *PASSecretClient) PathOfIDContext(ctx context.Context, id string) (string, *http.Response, error) {
+	var info *MetaData
+	r, err := c.retry(ctx, true, func() (r *http.Response, err error) {
+		info, r, err = c.getMetaData(ctx, id)
+		return r, err
+	})
+	if err == nil && info.ID != id {
+		// PAS looks up names before IDs, so 'id' is the path of another secret or folder
+		var path string
+		path, r, err = c.pathOfIDInList(ctx, id)
+		if err == nil {
+			return path, r, nil
+		}
+	}
+	if err != nil {
+		return "", r, pasError("PathOfID", id, r, err)
+	}
+	return strings.Trim(info.Name, "/"), r, nil
+}
+
+// errPASIDFound stops the listing in pathOfIDInList when the ID is found
+var errPASIDFound = errors.New("ID is found")
+
+// pathOfIDInList finds the path of the secret or folder with the ID 'id' by listing the secrets
+// with the ID, which cannot be shadowed by a secret or folder whose name is 'id'
+func (c *PASSecretClient) pathOfIDInList(ctx context.Context, id string) (string, *http.Response, error) {
+	var path string
+	filter := fmt.Sprintf("id eq '%s'", strings.ReplaceAll(id, "'", "''"))
+	r, err := c.ListSecretsContext(ctx, &ListOptions{Filter: filter}, func(item Item) error {
+		if item.ID == id {
+			path = strings.Trim(item.Name, "/")
+			return errPASIDFound
+		}
+		return nil
+	})
+	if path != "" {
+		return path, r, nil
+	}
+	var listErr *Error
+	if errors.As(err, &listErr) {
+		// the error is returned as an error of PathOfID
+		err = listErr.Err
+	}
+	if err != nil {
+		return "", r, err
+	}
+	return "", r, ErrSecretNotFound
 }
 
 // GetMetaData returns the metadata of a secret.
@@ -427,7 +943,22 @@ func (c *PASSecretClient) Modify(path string, description string, value interfac
 //	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
 //		technical support.
 func (c *PASSecretClient) GetMetaData(path string) (*MetaData, *http.Response, error) {
-	data, r, err := c.apiClient.SecretsApi.GetExecute(c.apiClient.SecretsApi.Get(context.Background(), path))
+	return c.GetMetaDataContext(context.Background(), path)
+}
+
+// GetMetaDataContext is the same as GetMetaData, but uses 'ctx' for the REST API request.
+func (c *PASSecretClient) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	var metadata *MetaData
+	r, err := c.retry(ctx, true, func() (r *http.Response, err error) {
+		metadata, r, err = c.getMetaData(ctx, path)
+		return r, err
+	})
+	return metadata, r, pasError("GetMetaData", path, r, err)
+}
+
+// getMetaData returns the metadata of a secret in a single attempt
+func (c *PASSecretClient) getMetaData(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	data, r, err := c.apiClient.SecretsApi.GetExecute(c.apiClient.SecretsApi.Get(ctx, path))
 	if err != nil {
 		// error
 		if r != nil {
@@ -439,7 +970,7 @@ func (c *PASSecretClient) GetMetaData(path string) (*MetaData, *http.Response, e
 				return nil, r, ErrSecretNotFound
 			}
 		}
-		return nil, r, err
+		return nil, r, contextError(ctx, err)
 	}
 
 	result := &MetaData{}
@@ -455,12 +986,26 @@ func (c *PASSecretClient) GetMetaData(path string) (*MetaData, *http.Response, e
 		return nil, r, fmt.Errorf("CRN should never be empty:%w", ErrUnexpectedResponse)
 	}
 	result.CRN = *data.Meta.Crn
+	result.Description = c.getDescriptionFromObject(&data.SecretWritable)
 	if data.Meta.Created != nil {
 		result.WhenCreated = *data.Meta.Created
 	}
 	if data.Meta.Modified != nil {
 		result.WhenModified = *data.Meta.Modified
 	}
+	for k, v := range data.Meta.AdditionalProperties {
+		switch k {
+		case "createdBy":
+			result.CreatedBy = attributeText(v)
+		case "modifiedBy":
+			result.ModifiedBy = attributeText(v)
+		default:
+			if result.Attributes == nil {
+				result.Attributes = make(map[string]string)
+			}
+			result.Attributes[k] = attributeText(v)
+		}
+	}
 
 	// extract information about secret metadata into result
 	return result, r, nil
@@ -485,6 +1030,12 @@ func (c *PASSecretClient) AddDefaultHeaders(hdrs map[string]string) {
 	c.apiClient.AddDefaultHeaders(hdrs)
 }
 
+// setBinaryOptions sets the maximum size of binary values, and whether Get decodes them
+func (c *PASSecretClient) setBinaryOptions(maxSize int, decode bool) {
+	c.maxBinarySize = maxSize
+	c.decodeBinary = decode
+}
+
 // getIDFromObject returns the ID that is returned in a secretinternal.SecretWritable object
 // in response
 func (c *PASSecretClient) getIDFromObject(obj *secretinternal.SecretWritable) (bool, string) {
@@ -508,6 +1059,25 @@ func (c *PASSecretClient) getIDFromObject(obj *secretinternal.SecretWritable) (b
 	return true, id.(string)
 }
 
+// getDescriptionFromObject returns the description that is returned in a secretinternal.SecretWritable
+// object in response.  An empty string is returned if there is no description.
+func (c *PASSecretClient) getDescriptionFromObject(obj *secretinternal.SecretWritable) string {
+	if obj == nil {
+		return ""
+	}
+	description, _ := obj.AdditionalProperties["description"].(string)
+	return description
+}
+
+// descriptionProperty returns the additional property that sets the description of a secret in
+// a request.  nil is returned if 'description' is empty.
+func descriptionProperty(description string) map[string]interface{} {
+	if description == "" {
+		return nil
+	}
+	return map[string]interface{}{"description": description}
+}
+
 // handleOpenAPIError checks if the error is a GenericOpenAPIError.
 // It returns true if it is and the associated "title" field in the error response.
 // Otherwise it returns false.
@@ -535,3 +1105,13 @@ func (c *PASSecretClient) handleOpenAPIError(err error) (bool, string) {
 	// cannot get error summary
 	return false, ""
 }
+
+// contextError returns the error in 'ctx' if it is canceled or its deadline has expired.
+// Otherwise 'err' is returned.  This allows the caller to tell a canceled or timed out request
+// apart from other transport errors.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
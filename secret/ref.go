@@ -0,0 +1,130 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SecretRef refers to a secret or folder either by its path or by its ID.  A reference by ID
+// stays valid when the secret is moved or renamed, and a path that happens to look like an ID,
+// e.g., a UUID, is never taken for one.  The zero value refers to the top level folder.
+type SecretRef struct {
+	id   string // ID of secret or folder.  Empty for a reference by path
+	path string // path of secret or folder
+}
+
+// ByPath returns a reference to the secret or folder in 'path'.
+func ByPath(path string) SecretRef {
+	return SecretRef{path: strings.Trim(path, "/")}
+}
+
+// ByID returns a reference to the secret or folder with the ID 'id', e.g., Item.ID returned by
+// List or MetaData.ID returned by GetMetaData.
+func ByID(id string) SecretRef {
+	return SecretRef{id: id}
+}
+
+// IsID returns whether the reference is by ID.
+func (ref SecretRef) IsID() bool {
+	return ref.id != ""
+}
+
+// String returns the path of a reference by path, or "id:" followed by the ID.
+func (ref SecretRef) String() string {
+	if ref.IsID() {
+		return "id:" + ref.id
+	}
+	return ref.path
+}
+
+// ResolveRef returns the current path of the secret or folder referred to by 'ref'.  A reference
+// by path is returned as is, without any request.
+//
+// The following errors may be returned, in addition to those returned by PathOfID:
+//	ErrSecretNotFound:  No secret or folder has the ID.
+func ResolveRef(cl Secret, ref SecretRef) (string, error) {
+	return ResolveRefContext(context.Background(), cl, ref)
+}
+
+// ResolveRefContext is the same as ResolveRef, but uses 'ctx' for the request.
+func ResolveRefContext(ctx context.Context, cl Secret, ref SecretRef) (string, error) {
+	if !ref.IsID() {
+		return ref.path, nil
+	}
+	path, _, err := cl.PathOfIDContext(ctx, ref.id)
+	if err != nil {
+		return "", fmt.Errorf("[%s]: %w", ref, err)
+	}
+	return path, nil
+}
+
+// GetRef is the same as Get, but the secret is referred to by 'ref'.
+//
+// A reference by ID is resolved to the current path of the secret first.  If the secret is moved
+// by another client in between, ErrSecretNotFound may be returned, or the secret that replaces
+// it in its previous path may be returned.  GetMetaDataRef does not have this problem.
+func GetRef(cl Secret, ref SecretRef) (interface{}, *http.Response, error) {
+	return GetRefContext(context.Background(), cl, ref)
+}
+
+// GetRefContext is the same as GetRef, but uses 'ctx' for the requests.
+func GetRefContext(ctx context.Context, cl Secret, ref SecretRef) (interface{}, *http.Response, error) {
+	path, err := ResolveRefContext(ctx, cl, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cl.GetContext(ctx, path)
+}
+
+// GetMetaDataRef is the same as GetMetaData, but the secret or folder is referred to by 'ref'.
+// For a reference by ID, ErrSecretNotFound is returned if the secret or folder found in its
+// current path does not have the ID.
+func GetMetaDataRef(cl Secret, ref SecretRef) (*MetaData, *http.Response, error) {
+	return GetMetaDataRefContext(context.Background(), cl, ref)
+}
+
+// GetMetaDataRefContext is the same as GetMetaDataRef, but uses 'ctx' for the requests.
+func GetMetaDataRefContext(ctx context.Context, cl Secret, ref SecretRef) (*MetaData, *http.Response, error) {
+	path, err := ResolveRefContext(ctx, cl, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, r, err := cl.GetMetaDataContext(ctx, path)
+	if err == nil && ref.IsID() && info.ID != ref.id {
+		// moved by another client after the ID is resolved
+		return nil, r, fmt.Errorf("[%s]: %w", ref, ErrSecretNotFound)
+	}
+	return info, r, err
+}
+
+// ModifyRef is the same as Modify, but the secret is referred to by 'ref'.  A reference by ID is
+// resolved to the current path of the secret first, as in GetRef.
+func ModifyRef(cl Secret, ref SecretRef, description string, value interface{}) (bool, string, *http.Response, error) {
+	return ModifyRefContext(context.Background(), cl, ref, description, value)
+}
+
+// ModifyRefContext is the same as ModifyRef, but uses 'ctx' for the requests.
+func ModifyRefContext(ctx context.Context, cl Secret, ref SecretRef, description string, value interface{}) (bool, string, *http.Response, error) {
+	path, err := ResolveRefContext(ctx, cl, ref)
+	if err != nil {
+		return false, "", nil, err
+	}
+	return cl.ModifyContext(ctx, path, description, value)
+}
+
+// DeleteRef is the same as Delete, but the secret or folder is referred to by 'ref'.  A reference
+// by ID is resolved to the current path first, as in GetRef.
+func DeleteRef(cl Secret, ref SecretRef) (*http.Response, error) {
+	return DeleteRefContext(context.Background(), cl, ref)
+}
+
+// DeleteRefContext is the same as DeleteRef, but uses 'ctx' for the requests.
+func DeleteRefContext(ctx context.Context, cl Secret, ref SecretRef) (*http.Response, error) {
+	path, err := ResolveRefContext(ctx, cl, ref)
+	if err != nil {
+		return nil, err
+	}
+	return cl.DeleteContext(ctx, path)
+}
@@ -0,0 +1,78 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretRefTestSuite struct {
+	memorySuite
+}
+
+func TestSecretRefTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretRefTestSuite))
+}
+
+func (s *SecretRefTestSuite) TestPathOfID() {
+	_, id, _, err := s.handle.Create("app/db", "", "value")
+	s.Require().NoError(err)
+	path, _, err := s.handle.PathOfID(id)
+	s.Require().NoError(err)
+	s.Assert().Equal("app/db", path)
+
+	info, _, _ := s.handle.GetMetaData("app")
+	path, _, err = s.handle.PathOfID(info.ID)
+	s.Require().NoError(err, "Should find folder by ID")
+	s.Assert().Equal("app", path)
+
+	_, _, err = s.handle.PathOfID("app/db")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Path should not be taken for ID")
+}
+
+func (s *SecretRefTestSuite) TestRefAfterRename() {
+	_, id, _, _ := s.handle.Create("app/db", "database", "v1")
+	ref := ByID(id)
+	s.Assert().True(ref.IsID())
+	s.Assert().Equal("id:"+id, ref.String())
+
+	_, _, err := s.handle.Rename("app/db", "database")
+	s.Require().NoError(err)
+	value, _, err := GetRef(s.handle, ref)
+	s.Require().NoError(err, "Reference by ID should survive rename")
+	s.Assert().Equal("v1", value)
+	info, _, err := GetMetaDataRef(s.handle, ref)
+	s.Require().NoError(err)
+	s.Assert().Equal(id, info.ID)
+	s.Assert().Equal("database", info.Description)
+
+	_, _, _, err = ModifyRef(s.handle, ref, "", "v2")
+	s.Require().NoError(err)
+	value, _, _ = s.handle.Get("app/database")
+	s.Assert().Equal("v2", value)
+
+	_, err = DeleteRef(s.handle, ref)
+	s.Require().NoError(err)
+	_, _, err = GetRef(s.handle, ref)
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	s.Assert().Contains(err.Error(), "[id:"+id+"]")
+}
+
+func (s *SecretRefTestSuite) TestRefByPath() {
+	// a name that looks like an ID of another secret
+	_, id, _, _ := s.handle.Create("target", "", "by id")
+	s.handle.Create(id, "", "by path")
+
+	value, _, err := GetRef(s.handle, ByPath("/"+id+"/"))
+	s.Require().NoError(err)
+	s.Assert().Equal("by path", value)
+	value, _, _ = GetRef(s.handle, ByID(id))
+	s.Assert().Equal("by id", value)
+	s.Assert().False(ByPath(id).IsID())
+	s.Assert().Equal(id, ByPath(id).String())
+
+	_, _, err = GetRef(s.handle, ByID("missing"))
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, _, _, err = ModifyRef(s.handle, ByPath("missing"), "", "value")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
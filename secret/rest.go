@@ -0,0 +1,205 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// keys used by secret stores that save secrets as JSON objects
+const (
+	secretTypeKey = "secret_type" // key in attributes/metadata that stores the type of secret
+	secretTextKey = "text"        // key in the data of a text secret that stores the text
+)
+
+// restClient sends JSON requests to the REST API of a secret store.  It is used by the secret
+// store clients that do not have a generated API client.  It implements SetDebug, SetUserAgent
+// and AddDefaultHeaders of the Secret interface.
+type restClient struct {
+	httpClient *http.Client      // http client
+	serverURL  string            // URL of server, e.g., https://vault.example.com:8200
+	headers    map[string]string // HTTP headers added to each request, including authorization
+	userAgent  string            // UserAgent in HTTP header
+	debug      bool              // whether debug is on/off
+
+	maxBinarySize int  // maximum size of binary values.  0 means DefaultMaxBinarySize
+	decodeBinary  bool // whether Get returns binary values as []byte.  See WithBinaryValues
+}
+
+// newRESTClient creates a restClient that sends requests to 'serverURL'
+func newRESTClient(serverURL string, httpFactory HTTPClientFactory) *restClient {
+	cl := &restClient{
+		serverURL: strings.TrimSuffix(serverURL, "/"),
+		headers:   make(map[string]string),
+	}
+	if httpFactory != nil {
+		cl.httpClient = httpFactory()
+	} else {
+		cl.httpClient = http.DefaultClient
+	}
+	return cl
+}
+
+// SetDebug enables/disables debug messages.  It dumps the HTTP request and response to the
+// standard logger.
+// DO NOT enable debugging in production environment as the full HTTP request
+// and response that may contain secret information are logged.
+func (c *restClient) SetDebug(onoff bool) {
+	c.debug = onoff
+}
+
+// SetUserAgent sets UserAgent in HTTP header
+func (c *restClient) SetUserAgent(agent string) {
+	c.userAgent = agent
+}
+
+// AddDefaultHeaders add extra headers to default HTTP request header
+func (c *restClient) AddDefaultHeaders(hdrs map[string]string) {
+	for name, value := range hdrs {
+		c.headers[name] = value
+	}
+}
+
+// setBinaryOptions sets the maximum size of binary values, and whether Get decodes them
+func (c *restClient) setBinaryOptions(maxSize int, decode bool) {
+	c.maxBinarySize = maxSize
+	c.decodeBinary = decode
+}
+
+// doRequest sends a request to the server.  If 'body' is not nil, it is sent as JSON.
+// If the request succeeds and 'result' is not nil, the response is decoded into 'result'.
+// An error is returned only when there is no response, or a successful response cannot be decoded.
+// The caller checks the status code for other errors.  The body of the response is kept in
+// the returned response so that it can be read again.
+func (c *restClient) doRequest(ctx context.Context, method string, apiPath string, body interface{}, result interface{}) (*http.Response, error) {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		reqBody, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.serverURL+apiPath, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+
+	if c.debug {
+		if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+			log.Printf("\n%s\n", string(dump))
+		}
+	}
+	r, err := c.httpClient.Do(req)
+	if err != nil {
+		return r, contextError(ctx, err)
+	}
+	respBody, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	if err != nil {
+		return r, contextError(ctx, err)
+	}
+	if c.debug {
+		if dump, err := httputil.DumpResponse(r, true); err == nil {
+			log.Printf("\n%s\n", string(dump))
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	}
+
+	if r.StatusCode >= 200 && r.StatusCode < 300 && result != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return r, fmt.Errorf("Cannot decode response: %v: %w", err, ErrUnexpectedResponse)
+		}
+	}
+	return r, nil
+}
+
+// responseBody returns the body of a response returned by doRequest
+func responseBody(r *http.Response) []byte {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+// escapePath escapes each segment in 'path' so that it can be used in the path of a URL
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// secretData converts 'value' into the data of a secret saved as a JSON object, and returns the
+// type of secret.  A text secret is saved in the key "text".
+func secretData(value interface{}) (string, map[string]string, error) {
+	switch v := value.(type) {
+	case string:
+		return SecretTypeText, map[string]string{secretTextKey: v}, nil
+	case map[string]string:
+		return SecretTypeKV, v, nil
+	}
+	return "", nil, ErrSecretTypeNotSupported
+}
+
+// secretValue converts the data of a secret saved as a JSON object into the value returned by Get.
+// The text of a binary secret is returned as []byte if 'decode' is set.
+func secretValue(secretType string, data map[string]interface{}, decode bool) (interface{}, error) {
+	if secretType == SecretTypeText {
+		text, ok := data[secretTextKey].(string)
+		if !ok {
+			return nil, fmt.Errorf("No text in text secret: %w", ErrUnexpectedResponse)
+		}
+		return decodeValue(text, decode), nil
+	}
+	res := make(map[string]string, len(data))
+	for k, v := range data {
+		if str, ok := v.(string); ok {
+			res[k] = str
+		} else {
+			// the secret store allows any JSON value
+			encoded, _ := json.Marshal(v)
+			res[k] = string(encoded)
+		}
+	}
+	return res, nil
+}
+
+// cleanPath removes leading and trailing "/" in 'path', and checks that the path can be
+// used as the path of a secret or folder in secret stores where folders are path prefixes.
+func cleanPath(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", ErrBadPathName
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if strings.TrimSpace(segment) == "" || segment == "." || segment == ".." {
+			return "", ErrBadPathName
+		}
+	}
+	return path, nil
+}
+
+// baseName returns the last segment of 'path'
+func baseName(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	return path[strings.LastIndex(path, "/")+1:]
+}
@@ -0,0 +1,190 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultRetryBaseDelay is the delay before the first retry when RetryPolicy.BaseDelay is not specified.
+	DefaultRetryBaseDelay = 100 * time.Millisecond
+
+	// DefaultRetryMaxDelay is the maximum delay between attempts when RetryPolicy.MaxDelay is not specified.
+	DefaultRetryMaxDelay = 5 * time.Second
+)
+
+// RetryPolicy specifies how PASSecretClient retries requests that fail with a transient error.  The
+// transient errors are transport errors, and the HTTP status 429 (Too Many Requests), 500 (Internal
+// Server Error), 502 (Bad Gateway), 503 (Service Unavailable) and 504 (Gateway Timeout).
+//
+// The delay before each retry grows exponentially from BaseDelay up to MaxDelay, and a random jitter
+// of up to half of the delay is subtracted from it so that clients do not retry in lockstep.  If the
+// response has a Retry-After header, the delay is at least the time specified in the header.  If the
+// header asks for a delay longer than MaxDelay, the request is not retried.
+//
+// Create and CreateFolder are not idempotent: if PAS has created the secret before the request fails,
+// retrying it returns ErrExists.  Unless RetryNonIdempotent is set, they are only retried when PAS has
+// not processed the request, i.e., the connection cannot be established, or the HTTP status is 429 or 503.
+// Other operations are retried for all transient errors.  A retried Delete may return ErrSecretNotFound
+// if the secret is deleted in an attempt that fails.
+type RetryPolicy struct {
+	MaxAttempts        int           // maximum number of attempts, including the first one.  0 or 1 means no retry
+	BaseDelay          time.Duration // delay before the first retry.  0 means DefaultRetryBaseDelay
+	MaxDelay           time.Duration // maximum delay between attempts.  0 means DefaultRetryMaxDelay
+	RetryNonIdempotent bool          // whether Create and CreateFolder are retried for all transient errors
+}
+
+// RetryError is the error returned when a request still fails after it is retried.  Err is the error of
+// the last attempt, which can be checked with errors.Is, e.g., errors.Is(err, ErrUnexpectedResponse).
+// If 'ctx' is done while waiting for the next attempt, Err is the error in 'ctx'.
+type RetryError struct {
+	Attempts int   // number of attempts made
+	Err      error // error of the last attempt
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%v (after %d attempts)", e.Err, e.Attempts)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// SetRetryPolicy sets the policy to retry requests that fail with a transient error.  nil disables
+// retry, which is the default.
+func (c *PASSecretClient) SetRetryPolicy(policy *RetryPolicy) {
+	if policy == nil {
+		c.retryPolicy = nil
+		return
+	}
+	p := *policy
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryMaxDelay
+	}
+	c.retryPolicy = &p
+}
+
+// retry calls 'op' until it succeeds, it fails with an error that is not transient, or the maximum
+// number of attempts is reached.  'idempotent' specifies whether 'op' can be repeated safely.
+// It returns the response and error of the last attempt.  The error is a RetryError if 'op' is
+// attempted more than once.
+func (c *PASSecretClient) retry(ctx context.Context, idempotent bool, op func() (*http.Response, error)) (*http.Response, error) {
+	policy := c.retryPolicy
+	for attempt := 1; ; attempt++ {
+		r, err := op()
+		if err == nil || policy == nil || attempt >= policy.MaxAttempts ||
+			!policy.transient(ctx, r, err, idempotent || policy.RetryNonIdempotent) {
+			return r, retryError(attempt, err)
+		}
+		delay, ok := policy.delay(attempt, r)
+		if !ok {
+			return r, retryError(attempt, err)
+		}
+		if c.debug {
+			log.Printf("Attempt %d failed: %v.  Retry in %v\n", attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return r, retryError(attempt, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// transient returns whether a request that fails with the response 'r' and error 'err' can be retried.
+func (p *RetryPolicy) transient(ctx context.Context, r *http.Response, err error, idempotent bool) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if r == nil {
+		var urlErr *url.Error
+		if !errors.As(err, &urlErr) {
+			// not a transport error, e.g., invalid parameters
+			return false
+		}
+		if idempotent {
+			return true
+		}
+		// the request is not sent if the connection cannot be established
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	switch r.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError:
+		// PAS returns 500 for some bad requests
+		return idempotent && !errors.Is(err, ErrBadPathName)
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// delay returns the delay before retrying the request that fails in 'attempt' with the response 'r'.
+// It returns false if the server asks for a delay longer than MaxDelay.
+func (p *RetryPolicy) delay(attempt int, r *http.Response) (time.Duration, bool) {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	d -= time.Duration(rand.Int63n(int64(d/2) + 1))
+
+	if after, ok := retryAfter(r); ok {
+		if after > p.MaxDelay {
+			return 0, false
+		}
+		if after > d {
+			d = after
+		}
+	}
+	return d, true
+}
+
+// retryAfter returns the delay specified in the Retry-After header of 'r', which can be in seconds
+// or a HTTP date.
+func retryAfter(r *http.Response) (time.Duration, bool) {
+	if r == nil {
+		return 0, false
+	}
+	value := r.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// retryError returns 'err' as a RetryError if there is more than one attempt
+func retryError(attempts int, err error) error {
+	if err == nil || attempts <= 1 {
+		return err
+	}
+	return &RetryError{Attempts: attempts, Err: err}
+}
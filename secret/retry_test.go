@@ -0,0 +1,249 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+// SecretRetryTestSuite tests the retry policy of PASSecretClient, using a local HTTP server
+// that stands in for PAS and fails some of the requests.
+type SecretRetryTestSuite struct {
+	testutils.CfyTestSuite
+	server     *httptest.Server               // local HTTP server that stands in for PAS
+	handle     *PASSecretClient               // interface to secret API
+	requests   int                            // number of requests received by server
+	fail       func(r *http.Request) int      // returns the HTTP status to fail a request with, or 0
+	retryAfter string                         // Retry-After header in failed responses
+	policy     *RetryPolicy                   // retry policy used in tests
+	pages      map[string][]map[string]string // secrets returned in each page of the secret list
+}
+
+func TestSecretRetryTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretRetryTestSuite))
+}
+
+func (s *SecretRetryTestSuite) SetupTest() {
+	s.requests = 0
+	s.fail = nil
+	s.retryAfter = ""
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+	s.handle = newPASSecretClient(s.server.URL, "token", s.server.Client)
+	s.policy = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	s.handle.SetRetryPolicy(s.policy)
+}
+
+func (s *SecretRetryTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// serve fails the request if s.fail returns a status.  Otherwise it returns a text secret, or a page
+// of the secret list.
+func (s *SecretRetryTestSuite) serve(w http.ResponseWriter, r *http.Request) {
+	s.requests++
+	w.Header().Set("Content-Type", "application/json")
+	if s.fail != nil {
+		if status := s.fail(r); status != 0 {
+			if s.retryAfter != "" {
+				w.Header().Set("Retry-After", s.retryAfter)
+			}
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"title": "%s", "status": %d}`, http.StatusText(status), status)
+			return
+		}
+	}
+	if r.URL.Path == "/api/v1.0/secrets" && r.Method == http.MethodGet {
+		page := r.URL.Query().Get("page")
+		result := map[string]interface{}{
+			"object": "list",
+			"items":  s.pages[page],
+		}
+		if page == "" {
+			result["next_url"] = "/api/v1.0/secrets?page=2"
+		}
+		_ = json.NewEncoder(w).Encode(result)
+		return
+	}
+	fmt.Fprint(w, `{
+		"name": "folder/secret",
+		"type": "text",
+		"data": "value",
+		"meta": {"id": "id-1", "crn": "crn-1"}
+	}`)
+}
+
+// failFirst fails the first 'n' requests with 'status'
+func (s *SecretRetryTestSuite) failFirst(n int, status int) {
+	s.fail = func(r *http.Request) int {
+		if s.requests <= n {
+			return status
+		}
+		return 0
+	}
+}
+
+func (s *SecretRetryTestSuite) TestRetrySucceeds() {
+	s.failFirst(2, http.StatusServiceUnavailable)
+	value, r, err := s.handle.Get("folder/secret")
+	s.Require().NoError(err, "Get should succeed after retry")
+	s.Assert().Equal("value", value)
+	s.Assert().Equal(200, r.StatusCode)
+	s.Assert().Equal(3, s.requests)
+
+	s.requests = 0
+	s.failFirst(1, http.StatusBadGateway)
+	_, _, err = s.handle.GetMetaData("folder/secret")
+	s.Assert().NoError(err, "GetMetaData should succeed after retry")
+	s.requests = 0
+	_, _, _, err = s.handle.Modify("folder/secret", "", "new value")
+	s.Assert().NoError(err, "Modify should succeed after retry")
+	s.requests = 0
+	_, err = s.handle.Delete("folder/secret")
+	s.Assert().NoError(err, "Delete should succeed after retry")
+	s.Assert().Equal(2, s.requests)
+}
+
+func (s *SecretRetryTestSuite) TestWithRetryPolicy() {
+	cl, err := NewSecretClient(s.server.URL, ServerPAS, "token", s.server.Client, WithRetryPolicy(*s.policy))
+	s.Require().NoError(err)
+	s.failFirst(2, http.StatusServiceUnavailable)
+	_, _, err = cl.Get("folder/secret")
+	s.Require().NoError(err, "Get should succeed after retry")
+	s.Assert().Equal(3, s.requests)
+
+	cl, _ = NewSecretClient(s.server.URL, ServerPAS, "token", s.server.Client)
+	s.requests = 0
+	_, _, err = cl.Get("folder/secret")
+	s.Assert().ErrorIs(err, ErrUnexpectedResponse, "Requests should not be retried by default")
+	s.Assert().Equal(1, s.requests)
+}
+
+func (s *SecretRetryTestSuite) TestRetryExhausted() {
+	s.fail = func(r *http.Request) int { return http.StatusGatewayTimeout }
+	_, r, err := s.handle.Get("folder/secret")
+	s.Assert().ErrorIs(err, ErrUnexpectedResponse, "Should return error of last attempt")
+	var retryErr *RetryError
+	s.Require().True(errors.As(err, &retryErr), "Error should be RetryError")
+	s.Assert().Equal(3, retryErr.Attempts)
+	s.Assert().Equal(504, r.StatusCode)
+	s.Assert().Equal(3, s.requests)
+
+	s.requests = 0
+	s.handle.SetRetryPolicy(nil)
+	_, _, err = s.handle.Get("folder/secret")
+	s.Assert().ErrorIs(err, ErrUnexpectedResponse)
+	s.Assert().False(errors.As(err, &retryErr), "Should not retry without policy")
+	s.Assert().Equal(1, s.requests)
+}
+
+func (s *SecretRetryTestSuite) TestNotTransient() {
+	for _, status := range []int{400, 401, 404, 409, 501} {
+		s.requests = 0
+		s.fail = func(r *http.Request) int { return status }
+		_, _, err := s.handle.Get("folder/secret")
+		s.Assert().Error(err)
+		s.Assert().Equal(1, s.requests, "Should not retry status %d", status)
+	}
+}
+
+func (s *SecretRetryTestSuite) TestCreate() {
+	s.fail = func(r *http.Request) int { return http.StatusInternalServerError }
+	_, _, _, err := s.handle.Create("folder/secret", "", "value")
+	s.Assert().ErrorIs(err, ErrUnexpectedResponse)
+	s.Assert().Equal(1, s.requests, "Create should not be retried when PAS may have processed it")
+
+	s.requests = 0
+	s.failFirst(1, http.StatusTooManyRequests)
+	_, id, _, err := s.handle.Create("folder/secret", "", "value")
+	s.Assert().NoError(err, "Create should be retried when PAS has not processed it")
+	s.Assert().Equal("id-1", id)
+	s.Assert().Equal(2, s.requests)
+
+	s.requests = 0
+	s.failFirst(1, http.StatusInternalServerError)
+	s.policy.RetryNonIdempotent = true
+	s.handle.SetRetryPolicy(s.policy)
+	_, _, _, err = s.handle.CreateFolder("folder", "")
+	s.Assert().NoError(err, "CreateFolder should be retried with RetryNonIdempotent")
+	s.Assert().Equal(2, s.requests)
+}
+
+func (s *SecretRetryTestSuite) TestRetryAfter() {
+	s.retryAfter = "1"
+	s.fail = func(r *http.Request) int { return http.StatusServiceUnavailable }
+	_, _, err := s.handle.Get("folder/secret")
+	s.Assert().ErrorIs(err, ErrUnexpectedResponse)
+	s.Assert().Equal(1, s.requests, "Should not retry if Retry-After is longer than MaxDelay")
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	policy := &RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Minute}
+	delay, ok := policy.delay(1, resp)
+	s.Assert().True(ok)
+	s.Assert().Equal(2*time.Second, delay, "Should wait for time in Retry-After")
+	resp.Header.Set("Retry-After", time.Now().Add(10*time.Second).UTC().Format(http.TimeFormat))
+	delay, ok = policy.delay(1, resp)
+	s.Assert().True(ok)
+	s.Assert().True(delay > 8*time.Second && delay <= 10*time.Second, "Should wait until time in Retry-After, got %v", delay)
+}
+
+func (s *SecretRetryTestSuite) TestBackoff() {
+	policy := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 10; i++ {
+			delay, ok := policy.delay(attempt+1, nil)
+			s.Require().True(ok)
+			s.Assert().True(delay >= max/2 && delay <= max, "Delay of attempt %d should be between %v and %v, got %v",
+				attempt+1, max/2, max, delay)
+		}
+	}
+}
+
+func (s *SecretRetryTestSuite) TestContext() {
+	s.policy.BaseDelay = time.Minute
+	s.policy.MaxDelay = time.Minute
+	s.handle.SetRetryPolicy(s.policy)
+	s.fail = func(r *http.Request) int { return http.StatusServiceUnavailable }
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err := s.handle.GetContext(ctx, "folder/secret")
+	s.Assert().ErrorIs(err, context.DeadlineExceeded, "Should stop waiting for retry when context is done")
+	s.Assert().Equal(1, s.requests)
+}
+
+func (s *SecretRetryTestSuite) TestTransportError() {
+	s.server.Close()
+	_, _, _, err := s.handle.Create("folder/secret", "", "value")
+	var retryErr *RetryError
+	s.Require().True(errors.As(err, &retryErr), "Create should be retried when connection cannot be established")
+	s.Assert().Equal(3, retryErr.Attempts)
+}
+
+func (s *SecretRetryTestSuite) TestListSecrets() {
+	s.pages = map[string][]map[string]string{
+		"":  {{"id": "id-1", "name": "one", "type": "text"}},
+		"2": {{"id": "id-2", "name": "two", "type": "text"}},
+	}
+	s.fail = func(r *http.Request) int {
+		if r.URL.Query().Get("page") == "2" && s.requests == 2 {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+	var names []string
+	_, err := s.handle.ListSecrets(nil, func(item Item) error {
+		names = append(names, item.Name)
+		return nil
+	})
+	s.Require().NoError(err, "Should retry failed page")
+	s.Assert().Equal([]string{"one", "two"}, names, "Each secret should be listed once")
+	s.Assert().Equal(3, s.requests)
+}
@@ -1,6 +1,7 @@
 package secret
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
@@ -11,14 +12,67 @@ import (
 // client.
 type HTTPClientFactory func() *http.Client
 
+// SecretContext is the collection of context-aware APIs that manage secrets.  Each method
+// behaves the same as its counterpart in Secret, except that 'ctx' is used for all REST API
+// requests sent to the secret store.  The caller can use 'ctx' to cancel a request, to
+// set a deadline for it, or to pass tracing information to a custom HTTP client.
+//
+// If 'ctx' is canceled or its deadline expires before the request completes, the error
+// returned is context.Canceled or context.DeadlineExceeded respectively, instead of
+// ErrUnexpectedResponse or the underlying transport error.
+type SecretContext interface {
+	// CreateContext is the same as Create, but uses 'ctx' for the request.
+	CreateContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error)
+
+	// CreateFolderContext is the same as CreateFolder, but uses 'ctx' for the request.
+	CreateFolderContext(ctx context.Context, path string, description string) (bool, string, *http.Response, error)
+
+	// DeleteContext is the same as Delete, but uses 'ctx' for the request.
+	DeleteContext(ctx context.Context, path string) (*http.Response, error)
+
+	// GetContext is the same as Get, but uses 'ctx' for the request.
+	GetContext(ctx context.Context, path string) (interface{}, *http.Response, error)
+
+	// GetMetaDataContext is the same as GetMetaData, but uses 'ctx' for the request.
+	GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error)
+
+	// ListContext is the same as List, but uses 'ctx' for the request.
+	ListContext(ctx context.Context, path string) ([]Item, *http.Response, error)
+
+	// ListSecretsContext is the same as ListSecrets, but uses 'ctx' for the requests.
+	ListSecretsContext(ctx context.Context, opts *ListOptions, fn ListFunc) (*http.Response, error)
+
+	// ModifyContext is the same as Modify, but uses 'ctx' for the request.
+	ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error)
+
+	// ModifyMetaDataContext is the same as ModifyMetaData, but uses 'ctx' for the requests.
+	ModifyMetaDataContext(ctx context.Context, path string, description string, attributes map[string]string) (*http.Response, error)
+
+	// MoveContext is the same as Move, but uses 'ctx' for the requests.
+	MoveContext(ctx context.Context, srcPath string, dstPath string) (string, *http.Response, error)
+
+	// PathOfIDContext is the same as PathOfID, but uses 'ctx' for the requests.
+	PathOfIDContext(ctx context.Context, id string) (string, *http.Response, error)
+
+	// RenameContext is the same as Rename, but uses 'ctx' for the requests.
+	RenameContext(ctx context.Context, path string, newName string) (string, *http.Response, error)
+}
+
 // Secret is the collection of APIs that manage secrets stored in different secret stores.
 // A secret storage implmentation must implement the functions defined here.
 // Types PASSecretClient implements the methods specified in this interface.
+//
+// Each method in Secret uses context.Background() for its requests.  Use the corresponding
+// method in SecretContext if the request needs to be canceled or have a deadline.
 type Secret interface {
+	SecretContext
+
 	// Create creates a secret in 'path'. 'description' is an optional description
 	// of the secret.  If 'value' is a string, it saves the secret as a
 	// secret text string.  If 'value' is type map[string]string, the secret
-	// is stored as 'keyvalue' secret.
+	// is stored as 'keyvalue' secret.  If 'value' is []byte or io.Reader, the
+	// bytes are stored as base64 text in a text secret, which Get returns as
+	// []byte.  See WithMaxBinarySize for the size limit.
 	// Returns the following information:
 	//  bool: whether the secret is created or not.
 	//  id: a unique ID of the secret
@@ -28,6 +82,7 @@ type Secret interface {
 	//
 	//	 ErrExists: Secret already exists
 	//	 ErrNoCreatePermission: No permission to create secret.
+	//	 ErrSecretTooLarge:  A binary value is larger than the size limit.
 	//	 ErrSecretTypeNotSupported:  Cannot create secret for the specified type.
 	//	 ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
 	//				technical support.
@@ -59,6 +114,7 @@ type Secret interface {
 	// Get returns the secret content.
 	// If the secret is a keyvalue secret, it returns the secret as map[string]string
 	// If the secret is a text string, it returns the secret as string.
+	// If the secret stores a binary value, it returns the secret as []byte.
 	// The following errors may be returned:
 	//	 ErrSecretNotFound: Secret specified in path cannot be found.  It is possible that
 	//					the caller may not have permission to read the secret.
@@ -88,11 +144,27 @@ type Secret interface {
 	//		technical support.
 	List(path string) ([]Item, *http.Response, error)
 
+	// ListSecrets lists the secrets that match the search, filter and ordering options in 'opts'.
+	// 'opts' can be nil, which lists all secrets that the caller can access.
+	// The results are returned in pages by the secret store.  ListSecrets retrieves the
+	// pages one at a time and calls 'fn' for each item, so that a large number of secrets
+	// can be enumerated without loading all of them in memory.
+	// If 'fn' returns an error, the listing stops and the error is returned.
+	// Returns the following:
+	//  response: the HTTP response of the last page retrieved
+	// the following errors may be returned:
+	//	ErrInvalidListOption: The secret store rejects the options in 'opts'.
+	//	ErrNoGetMetaDataPermission:  The caller has no permission to list secrets.
+	//	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
+	//		technical support.
+	ListSecrets(opts *ListOptions, fn ListFunc) (*http.Response, error)
+
 	// Modify modifies a secret in 'path'.
 	// If 'description' is not an empty string, it replaces the current secret description.
 	// If 'value' is a string, it saves the secret as a
 	// secret text string.  If 'value' is type map[string]interface{} or map[string]string, the secret
-	// is stored as 'keyvalue' secret.
+	// is stored as 'keyvalue' secret.  If 'value' is []byte or io.Reader, the bytes are
+	// stored in a text secret as in Create.
 	//
 	// Returns the following information:
 	//  bool: whether the secret is modified or not.
@@ -102,10 +174,61 @@ type Secret interface {
 	//	ErrNoModifyPermission: No permission to modify secret
 	//	ErrNotSecretObject: specified path is not a secret
 	//	ErrSecretNotFound: secret cannot be found
+	//	ErrSecretTooLarge: a binary value is larger than the size limit
 	//	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
 	//				technical support.
 	Modify(path string, description string, value interface{}) (bool, string, *http.Response, error)
 
+	// ModifyMetaData modifies the description and attributes of the secret or folder in 'path'
+	// without changing its value.  If 'description' is not an empty string, it replaces the
+	// current description.  Each key in 'attributes' is set to its value, or removed if the value
+	// is empty.  Attributes that are not in 'attributes' are kept.
+	// Returns the following information:
+	//  response: the HTTP response of the last request
+	// The following errors may be returned:
+	//	ErrCannotModifySecretFolder: the secret store does not keep metadata of folders
+	//	ErrMetaDataNotSupported: the secret store does not keep the description or attributes
+	//	ErrNoModifyPermission: No permission to modify secret
+	//	ErrSecretNotFound: secret cannot be found
+	//	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
+	//		technical support.
+	ModifyMetaData(path string, description string, attributes map[string]string) (*http.Response, error)
+
+	// Move moves the secret or folder in 'srcPath', including the contents of a folder, to 'dstPath'.
+	// Missing parent folders of 'dstPath' are created.  Where the secret store supports it, the
+	// secret or folder keeps its ID.  Otherwise it is copied to 'dstPath' and deleted from 'srcPath',
+	// which gives the copies new IDs.  If the copy cannot be completed or undone, a MoveError is
+	// returned that describes what is left in the source and destination.
+	// Returns the following information:
+	//  id: the unique ID of the secret or folder in 'dstPath'
+	//  response: the HTTP response of the last request
+	// The following errors may be returned:
+	//	ErrBadPathName: 'srcPath' or 'dstPath' is invalid, or 'dstPath' is in the folder 'srcPath'
+	//	ErrExists: A secret or folder already exists in 'dstPath'
+	//	ErrMoveIncomplete: The secret or folder is only partly moved.  The error is a MoveError.
+	//	ErrSecretNotFound: Secret or folder specified in 'srcPath' cannot be found
+	//	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
+	//		technical support.
+	Move(srcPath string, dstPath string) (string, *http.Response, error)
+
+	// PathOfID returns the current path of the secret or folder with the ID 'id', e.g., the ID
+	// returned by Create or in Item.ID, so that a stored ID can be used after the secret is moved
+	// or renamed.  A path that happens to look like an ID is never mistaken for the ID.  See
+	// SecretRef for accessing a secret by ID.
+	// Returns the following information:
+	//  path: the full path of the secret or folder
+	//  response: the HTTP response of the last request
+	// The following errors may be returned:
+	//	ErrSecretNotFound: No secret or folder has the ID, or the caller has no permission to
+	//		access it.
+	//	ErrUnexpectedResponse:  The response for the REST API is not expected.  Please contact
+	//		technical support.
+	PathOfID(id string) (string, *http.Response, error)
+
+	// Rename renames the secret or folder in 'path' to 'newName' in the same folder.  It is the
+	// same as Move to the new path.  ErrBadPathName is returned if 'newName' is empty or has "/".
+	Rename(path string, newName string) (string, *http.Response, error)
+
 	// Additional functions for additional HTTP support
 
 	// SetDebug enables/disables debug messages
@@ -125,24 +248,55 @@ type Item struct {
 	ID   string // unique ID of secret
 }
 
+// ListOptions specifies the options for a ListSecrets operation.  The zero value lists all
+// secrets using the default page size of the secret store.
+type ListOptions struct {
+	Limit   int32    // number of items returned in each page, between 1 and 100.  0 means default
+	OrderBy []string // properties to sort by, e.g., "name desc"
+	Search  string   // search text
+	Filter  string   // filter expression, e.g., "type eq 'text'"
+}
+
+// ListFunc is the function called by ListSecrets for each item returned.  If it returns an
+// error, ListSecrets stops and returns the same error.
+type ListFunc func(item Item) error
+
 // MetaData stores all metadata associated with a secret object that is returned in a GetMetaData operation.
 type MetaData struct {
 	Item
-	CRN          string    // string that can be used in URL path
-	WhenCreated  time.Time // creation time
-	WhenModified time.Time // last modified time
+	CRN          string            // string that can be used in URL path
+	Description  string            // description of secret.  Empty if there is none
+	WhenCreated  time.Time         // creation time
+	WhenModified time.Time         // last modified time
+	CreatedBy    string            // user who created the secret.  Empty if the secret store does not report it
+	ModifiedBy   string            // user who last modified the secret.  Empty if the secret store does not report it
+	Version      string            // version of the value of the secret.  Empty if the secret store does not report it
+	Attributes   map[string]string // other metadata returned by the secret store.  nil if there is none.  See ModifyMetaData
 }
 
 // Common errors
 var (
+	ErrBadPassphrase            = errors.New("Incorrect passphrase or corrupted export document")
 	ErrBadPathName              = errors.New("Invalid secret path name")
 	ErrBadServerType            = errors.New("Bad server type")
 	ErrCannotModifySecretType   = errors.New("Cannot change type of secret")
 	ErrCannotModifySecretFolder = errors.New("Cannot modify a secret folder")
+	ErrConflict                 = errors.New("Secret has been changed by another client")
+	ErrCopyIncomplete           = errors.New("Some secrets/folders cannot be copied")
+	ErrDeleteIncomplete         = errors.New("Some secrets/folders cannot be deleted")
 	ErrDeletedSecretExists      = errors.New("A mark-for-delete secret already exists in the same path")
 	ErrExists                   = errors.New("Secret/folder already exists")
 	ErrFolderNotEmpty           = errors.New("Folder is not empty")
 	ErrFolderNotFound           = errors.New("Specified folder cannot be found")
+	ErrImportIncomplete         = errors.New("Some secrets cannot be imported")
+	ErrInvalidExportDocument    = errors.New("Invalid export document")
+	ErrInvalidExportOption      = errors.New("Invalid export/import option")
+	ErrInvalidKeyValue          = errors.New("Value of key cannot be converted")
+	ErrInvalidListOption        = errors.New("Invalid list option")
+	ErrInvalidManifest          = errors.New("Invalid manifest")
+	ErrMetaDataNotSupported     = errors.New("Secret store does not keep the metadata")
+	ErrMissingRequiredKey       = errors.New("Required key is missing in secret")
+	ErrMoveIncomplete           = errors.New("Secret/folder is only partly moved")
 	ErrNoCreatePermission       = errors.New("No permission to create secret")
 	ErrNoDeletePermission       = errors.New("No permission to delete secret/folder")
 	ErrNoGetMetaDataPermission  = errors.New("No permission to get ")
@@ -151,7 +305,9 @@ var (
 	ErrNotImplementedYet        = errors.New("Not implemented yet")
 	ErrNotSecretObject          = errors.New("Specified path is not a secret")
 	ErrNotSecretFolder          = errors.New("Specified path is not a secret folder")
+	ErrPassphraseRequired       = errors.New("Passphrase is required for encrypted export document")
 	ErrSecretNotFound           = errors.New("Secret cannot be found")
+	ErrSecretTooLarge           = errors.New("Secret value is too large")
 	ErrSecretTypeNotSupported   = errors.New("Cannot created secret for input type")
 	ErrUnexpectedResponse       = errors.New("Unexpected response from PAS")
 )
@@ -161,6 +317,9 @@ const (
 	ServerPAS = "pas" // PAS
 	ServerDSV = "dsv" // DSV
 	ServerTSS = "tss" // TSS
+
+	ServerHCVault = "hcvault" // HashiCorp Vault, KV version 2 secrets engine
+	ServerMemory  = "memory"  // in-memory secret store for testing
 )
 
 // constant definition for secret types
@@ -168,31 +327,77 @@ const (
 	SecretTypeFolder = "folder"
 	SecretTypeText   = "text"
 	SecretTypeKV     = "keyvalue"
+	SecretTypeFile   = "file" // binary value saved in a text secret.  See Create
 )
 
+// WithRetryPolicy specifies the policy to retry requests that fail with a transient error, which is
+// the same as calling PASSecretClient.SetRetryPolicy on the client.  The option is only supported by
+// ServerPAS and is ignored for the other server types.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.retryPolicy = &policy
+	}
+}
+
 // NewSecretClient creates a secret client to access secrets stored in 'server' of type 'serverType'.
 // 'serverType' must be one of the followings:
 //   pas - Centrify PAS
-// You can specify the Oauth Token to use in 'accessToken'.
+//   tss - Thycotic Secret Server.  'server' is the URL of Secret Server, e.g., https://tss.example.com/SecretServer
+//   dsv - DevOps Secrets Vault.  'server' is the URL or host name of the tenant,
+//         e.g., mytenant.secretsvaultcloud.com
+//   hcvault - KV version 2 secrets engine in HashiCorp Vault.  'server' is the URL of Vault, optionally
+//             followed by the mount path of the secrets engine (default "secret"),
+//             e.g., https://vault.example.com:8200/kv
+//   memory - in-memory secret store for testing.  Clients with the same 'server' name share the same
+//            secrets.  An empty 'server' creates a private store.  'accessToken' is not used.
+// You can specify the Oauth Token to use in 'accessToken'.  For hcvault, specify the Vault token, which
+// can be obtained by vault.GetHashiVaultToken.
 //
 // If you need to use a different HTTP Client for the REST API call, you can specify a HTTPClientFactory
 // function that returns a http.Client object.
 //
-func NewSecretClient(server string, serverType string, accessToken string, httpFactory HTTPClientFactory) (Secret, error) {
+// Additional options can be specified in 'opts', e.g., WithTokenSource to get access tokens on demand,
+// WithMaxBinarySize to limit the size of binary values, or WithRetryPolicy to retry transient failures.
+//
+func NewSecretClient(server string, serverType string, accessToken string, httpFactory HTTPClientFactory, opts ...ClientOption) (Secret, error) {
+
+	options := &clientOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
 
 	var cl Secret
 	// validate serverType
 	sType := strings.TrimSpace(strings.ToLower(serverType))
+	if options.tokenSource != nil && sType != ServerMemory {
+		// tokens are added to each request by the HTTP client
+		header, bearer := "Authorization", true
+		if sType == ServerHCVault {
+			header, bearer = "X-Vault-Token", false
+		}
+		httpFactory = tokenHTTPFactory(httpFactory, options.tokenSource, header, bearer)
+		accessToken = ""
+	}
 	switch sType {
 	case ServerPAS:
 		cl = newPASSecretClient(server, accessToken, httpFactory)
+	case ServerHCVault:
+		cl = newHCVaultSecretClient(server, accessToken, httpFactory)
 	case ServerTSS:
-		return nil, ErrNotImplementedYet
+		cl = newTSSSecretClient(server, accessToken, httpFactory)
 	case ServerDSV:
-		return nil, ErrNotImplementedYet
+		cl = newDSVSecretClient(server, accessToken, httpFactory)
+	case ServerMemory:
+		cl = newMemorySecretClient(server)
 	default:
 		// unknown server type
 		return nil, ErrBadServerType
 	}
+	if codec, ok := cl.(binaryCodec); ok {
+		codec.setBinaryOptions(options.maxBinarySize, options.binaryValues)
+	}
+	if pas, ok := cl.(*PASSecretClient); ok && options.retryPolicy != nil {
+		pas.SetRetryPolicy(options.retryPolicy)
+	}
 	return cl, nil
 }
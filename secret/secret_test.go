@@ -236,7 +236,7 @@ func (s *SecretTestSuite) findNonExistentSecretInTop(path string) (string, error
 
 	for {
 		_, _, err := s.handle.GetMetaData(fpath)
-		if err == ErrSecretNotFound {
+		if errors.Is(err, ErrSecretNotFound) {
 			// this is the one
 			return fpath, nil
 		}
@@ -385,6 +385,39 @@ func (s *SecretTestSuite) TestListFolder() {
 
 }
 
+func (s *SecretTestSuite) TestPathOfID() {
+	path := s.testFolder + "/idsecret" + s.suffix
+	id := s.createTextSecret(path, "value")
+	defer s.handle.Delete(path)
+
+	resolved, _, err := s.handle.PathOfID(id)
+	s.Require().NoError(err, "Should find secret by ID")
+	s.Assert().Equal(path, resolved)
+	resolved, _, err = s.handle.PathOfID(s.testFolderID)
+	s.Require().NoError(err, "Should find folder by ID")
+	s.Assert().Equal(s.testFolder, resolved)
+
+	_, _, err = s.handle.PathOfID(path)
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Path should not be taken for ID")
+}
+
+func (s *SecretTestSuite) TestModifyIfUnchanged() {
+	path := s.testFolder + "/conditional" + s.suffix
+	s.createTextSecret(path, "v1")
+	defer s.handle.Delete(path)
+
+	observed, _, err := s.handle.GetMetaData(path)
+	s.Require().NoError(err)
+	_, _, _, err = ModifyIfUnchanged(s.handle, path, observed, "", "v2")
+	s.Require().NoError(err, "Should modify unchanged secret")
+	_, _, _, err = ModifyIfUnchanged(s.handle, path, observed, "", "v3")
+	s.Assert().ErrorIs(err, ErrConflict, "Should detect modification")
+	_, err = DeleteIfUnchanged(s.handle, path, observed)
+	s.Assert().ErrorIs(err, ErrConflict)
+	value, _, _ := s.handle.Get(path)
+	s.Assert().Equal("v2", value)
+}
+
 func (s *SecretTestSuite) TestModifyTextSecret() {
 
 	path := s.testFolder + "/modify_secret_test"
@@ -0,0 +1,220 @@
+package secret
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultTokenRefreshBefore is how long before a token expires that the token is refreshed.  For
+// tokens with a shorter lifetime, the token is refreshed in the second half of its lifetime.
+const DefaultTokenRefreshBefore = time.Minute
+
+// ClientOption specifies an option of the client created by NewSecretClient.
+type ClientOption func(*clientOptions)
+
+// clientOptions are the options of the client created by NewSecretClient
+type clientOptions struct {
+	tokenSource   oauth2.TokenSource // source of access tokens
+	maxBinarySize int                // maximum size of binary values
+	binaryValues  bool               // whether Get returns binary values as []byte
+	retryPolicy   *RetryPolicy       // policy to retry requests that fail with a transient error
+}
+
+// WithTokenSource specifies that the client gets access tokens from 'source' instead of using the
+// 'accessToken' passed to NewSecretClient.  This allows a long-lived client to keep working after a
+// token expires.
+//
+// The token is reused until shortly before its Expiry, when a new token is obtained from 'source'.
+// If the secret store rejects a request with HTTP status 401 (Unauthorized), e.g., the token is
+// revoked, a new token is obtained and the request is sent again once.  A token without Expiry is
+// only refreshed on a 401 response.
+//
+// Tokens are sent as bearer tokens, except for ServerHCVault, where they are sent as Vault tokens.
+// The option is ignored for ServerMemory.
+func WithTokenSource(source oauth2.TokenSource) ClientOption {
+	return func(o *clientOptions) {
+		o.tokenSource = source
+	}
+}
+
+// tokenSourceFunc is an oauth2.TokenSource that calls a function
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+// Token returns a new token
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+// jwtExpiry returns the time in the "exp" claim of 'token' if it is a JWT.  Otherwise it returns
+// the zero time.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// refreshingTokenSource reuses the token from a token source until it is about to expire
+type refreshingTokenSource struct {
+	source oauth2.TokenSource
+	now    func() time.Time // returns the current time
+
+	mu        sync.Mutex
+	token     *oauth2.Token // current token
+	refreshAt time.Time     // when to refresh the current token.  Zero if it does not expire
+}
+
+// newRefreshingTokenSource returns a token source that reuses tokens from 'source'
+func newRefreshingTokenSource(source oauth2.TokenSource) *refreshingTokenSource {
+	return &refreshingTokenSource{source: source, now: time.Now}
+}
+
+// Token returns the current token, or a new token if it is about to expire.  If a new token cannot
+// be obtained, the current token is returned if it has not expired.
+func (s *refreshingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.token != nil && (s.refreshAt.IsZero() || now.Before(s.refreshAt)) {
+		return s.token, nil
+	}
+	token, err := s.fetch(now)
+	if err != nil {
+		if s.token != nil && now.Before(s.token.Expiry) {
+			return s.token, nil
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// refresh returns a new token if 'rejected' is the current token.  Otherwise the current token has
+// already been refreshed, and it is returned.
+func (s *refreshingTokenSource) refresh(rejected *oauth2.Token) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != nil && s.token.AccessToken != rejected.AccessToken {
+		return s.token, nil
+	}
+	return s.fetch(s.now())
+}
+
+// fetch gets a new token from the source.  s.mu must be held.
+func (s *refreshingTokenSource) fetch(now time.Time) (*oauth2.Token, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.AccessToken == "" {
+		return nil, fmt.Errorf("Token source returns no access token: %w", ErrUnexpectedResponse)
+	}
+	s.token = token
+	s.refreshAt = time.Time{}
+	if !token.Expiry.IsZero() {
+		before := DefaultTokenRefreshBefore
+		if lifetime := token.Expiry.Sub(now); lifetime < 2*before {
+			before = lifetime / 2
+		}
+		s.refreshAt = token.Expiry.Add(-before)
+	}
+	return token, nil
+}
+
+// tokenTransport is a http.RoundTripper that adds the token from a token source to each request,
+// and sends the request again with a new token if the token is rejected.
+type tokenTransport struct {
+	base   http.RoundTripper      // transport that sends the requests
+	source *refreshingTokenSource // source of tokens
+	header string                 // name of header that has the token
+	bearer bool                   // whether the token is sent as a bearer token
+}
+
+// tokenHTTPFactory returns a HTTPClientFactory that creates clients from 'httpFactory' which
+// add tokens from 'source' to requests in 'header'
+func tokenHTTPFactory(httpFactory HTTPClientFactory, source oauth2.TokenSource, header string, bearer bool) HTTPClientFactory {
+	tokens := newRefreshingTokenSource(source)
+	return func() *http.Client {
+		client := http.DefaultClient
+		if httpFactory != nil {
+			client = httpFactory()
+		}
+		copied := *client
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		copied.Transport = &tokenTransport{base: base, source: tokens, header: header, bearer: bearer}
+		return &copied
+	}
+}
+
+// RoundTrip sends 'req' with the current token.  If the response is 401 (Unauthorized), it sends the
+// request again with a new token if the body of the request can be sent again.
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token()
+	if err != nil {
+		closeRequestBody(req)
+		return nil, fmt.Errorf("Cannot get access token: %w", err)
+	}
+	resp, err := t.base.RoundTrip(t.authorize(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// the request cannot be sent again
+		return resp, nil
+	}
+
+	// the token may be revoked or expired earlier than expected
+	newToken, err := t.source.refresh(token)
+	if err != nil || newToken.AccessToken == token.AccessToken {
+		return resp, nil
+	}
+	retry := t.authorize(req, newToken)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+// authorize returns a copy of 'req' with 'token' in the header
+func (t *tokenTransport) authorize(req *http.Request, token *oauth2.Token) *http.Request {
+	authorized := req.Clone(req.Context())
+	if t.bearer {
+		authorized.Header.Set(t.header, "Bearer "+token.AccessToken)
+	} else {
+		authorized.Header.Set(t.header, token.AccessToken)
+	}
+	return authorized
+}
+
+// closeRequestBody closes the body of 'req' as required for a http.RoundTripper that does not send it
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,53 @@
+package secret
+
+import (
+	"time"
+
+	"github.com/centrify/platform-go-sdk/dmc"
+	"github.com/centrify/platform-go-sdk/oauthhelper"
+	"golang.org/x/oauth2"
+)
+
+// functions that get tokens from Centrify Client, which can be replaced in tests
+var (
+	getDMCToken           = dmc.GetDMCToken
+	getResourceOwnerToken = oauthhelper.GetResourceOwnerToken
+)
+
+// NewDMCTokenSource returns a token source that gets Delegated Machine Credential (DMC) tokens for
+// 'scope' with dmc.GetDMCToken.  The expiry of a token is read from the token.
+func NewDMCTokenSource(scope string) oauth2.TokenSource {
+	return tokenSourceFunc(func() (*oauth2.Token, error) {
+		accessToken, err := getDMCToken(scope)
+		if err != nil {
+			return nil, err
+		}
+		return &oauth2.Token{
+			AccessToken: accessToken,
+			TokenType:   "Bearer",
+			Expiry:      jwtExpiry(accessToken),
+		}, nil
+	})
+}
+
+// NewResourceOwnerTokenSource returns a token source that gets tokens of 'user' for the web application
+// 'appID' and 'scope' with oauthhelper.GetResourceOwnerToken.  A new token is requested from Centrify
+// Client when the token expires.
+func NewResourceOwnerTokenSource(appID string, scope string, user string, password string) oauth2.TokenSource {
+	return tokenSourceFunc(func() (*oauth2.Token, error) {
+		now := time.Now()
+		accessToken, tokenType, expiresIn, refreshToken, err := getResourceOwnerToken(appID, scope, user, password)
+		if err != nil {
+			return nil, err
+		}
+		token := &oauth2.Token{
+			AccessToken:  accessToken,
+			TokenType:    tokenType,
+			RefreshToken: refreshToken,
+		}
+		if expiresIn > 0 {
+			token.Expiry = now.Add(time.Duration(expiresIn) * time.Second)
+		}
+		return token, nil
+	})
+}
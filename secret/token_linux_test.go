@@ -0,0 +1,42 @@
+package secret
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+func (s *SecretTokenTestSuite) TestBuiltinSources() {
+	defer func(dmcToken func(string) (string, error)) { getDMCToken = dmcToken }(getDMCToken)
+	defer func(roToken func(string, string, string, string) (string, string, uint32, string, error)) {
+		getResourceOwnerToken = roToken
+	}(getResourceOwnerToken)
+
+	exp := time.Now().Add(30 * time.Minute).Unix()
+	payload, _ := json.Marshal(map[string]interface{}{"exp": exp, "sub": "machine"})
+	jwt := "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+	var scopes []string
+	getDMCToken = func(scope string) (string, error) {
+		scopes = append(scopes, scope)
+		return jwt, nil
+	}
+	token, err := NewDMCTokenSource("testsdk").Token()
+	s.Require().NoError(err)
+	s.Assert().Equal(jwt, token.AccessToken)
+	s.Assert().Equal(exp, token.Expiry.Unix(), "Expiry should be read from token")
+	s.Assert().Equal([]string{"testsdk"}, scopes)
+
+	getDMCToken = func(scope string) (string, error) { return "opaque", nil }
+	token, _ = NewDMCTokenSource("testsdk").Token()
+	s.Assert().True(token.Expiry.IsZero(), "Expiry of token that is not JWT should be unknown")
+
+	getResourceOwnerToken = func(appID string, scope string, user string, password string) (string, string, uint32, string, error) {
+		s.Assert().Equal([]string{"app", "scope", "user", "password"}, []string{appID, scope, user, password})
+		return "access", "Bearer", 3600, "refresh", nil
+	}
+	token, err = NewResourceOwnerTokenSource("app", "scope", "user", "password").Token()
+	s.Require().NoError(err)
+	s.Assert().Equal("access", token.AccessToken)
+	s.Assert().Equal("refresh", token.RefreshToken)
+	s.Assert().WithinDuration(time.Now().Add(time.Hour), token.Expiry, time.Minute)
+}
@@ -0,0 +1,195 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/oauth2"
+)
+
+// SecretTokenTestSuite tests WithTokenSource, using a local HTTP server that stands in for PAS and
+// only accepts the tokens that are valid.
+type SecretTokenTestSuite struct {
+	testutils.CfyTestSuite
+	server *httptest.Server // local HTTP server that stands in for PAS
+	mu     sync.Mutex
+	valid  map[string]bool // tokens accepted by server
+	auths  []string        // authorization headers received by server
+	bodies []string        // bodies of requests accepted by server
+	issued int             // number of tokens issued by source
+	expiry time.Duration   // lifetime of tokens issued by source.  0 means the tokens do not expire
+}
+
+func TestSecretTokenTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretTokenTestSuite))
+}
+
+func (s *SecretTokenTestSuite) SetupTest() {
+	s.valid = make(map[string]bool)
+	s.auths = nil
+	s.bodies = nil
+	s.issued = 0
+	s.expiry = time.Hour
+	s.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		auth := r.Header.Get("Authorization")
+		if auth == "" {
+			auth = r.Header.Get("X-Vault-Token")
+		}
+		s.auths = append(s.auths, auth)
+		if !s.valid[strings.TrimPrefix(auth, "Bearer ")] {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		s.bodies = append(s.bodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"name": "folder/secret",
+			"type": "text",
+			"data": "value",
+			"meta": {"id": "id-1", "crn": "crn-1"}
+		}`)
+	}))
+}
+
+func (s *SecretTokenTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// Token issues a new token that is accepted by the server
+func (s *SecretTokenTestSuite) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issued++
+	token := &oauth2.Token{AccessToken: fmt.Sprintf("token-%d", s.issued)}
+	if s.expiry != 0 {
+		token.Expiry = time.Now().Add(s.expiry)
+	}
+	s.valid[token.AccessToken] = true
+	return token, nil
+}
+
+// revoke makes the server reject 'token'
+func (s *SecretTokenTestSuite) revoke(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.valid, token)
+}
+
+func (s *SecretTokenTestSuite) newClient(serverType string) Secret {
+	handle, err := NewSecretClient(s.server.URL, serverType, "ignored", s.server.Client, WithTokenSource(s))
+	s.Require().NoError(err)
+	return handle
+}
+
+func (s *SecretTokenTestSuite) TestReuseToken() {
+	handle := s.newClient(ServerPAS)
+	for i := 0; i < 3; i++ {
+		_, _, err := handle.Get("folder/secret")
+		s.Require().NoError(err, "Should get secret with token from source")
+	}
+	s.Assert().Equal(1, s.issued, "Token should be reused")
+	s.Assert().Equal([]string{"Bearer token-1", "Bearer token-1", "Bearer token-1"}, s.auths,
+		"Access token passed to NewSecretClient should not be used")
+}
+
+func (s *SecretTokenTestSuite) TestRefreshOnUnauthorized() {
+	handle := s.newClient(ServerPAS)
+	_, _, err := handle.Get("folder/secret")
+	s.Require().NoError(err)
+
+	s.revoke("token-1")
+	_, _, _, err = handle.Create("folder/secret", "", "value")
+	s.Require().NoError(err, "Should send request again with new token")
+	s.Assert().Equal(2, s.issued)
+	s.Assert().Equal([]string{"Bearer token-1", "Bearer token-1", "Bearer token-2"}, s.auths)
+	s.Require().Len(s.bodies, 2)
+	s.Assert().Contains(s.bodies[1], `"value"`, "Body should be sent again")
+
+	s.revoke("token-2")
+	s.valid = map[string]bool{}
+	s.expiry = 0
+	source := tokenSourceFunc(func() (*oauth2.Token, error) {
+		return &oauth2.Token{AccessToken: "rejected"}, nil
+	})
+	handle, _ = NewSecretClient(s.server.URL, ServerPAS, "", s.server.Client, WithTokenSource(source))
+	_, _, err = handle.Get("folder/secret")
+	s.Assert().ErrorIs(err, ErrNoRetrievePermission, "Should return error if new token is also rejected")
+}
+
+func (s *SecretTokenTestSuite) TestProactiveRefresh() {
+	tokens := newRefreshingTokenSource(s)
+	now := time.Now()
+	tokens.now = func() time.Time { return now }
+
+	token, err := tokens.Token()
+	s.Require().NoError(err)
+	s.Assert().Equal("token-1", token.AccessToken)
+	now = now.Add(time.Hour - DefaultTokenRefreshBefore - time.Second)
+	token, _ = tokens.Token()
+	s.Assert().Equal("token-1", token.AccessToken, "Token should be reused before it is about to expire")
+	now = now.Add(2 * time.Second)
+	token, _ = tokens.Token()
+	s.Assert().Equal("token-2", token.AccessToken, "Token should be refreshed before it expires")
+
+	s.expiry = 10 * time.Second
+	now = time.Now()
+	tokens = newRefreshingTokenSource(s)
+	tokens.now = func() time.Time { return now }
+	tokens.Token()
+	now = now.Add(4 * time.Second)
+	token, _ = tokens.Token()
+	s.Assert().Equal("token-3", token.AccessToken, "Short-lived token should be reused in the first half of lifetime")
+	now = now.Add(2 * time.Second)
+	token, _ = tokens.Token()
+	s.Assert().Equal("token-4", token.AccessToken, "Short-lived token should be refreshed in the second half of lifetime")
+
+	failing := newRefreshingTokenSource(tokenSourceFunc(func() (*oauth2.Token, error) {
+		return nil, errors.New("cannot get token")
+	}))
+	failing.token = token
+	failing.refreshAt = now
+	failing.now = func() time.Time { return now }
+	refreshed, err := failing.Token()
+	s.Require().NoError(err, "Should use current token if it cannot be refreshed before expiry")
+	s.Assert().Equal(token, refreshed)
+	now = token.Expiry
+	_, err = failing.Token()
+	s.Assert().Error(err, "Should return error when current token expires")
+}
+
+func (s *SecretTokenTestSuite) TestOtherServers() {
+	handle := s.newClient(ServerHCVault)
+	handle.Get("folder/secret")
+	s.Assert().Equal("token-1", s.auths[0], "Should send Vault token")
+
+	handle = s.newClient(ServerDSV)
+	handle.Get("folder/secret")
+	s.Assert().Equal("Bearer token-2", s.auths[1], "Should send bearer token")
+
+	handle = s.newClient(ServerMemory)
+	_, _, err := handle.Get("folder/secret")
+	s.Assert().ErrorIs(err, ErrSecretNotFound, "Memory should not use token source")
+}
+
+func (s *SecretTokenTestSuite) TestSourceError() {
+	source := tokenSourceFunc(func() (*oauth2.Token, error) {
+		return nil, errors.New("cannot get token")
+	})
+	handle, _ := NewSecretClient(s.server.URL, ServerPAS, "", s.server.Client, WithTokenSource(source))
+	_, _, err := handle.Get("folder/secret")
+	s.Assert().Error(err)
+	s.Assert().Contains(err.Error(), "cannot get token")
+	s.Assert().Empty(s.auths, "Should not send request without token")
+}
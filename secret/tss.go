@@ -0,0 +1,1020 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// constants for Secret Server
+const (
+	tssRootFolderID        = -1            // ID of the parent of top level folders
+	tssPageSize            = 100           // number of records requested in each page
+	tssDefaultTextTemplate = "Secure Note" // default template of text secrets
+	tssDefaultKVTemplate   = "Password"    // default template of keyvalue secrets
+)
+
+// TSSSecretClient implements the Secret interface where the secret is stored in Thycotic Secret Server (TSS).
+//
+// Secrets in Secret Server are created from secret templates.  A text secret is a secret of the text
+// template (default "Secure Note"), and its value is saved in the first field of the template.  Secrets of
+// all other templates are keyvalue secrets, where the keys are the slug names of the template fields.  New
+// keyvalue secrets are created from the keyvalue template (default "Password"), so the keys must be fields
+// of that template.  Fields with empty values and file attachments are not returned.
+// The templates can be changed with SetTemplates.
+//
+// The ID of a secret or folder is the ID assigned by Secret Server, and the CRN is its path.
+// Secret Server does not keep a description or the creation and modification time of secrets and folders,
+// so the description is ignored and the times are not returned in MetaData.
+type TSSSecretClient struct {
+	*restClient
+	textTemplate string // name of template of text secrets
+	kvTemplate   string // name of template for new keyvalue secrets
+}
+
+// tssFolder is a folder returned by Secret Server
+type tssFolder struct {
+	ID             int    `json:"id"`
+	FolderName     string `json:"folderName"`
+	FolderPath     string `json:"folderPath"`
+	ParentFolderID int    `json:"parentFolderId"`
+}
+
+// tssSecretSummary is a secret returned by Secret Server in search results
+type tssSecretSummary struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	FolderID           int    `json:"folderId"`
+	SecretTemplateID   int    `json:"secretTemplateId"`
+	SecretTemplateName string `json:"secretTemplateName"`
+}
+
+// tssPage is a page of records returned by Secret Server
+type tssPage struct {
+	Records  []json.RawMessage `json:"records"`
+	HasNext  bool              `json:"hasNext"`
+	NextSkip int               `json:"nextSkip"`
+}
+
+// newTSSSecretClient creates a new client handle to access secrets in the Secret Server specified
+// in 'server' with the OAuth access token 'accessToken'.  'server' is the URL of Secret Server,
+// e.g., https://tss.example.com/SecretServer.
+func newTSSSecretClient(server string, accessToken string, httpFactory HTTPClientFactory) *TSSSecretClient {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	server = strings.TrimSuffix(strings.TrimSuffix(server, "/"), "/api/v1")
+	cl := &TSSSecretClient{
+		restClient:   newRESTClient(server, httpFactory),
+		textTemplate: tssDefaultTextTemplate,
+		kvTemplate:   tssDefaultKVTemplate,
+	}
+	if accessToken != "" {
+		cl.headers["Authorization"] = "Bearer " + accessToken
+	}
+	return cl
+}
+
+// SetTemplates sets the names of the secret templates of text secrets and new keyvalue secrets.
+// An empty name keeps the current template.
+func (c *TSSSecretClient) SetTemplates(text string, keyValue string) {
+	if text != "" {
+		c.textTemplate = text
+	}
+	if keyValue != "" {
+		c.kvTemplate = keyValue
+	}
+}
+
+// Get returns the secret content.
+// If the secret is a keyvalue secret, it returns the secret as map[string]string
+// If the secret is a text string, it returns the secret as string.
+// The following errors may be returned:
+//	 ErrNoRetrievePermission: No permission to read the secret.
+//	 ErrSecretNotFound: Secret specified in path cannot be found.
+//	 ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *TSSSecretClient) Get(path string) (interface{}, *http.Response, error) {
+	return c.GetContext(context.Background(), path)
+}
+
+// GetContext is the same as Get, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) GetContext(ctx context.Context, path string) (interface{}, *http.Response, error) {
+	summary, r, err := c.lookupSecret(ctx, path)
+	if err != nil {
+		if err == ErrNoGetMetaDataPermission {
+			err = ErrNoRetrievePermission
+		}
+		return nil, r, err
+	}
+	model, r, err := c.getSecretModel(ctx, summary.ID)
+	if err != nil {
+		return nil, r, err
+	}
+
+	items := tssItems(model)
+	if c.secretType(summary) == SecretTypeText {
+		if len(items) == 0 {
+			return nil, r, fmt.Errorf("No field in text secret: %w", ErrUnexpectedResponse)
+		}
+		text, _ := items[0]["itemValue"].(string)
+		return decodeValue(text, c.decodeBinary), r, nil
+	}
+	res := make(map[string]string)
+	for _, item := range items {
+		if isFile, _ := item["isFile"].(bool); isFile {
+			continue
+		}
+		if value, _ := item["itemValue"].(string); value != "" {
+			res[tssItemKey(item)] = value
+		}
+	}
+	return res, r, nil
+}
+
+// Create creates a secret in 'path'.  The parent folders are created if they do not exist.
+// 'description' is ignored as Secret Server does not keep a description of secrets.
+// If 'value' is a string, it creates a text secret from the text template.  If 'value' is
+// type map[string]string, it creates a keyvalue secret from the keyvalue template.
+// Returns the following information:
+//  bool: whether the secret is created or not.
+//  id: the ID of the secret
+//  response: the actual HTTP response
+//
+// The following errors may be returned:
+//
+//   ErrBadPathName: Invalid secret path name
+//	 ErrExists: Secret or folder already exists
+//	 ErrNoCreatePermission: No permission to create secret.
+//	 ErrSecretTypeNotSupported:  Cannot create secret for the specified type, or a key is not a
+//		field of the keyvalue template.
+//	 ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *TSSSecretClient) Create(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.CreateContext(context.Background(), path, description, value)
+}
+
+// CreateContext is the same as Create, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) CreateContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, err
+	}
+	secretType, _, err := secretData(value)
+	if err != nil {
+		return false, "", nil, err
+	}
+	path, err = cleanPath(path)
+	if err != nil {
+		return false, "", nil, err
+	}
+	folderPath, name := splitPath(path)
+
+	folder, r, err := c.ensureFolder(ctx, folderPath)
+	if err == nil {
+		r, err = c.checkNameNotUsed(ctx, folder, name)
+	}
+	if err == ErrNoGetMetaDataPermission {
+		err = ErrNoCreatePermission
+	}
+	if err != nil {
+		return false, "", r, err
+	}
+
+	templateName := c.kvTemplate
+	if secretType == SecretTypeText {
+		templateName = c.textTemplate
+	}
+	templateID, r, err := c.lookupTemplate(ctx, templateName)
+	if err != nil {
+		return false, "", r, err
+	}
+
+	// the stub is a new secret of the template with empty fields
+	query := url.Values{}
+	query.Set("filter.secretTemplateId", strconv.Itoa(templateID))
+	query.Set("filter.folderId", strconv.Itoa(folder.ID))
+	var model map[string]interface{}
+	r, err = c.doRequest(ctx, http.MethodGet, "/api/v1/secrets/stub?"+query.Encode(), nil, &model)
+	if err != nil {
+		return false, "", r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, "", r, ErrNoCreatePermission
+	default:
+		return false, "", r, ErrUnexpectedResponse
+	}
+	model["name"] = name
+	model["folderId"] = folder.ID
+	if err := setTSSItems(model, value); err != nil {
+		return false, "", r, err
+	}
+
+	var result tssSecretSummary
+	r, err = c.doRequest(ctx, http.MethodPost, "/api/v1/secrets", model, &result)
+	if err != nil {
+		return false, "", r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return true, strconv.Itoa(result.ID), r, nil
+	case http.StatusBadRequest:
+		return false, "", r, ErrBadPathName
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, "", r, ErrNoCreatePermission
+	default:
+		return false, "", r, ErrUnexpectedResponse
+	}
+}
+
+// CreateFolder creates a secret folder in 'path'.  The parent folders are created if they do not exist.
+// 'description' is ignored as Secret Server does not keep a description of folders.
+// Returns the following information:
+//  bool: whether the secret folder is created or not.
+//  id: the ID of the secret folder
+//  response: the actual HTTP response
+//
+// The following errors may be returned:
+//   ErrBadPathName: Invalid secret path name
+//	 ErrExists: Secret or folder already exists
+//	 ErrNoCreatePermission: No permission to create folder.
+//	 ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *TSSSecretClient) CreateFolder(path string, description string) (bool, string, *http.Response, error) {
+	return c.CreateFolderContext(context.Background(), path, description)
+}
+
+// CreateFolderContext is the same as CreateFolder, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) CreateFolderContext(ctx context.Context, path string, description string) (bool, string, *http.Response, error) {
+	path, err := cleanPath(path)
+	if err != nil {
+		return false, "", nil, err
+	}
+	folderPath, name := splitPath(path)
+
+	parent, r, err := c.ensureFolder(ctx, folderPath)
+	if err == nil {
+		r, err = c.checkNameNotUsed(ctx, parent, name)
+	}
+	if err == ErrNoGetMetaDataPermission {
+		err = ErrNoCreatePermission
+	}
+	if err != nil {
+		return false, "", r, err
+	}
+	folder, r, err := c.createFolder(ctx, parent.ID, name)
+	if err != nil {
+		return false, "", r, err
+	}
+	return true, strconv.Itoa(folder.ID), r, nil
+}
+
+// List lists all secrets in a folder specified in 'path'
+// Returns the following:
+//  items: an array of Item. If the folder is empty, nil is returned.
+//  response: the actual HTTP response
+// the following errors may be returned:
+//	ErrFolderNotFound: Secret specified in path cannot be found.  It is possible that
+//		the caller may not have permission to access the folder.
+//	ErrNotSecretFolder: The path specifies a secret.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *TSSSecretClient) List(path string) ([]Item, *http.Response, error) {
+	return c.ListContext(context.Background(), path)
+}
+
+// ListContext is the same as List, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	folder, r, err := c.lookupFolder(ctx, path)
+	if err == ErrFolderNotFound {
+		if _, _, err := c.lookupSecret(ctx, path); err == nil {
+			return nil, r, ErrNotSecretFolder
+		}
+		return nil, r, ErrFolderNotFound
+	}
+	if err == ErrNoGetMetaDataPermission {
+		return nil, r, ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, r, err
+	}
+
+	var items []Item
+	folders, r, err := c.childFolders(ctx, folder.ID, 0)
+	if err == ErrNoGetMetaDataPermission {
+		return nil, r, ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, r, err
+	}
+	for _, child := range folders {
+		items = append(items, Item{Name: child.FolderName, Type: SecretTypeFolder, ID: strconv.Itoa(child.ID)})
+	}
+	secrets, r, err := c.folderSecrets(ctx, folder.ID, "", 0)
+	if err == ErrNoGetMetaDataPermission {
+		return nil, r, ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, r, err
+	}
+	for i := range secrets {
+		items = append(items, Item{Name: secrets[i].Name, Type: c.secretType(&secrets[i]), ID: strconv.Itoa(secrets[i].ID)})
+	}
+	return items, r, nil
+}
+
+// ListSecrets lists the secrets that match the search and ordering options in 'opts'.
+// 'opts' can be nil, which lists all secrets that the caller can access.
+// The name of each item is the full path of the secret.  Search is done by Secret Server, which
+// matches the name of secrets.  OrderBy can only be "name" or "name desc", which orders the secrets by
+// their name without the folder.  Filter is not supported.
+// If 'fn' returns an error, the listing stops and the error is returned.
+// Returns the following:
+//  response: the HTTP response of the last page
+// the following errors may be returned:
+//	ErrInvalidListOption: The options in 'opts' are not supported.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *TSSSecretClient) ListSecrets(opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	return c.ListSecretsContext(context.Background(), opts, fn)
+}
+
+// ListSecretsContext is the same as ListSecrets, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) ListSecretsContext(ctx context.Context, opts *ListOptions, fn ListFunc) (*http.Response, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	if opts.Limit < 0 || opts.Limit > maxListLimit || opts.Filter != "" {
+		return nil, ErrInvalidListOption
+	}
+	direction := "asc"
+	for _, order := range opts.OrderBy {
+		switch strings.ToLower(strings.Join(strings.Fields(order), " ")) {
+		case "name", "name asc":
+			direction = "asc"
+		case "name desc":
+			direction = "desc"
+		default:
+			return nil, fmt.Errorf("Cannot order by [%s]: %w", order, ErrInvalidListOption)
+		}
+	}
+
+	query := url.Values{}
+	query.Set("filter.searchText", opts.Search)
+	query.Set("sortBy[0].name", "name")
+	query.Set("sortBy[0].direction", direction)
+	folderPaths := map[int]string{tssRootFolderID: ""}
+	var fnErr error
+	r, err := c.getPages(ctx, "/api/v1/secrets", query, int(opts.Limit), func(record json.RawMessage) (bool, error) {
+		var summary tssSecretSummary
+		if err := json.Unmarshal(record, &summary); err != nil {
+			return false, fmt.Errorf("Cannot decode secret: %v: %w", err, ErrUnexpectedResponse)
+		}
+		folderPath, ok := folderPaths[summary.FolderID]
+		if !ok {
+			folder, _, err := c.getFolder(ctx, summary.FolderID)
+			if err != nil {
+				return false, err
+			}
+			folderPath = tssFolderPath(folder.FolderPath)
+			folderPaths[summary.FolderID] = folderPath
+		}
+		item := Item{Name: joinPath(folderPath, summary.Name), Type: c.secretType(&summary), ID: strconv.Itoa(summary.ID)}
+		if fnErr = fn(item); fnErr != nil {
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return r, err
+	}
+	return r, fnErr
+}
+
+// Delete deletes the folder/secret specified in 'path'.
+// Returns the following information:
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrFolderNotEmpty: Folder is not empty
+//	ErrNoDeletePermission: No permission to delete secret/folder
+//	ErrSecretNotFound: Secret specified in path cannot be found.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *TSSSecretClient) Delete(path string) (*http.Response, error) {
+	return c.DeleteContext(context.Background(), path)
+}
+
+// DeleteContext is the same as Delete, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) DeleteContext(ctx context.Context, path string) (*http.Response, error) {
+	var apiPath string
+	summary, r, err := c.lookupSecret(ctx, path)
+	switch err {
+	case nil:
+		apiPath = "/api/v1/secrets/" + strconv.Itoa(summary.ID)
+	case ErrSecretNotFound:
+		folder, r, err := c.lookupFolder(ctx, path)
+		if err == ErrFolderNotFound || err == nil && folder.ID == tssRootFolderID {
+			return r, ErrSecretNotFound
+		}
+		if err != nil {
+			return r, err
+		}
+		folders, r, err := c.childFolders(ctx, folder.ID, 1)
+		if err != nil {
+			return r, err
+		}
+		secrets, r, err := c.folderSecrets(ctx, folder.ID, "", 1)
+		if err != nil {
+			return r, err
+		}
+		if len(folders) > 0 || len(secrets) > 0 {
+			return r, ErrFolderNotEmpty
+		}
+		apiPath = "/api/v1/folders/" + strconv.Itoa(folder.ID)
+	case ErrNoGetMetaDataPermission:
+		return r, ErrNoDeletePermission
+	default:
+		return r, err
+	}
+
+	r, err = c.doRequest(ctx, http.MethodDelete, apiPath, nil, nil)
+	if err != nil {
+		return r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return r, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return r, ErrNoDeletePermission
+	case http.StatusNotFound:
+		return r, ErrSecretNotFound
+	default:
+		return r, ErrUnexpectedResponse
+	}
+}
+
+// Modify modifies a secret in 'path'.  'description' is ignored as Secret Server does not keep a
+// description of secrets.
+// If 'value' is a string, it replaces the text of a text secret.  If 'value' is type map[string]string,
+// it replaces the fields of a keyvalue secret.  Fields that are not in 'value' are cleared.
+//
+// Returns the following information:
+//  bool: whether the secret is modified or not.
+//  id: the ID of the secret
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrCannotModifySecretFolder:  Modification of a secret folder is not supported.
+//	ErrCannotModifySecretType:  Modification of keyvalue secret to text or vice versa is not supported.
+//	ErrNoModifyPermission: No permission to modify secret
+//	ErrSecretNotFound: secret cannot be found
+//	ErrSecretTypeNotSupported:  A key is not a field of the template of the secret.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *TSSSecretClient) Modify(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.ModifyContext(context.Background(), path, description, value)
+}
+
+// ModifyContext is the same as Modify, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	value, err := encodeValue(value, c.maxBinarySize)
+	if err != nil {
+		return false, "", nil, err
+	}
+	secretType, _, err := secretData(value)
+	if err != nil {
+		return false, "", nil, err
+	}
+
+	summary, r, err := c.lookupSecret(ctx, path)
+	switch err {
+	case nil:
+	case ErrSecretNotFound:
+		if _, r, err := c.lookupFolder(ctx, path); err == nil {
+			return false, "", r, ErrCannotModifySecretFolder
+		}
+		return false, "", r, ErrSecretNotFound
+	case ErrNoGetMetaDataPermission:
+		return false, "", r, ErrNoModifyPermission
+	default:
+		return false, "", r, err
+	}
+	if c.secretType(summary) != secretType {
+		return false, "", r, ErrCannotModifySecretType
+	}
+
+	model, r, err := c.getSecretModel(ctx, summary.ID)
+	if err == ErrNoRetrievePermission {
+		err = ErrNoModifyPermission
+	}
+	if err != nil {
+		return false, "", r, err
+	}
+	if err := setTSSItems(model, value); err != nil {
+		return false, "", r, err
+	}
+
+	id := strconv.Itoa(summary.ID)
+	r, err = c.doRequest(ctx, http.MethodPut, "/api/v1/secrets/"+id, model, nil)
+	if err != nil {
+		return false, "", r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return true, id, r, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, "", r, ErrNoModifyPermission
+	case http.StatusNotFound:
+		return false, "", r, ErrSecretNotFound
+	default:
+		return false, "", r, ErrUnexpectedResponse
+	}
+}
+
+// ModifyMetaData returns ErrMetaDataNotSupported if 'description' or 'attributes' is not empty, as
+// Secret Server does not keep a description or attributes of secrets and folders.
+// The following errors may be returned:
+//	ErrMetaDataNotSupported: 'description' or 'attributes' is not empty
+//	ErrSecretNotFound: secret or folder cannot be found
+func (c *TSSSecretClient) ModifyMetaData(path string, description string, attributes map[string]string) (*http.Response, error) {
+	return c.ModifyMetaDataContext(context.Background(), path, description, attributes)
+}
+
+// ModifyMetaDataContext is the same as ModifyMetaData, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) ModifyMetaDataContext(ctx context.Context, path string, description string, attributes map[string]string) (*http.Response, error) {
+	_, r, err := c.GetMetaDataContext(ctx, path)
+	if err != nil {
+		return r, err
+	}
+	if description != "" || len(attributes) > 0 {
+		return r, ErrMetaDataNotSupported
+	}
+	return r, nil
+}
+
+// Move moves the secret or folder in 'srcPath', including the contents of a folder, to 'dstPath'.
+// As Secret Server cannot move secrets, the secret or folder is copied to 'dstPath' and deleted from
+// 'srcPath', which gives the copies new IDs.  If copying fails, the copies already created are
+// deleted.  If the copy cannot be completed or undone, a MoveError is returned that describes what
+// is left in the source and destination.
+// Returns the following information:
+//  id: the unique ID of the secret or folder in 'dstPath'
+//  response: the HTTP response of the last request
+// The following errors may be returned, in addition to those returned by Get, Create, CreateFolder
+// and Delete:
+//	ErrBadPathName: 'srcPath' or 'dstPath' is invalid, or 'dstPath' is in the folder 'srcPath'
+//	ErrMoveIncomplete: The secret or folder is only partly moved.  The error is a MoveError.
+func (c *TSSSecretClient) Move(srcPath string, dstPath string) (string, *http.Response, error) {
+	return c.MoveContext(context.Background(), srcPath, dstPath)
+}
+
+// MoveContext is the same as Move, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) MoveContext(ctx context.Context, srcPath string, dstPath string) (string, *http.Response, error) {
+	return moveByCopy(ctx, c, srcPath, dstPath)
+}
+
+// Rename renames the secret or folder in 'path' to 'newName' in the same folder.  It is the same as
+// Move to the new path.  ErrBadPathName is returned if 'newName' is empty or has "/".
+func (c *TSSSecretClient) Rename(path string, newName string) (string, *http.Response, error) {
+	return c.RenameContext(context.Background(), path, newName)
+}
+
+// RenameContext is the same as Rename, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) RenameContext(ctx context.Context, path string, newName string) (string, *http.Response, error) {
+	newPath, err := renamePath(path, newName)
+	if err != nil {
+		return "", nil, err
+	}
+	return c.MoveContext(ctx, path, newPath)
+}
+
+// PathOfID returns the current path of the secret or folder with the ID 'id'.  Secrets and
+// folders in Secret Server have separate IDs.  If both a secret and a folder have the ID, the
+// path of the secret is returned.
+// The following errors may be returned:
+//	ErrNoRetrievePermission: No permission to get the secret.
+//	ErrSecretNotFound: No secret or folder has the ID.
+func (c *TSSSecretClient) PathOfID(id string) (string, *http.Response, error) {
+	return c.PathOfIDContext(context.Background(), id)
+}
+
+// PathOfIDContext is the same as PathOfID, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) PathOfIDContext(ctx context.Context, id string) (string, *http.Response, error) {
+	number, err := strconv.Atoi(id)
+	if err != nil || number <= 0 {
+		return "", nil, ErrSecretNotFound
+	}
+	model, r, err := c.getSecretModel(ctx, number)
+	if err == ErrSecretNotFound {
+		folder, r, err := c.getFolder(ctx, number)
+		if err == ErrFolderNotFound {
+			err = ErrSecretNotFound
+		}
+		if err != nil {
+			return "", r, err
+		}
+		return tssFolderPath(folder.FolderPath), r, nil
+	}
+	if err != nil {
+		return "", r, err
+	}
+
+	name, _ := model["name"].(string)
+	folderID, _ := model["folderId"].(float64)
+	if name == "" {
+		return "", r, fmt.Errorf("No name in secret: %w", ErrUnexpectedResponse)
+	}
+	if int(folderID) <= 0 {
+		return name, r, nil
+	}
+	folder, r, err := c.getFolder(ctx, int(folderID))
+	if err != nil {
+		return "", r, err
+	}
+	return joinPath(tssFolderPath(folder.FolderPath), name), r, nil
+}
+
+// GetMetaData returns the metadata of a secret.
+// Returns the following information:
+//  MetaData: metadata information for the secret
+//  response: the actual HTTP response
+// The following errors may be returned:
+//	ErrNoGetMetaDataPermission:  The caller has no permission to get metadata information
+//	ErrSecretNotFound: Secret specified in path cannot be found.
+//	ErrUnexpectedResponse:  The response for the REST API is not expected.
+func (c *TSSSecretClient) GetMetaData(path string) (*MetaData, *http.Response, error) {
+	return c.GetMetaDataContext(context.Background(), path)
+}
+
+// GetMetaDataContext is the same as GetMetaData, but uses 'ctx' for the REST API requests.
+func (c *TSSSecretClient) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	path = strings.Trim(path, "/")
+	result := &MetaData{}
+	result.CRN = path
+
+	summary, r, err := c.lookupSecret(ctx, path)
+	if err == ErrSecretNotFound {
+		// check if it is a folder
+		folder, r, err := c.lookupFolder(ctx, path)
+		if err == ErrFolderNotFound || err == nil && folder.ID == tssRootFolderID {
+			return nil, r, ErrSecretNotFound
+		}
+		if err != nil {
+			return nil, r, err
+		}
+		result.Name = folder.FolderName
+		result.Type = SecretTypeFolder
+		result.ID = strconv.Itoa(folder.ID)
+		return result, r, nil
+	}
+	if err != nil {
+		return nil, r, err
+	}
+	result.Name = summary.Name
+	result.Type = c.secretType(summary)
+	result.ID = strconv.Itoa(summary.ID)
+	return result, r, nil
+}
+
+// lookupFolder returns the folder in 'path'.  The top level folder has ID tssRootFolderID.
+// ErrFolderNotFound is returned if the folder does not exist or the caller cannot see it, and
+// ErrNoGetMetaDataPermission is returned if the caller cannot search folders.
+func (c *TSSSecretClient) lookupFolder(ctx context.Context, path string) (*tssFolder, *http.Response, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return &tssFolder{ID: tssRootFolderID}, nil, nil
+	}
+
+	// search the folders by name, and match the full path
+	wanted := `\` + strings.ReplaceAll(path, "/", `\`)
+	query := url.Values{}
+	query.Set("filter.searchText", baseName(path))
+	var found *tssFolder
+	r, err := c.getPages(ctx, "/api/v1/folders", query, 0, func(record json.RawMessage) (bool, error) {
+		var folder tssFolder
+		if err := json.Unmarshal(record, &folder); err != nil {
+			return false, fmt.Errorf("Cannot decode folder: %v: %w", err, ErrUnexpectedResponse)
+		}
+		if strings.EqualFold(folder.FolderPath, wanted) {
+			found = &folder
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, r, err
+	}
+	if found == nil {
+		return nil, r, ErrFolderNotFound
+	}
+	return found, r, nil
+}
+
+// lookupSecret returns the summary of the secret in 'path'
+func (c *TSSSecretClient) lookupSecret(ctx context.Context, path string) (*tssSecretSummary, *http.Response, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, nil, ErrSecretNotFound
+	}
+	folderPath, name := splitPath(path)
+	folder, r, err := c.lookupFolder(ctx, folderPath)
+	if err == ErrFolderNotFound {
+		return nil, r, ErrSecretNotFound
+	}
+	if err != nil {
+		return nil, r, err
+	}
+	secrets, r, err := c.folderSecrets(ctx, folder.ID, name, 0)
+	if err != nil {
+		return nil, r, err
+	}
+	for i := range secrets {
+		if strings.EqualFold(secrets[i].Name, name) {
+			return &secrets[i], r, nil
+		}
+	}
+	return nil, r, ErrSecretNotFound
+}
+
+// lookupTemplate returns the ID of the secret template 'name'
+func (c *TSSSecretClient) lookupTemplate(ctx context.Context, name string) (int, *http.Response, error) {
+	query := url.Values{}
+	query.Set("filter.searchText", name)
+	id := 0
+	r, err := c.getPages(ctx, "/api/v1/secret-templates", query, 0, func(record json.RawMessage) (bool, error) {
+		var template struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(record, &template); err != nil {
+			return false, fmt.Errorf("Cannot decode secret template: %v: %w", err, ErrUnexpectedResponse)
+		}
+		if strings.EqualFold(template.Name, name) {
+			id = template.ID
+			return false, nil
+		}
+		return true, nil
+	})
+	if err == ErrNoGetMetaDataPermission {
+		return 0, r, ErrNoCreatePermission
+	}
+	if err != nil {
+		return 0, r, err
+	}
+	if id == 0 {
+		return 0, r, fmt.Errorf("Secret template [%s] not found: %w", name, ErrSecretTypeNotSupported)
+	}
+	return id, r, nil
+}
+
+// ensureFolder returns the folder in 'path', creating it and its parents if they do not exist
+func (c *TSSSecretClient) ensureFolder(ctx context.Context, path string) (*tssFolder, *http.Response, error) {
+	folder, r, err := c.lookupFolder(ctx, path)
+	if err != ErrFolderNotFound {
+		return folder, r, err
+	}
+	parentPath, name := splitPath(path)
+	parent, r, err := c.ensureFolder(ctx, parentPath)
+	if err != nil {
+		return nil, r, err
+	}
+	return c.createFolder(ctx, parent.ID, name)
+}
+
+// createFolder creates the folder 'name' in the folder 'parentID'
+func (c *TSSSecretClient) createFolder(ctx context.Context, parentID int, name string) (*tssFolder, *http.Response, error) {
+	body := map[string]interface{}{
+		"folderName":          name,
+		"parentFolderId":      parentID,
+		"folderTypeId":        1,
+		"inheritPermissions":  true,
+		"inheritSecretPolicy": true,
+	}
+	var folder tssFolder
+	r, err := c.doRequest(ctx, http.MethodPost, "/api/v1/folders", body, &folder)
+	if err != nil {
+		return nil, r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return &folder, r, nil
+	case http.StatusBadRequest:
+		return nil, r, ErrBadPathName
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, r, ErrNoCreatePermission
+	default:
+		return nil, r, ErrUnexpectedResponse
+	}
+}
+
+// checkNameNotUsed returns ErrExists if there is a secret or folder 'name' in 'folder'
+func (c *TSSSecretClient) checkNameNotUsed(ctx context.Context, folder *tssFolder, name string) (*http.Response, error) {
+	folders, r, err := c.childFolders(ctx, folder.ID, 0)
+	if err != nil {
+		return r, err
+	}
+	for _, child := range folders {
+		if strings.EqualFold(child.FolderName, name) {
+			return r, ErrExists
+		}
+	}
+	secrets, r, err := c.folderSecrets(ctx, folder.ID, name, 0)
+	if err != nil {
+		return r, err
+	}
+	for _, secret := range secrets {
+		if strings.EqualFold(secret.Name, name) {
+			return r, ErrExists
+		}
+	}
+	return r, nil
+}
+
+// getFolder returns the folder with ID 'id'
+func (c *TSSSecretClient) getFolder(ctx context.Context, id int) (*tssFolder, *http.Response, error) {
+	var folder tssFolder
+	r, err := c.doRequest(ctx, http.MethodGet, "/api/v1/folders/"+strconv.Itoa(id), nil, &folder)
+	if err != nil {
+		return nil, r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK:
+		return &folder, r, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, r, ErrNoGetMetaDataPermission
+	case http.StatusNotFound:
+		return nil, r, ErrFolderNotFound
+	default:
+		return nil, r, ErrUnexpectedResponse
+	}
+}
+
+// childFolders returns the folders in the folder 'id'.  At most 'max' folders are returned if 'max'
+// is larger than 0.
+func (c *TSSSecretClient) childFolders(ctx context.Context, id int, max int) ([]tssFolder, *http.Response, error) {
+	query := url.Values{}
+	query.Set("filter.parentFolderId", strconv.Itoa(id))
+	var folders []tssFolder
+	r, err := c.getPages(ctx, "/api/v1/folders", query, max, func(record json.RawMessage) (bool, error) {
+		var folder tssFolder
+		if err := json.Unmarshal(record, &folder); err != nil {
+			return false, fmt.Errorf("Cannot decode folder: %v: %w", err, ErrUnexpectedResponse)
+		}
+		folders = append(folders, folder)
+		return max <= 0 || len(folders) < max, nil
+	})
+	return folders, r, err
+}
+
+// folderSecrets returns the secrets in the folder 'id' whose name contains 'search'.  At most 'max'
+// secrets are returned if 'max' is larger than 0.
+func (c *TSSSecretClient) folderSecrets(ctx context.Context, id int, search string, max int) ([]tssSecretSummary, *http.Response, error) {
+	query := url.Values{}
+	query.Set("filter.folderId", strconv.Itoa(id))
+	query.Set("filter.includeSubFolders", "false")
+	if search != "" {
+		query.Set("filter.searchText", search)
+	}
+	var secrets []tssSecretSummary
+	r, err := c.getPages(ctx, "/api/v1/secrets", query, max, func(record json.RawMessage) (bool, error) {
+		var summary tssSecretSummary
+		if err := json.Unmarshal(record, &summary); err != nil {
+			return false, fmt.Errorf("Cannot decode secret: %v: %w", err, ErrUnexpectedResponse)
+		}
+		secrets = append(secrets, summary)
+		return max <= 0 || len(secrets) < max, nil
+	})
+	return secrets, r, err
+}
+
+// getSecretModel returns the secret with ID 'id', including its fields
+func (c *TSSSecretClient) getSecretModel(ctx context.Context, id int) (map[string]interface{}, *http.Response, error) {
+	var model map[string]interface{}
+	r, err := c.doRequest(ctx, http.MethodGet, "/api/v1/secrets/"+strconv.Itoa(id), nil, &model)
+	if err != nil {
+		return nil, r, err
+	}
+	switch r.StatusCode {
+	case http.StatusOK:
+		return model, r, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, r, ErrNoRetrievePermission
+	case http.StatusNotFound:
+		return nil, r, ErrSecretNotFound
+	default:
+		return nil, r, ErrUnexpectedResponse
+	}
+}
+
+// getPages gets the records returned by 'apiPath' with the query parameters in 'query', and
+// calls 'fn' for each record.  'take' is the number of records in each page.  Paging stops when
+// 'fn' returns false or an error.  ErrNoGetMetaDataPermission is returned if the caller has no
+// permission to get the records.
+func (c *TSSSecretClient) getPages(ctx context.Context, apiPath string, query url.Values, take int, fn func(record json.RawMessage) (bool, error)) (*http.Response, error) {
+	if take <= 0 {
+		take = tssPageSize
+	}
+	query.Set("take", strconv.Itoa(take))
+	skip := 0
+	for {
+		query.Set("skip", strconv.Itoa(skip))
+		var page tssPage
+		r, err := c.doRequest(ctx, http.MethodGet, apiPath+"?"+query.Encode(), nil, &page)
+		if err != nil {
+			return r, err
+		}
+		switch r.StatusCode {
+		case http.StatusOK:
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return r, ErrNoGetMetaDataPermission
+		default:
+			return r, ErrUnexpectedResponse
+		}
+		for _, record := range page.Records {
+			more, err := fn(record)
+			if err != nil || !more {
+				return r, err
+			}
+		}
+		if !page.HasNext || len(page.Records) == 0 {
+			return r, nil
+		}
+		if page.NextSkip > skip {
+			skip = page.NextSkip
+		} else {
+			skip += len(page.Records)
+		}
+	}
+}
+
+// secretType returns the type of secret, which depends on its template
+func (c *TSSSecretClient) secretType(summary *tssSecretSummary) string {
+	if strings.EqualFold(summary.SecretTemplateName, c.textTemplate) {
+		return SecretTypeText
+	}
+	return SecretTypeKV
+}
+
+// tssItems returns the fields in a secret model
+func tssItems(model map[string]interface{}) []map[string]interface{} {
+	list, _ := model["items"].([]interface{})
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, v := range list {
+		if item, ok := v.(map[string]interface{}); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// tssItemKey returns the key of a field in a keyvalue secret
+func tssItemKey(item map[string]interface{}) string {
+	if slug, _ := item["slug"].(string); slug != "" {
+		return slug
+	}
+	name, _ := item["fieldName"].(string)
+	return name
+}
+
+// setTSSItems sets the fields in a secret model to 'value'.  A text value is saved in the first field.
+// The keys of a keyvalue value must be fields of the secret, and the other fields are cleared.
+func setTSSItems(model map[string]interface{}, value interface{}) error {
+	items := tssItems(model)
+	switch v := value.(type) {
+	case string:
+		if len(items) == 0 {
+			return fmt.Errorf("No field in text template: %w", ErrSecretTypeNotSupported)
+		}
+		items[0]["itemValue"] = v
+	case map[string]string:
+		fields := make(map[string]map[string]interface{}, len(items))
+		for _, item := range items {
+			if isFile, _ := item["isFile"].(bool); !isFile {
+				fields[strings.ToLower(tssItemKey(item))] = item
+				item["itemValue"] = ""
+			}
+		}
+		for key, value := range v {
+			item, ok := fields[strings.ToLower(key)]
+			if !ok {
+				return fmt.Errorf("Key [%s] is not a field of the secret template: %w", key, ErrSecretTypeNotSupported)
+			}
+			item["itemValue"] = value
+		}
+	default:
+		return ErrSecretTypeNotSupported
+	}
+	return nil
+}
+
+// splitPath returns the folder and name of the object in 'path'
+func splitPath(path string) (string, string) {
+	path = strings.Trim(path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return "", path
+}
+
+// tssFolderPath converts the path of a folder returned by Secret Server to a path separated by "/"
+func tssFolderPath(path string) string {
+	return strings.Trim(strings.ReplaceAll(path, `\`, "/"), "/")
+}
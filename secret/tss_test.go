@@ -0,0 +1,433 @@
+package secret
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+const testTSSToken = "test-tss-token"
+
+// tssTemplates are the secret templates in tssServer, with the slug names of their fields
+var tssTemplates = map[int]struct {
+	name   string
+	fields []string
+}{
+	1: {"Secure Note", []string{"notes"}},
+	2: {"Password", []string{"resource", "username", "password", "notes"}},
+}
+
+// tssServer is a minimal stand-in of the REST API of Secret Server.  It supports folders, secrets
+// and searching secret templates.  Records are returned in pages of at most 'take' records.
+type tssServer struct {
+	mu        sync.Mutex                     // protects folders and secrets
+	folders   map[int]*tssFolder             // folders indexed by ID
+	secrets   map[int]map[string]interface{} // secrets indexed by ID
+	forbidden map[int]bool                   // IDs of secrets that return 403
+	nextID    int                            // ID of next folder or secret
+}
+
+func newTSSServer() *tssServer {
+	return &tssServer{
+		folders:   make(map[int]*tssFolder),
+		secrets:   make(map[int]map[string]interface{}),
+		forbidden: make(map[int]bool),
+		nextID:    1,
+	}
+}
+
+func (t *tssServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+testTSSToken {
+		t.reply(w, http.StatusUnauthorized, map[string]interface{}{"message": "Authentication failed"})
+		return
+	}
+	var body map[string]interface{}
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+	query := r.URL.Query()
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/SecretServer/api/v1/"), "/")
+	id := -1
+	if len(segments) > 1 {
+		id, _ = strconv.Atoi(segments[1])
+	}
+
+	switch {
+	case segments[0] == "secret-templates" && r.Method == http.MethodGet:
+		var records []interface{}
+		for id, template := range tssTemplates {
+			if strings.Contains(strings.ToLower(template.name), strings.ToLower(query.Get("filter.searchText"))) {
+				records = append(records, map[string]interface{}{"id": id, "name": template.name})
+			}
+		}
+		t.page(w, r, records)
+	case segments[0] == "folders" && len(segments) == 1 && r.Method == http.MethodGet:
+		t.searchFolders(w, r)
+	case segments[0] == "folders" && len(segments) == 1 && r.Method == http.MethodPost:
+		parentID := int(body["parentFolderId"].(float64))
+		name := body["folderName"].(string)
+		folder := &tssFolder{ID: t.nextID, FolderName: name, ParentFolderID: parentID, FolderPath: `\` + name}
+		if parent, ok := t.folders[parentID]; ok {
+			folder.FolderPath = parent.FolderPath + `\` + name
+		}
+		t.nextID++
+		t.folders[folder.ID] = folder
+		t.reply(w, http.StatusOK, folder)
+	case segments[0] == "folders" && r.Method == http.MethodGet && t.folders[id] != nil:
+		t.reply(w, http.StatusOK, t.folders[id])
+	case segments[0] == "folders" && r.Method == http.MethodDelete && t.folders[id] != nil:
+		delete(t.folders, id)
+		t.reply(w, http.StatusOK, map[string]interface{}{"id": id})
+	case segments[0] == "secrets" && len(segments) == 1 && r.Method == http.MethodGet:
+		t.searchSecrets(w, r)
+	case segments[0] == "secrets" && len(segments) == 1 && r.Method == http.MethodPost:
+		body["id"] = t.nextID
+		t.nextID++
+		t.secrets[int(body["id"].(int))] = body
+		t.reply(w, http.StatusOK, body)
+	case segments[0] == "secrets" && segments[1] == "stub":
+		templateID, _ := strconv.Atoi(query.Get("filter.secretTemplateId"))
+		template := tssTemplates[templateID]
+		var items []interface{}
+		for i, slug := range template.fields {
+			items = append(items, map[string]interface{}{"fieldId": i + 1, "slug": slug, "fieldName": strings.Title(slug), "itemValue": ""})
+		}
+		t.reply(w, http.StatusOK, map[string]interface{}{
+			"secretTemplateId":   templateID,
+			"secretTemplateName": template.name,
+			"items":              items,
+		})
+	case segments[0] == "secrets" && t.secrets[id] != nil && t.forbidden[id]:
+		t.reply(w, http.StatusForbidden, map[string]interface{}{"message": "Access Denied"})
+	case segments[0] == "secrets" && r.Method == http.MethodGet && t.secrets[id] != nil:
+		t.reply(w, http.StatusOK, t.secrets[id])
+	case segments[0] == "secrets" && r.Method == http.MethodPut && t.secrets[id] != nil:
+		t.secrets[id] = body
+		t.reply(w, http.StatusOK, body)
+	case segments[0] == "secrets" && r.Method == http.MethodDelete && t.secrets[id] != nil:
+		delete(t.secrets, id)
+		t.reply(w, http.StatusOK, map[string]interface{}{"id": id})
+	default:
+		t.reply(w, http.StatusNotFound, map[string]interface{}{"message": "Not found"})
+	}
+}
+
+func (t *tssServer) reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// page returns the page of 'records' specified by skip and take
+func (t *tssServer) page(w http.ResponseWriter, r *http.Request, records []interface{}) {
+	skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+	take, _ := strconv.Atoi(r.URL.Query().Get("take"))
+	end := skip + take
+	if end > len(records) {
+		end = len(records)
+	}
+	page := []interface{}{}
+	if skip < end {
+		page = records[skip:end]
+	}
+	t.reply(w, http.StatusOK, map[string]interface{}{
+		"records":  page,
+		"hasNext":  end < len(records),
+		"nextSkip": end,
+	})
+}
+
+func (t *tssServer) searchFolders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var ids []int
+	for id, folder := range t.folders {
+		if parent := query.Get("filter.parentFolderId"); parent != "" && parent != strconv.Itoa(folder.ParentFolderID) {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(folder.FolderName), strings.ToLower(query.Get("filter.searchText"))) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	var records []interface{}
+	for _, id := range ids {
+		records = append(records, t.folders[id])
+	}
+	t.page(w, r, records)
+}
+
+func (t *tssServer) searchSecrets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var secrets []map[string]interface{}
+	for _, secret := range t.secrets {
+		if folder := query.Get("filter.folderId"); folder != "" && folder != strconv.Itoa(int(toFloat(secret["folderId"]))) {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(secret["name"].(string)), strings.ToLower(query.Get("filter.searchText"))) {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		if query.Get("sortBy[0].direction") == "desc" {
+			return secrets[i]["name"].(string) > secrets[j]["name"].(string)
+		}
+		return secrets[i]["name"].(string) < secrets[j]["name"].(string)
+	})
+	var records []interface{}
+	for _, secret := range secrets {
+		records = append(records, map[string]interface{}{
+			"id":                 secret["id"],
+			"name":               secret["name"],
+			"folderId":           secret["folderId"],
+			"secretTemplateId":   secret["secretTemplateId"],
+			"secretTemplateName": secret["secretTemplateName"],
+		})
+	}
+	t.page(w, r, records)
+}
+
+// toFloat returns a JSON number decoded by the server or set by the test as float64
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	}
+	return 0
+}
+
+// SecretTSSTestSuite tests TSSSecretClient against a local stand-in of the Secret Server REST API.
+type SecretTSSTestSuite struct {
+	testutils.CfyTestSuite
+	tss    *tssServer
+	server *httptest.Server
+	handle Secret
+}
+
+func TestSecretTSSTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretTSSTestSuite))
+}
+
+func (s *SecretTSSTestSuite) SetupTest() {
+	s.tss = newTSSServer()
+	s.server = httptest.NewTLSServer(s.tss)
+	var err error
+	s.handle, err = NewSecretClient(s.server.URL+"/SecretServer", ServerTSS, testTSSToken, s.server.Client)
+	s.Require().NoError(err, "Should create client for tss")
+}
+
+func (s *SecretTSSTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *SecretTSSTestSuite) TestTextSecret() {
+	success, id, _, err := s.handle.Create("app/note", "ignored", "my note")
+	s.Require().NoError(err, "Should create text secret")
+	s.Assert().True(success)
+	s.Assert().Equal("2", id, "ID should be assigned after the parent folder")
+
+	value, r, err := s.handle.Get("app/note")
+	s.Require().NoError(err, "Should get text secret")
+	s.Assert().Equal(200, r.StatusCode)
+	s.Assert().Equal("my note", value)
+
+	metadata, _, err := s.handle.GetMetaData("app/note")
+	s.Require().NoError(err, "Should get metadata")
+	s.Assert().Equal("note", metadata.Name)
+	s.Assert().Equal(SecretTypeText, metadata.Type)
+	s.Assert().Equal("2", metadata.ID)
+	s.Assert().Equal("app/note", metadata.CRN)
+
+	metadata, _, err = s.handle.GetMetaData("app")
+	s.Require().NoError(err, "Should get metadata of parent folder")
+	s.Assert().Equal(SecretTypeFolder, metadata.Type)
+	s.Assert().Equal("1", metadata.ID)
+}
+
+func (s *SecretTSSTestSuite) TestKeyValueSecret() {
+	kv := map[string]string{"username": "admin", "password": "secret"}
+	_, _, _, err := s.handle.Create("app/creds", "", kv)
+	s.Require().NoError(err, "Should create keyvalue secret")
+
+	value, _, err := s.handle.Get("app/creds")
+	s.Require().NoError(err, "Should get keyvalue secret")
+	s.Assert().Equal(kv, value, "Empty fields should not be returned")
+
+	_, _, _, err = s.handle.Create("app/other", "", map[string]string{"color": "blue"})
+	s.Assert().ErrorIs(err, ErrSecretTypeNotSupported, "Key must be a field of the template")
+
+	s.handle.(*TSSSecretClient).SetTemplates("", "Missing")
+	_, _, _, err = s.handle.Create("app/missing", "", kv)
+	s.Assert().ErrorIs(err, ErrSecretTypeNotSupported, "Template must exist")
+}
+
+func (s *SecretTSSTestSuite) TestCreateErrors() {
+	_, _, _, err := s.handle.Create("app/secret", "", "value")
+	s.Require().NoError(err)
+
+	_, _, _, err = s.handle.Create("app/secret", "", "value")
+	s.Assert().ErrorIs(err, ErrExists, "Duplicate secret")
+	_, _, _, err = s.handle.Create("app", "", "value")
+	s.Assert().ErrorIs(err, ErrExists, "Secret with same name as folder")
+	_, _, _, err = s.handle.CreateFolder("app", "")
+	s.Assert().ErrorIs(err, ErrExists, "Existing folder")
+	_, _, _, err = s.handle.CreateFolder("app/secret", "")
+	s.Assert().ErrorIs(err, ErrExists, "Folder with same name as secret")
+	_, _, _, err = s.handle.Create("app/ /bad", "", "value")
+	s.Assert().ErrorIs(err, ErrBadPathName)
+	_, _, _, err = s.handle.Create("app/number", "", 123)
+	s.Assert().ErrorIs(err, ErrSecretTypeNotSupported)
+}
+
+func (s *SecretTSSTestSuite) TestList() {
+	s.handle.Create("app/db/password", "", "pw")
+	s.handle.Create("app/api", "", map[string]string{"password": "value"})
+	s.handle.Create("top", "", "value")
+
+	items, _, err := s.handle.List("app")
+	s.Require().NoError(err, "Should list folder")
+	s.Assert().Equal([]Item{
+		{Name: "db", Type: SecretTypeFolder, ID: "2"},
+		{Name: "api", Type: SecretTypeKV, ID: "4"},
+	}, items)
+
+	items, _, err = s.handle.List("/")
+	s.Require().NoError(err, "Should list top level")
+	s.Assert().Equal([]Item{
+		{Name: "app", Type: SecretTypeFolder, ID: "1"},
+		{Name: "top", Type: SecretTypeText, ID: "5"},
+	}, items)
+
+	_, _, err = s.handle.List("missing")
+	s.Assert().ErrorIs(err, ErrFolderNotFound)
+	_, _, err = s.handle.List("top")
+	s.Assert().ErrorIs(err, ErrNotSecretFolder)
+}
+
+func (s *SecretTSSTestSuite) TestListSecrets() {
+	s.handle.Create("app/db/password", "", "pw")
+	s.handle.Create("app/api-key", "", "key")
+	s.handle.Create("other", "", "value")
+
+	var names []string
+	_, err := s.handle.ListSecrets(&ListOptions{Limit: 1, OrderBy: []string{"name desc"}}, func(item Item) error {
+		names = append(names, item.Name)
+		return nil
+	})
+	s.Require().NoError(err, "Should list secrets across pages")
+	s.Assert().Equal([]string{"app/db/password", "other", "app/api-key"}, names)
+
+	names = nil
+	_, err = s.handle.ListSecrets(&ListOptions{Search: "key"}, func(item Item) error {
+		names = append(names, item.Name)
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"app/api-key"}, names)
+
+	_, err = s.handle.ListSecrets(&ListOptions{Filter: "type eq 'text'"}, func(item Item) error { return nil })
+	s.Assert().ErrorIs(err, ErrInvalidListOption)
+}
+
+func (s *SecretTSSTestSuite) TestModify() {
+	s.handle.Create("app/creds", "", map[string]string{"username": "admin", "password": "old"})
+	s.handle.Create("app/sub/note", "", "value")
+
+	_, id, _, err := s.handle.Modify("app/creds", "", map[string]string{"username": "root", "Password": "new"})
+	s.Require().NoError(err, "Should modify secret")
+	s.Assert().Equal("2", id)
+	value, _, _ := s.handle.Get("app/creds")
+	s.Assert().Equal(map[string]string{"username": "root", "password": "new"}, value)
+
+	_, _, _, err = s.handle.Modify("app/creds", "", map[string]string{"password": "newer"})
+	s.Require().NoError(err)
+	value, _, _ = s.handle.Get("app/creds")
+	s.Assert().Equal(map[string]string{"password": "newer"}, value, "Missing keys should be cleared")
+
+	_, _, _, err = s.handle.Modify("app/creds", "", "text")
+	s.Assert().ErrorIs(err, ErrCannotModifySecretType)
+	_, _, _, err = s.handle.Modify("app/sub", "", "value")
+	s.Assert().ErrorIs(err, ErrCannotModifySecretFolder)
+	_, _, _, err = s.handle.Modify("app/missing", "", "value")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretTSSTestSuite) TestModifyMetaData() {
+	s.handle.Create("app/note", "", "value")
+
+	_, err := s.handle.ModifyMetaData("app/note", "", nil)
+	s.Assert().NoError(err)
+	_, err = s.handle.ModifyMetaData("app/note", "description", nil)
+	s.Assert().ErrorIs(err, ErrMetaDataNotSupported)
+	_, err = s.handle.ModifyMetaData("app", "", map[string]string{"owner": "ops"})
+	s.Assert().ErrorIs(err, ErrMetaDataNotSupported)
+	_, err = s.handle.ModifyMetaData("app/missing", "description", nil)
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretTSSTestSuite) TestDelete() {
+	s.handle.Create("app/secret", "", "value")
+
+	_, err := s.handle.Delete("app")
+	s.Assert().ErrorIs(err, ErrFolderNotEmpty)
+	_, err = s.handle.Delete("app/secret")
+	s.Require().NoError(err, "Should delete secret")
+	_, err = s.handle.Delete("app/secret")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, err = s.handle.Delete("app")
+	s.Require().NoError(err, "Should delete empty folder")
+	_, _, err = s.handle.GetMetaData("app")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretTSSTestSuite) TestPermission() {
+	_, id, _, err := s.handle.Create("locked", "", "value")
+	s.Require().NoError(err)
+	secretID, _ := strconv.Atoi(id)
+	s.tss.forbidden[secretID] = true
+
+	_, _, err = s.handle.Get("locked")
+	s.Assert().ErrorIs(err, ErrNoRetrievePermission)
+	_, _, _, err = s.handle.Modify("locked", "", "value")
+	s.Assert().ErrorIs(err, ErrNoModifyPermission)
+	_, err = s.handle.Delete("locked")
+	s.Assert().ErrorIs(err, ErrNoDeletePermission)
+
+	handle, err := NewSecretClient(s.server.URL+"/SecretServer", ServerTSS, "bad token", s.server.Client)
+	s.Require().NoError(err)
+	_, _, err = handle.Get("locked")
+	s.Assert().ErrorIs(err, ErrNoRetrievePermission, "Should reject bad token")
+}
+
+func (s *SecretTSSTestSuite) TestPathOfID() {
+	s.handle.Create("app/db/password", "", "pw")
+	s.handle.Create("top", "", "value")
+
+	path, _, err := s.handle.PathOfID("3")
+	s.Require().NoError(err, "Should find secret by ID")
+	s.Assert().Equal("app/db/password", path)
+	path, _, _ = s.handle.PathOfID("4")
+	s.Assert().Equal("top", path)
+	path, _, err = s.handle.PathOfID("2")
+	s.Require().NoError(err, "Should find folder by ID")
+	s.Assert().Equal("app/db", path)
+
+	for _, id := range []string{"9", "top", "-1"} {
+		_, _, err = s.handle.PathOfID(id)
+		s.Assert().ErrorIs(err, ErrSecretNotFound, id)
+	}
+}
@@ -0,0 +1,315 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHistoryFolder is the top level folder where VersionedSecretClient archives previous
+// values of secrets when VersionOptions.HistoryFolder is not specified.
+const DefaultHistoryFolder = ".history"
+
+// versionAttempts is the number of version numbers tried when a version is archived concurrently
+const versionAttempts = 3
+
+// versionsFolder is the folder under the path of a secret in the history folder where its versions
+// are archived, so that they are not mixed with the versions of the secrets under the same path
+const versionsFolder = ".versions"
+
+// VersionOptions specifies the options for NewVersionedSecretClient.
+type VersionOptions struct {
+	// HistoryFolder is the folder where previous values are archived, e.g., ".history" or
+	// "ops/.history".  The versions of the secret "app/db" are saved in the folder
+	// "<HistoryFolder>/app/db/.versions".  "" means DefaultHistoryFolder.
+	HistoryFolder string
+
+	// MaxVersions is the maximum number of versions kept for each secret.  When it is exceeded,
+	// the oldest versions are deleted after the secret is modified.  Deleting them is best-effort:
+	// errors are not returned, and the versions left are deleted by a later modification.  0 means
+	// all versions are kept.
+	MaxVersions int
+}
+
+// Version is a previous value of a secret archived by VersionedSecretClient.
+type Version struct {
+	Version      int       // version number.  Versions of a secret are numbered from 1 in the order they are archived
+	Description  string    // description of the secret when it is archived
+	WhenArchived time.Time // when the value is replaced.  Zero if the secret store does not report it
+}
+
+// VersionedSecretClient implements the Secret interface by archiving the value of a secret
+// before it is modified by Modify.  All other methods are passed to the underlying Secret.
+//
+// Each archived value is saved as a secret in the history folder with a version number that
+// increases monotonically, so that ListVersions, GetVersion and Rollback can access previous
+// values.  The history folder is hidden from the listing of its parent folder by List, so Walk,
+// DeleteTree, Export and PlanManifest with PlanOptions.Prune skip it when they are given the
+// VersionedSecretClient.  They include the archived versions when they are given the underlying
+// Secret, or a path in the history folder.  Versions are kept by path: they do not follow Move or
+// Rename, and they are kept when the secret is deleted.  Modifications made through other clients
+// are not archived.
+type VersionedSecretClient struct {
+	Secret // underlying secret client
+
+	historyFolder string // folder of archived versions
+	maxVersions   int    // maximum number of versions of a secret.  0 means unlimited
+}
+
+// NewVersionedSecretClient returns a client that archives previous values of the secrets of 'cl'
+// as specified in 'opts'.  'opts' can be nil, which keeps all versions in DefaultHistoryFolder.
+func NewVersionedSecretClient(cl Secret, opts *VersionOptions) *VersionedSecretClient {
+	if opts == nil {
+		opts = &VersionOptions{}
+	}
+	c := &VersionedSecretClient{
+		Secret:        cl,
+		historyFolder: strings.Trim(opts.HistoryFolder, "/"),
+		maxVersions:   opts.MaxVersions,
+	}
+	if c.historyFolder == "" {
+		c.historyFolder = DefaultHistoryFolder
+	}
+	return c
+}
+
+// Modify archives the current value and description of the secret in 'path' as a new version,
+// and modifies the secret.  If the modification fails, the archived version is deleted.  Secrets
+// in the history folder are modified without being archived.
+//
+// In addition to the errors returned by Modify of the underlying Secret, errors in getting the
+// current value or archiving it are returned, in which case the secret is not modified.  Errors in
+// deleting versions beyond VersionOptions.MaxVersions are ignored.
+// ErrBadPathName is returned for a secret with a folder or name ".versions" in its path, as it is
+// reserved for archived versions.
+func (c *VersionedSecretClient) Modify(path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	return c.ModifyContext(context.Background(), path, description, value)
+}
+
+// ModifyContext is the same as Modify, but uses 'ctx' for the requests.
+func (c *VersionedSecretClient) ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	path = strings.Trim(path, "/")
+	if c.inHistory(path) {
+		return c.Secret.ModifyContext(ctx, path, description, value)
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == versionsFolder {
+			return false, "", nil, fmt.Errorf("cannot archive [%s] as %s is reserved: %w", path, versionsFolder, ErrBadPathName)
+		}
+	}
+	info, _, err := c.Secret.GetMetaDataContext(ctx, path)
+	if err != nil || strings.EqualFold(info.Type, SecretTypeFolder) {
+		// let the secret store report the error
+		return c.Secret.ModifyContext(ctx, path, description, value)
+	}
+	current, r, err := c.Secret.GetContext(ctx, path)
+	if err != nil {
+		return false, "", r, fmt.Errorf("cannot get value of [%s]: %w", path, err)
+	}
+	current, _, err = normalizeValue(current)
+	if err != nil {
+		return false, "", r, fmt.Errorf("cannot archive [%s]: %w", path, err)
+	}
+
+	versions, err := c.versionNumbers(ctx, path)
+	if err != nil {
+		return false, "", nil, err
+	}
+	var archived string
+	for attempt := 0; archived == "" && attempt < versionAttempts; attempt++ {
+		next := 1
+		if len(versions) > 0 {
+			next = versions[len(versions)-1] + 1
+		}
+		versionPath := c.versionPath(path, next)
+		_, _, r, err = c.Secret.CreateContext(ctx, versionPath, info.Description, current)
+		switch {
+		case err == nil:
+			archived = versionPath
+			versions = append(versions, next)
+		case errors.Is(err, ErrExists):
+			// archived concurrently by another client
+			versions = append(versions, next)
+		default:
+			return false, "", r, fmt.Errorf("cannot archive [%s]: %w", path, err)
+		}
+	}
+	if archived == "" {
+		return false, "", r, fmt.Errorf("cannot archive [%s]: %w", path, err)
+	}
+
+	success, id, r, err := c.Secret.ModifyContext(ctx, path, description, value)
+	if err != nil {
+		c.Secret.DeleteContext(ctx, archived)
+		return success, id, r, err
+	}
+	if c.maxVersions > 0 {
+		c.pruneVersions(ctx, path)
+	}
+	return success, id, r, nil
+}
+
+// pruneVersions deletes the oldest versions of the secret in 'path' beyond the maximum number of
+// versions.  The versions are listed again, as other clients may have archived or deleted versions
+// after they were listed by ModifyContext.  Errors are ignored: versions that cannot be listed or
+// deleted are pruned by a later modification.
+func (c *VersionedSecretClient) pruneVersions(ctx context.Context, path string) {
+	versions, err := c.versionNumbers(ctx, path)
+	if err != nil || len(versions) <= c.maxVersions {
+		return
+	}
+	for _, version := range versions[:len(versions)-c.maxVersions] {
+		c.Secret.DeleteContext(ctx, c.versionPath(path, version))
+	}
+}
+
+// List lists all secrets in a folder specified in 'path'.  The history folder is not listed
+// in its parent folder.
+func (c *VersionedSecretClient) List(path string) ([]Item, *http.Response, error) {
+	return c.ListContext(context.Background(), path)
+}
+
+// ListContext is the same as List, but uses 'ctx' for the request.
+func (c *VersionedSecretClient) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	items, r, err := c.Secret.ListContext(ctx, path)
+	folder := strings.Trim(path, "/")
+	if err != nil || !c.inFolder(folder) {
+		return items, r, err
+	}
+	var visible []Item
+	for _, item := range items {
+		if joinPath(folder, strings.Trim(item.Name, "/")) != c.historyFolder {
+			visible = append(visible, item)
+		}
+	}
+	return visible, r, nil
+}
+
+// ListVersions returns the archived versions of the secret in 'path', oldest first.  It returns
+// nil if there is none.
+func (c *VersionedSecretClient) ListVersions(path string) ([]Version, error) {
+	return c.ListVersionsContext(context.Background(), path)
+}
+
+// ListVersionsContext is the same as ListVersions, but uses 'ctx' for the requests.
+func (c *VersionedSecretClient) ListVersionsContext(ctx context.Context, path string) ([]Version, error) {
+	path = strings.Trim(path, "/")
+	numbers, err := c.versionNumbers(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var versions []Version
+	for _, number := range numbers {
+		info, _, err := c.Secret.GetMetaDataContext(ctx, c.versionPath(path, number))
+		if errors.Is(err, ErrSecretNotFound) {
+			// deleted after it is listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, Version{Version: number, Description: info.Description, WhenArchived: info.WhenCreated})
+	}
+	return versions, nil
+}
+
+// GetVersion returns the value of the secret in 'path' in 'version', in the same type as Get.
+//
+// The following errors may be returned, in addition to those returned by Get:
+//	ErrSecretNotFound:  The version does not exist.
+func (c *VersionedSecretClient) GetVersion(path string, version int) (interface{}, error) {
+	return c.GetVersionContext(context.Background(), path, version)
+}
+
+// GetVersionContext is the same as GetVersion, but uses 'ctx' for the request.
+func (c *VersionedSecretClient) GetVersionContext(ctx context.Context, path string, version int) (interface{}, error) {
+	path = strings.Trim(path, "/")
+	value, _, err := c.Secret.GetContext(ctx, c.versionPath(path, version))
+	if err != nil {
+		return nil, fmt.Errorf("version %d of [%s]: %w", version, path, err)
+	}
+	return value, nil
+}
+
+// Rollback restores the value and description of the secret in 'path' to those in 'version'.
+// The current value is archived as a new version, so a rollback can be reverted.  If the secret
+// has been deleted, it is created again.
+//
+// The following errors may be returned, in addition to those returned by Modify and Create:
+//	ErrSecretNotFound:  The version does not exist.
+func (c *VersionedSecretClient) Rollback(path string, version int) error {
+	return c.RollbackContext(context.Background(), path, version)
+}
+
+// RollbackContext is the same as Rollback, but uses 'ctx' for the requests.
+func (c *VersionedSecretClient) RollbackContext(ctx context.Context, path string, version int) error {
+	path = strings.Trim(path, "/")
+	versionPath := c.versionPath(path, version)
+	info, _, err := c.Secret.GetMetaDataContext(ctx, versionPath)
+	if err != nil {
+		return fmt.Errorf("version %d of [%s]: %w", version, path, err)
+	}
+	value, err := c.GetVersionContext(ctx, path, version)
+	if err != nil {
+		return err
+	}
+	if value, _, err = normalizeValue(value); err != nil {
+		return fmt.Errorf("version %d of [%s]: %w", version, path, err)
+	}
+
+	_, _, _, err = c.ModifyContext(ctx, path, info.Description, value)
+	if errors.Is(err, ErrSecretNotFound) {
+		_, _, _, err = c.Secret.CreateContext(ctx, path, info.Description, value)
+	}
+	return err
+}
+
+// inHistory returns whether 'path' is in the history folder
+func (c *VersionedSecretClient) inHistory(path string) bool {
+	return path == c.historyFolder || strings.HasPrefix(path, c.historyFolder+"/")
+}
+
+// inFolder returns whether the history folder is directly in 'folder'
+func (c *VersionedSecretClient) inFolder(folder string) bool {
+	parent := ""
+	if i := strings.LastIndex(c.historyFolder, "/"); i >= 0 {
+		parent = c.historyFolder[:i]
+	}
+	return folder == parent
+}
+
+// versionPath returns the path where 'version' of the secret in 'path' is archived
+func (c *VersionedSecretClient) versionPath(path string, version int) string {
+	return joinPath(c.versionsPath(path), strconv.Itoa(version))
+}
+
+// versionsPath returns the path of the folder where the versions of the secret in 'path' are archived
+func (c *VersionedSecretClient) versionsPath(path string) string {
+	return joinPath(joinPath(c.historyFolder, path), versionsFolder)
+}
+
+// versionNumbers returns the numbers of the archived versions of the secret in 'path' in
+// ascending order
+func (c *VersionedSecretClient) versionNumbers(ctx context.Context, path string) ([]int, error) {
+	items, _, err := c.Secret.ListContext(ctx, c.versionsPath(path))
+	if errors.Is(err, ErrFolderNotFound) || errors.Is(err, ErrSecretNotFound) {
+		// nothing is archived
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot list versions of [%s]: %w", path, err)
+	}
+	var numbers []int
+	for _, item := range items {
+		if number, err := strconv.Atoi(item.Name); err == nil && number > 0 {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
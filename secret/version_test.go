@@ -0,0 +1,200 @@
+package secret
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretVersionTestSuite struct {
+	testutils.CfyTestSuite
+	store  *MemorySecretClient    // underlying secret client
+	handle *VersionedSecretClient // interface to secret API
+}
+
+func TestSecretVersionTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretVersionTestSuite))
+}
+
+func (s *SecretVersionTestSuite) SetupTest() {
+	s.store = newMemorySecretClient("")
+	s.store.setBinaryOptions(0, true)
+	s.handle = NewVersionedSecretClient(s.store, nil)
+}
+
+func (s *SecretVersionTestSuite) TestModifyArchives() {
+	s.handle.Create("app/db", "first", "v1")
+	versions, err := s.handle.ListVersions("app/db")
+	s.Require().NoError(err)
+	s.Assert().Nil(versions, "Create should not archive")
+
+	_, _, _, err = s.handle.Modify("app/db", "second", "v2")
+	s.Require().NoError(err)
+	_, _, _, err = s.handle.Modify("/app/db/", "third", map[string]string{"k": "v3"})
+	s.Assert().ErrorIs(err, ErrCannotModifySecretType)
+	_, _, _, err = s.handle.Modify("app/db", "", "v3")
+	s.Require().NoError(err)
+
+	versions, err = s.handle.ListVersions("app/db")
+	s.Require().NoError(err)
+	s.Require().Len(versions, 2, "Failed modification should not be archived")
+	s.Assert().Equal(1, versions[0].Version)
+	s.Assert().Equal("first", versions[0].Description)
+	s.Assert().False(versions[0].WhenArchived.IsZero())
+	s.Assert().Equal(2, versions[1].Version)
+	s.Assert().Equal("second", versions[1].Description)
+
+	value, err := s.handle.GetVersion("app/db", 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("v1", value)
+	value, _ = s.handle.GetVersion("app/db", 2)
+	s.Assert().Equal("v2", value)
+	_, err = s.handle.GetVersion("app/db", 3)
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	value, _, _ = s.handle.Get("app/db")
+	s.Assert().Equal("v3", value)
+
+	_, _, _, err = s.handle.Modify("app/missing", "", "value")
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+	_, _, _, err = s.handle.Modify("app", "", "value")
+	s.Assert().ErrorIs(err, ErrCannotModifySecretFolder)
+}
+
+func (s *SecretVersionTestSuite) TestRollback() {
+	s.handle.Create("db", "first", []byte{1})
+	s.handle.Modify("db", "second", []byte{2})
+
+	s.Require().NoError(s.handle.Rollback("db", 1))
+	value, _, _ := s.handle.Get("db")
+	s.Assert().Equal([]byte{1}, value)
+	metadata, _, _ := s.handle.GetMetaData("db")
+	s.Assert().Equal("first", metadata.Description)
+	versions, _ := s.handle.ListVersions("db")
+	s.Require().Len(versions, 2, "Rollback should archive current value")
+	value, _ = s.handle.GetVersion("db", 2)
+	s.Assert().Equal([]byte{2}, value)
+
+	s.handle.Delete("db")
+	s.Require().NoError(s.handle.Rollback("db", 2), "Deleted secret should be restored")
+	value, _, _ = s.handle.Get("db")
+	s.Assert().Equal([]byte{2}, value)
+
+	s.Assert().ErrorIs(s.handle.Rollback("db", 9), ErrSecretNotFound)
+}
+
+func (s *SecretVersionTestSuite) TestNestedPaths() {
+	s.handle.Create("a", "", "a1")
+	_, _, _, err := s.handle.Modify("a", "", "a2")
+	s.Require().NoError(err)
+
+	// versions are kept when "a" is deleted, so that they are in the history folder with "a/1"
+	s.handle.Delete("a")
+	s.handle.Create("a/1", "", "b1")
+	_, _, _, err = s.handle.Modify("a/1", "", "b2")
+	s.Require().NoError(err, "Versions of [a/1] should not collide with versions of [a]")
+
+	value, err := s.handle.GetVersion("a", 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("a1", value)
+	value, err = s.handle.GetVersion("a/1", 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("b1", value)
+	versions, _ := s.handle.ListVersions("a")
+	s.Assert().Len(versions, 1)
+
+	_, _, _, err = s.handle.Modify("app/.versions", "", "v")
+	s.Assert().ErrorIs(err, ErrBadPathName, "Folder of versions should be reserved")
+}
+
+func (s *SecretVersionTestSuite) TestMaxVersionsAndHistoryFolder() {
+	s.handle = NewVersionedSecretClient(s.store, &VersionOptions{HistoryFolder: "/versions/", MaxVersions: 2})
+	s.handle.Create("db", "", "v1")
+	for _, value := range []string{"v2", "v3", "v4"} {
+		_, _, _, err := s.handle.Modify("db", "", value)
+		s.Require().NoError(err)
+	}
+	versions, err := s.handle.ListVersions("db")
+	s.Require().NoError(err)
+	s.Require().Len(versions, 2)
+	s.Assert().Equal(2, versions[0].Version, "Oldest version should be deleted")
+	s.Assert().Equal(3, versions[1].Version)
+
+	s.handle.Modify("db", "", "v5")
+	versions, _ = s.handle.ListVersions("db")
+	s.Assert().Equal(4, versions[1].Version, "Version numbers should increase")
+
+	items, _, err := s.handle.List("")
+	s.Require().NoError(err)
+	s.Require().Len(items, 1, "History folder should be hidden")
+	s.Assert().Equal("db", items[0].Name)
+	items, _, _ = s.store.List("")
+	s.Assert().Len(items, 2)
+
+	_, _, _, err = s.handle.Modify("versions/db/.versions/4", "", "changed")
+	s.Require().NoError(err)
+	versions, _ = s.handle.ListVersions("versions/db/.versions/4")
+	s.Assert().Nil(versions, "Versions should not be archived")
+}
+
+func (s *SecretVersionTestSuite) TestNestedHistoryFolder() {
+	s.handle = NewVersionedSecretClient(s.store, &VersionOptions{HistoryFolder: "ops/.history"})
+	s.handle.Create("ops/db", "", "v1")
+	_, _, _, err := s.handle.Modify("ops/db", "", "v2")
+	s.Require().NoError(err)
+
+	items, _, err := s.handle.List("ops")
+	s.Require().NoError(err)
+	s.Require().Len(items, 1, "History folder should be hidden in its parent folder")
+	s.Assert().Equal("db", items[0].Name)
+	items, _, _ = s.handle.List("/ops/")
+	s.Assert().Len(items, 1)
+	items, _, _ = s.handle.List("")
+	s.Assert().Len(items, 1)
+
+	var paths []string
+	s.Require().NoError(Walk(s.handle, "", func(path string, info *MetaData, err error) error {
+		paths = append(paths, path)
+		return err
+	}))
+	s.Assert().Equal([]string{"", "ops", "ops/db"}, paths, "Walk should skip history folder")
+	paths = nil
+	Walk(s.store, "ops", func(path string, info *MetaData, err error) error {
+		paths = append(paths, path)
+		return err
+	})
+	s.Assert().Contains(paths, "ops/.history/ops/db/.versions/1", "Underlying client should include versions")
+}
+
+// modifyHookClient is a Secret that calls modifying before each modification
+type modifyHookClient struct {
+	Secret
+	modifying func()
+}
+
+func (c *modifyHookClient) ModifyContext(ctx context.Context, path string, description string, value interface{}) (bool, string, *http.Response, error) {
+	c.modifying()
+	return c.Secret.ModifyContext(ctx, path, description, value)
+}
+
+func (s *SecretVersionTestSuite) TestPruneArchivedConcurrently() {
+	other := NewVersionedSecretClient(s.store, nil)
+	hook := &modifyHookClient{Secret: s.store, modifying: func() {}}
+	s.handle = NewVersionedSecretClient(hook, &VersionOptions{MaxVersions: 2})
+	s.handle.Create("db", "", "v1")
+	hook.modifying = func() {
+		// archived by another client after the versions are listed
+		hook.modifying = func() {}
+		other.Modify("db", "", "v2")
+		other.Modify("db", "", "v3")
+	}
+
+	_, _, _, err := s.handle.Modify("db", "", "v4")
+	s.Require().NoError(err)
+	versions, _ := s.handle.ListVersions("db")
+	s.Require().Len(versions, 2, "Versions archived concurrently should be pruned")
+	s.Assert().Equal(2, versions[0].Version)
+	s.Assert().Equal(3, versions[1].Version)
+}
@@ -0,0 +1,232 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SkipFolder is used as a return value from a WalkFunc to indicate that the folder named in
+// the call is to be skipped.  It is not returned as an error by any function.
+var SkipFolder = errors.New("skip this folder")
+
+// WalkFunc is the type of the function called by Walk for each folder or secret visited.
+//
+// 'path' is the full path of the object, which can be used in other methods of Secret.  'info'
+// describes the object.  If WalkOptions.MetaData is not set, only the Item in 'info' is filled in.
+//
+// If there is a problem in getting information about 'path' or listing the contents of a folder,
+// 'err' describes the problem and the function decides how to handle it.  If an error is returned,
+// Walk stops.  When listing a folder fails, the function is called a second time for the folder
+// with 'err' set.
+//
+// If the function returns SkipFolder when invoked on a folder, Walk skips the contents of the
+// folder.  If the function returns SkipFolder when invoked on a secret, Walk skips the remaining
+// objects in the containing folder.
+type WalkFunc func(path string, info *MetaData, err error) error
+
+// WalkOptions specifies the options for WalkContext.
+type WalkOptions struct {
+	// Concurrency is the maximum number of folders that are listed concurrently.  If it is 0 or 1,
+	// the tree is walked sequentially in lexical order.  Otherwise folders are listed from multiple
+	// goroutines, and the order of calls to WalkFunc is not defined, but WalkFunc is called from one
+	// goroutine at a time, so it does not need to be safe for concurrent use.
+	Concurrency int
+
+	// MetaData specifies whether to retrieve the full metadata of each object visited.  This
+	// requires an additional request for each object.
+	MetaData bool
+}
+
+// Walk walks the secret tree rooted at 'root', calling 'fn' for each folder or secret in the tree,
+// including 'root'.  The tree is walked sequentially in lexical order.  'root' can be "" or "/"
+// to walk all secrets that the caller can access.
+//
+// Walk stops and returns the error when 'fn' returns an error other than SkipFolder.
+func Walk(cl Secret, root string, fn WalkFunc) error {
+	return WalkContext(context.Background(), cl, root, nil, fn)
+}
+
+// WalkContext is the same as Walk, but uses 'ctx' for all the requests and supports additional
+// options in 'opts'.  'opts' can be nil.
+// If 'ctx' is canceled, WalkContext stops and returns the error in 'ctx'.
+func WalkContext(ctx context.Context, cl Secret, root string, opts *WalkOptions, fn WalkFunc) error {
+	if opts == nil {
+		opts = &WalkOptions{}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := &walker{
+		ctx:      ctx,
+		cancel:   cancel,
+		cl:       cl,
+		opts:     opts,
+		fn:       fn,
+		sequence: opts.Concurrency <= 1,
+	}
+	if !w.sequence {
+		// the current goroutine is one of the workers
+		w.slots = make(chan struct{}, opts.Concurrency-1)
+		w.fn = w.serializedFunc(fn)
+	}
+
+	info, err := w.rootInfo(root)
+	if err != nil {
+		err = w.fn(root, nil, err)
+		if err == SkipFolder {
+			return nil
+		}
+		return err
+	}
+	w.walk(root, info)
+	w.wg.Wait()
+
+	if w.err != nil {
+		return w.err
+	}
+	return ctx.Err()
+}
+
+// walker keeps the state of a walk
+type walker struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	cl       Secret
+	opts     *WalkOptions
+	fn       WalkFunc
+	sequence bool          // whether the walk is done sequentially
+	slots    chan struct{} // available slots for additional goroutines
+	wg       sync.WaitGroup
+	errOnce  sync.Once
+	err      error // first error returned by fn
+}
+
+// serializedFunc returns a WalkFunc that ensures 'fn' is not called concurrently.
+func (w *walker) serializedFunc(fn WalkFunc) WalkFunc {
+	var mu sync.Mutex
+	return func(path string, info *MetaData, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return fn(path, info, err)
+	}
+}
+
+// rootInfo returns information about the root of the walk
+func (w *walker) rootInfo(root string) (*MetaData, error) {
+	if isTopLevel(root) {
+		return &MetaData{Item: Item{Name: "/", Type: SecretTypeFolder}}, nil
+	}
+	info, _, err := w.cl.GetMetaDataContext(w.ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// setError saves the first error and stops the walk
+func (w *walker) setError(err error) {
+	w.errOnce.Do(func() {
+		w.err = err
+		w.cancel()
+	})
+}
+
+// walk calls fn for 'path' and walks its contents if it is a folder.
+// It returns false if the remaining objects in the containing folder are to be skipped.
+func (w *walker) walk(path string, info *MetaData) bool {
+	if w.ctx.Err() != nil {
+		return false
+	}
+	err := w.fn(path, info, nil)
+	if err == SkipFolder {
+		// skip contents of folder, or the remaining objects in containing folder
+		return strings.EqualFold(info.Type, SecretTypeFolder)
+	}
+	if err != nil {
+		w.setError(err)
+		return false
+	}
+	if !strings.EqualFold(info.Type, SecretTypeFolder) {
+		return true
+	}
+
+	if w.sequence {
+		w.walkFolder(path, info)
+		return true
+	}
+	select {
+	case w.slots <- struct{}{}:
+		// list the folder in another goroutine
+		w.wg.Add(1)
+		go func() {
+			defer func() {
+				<-w.slots
+				w.wg.Done()
+			}()
+			w.walkFolder(path, info)
+		}()
+	default:
+		// no slot available, list the folder in current goroutine
+		w.walkFolder(path, info)
+	}
+	return true
+}
+
+// walkFolder walks the contents of the folder in 'path'
+func (w *walker) walkFolder(path string, info *MetaData) {
+	listPath := path
+	if isTopLevel(path) {
+		listPath = "/"
+	}
+	items, _, err := w.cl.ListContext(w.ctx, listPath)
+	if err != nil {
+		if w.ctx.Err() != nil {
+			return
+		}
+		err = w.fn(path, info, err)
+		if err != nil && err != SkipFolder {
+			w.setError(err)
+		}
+		return
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+
+	for _, item := range items {
+		childPath := joinPath(path, item.Name)
+		childInfo := &MetaData{Item: item}
+		if w.opts.MetaData {
+			childInfo, _, err = w.cl.GetMetaDataContext(w.ctx, childPath)
+			if err != nil {
+				if w.ctx.Err() != nil {
+					return
+				}
+				// let fn decide whether to continue; the object itself is not visited
+				err = w.fn(childPath, &MetaData{Item: item}, err)
+				if err != nil && err != SkipFolder {
+					w.setError(err)
+					return
+				}
+				continue
+			}
+		}
+		if !w.walk(childPath, childInfo) {
+			return
+		}
+	}
+}
+
+// isTopLevel returns whether 'path' refers to the top level folder
+func isTopLevel(path string) bool {
+	return path == "" || path == "/"
+}
+
+// joinPath returns the path of the object 'name' in the folder 'folder'
+func joinPath(folder string, name string) string {
+	if isTopLevel(folder) {
+		return name
+	}
+	return strings.TrimSuffix(folder, "/") + "/" + name
+}
@@ -0,0 +1,216 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+// treeSecret is a Secret that only supports listing and getting metadata of a fixed tree of secrets.
+// Calling other methods panics.
+type treeSecret struct {
+	Secret                    // not set, other methods are not supported
+	folders map[string][]Item // contents of each folder, indexed by path.  Top level is "/"
+	delay   time.Duration     // delay in each list request
+
+	mu      sync.Mutex
+	active  int // number of active list requests
+	maxSeen int // maximum number of concurrent list requests
+}
+
+func (t *treeSecret) ListContext(ctx context.Context, path string) ([]Item, *http.Response, error) {
+	t.mu.Lock()
+	t.active++
+	if t.active > t.maxSeen {
+		t.maxSeen = t.active
+	}
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.active--
+		t.mu.Unlock()
+	}()
+
+	time.Sleep(t.delay)
+	items, ok := t.folders[path]
+	if !ok {
+		return nil, nil, ErrFolderNotFound
+	}
+	return append([]Item(nil), items...), nil, nil
+}
+
+func (t *treeSecret) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	folder, name := "/", path
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			folder, name = path[:i], path[i+1:]
+			break
+		}
+	}
+	for _, item := range t.folders[folder] {
+		if item.Name == name {
+			return &MetaData{Item: item, CRN: "crn:" + path}, nil, nil
+		}
+	}
+	return nil, nil, ErrSecretNotFound
+}
+
+type SecretWalkTestSuite struct {
+	testutils.CfyTestSuite
+	tree *treeSecret
+}
+
+func TestSecretWalkTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretWalkTestSuite))
+}
+
+func (s *SecretWalkTestSuite) SetupTest() {
+	s.tree = &treeSecret{
+		folders: map[string][]Item{
+			"/": {
+				{Name: "b", Type: SecretTypeFolder, ID: "id-b"},
+				{Name: "a", Type: SecretTypeFolder, ID: "id-a"},
+				{Name: "top", Type: SecretTypeText, ID: "id-top"},
+			},
+			"a": {
+				{Name: "s2", Type: SecretTypeKV, ID: "id-a-s2"},
+				{Name: "s1", Type: SecretTypeText, ID: "id-a-s1"},
+				{Name: "sub", Type: SecretTypeFolder, ID: "id-a-sub"},
+			},
+			"a/sub": {
+				{Name: "deep", Type: SecretTypeText, ID: "id-deep"},
+			},
+			"b": {
+				{Name: "s3", Type: SecretTypeText, ID: "id-b-s3"},
+			},
+		},
+	}
+}
+
+func (s *SecretWalkTestSuite) TestWalkAll() {
+	var visited []string
+	err := Walk(s.tree, "/", func(path string, info *MetaData, err error) error {
+		s.Assert().NoError(err, "Should not have error for [%s]", path)
+		visited = append(visited, path)
+		return nil
+	})
+	s.Assert().NoError(err, "Walk should not return error")
+	expected := []string{"/", "a", "a/s1", "a/s2", "a/sub", "a/sub/deep", "b", "b/s3", "top"}
+	s.Assert().Equal(expected, visited, "Should visit all objects in lexical order")
+}
+
+func (s *SecretWalkTestSuite) TestWalkSubtree() {
+	var visited []string
+	var ids []string
+	err := Walk(s.tree, "a", func(path string, info *MetaData, err error) error {
+		visited = append(visited, path)
+		ids = append(ids, info.ID)
+		return nil
+	})
+	s.Assert().NoError(err, "Walk should not return error")
+	s.Assert().Equal([]string{"a", "a/s1", "a/s2", "a/sub", "a/sub/deep"}, visited)
+	s.Assert().Equal([]string{"id-a", "id-a-s1", "id-a-s2", "id-a-sub", "id-deep"}, ids)
+}
+
+func (s *SecretWalkTestSuite) TestSkipFolder() {
+	var visited []string
+	err := Walk(s.tree, "", func(path string, info *MetaData, err error) error {
+		visited = append(visited, path)
+		if path == "a" || path == "b/s3" {
+			return SkipFolder
+		}
+		return nil
+	})
+	s.Assert().NoError(err, "SkipFolder should not be returned as error")
+	s.Assert().Equal([]string{"", "a", "b", "b/s3", "top"}, visited)
+}
+
+func (s *SecretWalkTestSuite) TestStopOnError() {
+	errStop := errors.New("stop")
+	var visited []string
+	err := Walk(s.tree, "", func(path string, info *MetaData, err error) error {
+		visited = append(visited, path)
+		if path == "a/s2" {
+			return errStop
+		}
+		return nil
+	})
+	s.Assert().ErrorIs(err, errStop, "Walk should return error from WalkFunc")
+	s.Assert().Equal([]string{"", "a", "a/s1", "a/s2"}, visited)
+}
+
+func (s *SecretWalkTestSuite) TestListError() {
+	// folder b is listed in its parent but cannot be listed
+	delete(s.tree.folders, "b")
+	var errPaths []string
+	err := Walk(s.tree, "", func(path string, info *MetaData, err error) error {
+		if err != nil {
+			s.Assert().ErrorIs(err, ErrFolderNotFound)
+			errPaths = append(errPaths, path)
+		}
+		return nil
+	})
+	s.Assert().NoError(err, "Walk should continue when WalkFunc returns nil for error")
+	s.Assert().Equal([]string{"b"}, errPaths)
+}
+
+func (s *SecretWalkTestSuite) TestRootNotFound() {
+	err := Walk(s.tree, "missing", func(path string, info *MetaData, err error) error {
+		s.Assert().Nil(info, "No information for missing root")
+		return err
+	})
+	s.Assert().ErrorIs(err, ErrSecretNotFound)
+}
+
+func (s *SecretWalkTestSuite) TestWalkMetaData() {
+	crns := make(map[string]string)
+	err := WalkContext(context.Background(), s.tree, "a", &WalkOptions{MetaData: true}, func(path string, info *MetaData, err error) error {
+		crns[path] = info.CRN
+		return nil
+	})
+	s.Assert().NoError(err, "Walk should not return error")
+	s.Assert().Equal("crn:a/sub/deep", crns["a/sub/deep"], "Should return full metadata")
+	s.Assert().Equal("crn:a/s1", crns["a/s1"], "Should return full metadata")
+}
+
+func (s *SecretWalkTestSuite) TestConcurrentWalk() {
+	// create a wide tree
+	s.tree.folders["/"] = nil
+	for _, name := range []string{"f1", "f2", "f3", "f4", "f5", "f6"} {
+		s.tree.folders["/"] = append(s.tree.folders["/"], Item{Name: name, Type: SecretTypeFolder})
+		s.tree.folders[name] = []Item{{Name: "secret", Type: SecretTypeText}}
+	}
+	s.tree.delay = 20 * time.Millisecond
+
+	// visited is not locked, as WalkFunc is called from one goroutine at a time
+	var visited []string
+	opts := &WalkOptions{Concurrency: 3}
+	err := WalkContext(context.Background(), s.tree, "/", opts, func(path string, info *MetaData, err error) error {
+		visited = append(visited, path)
+		return nil
+	})
+	s.Assert().NoError(err, "Walk should not return error")
+	s.Assert().Len(visited, 13, "Should visit all objects")
+	sort.Strings(visited)
+	s.Assert().Equal([]string{"/", "f1", "f1/secret", "f2"}, visited[:4])
+	s.Assert().LessOrEqual(s.tree.maxSeen, 3, "Should not exceed concurrency limit")
+	s.Assert().Greater(s.tree.maxSeen, 1, "Should list folders concurrently")
+}
+
+func (s *SecretWalkTestSuite) TestCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := WalkContext(ctx, s.tree, "/", nil, func(path string, info *MetaData, err error) error {
+		if path == "a/s1" {
+			cancel()
+		}
+		return nil
+	})
+	s.Assert().ErrorIs(err, context.Canceled, "Walk should return context error")
+}
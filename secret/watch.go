@@ -0,0 +1,219 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is the interval between polls of Watch when 'interval' is not specified.
+const DefaultWatchInterval = time.Minute
+
+const (
+	watchMaxBackoff = 16 // maximum multiple of the interval that Watch waits after errors
+	watchBufferSize = 16 // number of events that Watch can send before they are received
+)
+
+// WatchAction is the change of a secret or folder reported by Watch.
+type WatchAction string
+
+// The actions reported by Watch
+const (
+	WatchCreated  WatchAction = "created"  // secret/folder is created
+	WatchModified WatchAction = "modified" // value or metadata of secret/folder is modified
+	WatchDeleted  WatchAction = "deleted"  // secret/folder is deleted
+	WatchFailed   WatchAction = "failed"   // secret store cannot be polled.  See WatchEvent.Err
+)
+
+// WatchEvent is a change of a secret or folder reported by Watch.
+type WatchEvent struct {
+	Action WatchAction
+	Path   string      // path of secret/folder.  For WatchFailed, the path passed to Watch
+	Info   *MetaData   // metadata of secret/folder after the change.  nil for WatchDeleted and WatchFailed
+	Value  interface{} // value of secret after the change, in the same type as Get.  nil for folders, WatchDeleted and WatchFailed
+	Err    error       // error in polling the secret store for WatchFailed
+}
+
+// Watch watches the secret or folder in 'path' for changes by polling the secret store every
+// 'interval', and sends the changes found to the returned channel.  For a folder, all secrets and
+// folders under it are watched.  'path' can be "" or "/" to watch all secrets that the caller can
+// access.  If 'interval' is 0 or less, DefaultWatchInterval is used.
+//
+// The metadata of the watched secrets and folders is read with GetMetaData in each poll, and a
+// secret or folder is modified if its ID, type, version or modification time has changed.  The new
+// value of a secret that is created or modified is read with Get and sent in WatchEvent.Value.
+// Changes that are undone before the next poll are not reported, and modifications cannot be
+// detected in secret stores that report neither the version nor the modification time, e.g., TSS.
+//
+// The state when Watch is called is not reported.  If 'path' does not exist, its creation is
+// reported as a change.  A random jitter of up to a tenth of the interval is added to or
+// subtracted from each interval, so that clients do not poll in lockstep.  If a poll fails, an
+// event with WatchFailed is sent, and the interval is doubled after each failure up to 16 times
+// 'interval' until a poll succeeds.  Events are not sent for a failed poll, so a change is
+// reported by the next successful poll.
+//
+// The channel is closed when 'ctx' is done.  Polling waits while the channel is full, so events
+// should be received promptly.
+func Watch(ctx context.Context, cl Secret, path string, interval time.Duration) <-chan WatchEvent {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &watcher{
+		cl:       cl,
+		path:     strings.Trim(path, "/"),
+		interval: interval,
+		events:   make(chan WatchEvent, watchBufferSize),
+	}
+	if cached, ok := cl.(*CachedSecretClient); ok {
+		// the current metadata is needed, not the cached one
+		w.cl = cached.Secret
+		w.cached = cached
+	}
+	go w.run(ctx)
+	return w.events
+}
+
+// watcher keeps the state of Watch
+type watcher struct {
+	cl       Secret
+	cached   *CachedSecretClient // client whose cached results are invalidated when a change is found
+	path     string
+	interval time.Duration
+	events   chan WatchEvent
+	objects  map[string]*MetaData // secrets and folders found in the last successful poll
+}
+
+// run polls the secret store until 'ctx' is done
+func (w *watcher) run(ctx context.Context) {
+	defer close(w.events)
+	failures := 0
+	for {
+		err := w.poll(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			failures++
+			if !w.send(ctx, WatchEvent{Action: WatchFailed, Path: w.path, Err: err}) {
+				return
+			}
+		} else {
+			failures = 0
+		}
+
+		timer := time.NewTimer(w.delay(failures))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the time to wait before the next poll after 'failures' consecutive failed polls
+func (w *watcher) delay(failures int) time.Duration {
+	d := w.interval
+	for i := 0; i < failures && d < w.interval*watchMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > w.interval*watchMaxBackoff {
+		d = w.interval * watchMaxBackoff
+	}
+	jitter := int64(d / 10)
+	return d + time.Duration(rand.Int63n(2*jitter+1)-jitter)
+}
+
+// poll reads the current state of the watched secrets and folders, and sends the changes since
+// the last successful poll.  Nothing is sent if it fails.
+func (w *watcher) poll(ctx context.Context) error {
+	objects := make(map[string]*MetaData)
+	err := WalkContext(ctx, w.cl, w.path, &WalkOptions{MetaData: true}, func(path string, info *MetaData, err error) error {
+		if errors.Is(err, ErrSecretNotFound) || errors.Is(err, ErrFolderNotFound) {
+			// does not exist, or deleted while walking
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !isTopLevel(path) {
+			objects[path] = info
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if w.objects == nil {
+		// the initial state is not reported
+		w.objects = objects
+		return nil
+	}
+
+	var events []WatchEvent
+	for path, info := range objects {
+		old, ok := w.objects[path]
+		switch {
+		case !ok:
+			events = append(events, WatchEvent{Action: WatchCreated, Path: path, Info: info})
+		case info.ID != old.ID || info.Type != old.Type || info.Version != old.Version ||
+			!info.WhenModified.Equal(old.WhenModified):
+			events = append(events, WatchEvent{Action: WatchModified, Path: path, Info: info})
+		}
+	}
+	for path := range w.objects {
+		if _, ok := objects[path]; !ok {
+			events = append(events, WatchEvent{Action: WatchDeleted, Path: path})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+
+	// read all new values before any event is sent, so that a failed poll sends nothing
+	for i := range events {
+		e := &events[i]
+		if e.Action == WatchDeleted || strings.EqualFold(e.Info.Type, SecretTypeFolder) {
+			continue
+		}
+		e.Value, _, err = w.cl.GetContext(ctx, e.Path)
+		if errors.Is(err, ErrSecretNotFound) {
+			// deleted after its metadata is read
+			delete(objects, e.Path)
+			e.Action, e.Info = WatchDeleted, nil
+			if _, ok := w.objects[e.Path]; !ok {
+				e.Action = ""
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	w.objects = objects
+	for _, e := range events {
+		if e.Action == "" {
+			// created and deleted between polls
+			continue
+		}
+		if w.cached != nil {
+			w.cached.Invalidate(e.Path)
+		}
+		if !w.send(ctx, e) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// send sends 'e' to the channel.  It returns false if 'ctx' is done before 'e' is received.
+func (w *watcher) send(ctx context.Context, e WatchEvent) bool {
+	select {
+	case w.events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,145 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretWatchTestSuite struct {
+	memorySuite
+}
+
+// unavailableClient fails GetMetaData while 'fail' is set
+type unavailableClient struct {
+	Secret
+	fail bool
+}
+
+func (c *unavailableClient) GetMetaDataContext(ctx context.Context, path string) (*MetaData, *http.Response, error) {
+	if c.fail {
+		return nil, nil, ErrUnexpectedResponse
+	}
+	return c.Secret.GetMetaDataContext(ctx, path)
+}
+
+func TestSecretWatchTestSuite(t *testing.T) {
+	suite.Run(t, new(SecretWatchTestSuite))
+}
+
+// newWatcher returns a watcher whose polls are made by the test
+func (s *SecretWatchTestSuite) newWatcher(cl Secret, path string) *watcher {
+	w := &watcher{cl: cl, path: path, interval: time.Second, events: make(chan WatchEvent, watchBufferSize)}
+	s.Require().NoError(w.poll(context.Background()))
+	return w
+}
+
+// received returns the events sent by the last poll of 'w'
+func (s *SecretWatchTestSuite) received(w *watcher) []WatchEvent {
+	var events []WatchEvent
+	for len(w.events) > 0 {
+		events = append(events, <-w.events)
+	}
+	return events
+}
+
+func (s *SecretWatchTestSuite) TestFolder() {
+	s.handle.Create("app/db", "", "v1")
+	s.handle.Create("app/old", "", "value")
+	s.handle.Create("other", "", "value")
+	w := s.newWatcher(s.handle, "app")
+	s.Assert().Empty(s.received(w), "Initial state should not be reported")
+
+	s.handle.Modify("app/db", "", "v2")
+	s.handle.Create("app/sub/new", "", map[string]string{"k": "v"})
+	s.handle.Delete("app/old")
+	s.handle.Modify("other", "", "changed")
+	s.Require().NoError(w.poll(context.Background()))
+	events := s.received(w)
+	s.Require().Len(events, 4)
+	s.Assert().Equal(WatchModified, events[0].Action)
+	s.Assert().Equal("app/db", events[0].Path)
+	s.Assert().Equal("v2", events[0].Value)
+	s.Assert().Equal(WatchDeleted, events[1].Action)
+	s.Assert().Equal("app/old", events[1].Path)
+	s.Assert().Nil(events[1].Info)
+	s.Assert().Equal(WatchCreated, events[2].Action)
+	s.Assert().Equal("app/sub", events[2].Path)
+	s.Assert().Equal(SecretTypeFolder, events[2].Info.Type)
+	s.Assert().Nil(events[2].Value, "Folder should have no value")
+	s.Assert().Equal(WatchCreated, events[3].Action)
+	s.Assert().Equal("app/sub/new", events[3].Path)
+	s.Assert().Equal(map[string]string{"k": "v"}, events[3].Value)
+
+	s.Require().NoError(w.poll(context.Background()))
+	s.Assert().Empty(s.received(w), "Unchanged secrets should not be reported")
+}
+
+func (s *SecretWatchTestSuite) TestSecret() {
+	w := s.newWatcher(s.handle, "db")
+	s.handle.Create("db", "", "v1")
+	s.Require().NoError(w.poll(context.Background()))
+	events := s.received(w)
+	s.Require().Len(events, 1, "Creation of missing secret should be reported")
+	s.Assert().Equal(WatchCreated, events[0].Action)
+	s.Assert().Equal("v1", events[0].Value)
+
+	s.handle.ModifyMetaData("db", "changed", nil)
+	s.Require().NoError(w.poll(context.Background()))
+	events = s.received(w)
+	s.Require().Len(events, 1)
+	s.Assert().Equal(WatchModified, events[0].Action)
+	s.Assert().Equal("changed", events[0].Info.Description)
+
+	s.handle.Delete("db")
+	s.Require().NoError(w.poll(context.Background()))
+	events = s.received(w)
+	s.Require().Len(events, 1)
+	s.Assert().Equal(WatchDeleted, events[0].Action)
+	s.Assert().Equal("db", events[0].Path)
+}
+
+func (s *SecretWatchTestSuite) TestFailure() {
+	cl := &unavailableClient{Secret: s.handle}
+	s.handle.Create("db", "", "v1")
+	w := s.newWatcher(cl, "db")
+	cl.fail = true
+	s.handle.Modify("db", "", "v2")
+	err := w.poll(context.Background())
+	s.Assert().ErrorIs(err, ErrUnexpectedResponse)
+	s.Assert().Empty(s.received(w))
+
+	cl.fail = false
+	s.Require().NoError(w.poll(context.Background()))
+	events := s.received(w)
+	s.Require().Len(events, 1, "Change should be reported after failure")
+	s.Assert().Equal("v2", events[0].Value)
+
+	d := w.delay(1)
+	s.Assert().True(d >= 1800*time.Millisecond && d <= 2200*time.Millisecond, "Unexpected delay %v", d)
+	d = w.delay(10)
+	s.Assert().True(d >= 14400*time.Millisecond && d <= 17600*time.Millisecond, "Backoff should be limited: %v", d)
+}
+
+func (s *SecretWatchTestSuite) TestWatch() {
+	cl := &unavailableClient{Secret: s.handle, fail: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := Watch(ctx, cl, "/app/", time.Millisecond)
+
+	select {
+	case e := <-events:
+		s.Assert().Equal(WatchFailed, e.Action)
+		s.Assert().Equal("app", e.Path)
+		s.Assert().True(errors.Is(e.Err, ErrUnexpectedResponse))
+	case <-time.After(5 * time.Second):
+		s.FailNow("No event is sent")
+	}
+	cancel()
+	for range events {
+		// drain the events sent before cancel
+	}
+}
@@ -0,0 +1,651 @@
+/*
+Package pasemu emulates the secrets API of Centrify PAS so that code that uses the secret package
+can be tested without a tenant.
+
+Endpoints
+
+Server implements the following endpoints of the secrets API in
+internal/secretinternal/api/openapi.yaml, under the base path /api/v1.0:
+
+	GET    /secrets                          List secrets, with limit, orderBy, search and filter
+	POST   /secrets                          Create a text, keyvalue or folder secret
+	GET    /secrets/{nameOrId}               Get a secret or folder, including the items of a folder
+	PATCH  /secrets/{nameOrId}               Modify a secret, rename a secret or folder, or modify its description
+	DELETE /secrets/{nameOrId}               Delete a secret or empty folder
+	GET    /privilegeddata/secrets/{nameOrId} Retrieve the value of a secret
+
+Secret lists are returned in pages.  The next_url of a page is an absolute URL of the next page, or null
+for the last page.  Errors are returned as JSON objects with type, title, status and detail, and each
+response has a X-CFY-TX-ID header.
+
+Secrets are stored in memory and follow the semantics of PAS: parent folders are created when a secret
+is created but not when it is renamed, only empty folders can be deleted, and the type of a secret
+cannot be changed.
+
+Authentication
+
+Each request to the secrets API must have a bearer token in the Authorization header.  The token is
+either added by AddToken, or obtained from the endpoint /oauth2/token/{appID} with the client
+credentials or resource owner grant, using the user name and password added by AddUser.  The endpoint
+/health/ping can be used to check whether the server is running.
+
+Server is a http.Handler.  PAS is only accessed by HTTPS, so the server must be run with TLS, e.g., by
+httptest.NewTLSServer, or by http.Server with the certificate returned by NewCertificate.
+*/
+package pasemu
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centrify/platform-go-sdk/internal/memstore"
+)
+
+// basePath is the base path of the secrets API
+const basePath = "/api/v1.0"
+
+// limits of the number of secrets in a page
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// DefaultTokenLifetime is the lifetime of tokens issued by the server if Server.TokenLifetime is not set
+const DefaultTokenLifetime = time.Hour
+
+// Server emulates the secrets API of Centrify PAS.  It is safe for concurrent use.
+type Server struct {
+	// PageSize is the number of secrets in a page when the list request does not specify a limit.
+	// The default is 10, which is the default of PAS.
+	PageSize int
+
+	// TokenLifetime is the lifetime of tokens issued by /oauth2/token.  The default is DefaultTokenLifetime.
+	TokenLifetime time.Duration
+
+	// NoRename makes the server answer modify requests that change the name of a secret or folder with
+	// status 501 Not Implemented, like a version of PAS that cannot rename secrets.
+	NoRename bool
+
+	// ValueRequired makes the server reject modify requests of secrets that do not have the value, like
+	// a version of PAS that cannot modify only the description of a secret.
+	ValueRequired bool
+
+	store  *memstore.Store      // secrets
+	mu     sync.Mutex           // protects users and tokens
+	users  map[string]string    // passwords of users indexed by user name
+	tokens map[string]time.Time // expiry time of accepted tokens.  Zero time means no expiry.
+}
+
+// NewServer returns a server with no secrets, users and tokens
+func NewServer() *Server {
+	return &Server{
+		store:  memstore.New(),
+		users:  make(map[string]string),
+		tokens: make(map[string]time.Time),
+	}
+}
+
+// AddUser adds a user that can get a token from /oauth2/token.  The user name and password are
+// accepted as client ID and secret in the client credentials grant, and as user name and password in
+// the resource owner grant.
+func (s *Server) AddUser(name string, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[name] = password
+}
+
+// AddToken adds a bearer token that never expires
+func (s *Server) AddToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token] = time.Time{}
+}
+
+// RevokeToken removes a bearer token, so that requests with the token are rejected
+func (s *Server) RevokeToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
+
+// Reset deletes all secrets and folders
+func (s *Server) Reset() {
+	s.store.Clear()
+}
+
+// ServeHTTP handles a request to the server
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-CFY-TX-ID", newTxID())
+
+	path := r.URL.Path
+	switch {
+	case path == "/health/ping" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+		return
+	case strings.HasPrefix(path, "/oauth2/token/"):
+		s.serveToken(w, r)
+		return
+	case !strings.HasPrefix(path, basePath+"/"):
+		writeError(w, r, http.StatusNotFound, "Not found", "Unknown endpoint")
+		return
+	}
+
+	if !s.authorized(r) {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized", "Missing, invalid or expired bearer token")
+		return
+	}
+	path = strings.TrimPrefix(path, basePath)
+	switch {
+	case path == "/secrets":
+		switch r.Method {
+		case http.MethodGet:
+			s.list(w, r)
+		case http.MethodPost:
+			s.create(w, r)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", r.Method)
+		}
+	case strings.HasPrefix(path, "/secrets/"):
+		nameOrID := strings.TrimPrefix(path, "/secrets/")
+		switch r.Method {
+		case http.MethodGet:
+			s.get(w, r, nameOrID)
+		case http.MethodPatch:
+			s.modify(w, r, nameOrID)
+		case http.MethodDelete:
+			s.delete(w, r, nameOrID)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", r.Method)
+		}
+	case strings.HasPrefix(path, "/privilegeddata/secrets/") && r.Method == http.MethodGet:
+		s.retrieve(w, r, strings.TrimPrefix(path, "/privilegeddata/secrets/"))
+	default:
+		writeError(w, r, http.StatusNotFound, "Not found", "Unknown endpoint")
+	}
+}
+
+// serveToken issues an access token for the client credentials or resource owner grant
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, tokenError("invalid_request", "POST is required"))
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, tokenError("invalid_request", err.Error()))
+		return
+	}
+	var user, password string
+	var ok bool
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		user, password, ok = r.BasicAuth()
+	case "password":
+		user, password = r.PostForm.Get("username"), r.PostForm.Get("password")
+		ok = user != ""
+	default:
+		writeJSON(w, http.StatusBadRequest, tokenError("unsupported_grant_type", "Only client_credentials and password grants are supported"))
+		return
+	}
+
+	s.mu.Lock()
+	expected, found := s.users[user]
+	if !ok || !found || expected != password {
+		s.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, tokenError("invalid_client", "Invalid user name or password"))
+		return
+	}
+	lifetime := s.TokenLifetime
+	if lifetime <= 0 {
+		lifetime = DefaultTokenLifetime
+	}
+	token := newToken()
+	s.tokens[token] = time.Now().Add(lifetime)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(lifetime / time.Second),
+		"scope":        r.PostForm.Get("scope"),
+	})
+}
+
+// authorized checks whether the request has a valid bearer token
+func (s *Server) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry, ok := s.tokens[token]
+	if !ok {
+		return false
+	}
+	if !expiry.IsZero() && time.Now().After(expiry) {
+		delete(s.tokens, token)
+		return false
+	}
+	return true
+}
+
+// list returns a page of secrets that match the query parameters
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := s.PageSize
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeError(w, r, http.StatusUnprocessableEntity, "Invalid parameter", fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if v := query.Get("after"); v != "" {
+		var err error
+		if offset, err = decodeCursor(v); err != nil {
+			writeError(w, r, http.StatusUnprocessableEntity, "Invalid parameter", "Invalid value of after")
+			return
+		}
+	}
+	var orderBy []string
+	for _, v := range query["orderBy"] {
+		orderBy = append(orderBy, strings.Split(v, ",")...)
+	}
+
+	objects, err := s.store.Search(&memstore.Query{
+		Search:  query.Get("search"),
+		Filter:  query.Get("filter"),
+		OrderBy: orderBy,
+	})
+	if err != nil {
+		writeError(w, r, http.StatusUnprocessableEntity, "Invalid parameter", err.Error())
+		return
+	}
+
+	if offset > len(objects) {
+		offset = len(objects)
+	}
+	end := offset + limit
+	if end > len(objects) {
+		end = len(objects)
+	}
+	items := make([]map[string]interface{}, 0, end-offset)
+	for _, obj := range objects[offset:end] {
+		items = append(items, map[string]interface{}{"id": obj.ID, "name": obj.Path, "type": obj.Type})
+	}
+	result := map[string]interface{}{
+		"object":       "secrets",
+		"items":        items,
+		"next_url":     nil,
+		"previous_url": nil,
+	}
+	if end < len(objects) {
+		result["next_url"] = pageURL(r, end)
+	}
+	if offset > 0 {
+		previous := offset - limit
+		if previous < 0 {
+			previous = 0
+		}
+		result["previous_url"] = pageURL(r, previous)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// create creates a secret or folder
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	name, _ := body["name"].(string)
+	obj, err := bodyObject(body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Bad request", err.Error())
+		return
+	}
+	if strings.Trim(name, "/") == "" {
+		// PAS reports a missing name as a server error
+		writeError(w, r, http.StatusInternalServerError, "A set must have a name", "name is required")
+		return
+	}
+
+	created, err := s.store.Create(name, obj)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusCreated, dense(created, nil))
+	case errors.Is(err, memstore.ErrExists):
+		writeError(w, r, http.StatusConflict, "Conflict", fmt.Sprintf("[%s] already exists", name))
+	case errors.Is(err, memstore.ErrBadPath), errors.Is(err, memstore.ErrNotFolder):
+		writeError(w, r, http.StatusBadRequest, "Invalid name", err.Error())
+	default:
+		writeError(w, r, http.StatusBadRequest, "Bad request", err.Error())
+	}
+}
+
+// get returns a secret or folder.  The items of a folder are included.
+func (s *Server) get(w http.ResponseWriter, r *http.Request, nameOrID string) {
+	if strings.Trim(nameOrID, "/") == "" {
+		children, _ := s.store.List("")
+		writeJSON(w, http.StatusOK, dense(&memstore.Object{Type: memstore.TypeFolder}, children))
+		return
+	}
+	obj, ok := s.lookup(w, r, nameOrID)
+	if !ok {
+		return
+	}
+	var children []*memstore.Object
+	if obj.Type == memstore.TypeFolder {
+		children, _ = s.store.List(obj.Path)
+		if children == nil {
+			children = []*memstore.Object{}
+		}
+	}
+	writeJSON(w, http.StatusOK, dense(obj, children))
+}
+
+// modify modifies the value and description of a secret.  If the request has a name that is different
+// from the path of the secret or folder, it is moved to the new path first.  A request that only has the
+// type and the new name renames a secret or folder.  A request without the value modifies the description
+// of a secret or folder.
+func (s *Server) modify(w http.ResponseWriter, r *http.Request, nameOrID string) {
+	obj, ok := s.lookup(w, r, nameOrID)
+	if !ok {
+		return
+	}
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	if name, _ := body["name"].(string); name != "" && strings.Trim(name, "/") != obj.Path {
+		if obj, ok = s.rename(w, r, obj, body, name); !ok {
+			return
+		}
+		if _, ok := body["data"]; !ok {
+			writeJSON(w, http.StatusOK, dense(obj, nil))
+			return
+		}
+	}
+	if _, ok := body["data"]; !ok && (obj.Type == memstore.TypeFolder || !s.ValueRequired) {
+		s.modifyDescription(w, r, obj, body)
+		return
+	}
+	update, err := bodyObject(body)
+	if err != nil || update.Type == memstore.TypeFolder {
+		writeError(w, r, http.StatusBadRequest, "Bad request", fmt.Sprintf("Invalid secret: %v", err))
+		return
+	}
+
+	modified, err := s.store.Modify(obj.Path, update)
+	switch err {
+	case nil:
+		writeJSON(w, http.StatusOK, dense(modified, nil))
+	case memstore.ErrTypeChanged:
+		writeError(w, r, http.StatusConflict, "Conflict", "Type of secret cannot be changed")
+	default:
+		// PAS does not find a secret of the specified type for folders
+		writeError(w, r, http.StatusNotFound, "Not found", fmt.Sprintf("Secret [%s] not found", nameOrID))
+	}
+}
+
+// modifyDescription modifies the description of the secret or folder 'obj' in a modify request without
+// the value
+func (s *Server) modifyDescription(w http.ResponseWriter, r *http.Request, obj *memstore.Object, body map[string]interface{}) {
+	if t, _ := body["type"].(string); t != obj.Type {
+		writeError(w, r, http.StatusConflict, "Conflict", "Type of secret cannot be changed")
+		return
+	}
+	description, _ := body["description"].(string)
+	modified, err := s.store.ModifyMetaData(obj.Path, description, nil)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, "Not found", fmt.Sprintf("Secret [%s] not found", obj.Path))
+		return
+	}
+	writeJSON(w, http.StatusOK, dense(modified, nil))
+}
+
+// rename moves the secret or folder 'obj' to the path 'name' in a modify request.  If it fails, an error
+// is returned to the client and false is returned.
+func (s *Server) rename(w http.ResponseWriter, r *http.Request, obj *memstore.Object, body map[string]interface{}, name string) (*memstore.Object, bool) {
+	if s.NoRename {
+		writeError(w, r, http.StatusNotImplemented, "Not implemented", "Name cannot be modified")
+		return nil, false
+	}
+	if t, _ := body["type"].(string); t != obj.Type {
+		writeError(w, r, http.StatusConflict, "Conflict", "Type of secret cannot be changed")
+		return nil, false
+	}
+	if i := strings.LastIndex(strings.Trim(name, "/"), "/"); i >= 0 {
+		parent := strings.Trim(name, "/")[:i]
+		if _, err := s.store.Get(parent); err != nil {
+			// PAS does not create parent folders when a secret is renamed
+			writeError(w, r, http.StatusNotFound, "Not found", fmt.Sprintf("Folder [%s] not found", parent))
+			return nil, false
+		}
+	}
+	moved, err := s.store.Move(obj.Path, name)
+	switch {
+	case err == nil:
+		return moved, true
+	case errors.Is(err, memstore.ErrExists):
+		writeError(w, r, http.StatusConflict, "Conflict", fmt.Sprintf("[%s] already exists", name))
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid name", err.Error())
+	}
+	return nil, false
+}
+
+// delete deletes a secret or empty folder
+func (s *Server) delete(w http.ResponseWriter, r *http.Request, nameOrID string) {
+	obj, ok := s.lookup(w, r, nameOrID)
+	if !ok {
+		return
+	}
+	switch s.store.Delete(obj.Path) {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case memstore.ErrFolderNotEmpty:
+		writeError(w, r, http.StatusConflict, "Conflict", fmt.Sprintf("Folder [%s] is not empty", obj.Path))
+	default:
+		writeError(w, r, http.StatusNotFound, "Not found", fmt.Sprintf("Secret [%s] not found", nameOrID))
+	}
+}
+
+// retrieve returns the privileged data of a secret
+func (s *Server) retrieve(w http.ResponseWriter, r *http.Request, nameOrID string) {
+	obj, ok := s.lookup(w, r, nameOrID)
+	if !ok {
+		return
+	}
+	switch obj.Type {
+	case memstore.TypeText:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"type": obj.Type, "data": obj.Text})
+	case memstore.TypeKeyValue:
+		data := obj.KeyValue
+		if data == nil {
+			data = map[string]string{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"type": obj.Type, "data": data})
+	default:
+		writeError(w, r, http.StatusNotFound, "Not found", "Folders do not have privileged data")
+	}
+}
+
+// lookup returns the object with the path or ID 'nameOrID'.  If it is not found, an error is returned
+// to the client and false is returned.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request, nameOrID string) (*memstore.Object, bool) {
+	obj, err := s.store.Get(nameOrID)
+	if err != nil {
+		obj, err = s.store.GetByID(nameOrID)
+	}
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, "Not found", fmt.Sprintf("Secret [%s] not found", nameOrID))
+		return nil, false
+	}
+	return obj, true
+}
+
+// readBody decodes the JSON object in the request body.  If it fails, an error is returned to the
+// client and false is returned.
+func readBody(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body == nil {
+		writeError(w, r, http.StatusBadRequest, "Bad request", "Request body must be a JSON object")
+		return nil, false
+	}
+	return body, true
+}
+
+// bodyObject returns the type, value and description in a create or modify request
+func bodyObject(body map[string]interface{}) (*memstore.Object, error) {
+	obj := &memstore.Object{}
+	obj.Type, _ = body["type"].(string)
+	obj.Description, _ = body["description"].(string)
+	switch obj.Type {
+	case memstore.TypeFolder:
+	case memstore.TypeText:
+		text, ok := body["data"].(string)
+		if !ok {
+			return nil, errors.New("data of text secret must be a string")
+		}
+		obj.Text = text
+	case memstore.TypeKeyValue:
+		data, ok := body["data"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("data of keyvalue secret must be an object")
+		}
+		obj.KeyValue = make(map[string]string, len(data))
+		for k, v := range data {
+			str, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("value of key [%s] must be a string", k)
+			}
+			obj.KeyValue[k] = str
+		}
+	default:
+		return nil, fmt.Errorf("invalid secret type [%s]", obj.Type)
+	}
+	return obj, nil
+}
+
+// dense returns the JSON representation of a secret or folder.  The items of a folder are included
+// if 'children' is not nil.
+func dense(obj *memstore.Object, children []*memstore.Object) map[string]interface{} {
+	result := map[string]interface{}{
+		"name": obj.Path,
+		"type": obj.Type,
+	}
+	if obj.ID != "" {
+		result["meta"] = meta(obj)
+	}
+	if obj.Description != "" {
+		result["description"] = obj.Description
+	}
+	if children != nil {
+		items := make([]map[string]interface{}, len(children))
+		for i, child := range children {
+			items[i] = map[string]interface{}{"name": child.Name, "type": child.Type, "meta": meta(child)}
+		}
+		result["items"] = items
+	}
+	return result
+}
+
+// meta returns the metadata of an object
+func meta(obj *memstore.Object) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       obj.ID,
+		"crn":      obj.CRN,
+		"created":  obj.Created.Format(time.RFC3339Nano),
+		"modified": obj.Modified.Format(time.RFC3339Nano),
+	}
+}
+
+// pageURL returns the absolute URL of the page that starts at 'offset', with the same query parameters
+// as the request
+func pageURL(r *http.Request, offset int) string {
+	query := r.URL.Query()
+	query.Set("after", encodeCursor(offset))
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// encodeCursor returns an opaque cursor for the position 'offset' in a list
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the position in a list from a cursor returned by encodeCursor
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), "offset:") {
+		return 0, errors.New("invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(b), "offset:"))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
+
+// writeJSON writes 'body' as a JSON response with 'status'
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes an error response in the format of PAS
+func writeError(w http.ResponseWriter, r *http.Request, status int, title string, detail string) {
+	writeJSON(w, status, map[string]interface{}{
+		"type":     "about:blank",
+		"title":    title,
+		"status":   status,
+		"detail":   detail,
+		"instance": r.URL.Path,
+	})
+}
+
+// tokenError returns the error response of the OAuth token endpoint
+func tokenError(code string, description string) map[string]string {
+	return map[string]string{"error": code, "error_description": description}
+}
+
+// newToken returns a random access token
+func newToken() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// newTxID returns a random transaction ID
+func newTxID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
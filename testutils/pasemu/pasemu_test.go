@@ -0,0 +1,426 @@
+package pasemu
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrify/cloud-golang-sdk/oauth"
+	"github.com/centrify/platform-go-sdk/secret"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/oauth2"
+)
+
+// pasemu test DOES NOT need to parse command line argument
+// do this here to avoid errors if -config is passed in
+// Anyway we need to declare them here so that go test will not complain
+var (
+	configPtr      = flag.String("config", "", "configuration file")
+	configString   = flag.String("config-string", "", "configuration string")
+	VaultRootToken = flag.String("vault-root-token", "root", "Vault root token")
+)
+
+const testToken = "test-token"
+
+// PASEmulatorTestSuite tests the emulator with PASSecretClient in the secret package
+type PASEmulatorTestSuite struct {
+	suite.Suite
+	emulator *Server
+	server   *httptest.Server
+	handle   secret.Secret
+}
+
+func TestPASEmulatorTestSuite(t *testing.T) {
+	suite.Run(t, &PASEmulatorTestSuite{})
+}
+
+func (s *PASEmulatorTestSuite) SetupTest() {
+	s.emulator = NewServer()
+	s.emulator.AddToken(testToken)
+	s.emulator.AddUser("user@example.com", "password")
+	s.server = httptest.NewTLSServer(s.emulator)
+	s.handle = s.newClient(testToken)
+}
+
+func (s *PASEmulatorTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// newClient returns a PAS secret client that accesses the emulator with 'token'
+func (s *PASEmulatorTestSuite) newClient(token string) secret.Secret {
+	handle, err := secret.NewSecretClient(s.server.URL, secret.ServerPAS, token, s.server.Client)
+	s.Require().NoError(err)
+	return handle
+}
+
+// request sends a request to the emulator with the test token and returns the response and decoded body
+func (s *PASEmulatorTestSuite) request(method string, url string) (*http.Response, map[string]interface{}) {
+	req, err := http.NewRequest(method, url, nil)
+	s.Require().NoError(err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := s.server.Client().Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp, body
+}
+
+func (s *PASEmulatorTestSuite) TestSecrets() {
+	success, id, r, err := s.handle.Create("app/db", "database", "password")
+	s.Require().NoError(err, "Should create text secret")
+	s.Assert().True(success)
+	s.Assert().Equal(201, r.StatusCode)
+	_, _, _, err = s.handle.Create("app/api", "", map[string]string{"key": "value"})
+	s.Require().NoError(err, "Should create keyvalue secret")
+
+	value, r, err := s.handle.Get("app/db")
+	s.Require().NoError(err)
+	s.Assert().Equal(200, r.StatusCode)
+	s.Assert().Equal("password", value)
+	value, _, err = s.handle.Get("app/api")
+	s.Require().NoError(err)
+	s.Assert().Equal(map[string]string{"key": "value"}, value)
+
+	metadata, _, err := s.handle.GetMetaData("app/db")
+	s.Require().NoError(err)
+	s.Assert().Equal(id, metadata.ID)
+	s.Assert().Equal("database", metadata.Description)
+	s.Assert().NotEmpty(metadata.CRN)
+	s.Assert().False(metadata.WhenCreated.IsZero())
+
+	items, r, err := s.handle.List("app")
+	s.Require().NoError(err)
+	s.Assert().Equal(200, r.StatusCode)
+	s.Require().Len(items, 2)
+	s.Assert().Equal(secret.Item{Name: "db", Type: secret.SecretTypeText, ID: id}, items[1])
+	items, _, err = s.handle.List("/")
+	s.Require().NoError(err, "Should list top level folder")
+	s.Assert().Len(items, 1)
+	_, _, err = s.handle.List("app/db")
+	s.Assert().ErrorIs(err, secret.ErrNotSecretFolder)
+
+	_, modifiedID, r, err := s.handle.Modify("app/db", "", "new password")
+	s.Require().NoError(err)
+	s.Assert().Equal(id, modifiedID)
+	s.Assert().Equal(200, r.StatusCode)
+	value, _, _ = s.handle.Get(id)
+	s.Assert().Equal("new password", value, "Secret can be retrieved by ID")
+
+	_, err = s.handle.Delete("app")
+	s.Assert().ErrorIs(err, secret.ErrFolderNotEmpty)
+	_, err = s.handle.Delete("app/db")
+	s.Require().NoError(err)
+	_, _, err = s.handle.Get("app/db")
+	s.Assert().ErrorIs(err, secret.ErrSecretNotFound)
+}
+
+func (s *PASEmulatorTestSuite) TestMove() {
+	_, folderID, _, _ := s.handle.CreateFolder("app", "application")
+	_, secretID, _, _ := s.handle.Create("app/db", "database", "password")
+
+	id, r, err := s.handle.Rename("app", "service")
+	s.Require().NoError(err, "Should rename folder")
+	s.Assert().Equal(folderID, id, "Folder should be renamed in PATCH request")
+	s.Assert().Equal(200, r.StatusCode)
+	metadata, _, err := s.handle.GetMetaData("service/db")
+	s.Require().NoError(err, "Contents should be moved with folder")
+	s.Assert().Equal(secretID, metadata.ID)
+
+	id, _, err = s.handle.Move("service/db", "other/db")
+	s.Require().NoError(err, "Should move secret into missing folder")
+	s.Assert().Equal(secretID, id)
+	id, _, err = s.handle.Move("other/db", "a/b/c/db")
+	s.Require().NoError(err, "Should create missing parent folders")
+	s.Assert().Equal(secretID, id)
+	metadata, _, _ = s.handle.GetMetaData("a/b")
+	s.Assert().Equal(secret.SecretTypeFolder, strings.ToLower(metadata.Type))
+	s.handle.Move("a/b/c/db", "other/db")
+	_, _, err = s.handle.Move("service", "other/db")
+	s.Assert().ErrorIs(err, secret.ErrExists)
+	_, _, err = s.handle.Move("missing", "found")
+	s.Assert().ErrorIs(err, secret.ErrSecretNotFound)
+
+	// PAS that cannot rename secrets
+	s.emulator.NoRename = true
+	id, _, err = s.handle.Move("other", "service/other")
+	s.Require().NoError(err, "Should move folder by copying it")
+	s.Assert().NotEqual(secretID, id)
+	value, _, err := s.handle.Get("service/other/db")
+	s.Require().NoError(err)
+	s.Assert().Equal("password", value)
+	metadata, _, _ = s.handle.GetMetaData("service/other/db")
+	s.Assert().Equal("database", metadata.Description)
+	_, _, err = s.handle.GetMetaData("other")
+	s.Assert().ErrorIs(err, secret.ErrSecretNotFound, "Source should be deleted")
+}
+
+func (s *PASEmulatorTestSuite) TestMoveRejected() {
+	// PAS that rejects the name in PATCH requests
+	reject := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reject && r.Method == http.MethodPatch {
+			writeError(w, r, http.StatusBadRequest, "Bad request", "Name is not valid")
+			return
+		}
+		s.emulator.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	handle, err := secret.NewSecretClient(server.URL, secret.ServerPAS, testToken, server.Client)
+	s.Require().NoError(err)
+	_, secretID, _, _ := handle.Create("app/db", "database", "password")
+
+	_, _, err = handle.Move("app/db", "a/b/db")
+	s.Assert().ErrorIs(err, secret.ErrBadPathName, "Rejected name should be returned")
+	_, _, err = handle.GetMetaData("a")
+	s.Assert().ErrorIs(err, secret.ErrSecretNotFound, "Created parent folders should be deleted")
+	metadata, _, err := handle.GetMetaData("app/db")
+	s.Require().NoError(err, "Source should not be moved by copying it")
+	s.Assert().Equal(secretID, metadata.ID)
+
+	reject = false
+	id, _, err := handle.Move("app/db", "a/b/db")
+	s.Require().NoError(err, "Rejected name should not disable renaming")
+	s.Assert().Equal(secretID, id)
+}
+
+func (s *PASEmulatorTestSuite) TestPathOfIDShadowedByName() {
+	_, id, _, _ := s.handle.Create("app/db", "", "password")
+	s.handle.Create(id, "", "other")
+
+	path, _, err := s.handle.PathOfID(id)
+	s.Require().NoError(err, "ID should be found when a secret is named after it")
+	s.Assert().Equal("app/db", path)
+	value, _, err := secret.GetRef(s.handle, secret.ByID(id))
+	s.Require().NoError(err)
+	s.Assert().Equal("password", value)
+
+	s.handle.Delete("app/db")
+	_, _, err = s.handle.PathOfID(id)
+	s.Assert().ErrorIs(err, secret.ErrSecretNotFound)
+}
+
+func (s *PASEmulatorTestSuite) TestModifyMetaData() {
+	s.handle.CreateFolder("app", "application")
+	s.handle.Create("app/db", "database", "password")
+
+	r, err := s.handle.ModifyMetaData("app/db", "primary database", nil)
+	s.Require().NoError(err, "Should modify description without value")
+	s.Assert().Equal(200, r.StatusCode)
+	metadata, _, _ := s.handle.GetMetaData("app/db")
+	s.Assert().Equal("primary database", metadata.Description)
+	value, _, _ := s.handle.Get("app/db")
+	s.Assert().Equal("password", value, "Value should not be changed")
+
+	_, err = s.handle.ModifyMetaData("app", "services", nil)
+	s.Require().NoError(err, "Should modify description of folder")
+	metadata, _, _ = s.handle.GetMetaData("app")
+	s.Assert().Equal("services", metadata.Description)
+
+	// PAS that needs the value in modify requests
+	s.emulator.ValueRequired = true
+	_, err = s.handle.ModifyMetaData("app/db", "replica", nil)
+	s.Require().NoError(err, "Should send current value with description")
+	metadata, _, _ = s.handle.GetMetaData("app/db")
+	s.Assert().Equal("replica", metadata.Description)
+	value, _, _ = s.handle.Get("app/db")
+	s.Assert().Equal("password", value)
+
+	_, err = s.handle.ModifyMetaData("app/db", "", map[string]string{"owner": "ops"})
+	s.Assert().ErrorIs(err, secret.ErrMetaDataNotSupported)
+	_, err = s.handle.ModifyMetaData("missing", "description", nil)
+	s.Assert().ErrorIs(err, secret.ErrSecretNotFound)
+}
+
+func (s *PASEmulatorTestSuite) TestErrors() {
+	_, _, _, err := s.handle.CreateFolder("folder", "")
+	s.Require().NoError(err)
+	s.handle.Create("folder/text", "", "value")
+
+	_, _, r, err := s.handle.Create("folder/text", "", "value")
+	s.Assert().ErrorIs(err, secret.ErrExists)
+	s.Assert().Equal(409, r.StatusCode)
+	_, _, _, err = s.handle.CreateFolder("folder/", "")
+	s.Assert().ErrorIs(err, secret.ErrExists)
+	for _, name := range []string{"&#", "abc<def", "   ", "  /empty_parent", "text/child"} {
+		_, _, _, err = s.handle.Create("folder/"+name, "", "value")
+		s.Assert().ErrorIs(err, secret.ErrBadPathName, "Path [%s] should be invalid", name)
+	}
+	_, _, _, err = s.handle.CreateFolder("/", "")
+	s.Assert().ErrorIs(err, secret.ErrBadPathName, "Name is required")
+
+	_, _, _, err = s.handle.Modify("folder/text", "", map[string]string{"k": "v"})
+	s.Assert().ErrorIs(err, secret.ErrCannotModifySecretType)
+	_, _, _, err = s.handle.Modify("folder", "", "value")
+	s.Assert().ErrorIs(err, secret.ErrCannotModifySecretFolder)
+	_, _, r, err = s.handle.Modify("folder/missing", "", "value")
+	s.Assert().ErrorIs(err, secret.ErrSecretNotFound)
+	s.Assert().Equal(404, r.StatusCode)
+	r, err = s.handle.Delete("folder/missing")
+	s.Assert().ErrorIs(err, secret.ErrSecretNotFound)
+	s.Assert().Equal(404, r.StatusCode)
+	_, _, err = s.handle.List("missing")
+	s.Assert().ErrorIs(err, secret.ErrFolderNotFound)
+
+	resp, body := s.request(http.MethodGet, s.server.URL+"/api/v1.0/secrets/missing")
+	s.Assert().Equal(404, resp.StatusCode)
+	s.Assert().Equal("Not found", body["title"], "Error should have title")
+	s.Assert().NotEmpty(resp.Header.Get("X-CFY-TX-ID"))
+
+	_, _, err = s.handle.Get("missing")
+	var secretErr *secret.Error
+	s.Require().True(errors.As(err, &secretErr), "Should return secret.Error")
+	s.Assert().Equal(404, secretErr.StatusCode)
+	s.Assert().Equal("Not found", secretErr.Title)
+	s.Assert().NotEmpty(secretErr.TxID)
+}
+
+func (s *PASEmulatorTestSuite) TestListSecrets() {
+	s.emulator.PageSize = 2
+	for _, path := range []string{"a/one", "a/two", "b/three", "four", "five"} {
+		_, _, _, err := s.handle.Create(path, "", "value")
+		s.Require().NoError(err)
+	}
+	s.handle.Create("a/bag", "", map[string]string{})
+
+	var names []string
+	listFn := func(item secret.Item) error {
+		names = append(names, item.Name)
+		return nil
+	}
+	_, err := s.handle.ListSecrets(nil, listFn)
+	s.Require().NoError(err, "Should list all pages")
+	s.Assert().Equal([]string{"a/bag", "a/one", "a/two", "b/three", "five", "four"}, names)
+
+	names = nil
+	_, err = s.handle.ListSecrets(&secret.ListOptions{
+		Limit:   1,
+		Filter:  "type eq 'text' and name sw 'a/'",
+		OrderBy: []string{"name desc"},
+	}, listFn)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"a/two", "a/one"}, names)
+
+	names = nil
+	_, err = s.handle.ListSecrets(&secret.ListOptions{Search: "F"}, listFn)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"five", "four"}, names)
+
+	r, err := s.handle.ListSecrets(&secret.ListOptions{Filter: "size gt 1"}, listFn)
+	s.Assert().ErrorIs(err, secret.ErrInvalidListOption)
+	s.Assert().Equal(422, r.StatusCode)
+
+	resp, body := s.request(http.MethodGet, s.server.URL+"/api/v1.0/secrets")
+	s.Require().Equal(200, resp.StatusCode)
+	next, _ := body["next_url"].(string)
+	s.Assert().True(strings.HasPrefix(next, s.server.URL+"/api/v1.0/secrets?"), "next_url [%s] should be absolute", next)
+	s.Assert().Nil(body["previous_url"])
+	_, body = s.request(http.MethodGet, next)
+	s.Assert().NotNil(body["previous_url"])
+}
+
+func (s *PASEmulatorTestSuite) TestAuthentication() {
+	handle := s.newClient("bad token")
+	_, _, err := handle.Get("anything")
+	s.Assert().ErrorIs(err, secret.ErrNoRetrievePermission)
+	_, _, _, err = handle.Create("anything", "", "value")
+	s.Assert().ErrorIs(err, secret.ErrNoCreatePermission)
+
+	host := strings.TrimPrefix(s.server.URL, "https://")
+	client, err := oauth.GetNewConfidentialClient("https://"+host, "user@example.com", "password", s.server.Client)
+	s.Require().NoError(err)
+	token, oauthErr, err := client.ClientCredentials("app", "scope")
+	s.Require().NoError(err)
+	s.Require().Nil(oauthErr)
+	s.Assert().Equal(int(DefaultTokenLifetime/time.Second), token.ExpiresIn)
+	_, _, _, err = s.newClient(token.AccessToken).Create("created", "", "value")
+	s.Assert().NoError(err, "Should accept issued token")
+
+	client, _ = oauth.GetNewConfidentialClient("https://"+host, "user@example.com", "wrong", s.server.Client)
+	_, oauthErr, err = client.ClientCredentials("app", "scope")
+	s.Require().NoError(err)
+	s.Require().NotNil(oauthErr, "Should reject wrong password")
+	s.Assert().Equal("invalid_client", oauthErr.Error)
+
+	client, _ = oauth.GetNewClient("https://"+host, s.server.Client)
+	token, oauthErr, err = client.ResourceOwner("app", "scope", "user@example.com", "password")
+	s.Require().NoError(err)
+	s.Require().Nil(oauthErr, "Should issue token for resource owner grant")
+
+	s.emulator.RevokeToken(token.AccessToken)
+	_, _, err = s.newClient(token.AccessToken).Get("created")
+	s.Assert().ErrorIs(err, secret.ErrNoRetrievePermission, "Should reject revoked token")
+}
+
+func (s *PASEmulatorTestSuite) TestTokenExpiry() {
+	s.emulator.TokenLifetime = time.Millisecond
+	client, _ := oauth.GetNewConfidentialClient(s.server.URL, "user@example.com", "password", s.server.Client)
+	token, _, err := client.ClientCredentials("app", "scope")
+	s.Require().NoError(err)
+	time.Sleep(10 * time.Millisecond)
+	_, _, err = s.newClient(token.AccessToken).Get("anything")
+	s.Assert().ErrorIs(err, secret.ErrNoRetrievePermission, "Should reject expired token")
+}
+
+// clientCredentials is a token source that gets tokens from the emulator with client credentials
+type clientCredentials struct {
+	client *oauth.OauthClient
+	issued int
+}
+
+func (c *clientCredentials) Token() (*oauth2.Token, error) {
+	token, _, err := c.client.ClientCredentials("app", "scope")
+	if err != nil {
+		return nil, err
+	}
+	c.issued++
+	return &oauth2.Token{AccessToken: token.AccessToken, Expiry: time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)}, nil
+}
+
+func (s *PASEmulatorTestSuite) TestTokenSource() {
+	s.emulator.TokenLifetime = 50 * time.Millisecond
+	client, _ := oauth.GetNewConfidentialClient(s.server.URL, "user@example.com", "password", s.server.Client)
+	source := &clientCredentials{client: client}
+	handle, err := secret.NewSecretClient(s.server.URL, secret.ServerPAS, "", s.server.Client, secret.WithTokenSource(source))
+	s.Require().NoError(err)
+
+	_, _, _, err = handle.Create("app/db", "", "password")
+	s.Require().NoError(err)
+	time.Sleep(100 * time.Millisecond)
+	value, _, err := handle.Get("app/db")
+	s.Require().NoError(err, "Should get new token when token expires")
+	s.Assert().Equal("password", value)
+	s.Assert().Equal(2, source.issued)
+}
+
+func (s *PASEmulatorTestSuite) TestHealth() {
+	resp, err := s.server.Client().Get(s.server.URL + "/health/ping")
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Assert().Equal(200, resp.StatusCode)
+}
+
+func (s *PASEmulatorTestSuite) TestNewCertificate() {
+	cert, certPEM, err := NewCertificate("localhost", "127.0.0.1")
+	s.Require().NoError(err)
+	s.Assert().Contains(string(certPEM), "BEGIN CERTIFICATE")
+
+	server := httptest.NewUnstartedServer(s.emulator)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	s.Require().True(pool.AppendCertsFromPEM(certPEM))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get(server.URL + "/health/ping")
+	s.Require().NoError(err, "Client should trust the certificate")
+	resp.Body.Close()
+}